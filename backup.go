@@ -0,0 +1,331 @@
+package photonsr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// --- Backup Naming and Conflict Handling ---
+
+// backupSuffix is appended to a file's path to form its backup path.
+const backupSuffix = ".bak"
+
+// Backup conflict policies, applied when a file's .bak already exists.
+const (
+	BackupPolicyOverwrite = "overwrite" // Replace the existing backup (historical behavior).
+	BackupPolicySkip      = "skip"      // Keep the existing backup and do not create a new one.
+	BackupPolicyVersion   = "version"   // Move the existing backup to <file>.bak.N, then create a new one.
+	BackupPolicyAsk       = "ask"       // Ask the caller (ReplaceOptions.ResolveBackupConflict) per file.
+)
+
+// validBackupPolicy reports whether policy is a known backup conflict policy.
+// The empty string is accepted and means BackupPolicyOverwrite.
+func validBackupPolicy(policy string) bool {
+	switch policy {
+	case "", BackupPolicyOverwrite, BackupPolicySkip, BackupPolicyVersion, BackupPolicyAsk:
+		return true
+	}
+	return false
+}
+
+// BackupPathFor returns the primary backup path of srcPath.
+func BackupPathFor(srcPath string) string {
+	return srcPath + backupSuffix
+}
+
+// versionedBackupPath returns the path of the n-th archived backup of srcPath.
+func versionedBackupPath(srcPath string, n int) string {
+	return fmt.Sprintf("%s%s.%d", srcPath, backupSuffix, n)
+}
+
+// ParseBackupName splits a backup file name into the original name and its version.
+// "a.txt.bak" yields ("a.txt", 0, true); "a.txt.bak.3" yields ("a.txt", 3, true).
+func ParseBackupName(name string) (original string, version int, ok bool) {
+	if strings.HasSuffix(name, backupSuffix) {
+		return strings.TrimSuffix(name, backupSuffix), 0, true
+	}
+	idx := strings.LastIndex(name, backupSuffix+".")
+	if idx <= 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(name[idx+len(backupSuffix)+1:])
+	if err != nil || n <= 0 {
+		return "", 0, false
+	}
+	return name[:idx], n, true
+}
+
+// backupOriginal returns the path of the file the backup at path was made of.
+func backupOriginal(path string) string {
+	dir, name := filepath.Split(path)
+	original, _, _ := ParseBackupName(name)
+	return dir + original
+}
+
+// newestBackups returns the backups at paths, with their infos, that are the newest
+// of their original: its current backup or, failing that, its highest-numbered
+// version. The traversal order is kept; infos may be nil.
+func newestBackups(paths []string, infos []os.FileInfo) ([]string, []os.FileInfo) {
+	rank := func(path string) int {
+		_, v, _ := ParseBackupName(filepath.Base(path))
+		if v == 0 {
+			return math.MaxInt // The current backup is newer than every archived one.
+		}
+		return v
+	}
+	newest := make(map[string]int, len(paths)) // Original → rank of its newest backup.
+	for _, path := range paths {
+		if original, r := backupOriginal(path), rank(path); r > newest[original] {
+			newest[original] = r
+		}
+	}
+	var keptPaths []string
+	var keptInfos []os.FileInfo
+	for i, path := range paths {
+		if rank(path) != newest[backupOriginal(path)] {
+			continue
+		}
+		keptPaths = append(keptPaths, path)
+		if infos != nil {
+			keptInfos = append(keptInfos, infos[i])
+		}
+	}
+	return keptPaths, keptInfos
+}
+
+// nextBackupVersion returns the lowest version number greater than every existing
+// archived backup of srcPath.
+func nextBackupVersion(srcPath string) (int, error) {
+	entries, err := os.ReadDir(filepath.Dir(srcPath))
+	if err != nil {
+		return 0, fmt.Errorf("listing backups of '%s': %w", srcPath, err)
+	}
+	dir, base := filepath.Dir(srcPath), filepath.Base(srcPath)
+	next := 1
+	for _, e := range entries {
+		original, v, ok := ParseBackupName(e.Name())
+		if ok && sameName(dir, original, base) && v >= next {
+			next = v + 1
+		}
+	}
+	return next, nil
+}
+
+// createBackupWithPolicy creates the backup of srcPath, resolving a conflict with an
+// existing backup according to policy. ask is consulted for BackupPolicyAsk and must
+// return one of the other policies. The returned resolution is empty when there was
+// no conflict, otherwise it describes what was done.
+func createBackupWithPolicy(srcPath, policy string, ask func(path string) string) (resolution string, err error) {
+	backupPath := BackupPathFor(srcPath)
+	renamed, err := matchBackupCase(srcPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if renamed != "" && resolution != "" {
+			resolution += fmt.Sprintf(" (renamed from %s, which differs only in case)", renamed)
+		}
+	}()
+	if _, err := os.Lstat(backupPath); os.IsNotExist(err) {
+		err = createBackup(srcPath)
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("checking existing backup '%s': %w", backupPath, err)
+	}
+
+	if policy == BackupPolicyAsk {
+		policy = BackupPolicySkip
+		if ask != nil {
+			policy = ask(srcPath)
+		}
+	}
+	switch policy {
+	case BackupPolicySkip:
+		return "kept existing backup", nil
+	case BackupPolicyVersion:
+		n, err := nextBackupVersion(srcPath)
+		if err != nil {
+			return "", err
+		}
+		archived := versionedBackupPath(srcPath, n)
+		if err := FS.Rename(backupPath, archived); err != nil {
+			return "", fmt.Errorf("archiving existing backup '%s' as '%s': %w", backupPath, archived, err)
+		}
+		err = createBackup(srcPath)
+		return fmt.Sprintf("archived existing backup as %s", filepath.Base(archived)), err
+	case "", BackupPolicyOverwrite:
+		err = createBackup(srcPath)
+		return "overwrote existing backup", err
+	default:
+		return "", fmt.Errorf("unknown backup conflict policy '%s'", policy)
+	}
+}
+
+// markBackupCurrent gives backupPath, a backup of srcPath, the same modification
+// time as srcPath itself. It is called right after PhotonSR rewrites srcPath, whether
+// the backup was just made or an existing one kept (BackupPolicySkip), and after a
+// restore for the archived backup that is then the newest, so that only a later
+// edit of srcPath is recognized by originalChangedSinceBackup.
+// Failures are ignored: the worst case is a spurious "newer" warning on restore.
+func markBackupCurrent(srcPath, backupPath string) {
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return
+	}
+	_ = FS.Chtimes(backupPath, info.ModTime(), info.ModTime())
+}
+
+// originalChangedSinceBackup reports whether restoring the backup at backupPath,
+// described by backupInfo, would discard later work on originalPath: the original
+// exists, was modified after the backup, and its content differs from the backup.
+func originalChangedSinceBackup(originalPath, backupPath string, backupInfo os.FileInfo) (bool, error) {
+	info, err := os.Stat(originalPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.ModTime().After(backupInfo.ModTime()) {
+		return false, nil
+	}
+	current, err := os.ReadFile(originalPath)
+	if err != nil {
+		return false, err
+	}
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(current, backup), nil
+}
+
+// FindBackupConflicts returns the files that PerformReplacement would back up with
+// opts but that already have a .bak file.
+func FindBackupConflicts(opts ReplaceOptions) ([]string, error) {
+	var conflicts []string
+	err := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			return nil
+		}
+		if IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || IsInternalEntry(info) {
+			return nil
+		}
+		matched, err := MatchesPattern(info.Name(), opts.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid file pattern '%s': %w", opts.Pattern, err)
+		}
+		if !matched || (opts.AllowedPaths != nil && !opts.AllowedPaths[CanonicalPath(path)]) {
+			return nil
+		}
+		if _, err := os.Lstat(BackupPathFor(path)); err == nil {
+			conflicts = append(conflicts, path)
+		}
+		return nil
+	})
+	return conflicts, err
+}
+
+// findBackups walks dir for backups, current and archived (see ParseBackupName), for the operation verb ("restore", "clean"
+// or "backup diff") reported by op.
+// Returns:
+//   - []string: The backup paths, in traversal order.
+//   - []os.FileInfo: Their file information.
+//   - error: The first path that could not be accessed, which is skipped.
+//   - error: A fatal error, e.g. when ctx is cancelled during the walk.
+func findBackups(ctx context.Context, dir, verb string, onWarning func(Warning), op string) ([]string, []os.FileInfo, error, error) {
+	var paths []string
+	var infos []os.FileInfo
+	var firstErr error
+	walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			accessErr := fmt.Errorf("accessing '%s' during %s: %w", path, verb, errInWalk)
+			if firstErr == nil {
+				firstErr = accessErr
+			}
+			warn(onWarning, op, "Access", accessErr, "Skipping")
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s interrupted while looking for backups: %w", verb, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if _, _, isBackup := ParseBackupName(info.Name()); !isBackup {
+			return nil
+		}
+		paths = append(paths, path)
+		infos = append(infos, info)
+		return nil
+	})
+	return paths, infos, firstErr, walkErr
+}
+
+// createBackup creates a backup copy of the source file.
+func createBackup(srcPath string) error {
+	return cloneOrCopyFile(srcPath, BackupPathFor(srcPath))
+}
+
+// CopyFile copies a file from src to dst, preserving permissions (and with
+// PreserveOwner the owner). The content is streamed, so large files are never
+// loaded into memory.
+func CopyFile(src, dst string) error {
+	input, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("reading source file '%s' for copy: %w", src, err)
+	}
+	defer input.Close()
+	info, err := input.Stat()
+	if err != nil {
+		return fmt.Errorf("getting file info for source '%s': %w", src, err)
+	}
+	err = WriteFileAtomicFrom(dst, info.Mode(), func(w io.Writer) error {
+		_, err := io.Copy(w, input)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return keepOwner(dst, info)
+}
+
+// cloneOrCopyFile is CopyFile, but makes dst a reflink clone of src where the file
+// system supports it (btrfs, XFS), which is instant and shares the data until
+// either file is written.
+func cloneOrCopyFile(src, dst string) error {
+	tmp, tmpPath, err := FS.CreateTemp(filepath.Dir(dst), tempFilePrefix+"*")
+	if err != nil {
+		return CopyFile(src, dst)
+	}
+	tmp.Close()
+	if err := CloneFile(src, tmpPath); err != nil {
+		FS.Remove(tmpPath)
+		return CopyFile(src, dst)
+	}
+	info, err := os.Stat(src)
+	if err == nil {
+		err = FS.Chmod(tmpPath, info.Mode())
+	}
+	if err == nil {
+		err = keepOwner(tmpPath, info)
+	}
+	if err == nil {
+		err = FS.Rename(tmpPath, dst)
+	}
+	if err != nil {
+		FS.Remove(tmpPath)
+		return CopyFile(src, dst)
+	}
+	return nil
+}
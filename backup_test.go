@@ -0,0 +1,188 @@
+package photonsr
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes content to path, failing t on error.
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setTestModTime sets the modification time of path, failing t on error.
+func setTestModTime(t *testing.T, path string, mtime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateBackupWithPolicy(t *testing.T) {
+	tests := []struct {
+		policy     string
+		resolution string
+		want       map[string]string // Content of the backups afterwards, by name.
+	}{
+		{BackupPolicySkip, "kept existing backup", map[string]string{"a.txt.bak": "old", "a.txt.bak.1": "older"}},
+		{BackupPolicyOverwrite, "overwrote existing backup", map[string]string{"a.txt.bak": "new", "a.txt.bak.1": "older"}},
+		{BackupPolicyVersion, "archived existing backup as a.txt.bak.2", map[string]string{"a.txt.bak": "new", "a.txt.bak.1": "older", "a.txt.bak.2": "old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "a.txt")
+			writeTestFile(t, file, "new")
+			writeTestFile(t, file+".bak", "old")
+			writeTestFile(t, file+".bak.1", "older")
+			resolution, err := createBackupWithPolicy(file, tt.policy, nil)
+			if err != nil || resolution != tt.resolution {
+				t.Fatalf("createBackupWithPolicy = %q, %v; want %q", resolution, err, tt.resolution)
+			}
+			for name, want := range tt.want {
+				if got := readTestFile(t, filepath.Join(dir, name)); got != want {
+					t.Errorf("%s is %q, want %q", name, got, want)
+				}
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != len(tt.want)+1 {
+				t.Errorf("the directory holds %d files, want %d", len(entries), len(tt.want)+1)
+			}
+		})
+	}
+}
+
+func TestCreateBackupWithPolicyNoConflict(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.txt")
+	writeTestFile(t, file, "new")
+	asked := false
+	resolution, err := createBackupWithPolicy(file, BackupPolicyAsk, func(string) string { asked = true; return BackupPolicySkip })
+	if err != nil || resolution != "" || asked {
+		t.Fatalf("createBackupWithPolicy = %q, %v (asked: %v); want no resolution and no question", resolution, err, asked)
+	}
+	if got := readTestFile(t, file+".bak"); got != "new" {
+		t.Errorf("the backup is %q, want \"new\"", got)
+	}
+}
+
+func TestCreateBackupWithPolicyAsk(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.txt")
+	writeTestFile(t, file, "new")
+	writeTestFile(t, file+".bak", "old")
+	var asked string
+	resolution, err := createBackupWithPolicy(file, BackupPolicyAsk, func(path string) string { asked = path; return BackupPolicyOverwrite })
+	if err != nil || resolution != "overwrote existing backup" || asked != file {
+		t.Fatalf("createBackupWithPolicy = %q, %v after asking about %q", resolution, err, asked)
+	}
+}
+
+func TestOriginalChangedSinceBackup(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		original string // "" for a missing original.
+		backup   string
+		newer    bool // Whether the original was modified after the backup.
+		want     bool
+	}{
+		{"missing original", "", "old", true, false},
+		{"not modified since", "new", "old", false, false},
+		{"modified to the backup's content", "old", "old", true, false},
+		{"modified", "new", "old", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file, backup := filepath.Join(dir, "a.txt"), filepath.Join(dir, "a.txt.bak.3")
+			writeTestFile(t, backup, tt.backup)
+			setTestModTime(t, backup, past)
+			if tt.original != "" {
+				writeTestFile(t, file, tt.original)
+				if !tt.newer {
+					setTestModTime(t, file, past)
+				}
+			}
+			info, err := os.Stat(backup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := originalChangedSinceBackup(file, backup, info); err != nil || got != tt.want {
+				t.Errorf("originalChangedSinceBackup = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkBackupCurrent(t *testing.T) {
+	dir := t.TempDir()
+	file, backup := filepath.Join(dir, "a.txt"), filepath.Join(dir, "a.txt.bak")
+	writeTestFile(t, file, "new")
+	writeTestFile(t, backup, "old")
+	setTestModTime(t, backup, time.Now().Add(-time.Hour))
+	markBackupCurrent(file, backup)
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupInfo, err := os.Stat(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !backupInfo.ModTime().Equal(fileInfo.ModTime()) {
+		t.Errorf("the backup was modified at %v, the file at %v", backupInfo.ModTime(), fileInfo.ModTime())
+	}
+	if changed, err := originalChangedSinceBackup(file, backup, backupInfo); err != nil || changed {
+		t.Errorf("originalChangedSinceBackup = %v, %v after markBackupCurrent; want false", changed, err)
+	}
+}
+
+func TestNewestBackups(t *testing.T) {
+	paths := []string{
+		filepath.Join("d", "a.txt.bak.1"),
+		filepath.Join("d", "a.txt.bak"),
+		filepath.Join("d", "a.txt.bak.2"),
+		filepath.Join("d", "b.txt.bak.1"),
+		filepath.Join("d", "b.txt.bak.10"),
+		filepath.Join("d", "b.txt.bak.2"),
+		filepath.Join("e", "a.txt.bak.1"),
+	}
+	want := []string{
+		filepath.Join("d", "a.txt.bak"),
+		filepath.Join("d", "b.txt.bak.10"),
+		filepath.Join("e", "a.txt.bak.1"),
+	}
+	if got, _ := newestBackups(paths, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("newestBackups = %v, want %v", got, want)
+	}
+}
+
+func TestPerformBackupDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	writeTestFile(t, path, "one\nnew\nthree\n")
+	writeTestFile(t, path+".bak", "one\nold\nthree\n")
+	writeTestFile(t, filepath.Join(dir, "b.txt.bak"), "gone\n")
+
+	messages, differing, err := PerformBackupDiff(BackupDiffOptions{Path: dir})
+	if err != nil || differing != 2 {
+		t.Fatalf("PerformBackupDiff = %d differing, %v; want 2, nil", differing, err)
+	}
+	want := []string{
+		"What restoring the backups would change (- current file, + backup):",
+		"  differs:  " + path + " (backup " + path + ".bak)",
+		"    @@ -1,3 +1,3 @@",
+		"     one",
+		"    -new",
+		"    +old",
+		"     three",
+		"  missing:  " + filepath.Join(dir, "b.txt") + " (restoring " + filepath.Join(dir, "b.txt.bak") + " recreates it)",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("PerformBackupDiff messages:\n%q\nwant\n%q", messages, want)
+	}
+}
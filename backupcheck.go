@@ -0,0 +1,163 @@
+package photonsr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// --- Backup Check ---
+
+// "photonsr backup-check [path]" lists the .bak backups that no longer protect a
+// change: orphaned backups, whose file is gone, and redundant backups, identical to
+// their file, so that restoring them would change nothing. Orphaned backups hold
+// the only copy of their file and are only deleted on request (-clean -orphans);
+// redundant ones can go (-clean -redundant, or r on the wizard's clean summary).
+// The path is a file, a backup or a directory, as for backup-diff.
+
+// Kinds of backups reported by "photonsr backup-check".
+const (
+	backupOrphaned  = "orphaned"  // The file of the backup is gone.
+	backupRedundant = "redundant" // The backup is identical to its file.
+)
+
+// BackupCheckOptions holds all parameters for PerformBackupCheck.
+type BackupCheckOptions struct {
+	Path string // File, backup or directory whose backups are checked.
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformBackupCheck from running with opts.
+func (opts BackupCheckOptions) Validate() error {
+	return validateBackupPath(opts.Path)
+}
+
+// PerformBackupCheck lists the orphaned and redundant backups of opts.Path.
+// Returns:
+//   - []string: The report.
+//   - int: The number of orphaned and redundant backups.
+//   - int: The number of redundant backups.
+//   - error: A fatal error or the first backup that could not be checked.
+func PerformBackupCheck(opts BackupCheckOptions) ([]string, int, int, error) {
+	return PerformBackupCheckCtx(context.Background(), opts)
+}
+
+// PerformBackupCheckCtx is PerformBackupCheck with cancellation: once ctx is done, no
+// further backup is checked and the returned error wraps ctx.Err().
+func PerformBackupCheckCtx(ctx context.Context, opts BackupCheckOptions) ([]string, int, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, 0, err
+	}
+	backups, firstEncounteredError, walkErr := backupsAt(ctx, opts.Path, "backup check", opts.OnWarning, "PerformBackupCheck")
+	if walkErr != nil {
+		return nil, 0, 0, walkErr
+	}
+	var orphaned, redundant []string
+	for i, backup := range backups {
+		if err := ctx.Err(); err != nil {
+			return nil, len(orphaned) + len(redundant), len(redundant), fmt.Errorf("backup check interrupted after %d of %d backup(s): %w", i, len(backups), err)
+		}
+		kind, err := classifyBackup(backup)
+		if err != nil {
+			if firstEncounteredError == nil {
+				firstEncounteredError = err
+			}
+			warn(opts.OnWarning, "PerformBackupCheck", "Compare", err, "Skipping")
+			continue
+		}
+		switch kind {
+		case backupOrphaned:
+			orphaned = append(orphaned, backup)
+		case backupRedundant:
+			redundant = append(redundant, backup)
+		}
+	}
+
+	var messages []string
+	if len(orphaned) > 0 {
+		messages = append(messages, "Orphaned backups (their file is gone; restoring recreates it):")
+		for _, path := range orphaned {
+			messages = append(messages, "  - "+path)
+		}
+	}
+	if len(redundant) > 0 {
+		messages = append(messages, "Redundant backups (identical to their file):")
+		for _, path := range redundant {
+			messages = append(messages, "  - "+path)
+		}
+	}
+	if len(backups) == 0 && firstEncounteredError == nil {
+		messages = append(messages, "No .bak files found to check in the specified directory.")
+	} else {
+		messages = append(messages, fmt.Sprintf("%d backup(s) checked: %d orphaned, %d redundant.", len(backups), len(orphaned), len(redundant)))
+	}
+	return messages, len(orphaned) + len(redundant), len(redundant), firstEncounteredError
+}
+
+// classifyBackup returns backupOrphaned or backupRedundant if the backup at path is
+// either, or "" if it still differs from its file or the file cannot be looked at.
+func classifyBackup(path string) (string, error) {
+	original := backupOriginal(path)
+	info, err := os.Lstat(original)
+	if os.IsNotExist(err) {
+		return backupOrphaned, nil
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		return "", nil
+	}
+	same, err := sameContent(original, path)
+	if err != nil {
+		return "", fmt.Errorf("comparing '%s' with its backup: %w", original, err)
+	}
+	if same {
+		return backupRedundant, nil
+	}
+	return "", nil
+}
+
+// sameContent reports whether the files at a and b hold the same bytes, reading
+// them side by side.
+func sameContent(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+	ia, err := fa.Stat()
+	if err != nil {
+		return false, err
+	}
+	ib, err := fb.Stat()
+	if err != nil {
+		return false, err
+	}
+	if ia.Size() != ib.Size() {
+		return false, nil
+	}
+	bufA, bufB := make([]byte, 64*1024), make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		switch {
+		case errA != nil && !doneA:
+			return false, errA
+		case errB != nil && !doneB:
+			return false, errB
+		case doneA || doneB:
+			return doneA && doneB, nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package photonsr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// --- Backup Diff ---
+
+// "photonsr backup-diff [path]" shows, before a restore or a clean, what restoring
+// the .bak backups would change: for each backup, the hunks of the unified diff
+// from its file as it is ("-") to the backup ("+"), as -diff prints them, and the
+// files that only their backup still holds. The path is a file (named by itself or by its backup) or a directory,
+// searched recursively; it defaults to -dir. Nothing is modified. The wizard shows
+// the same comparison when d is pressed before a restore or a clean.
+
+// BackupDiffOptions holds all parameters for PerformBackupDiff.
+type BackupDiffOptions struct {
+	Path string // File, backup or directory whose backups are compared.
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformBackupDiff from running with opts.
+func (opts BackupDiffOptions) Validate() error {
+	return validateBackupPath(opts.Path)
+}
+
+// validateBackupPath checks that path is a directory, or a file or backup whose
+// backup exists.
+func validateBackupPath(path string) error {
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		return ValidateDir(path)
+	}
+	if _, err := os.Stat(backupOf(path)); err != nil {
+		return fmt.Errorf("'%s' is neither a directory nor a file with a backup: %w", path, ErrInvalidOption)
+	}
+	return nil
+}
+
+// backupOf returns the backup path of path, which may name the backup itself.
+func backupOf(path string) string {
+	if _, _, isBackup := ParseBackupName(filepath.Base(path)); isBackup {
+		return path
+	}
+	return BackupPathFor(path)
+}
+
+// backupsAt returns the backups of path, a directory (see findBackups) or a file
+// or backup (see backupOf).
+func backupsAt(ctx context.Context, path, verb string, onWarning func(Warning), op string) ([]string, error, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		backups, _, firstErr, walkErr := findBackups(ctx, path, verb, onWarning, op)
+		return backups, firstErr, walkErr
+	}
+	return []string{backupOf(path)}, nil, nil
+}
+
+// PerformBackupDiff compares the backups of opts.Path with their files.
+// Returns:
+//   - []string: The changes restoring the backups would make.
+//   - int: The number of backups that differ from their file, or whose file is gone.
+//   - error: A fatal error or the first backup that could not be compared.
+func PerformBackupDiff(opts BackupDiffOptions) ([]string, int, error) {
+	return PerformBackupDiffCtx(context.Background(), opts)
+}
+
+// PerformBackupDiffCtx is PerformBackupDiff with cancellation: once ctx is done, no
+// further backup is compared and the returned error wraps ctx.Err().
+func PerformBackupDiffCtx(ctx context.Context, opts BackupDiffOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	backups, firstEncounteredError, walkErr := backupsAt(ctx, opts.Path, "backup diff", opts.OnWarning, "PerformBackupDiff")
+	if walkErr != nil {
+		return nil, 0, walkErr
+	}
+	backups, _ = newestBackups(backups, nil) // The backups a restore would use.
+
+	var changes []string
+	differing, identical := 0, 0
+	for i, backup := range backups {
+		if err := ctx.Err(); err != nil {
+			return changes, differing, fmt.Errorf("backup diff interrupted after %d of %d backup(s): %w", i, len(backups), err)
+		}
+		original := backupOriginal(backup)
+		backupData, err := os.ReadFile(backup)
+		if err != nil {
+			readErr := fmt.Errorf("reading backup '%s': %w", backup, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = readErr
+			}
+			warn(opts.OnWarning, "PerformBackupDiff", "Read", readErr, "Skipping")
+			continue
+		}
+		current, err := os.ReadFile(original)
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, fmt.Sprintf("  missing:  %s (restoring %s recreates it)", original, backup))
+			differing++
+		case err != nil:
+			readErr := fmt.Errorf("reading '%s': %w", original, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = readErr
+			}
+			warn(opts.OnWarning, "PerformBackupDiff", "Read", readErr, "Skipping")
+		case bytes.Equal(current, backupData):
+			identical++
+		default:
+			changes = append(changes, fmt.Sprintf("  differs:  %s (backup %s)", original, backup))
+			changes = append(changes, ChangedLines(current, backupData, 0)...)
+			differing++
+		}
+	}
+
+	var messages []string
+	if differing > 0 {
+		messages = append(messages, "What restoring the backups would change (- current file, + backup):")
+		messages = append(messages, changes...)
+	}
+	if identical > 0 {
+		messages = append(messages, fmt.Sprintf("%d backup(s) identical to their file.", identical))
+	}
+	if len(backups) == 0 && firstEncounteredError == nil {
+		messages = append(messages, "No .bak files found to compare in the specified directory.")
+	}
+	return messages, differing, firstEncounteredError
+}
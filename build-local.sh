@@ -14,7 +14,7 @@ LDFLAGS="-X 'main.version=$VERSION' \
          -X 'main.builtBy=$BUILT_BY'"
 
 echo "Building PhotonSR version $VERSION..."
-go build -ldflags="$LDFLAGS" -o photonsr ./cmd
+go build -ldflags="$LDFLAGS" -o photonsr ./cmd/photonsr
 echo "Build complete: ./photonsr"
 echo "Verifying version:"
 ./photonsr -version
@@ -0,0 +1,76 @@
+package photonsr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// --- Case-Insensitive File Systems ---
+
+// On case-insensitive file systems (the defaults of macOS and Windows) names that
+// differ only in case are the same entry. Left alone, that makes a new versioned
+// backup silently replace an archived one named in another case, and a copy of a
+// tree from a case-sensitive file system lose one of "foo.txt" and "FOO.txt". Such
+// collisions are detected: backup names are compared as the file system compares
+// them, an existing backup named in another case is renamed to the exact backup
+// name before it is used, and copying a tree fails on names it cannot keep apart.
+// -pattern stays case-sensitive everywhere, so a run selects the same files on
+// every system.
+
+var (
+	caseProbeMu sync.Mutex
+	caseProbes  = map[string]bool{} // Case-insensitivity by directory.
+)
+
+// caseInsensitiveDir reports whether names in dir are compared without regard to
+// case. The answer is probed once per directory with a temporary file; if the
+// probe fails, dir is assumed to be case-sensitive.
+func caseInsensitiveDir(dir string) bool {
+	caseProbeMu.Lock()
+	defer caseProbeMu.Unlock()
+	if insensitive, ok := caseProbes[dir]; ok {
+		return insensitive
+	}
+	insensitive := false
+	if f, name, err := FS.CreateTemp(dir, tempFilePrefix+"CaseProbe-*"); err == nil {
+		f.Close()
+		probe, statErr := os.Stat(name)
+		other, err := os.Stat(filepath.Join(dir, strings.ToLower(filepath.Base(name))))
+		insensitive = statErr == nil && err == nil && os.SameFile(probe, other)
+		FS.Remove(name)
+	}
+	caseProbes[dir] = insensitive
+	return insensitive
+}
+
+// sameName reports whether the names a and b denote the same entry of dir.
+func sameName(dir, a, b string) bool {
+	return a == b || strings.EqualFold(a, b) && caseInsensitiveDir(dir)
+}
+
+// matchBackupCase renames an entry that stands for the backup path of srcPath on a
+// case-insensitive file system but is named in another case, so that the backup
+// is always found and archived under its exact name. It returns the name the entry
+// had, or "" if nothing was renamed.
+func matchBackupCase(srcPath string) (string, error) {
+	dir, want := filepath.Dir(srcPath), filepath.Base(BackupPathFor(srcPath))
+	if !caseInsensitiveDir(dir) {
+		return "", nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("listing backups of '%s': %w", srcPath, err)
+	}
+	for _, e := range entries {
+		if name := e.Name(); name != want && strings.EqualFold(name, want) {
+			if err := FS.Rename(filepath.Join(dir, name), filepath.Join(dir, want)); err != nil {
+				return "", fmt.Errorf("renaming backup '%s' to '%s': %w", name, want, err)
+			}
+			return name, nil
+		}
+	}
+	return "", nil
+}
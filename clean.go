@@ -0,0 +1,152 @@
+package photonsr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// --- Clean ---
+
+// CleanOptions holds all parameters for the clean operation.
+type CleanOptions struct {
+	Dir         string        // Target directory for the operation.
+	OlderThan   time.Duration // If > 0, only delete backups last modified longer ago than this.
+	OrphansOnly bool          // Only delete backups whose original file no longer exists.
+
+	// RedundantOnly only deletes backups identical to their original file. With
+	// OrphansOnly, backups of either kind are deleted.
+	RedundantOnly bool
+
+	Jobs int // Number of backups deleted concurrently (values below 1 mean 1).
+
+	// OnProgress, if set, is called after each backup has been handled with the
+	// number handled so far and the number found. It is never called concurrently.
+	OnProgress func(done, total int)
+	// OnBackupDeleted, if set, is called for each backup deleted with the hex SHA-256
+	// of its content (used e.g. by the audit log). It is never called concurrently.
+	OnBackupDeleted func(path, hash string)
+	OnWarning       func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// PerformClean deletes .bak backup files, optionally limited to stale or orphaned ones.
+// The backups are found first and then deleted by up to opts.Jobs workers; the
+// messages keep the traversal order.
+// Returns:
+//   - []string: Slice of messages detailing individual actions taken.
+//   - int: Number of files successfully cleaned.
+//   - error: The first non-fatal error encountered or walk error.
+func PerformClean(opts CleanOptions) ([]string, int, error) {
+	return PerformCleanCtx(context.Background(), opts)
+}
+
+// PerformCleanCtx is PerformClean with cancellation: once ctx is done, no further
+// backup is deleted and the returned error wraps ctx.Err().
+func PerformCleanCtx(ctx context.Context, opts CleanOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	backups, infos, firstEncounteredError, walkErr := findBackups(ctx, opts.Dir, "clean", opts.OnWarning, "PerformClean")
+	if walkErr != nil {
+		return nil, 0, walkErr
+	}
+	cutoff := time.Now().Add(-opts.OlderThan)
+	outcomes := make([]backupOutcome, len(backups))
+	processed := forEachBackup(ctx, backups, opts.Jobs, func(i int) {
+		outcomes[i] = cleanBackup(backups[i], infos[i], opts, cutoff)
+	}, func(i, done int) {
+		for _, w := range outcomes[i].warnings {
+			warn(opts.OnWarning, w.Op, w.Stage, w.Err, w.Action)
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, len(backups))
+		}
+	})
+
+	var messages []string
+	filesCleaned := 0
+	filesKept := 0
+	for _, o := range outcomes {
+		if o.message != "" {
+			messages = append(messages, o.message)
+		}
+		if o.done {
+			filesCleaned++
+			if opts.OnBackupDeleted != nil {
+				opts.OnBackupDeleted(o.path, o.hashBefore)
+			}
+		}
+		if o.kept {
+			filesKept++
+		}
+		if o.err != nil && firstEncounteredError == nil {
+			firstEncounteredError = o.err
+		}
+	}
+	if processed < len(backups) && ctx.Err() != nil {
+		return messages, filesCleaned, fmt.Errorf("clean interrupted after %d of %d backup(s): %w", processed, len(backups), ctx.Err())
+	}
+	if filesCleaned == 0 && firstEncounteredError == nil {
+		if filesKept > 0 {
+			messages = append(messages, fmt.Sprintf("No .bak files found to clean in the specified directory (%d backup(s) kept by the -older-than/-orphans/-redundant filters).", filesKept))
+		} else {
+			messages = append(messages, "No .bak files found to clean in the specified directory.")
+		}
+	}
+	return messages, filesCleaned, firstEncounteredError
+}
+
+// cleanBackup deletes the backup at path, with info, unless the -older-than,
+// -orphans and -redundant filters of opts keep it. It is safe to call concurrently for different
+// paths.
+func cleanBackup(path string, info os.FileInfo, opts CleanOptions, cutoff time.Time) backupOutcome {
+	var o backupOutcome
+	if opts.OlderThan > 0 && !info.ModTime().Before(cutoff) {
+		o.kept = true
+		return o
+	}
+	if opts.OrphansOnly || opts.RedundantOnly {
+		kind, err := classifyBackup(path)
+		if err != nil {
+			o.err = err
+			o.warn("PerformClean", "Compare", err, "Skipping")
+			return o
+		}
+		if !(opts.OrphansOnly && kind == backupOrphaned || opts.RedundantOnly && kind == backupRedundant) {
+			o.kept = true
+			return o
+		}
+	}
+	if opts.OnBackupDeleted != nil {
+		o.path = path
+		o.hashBefore, _ = hashFile(path) // Left empty if the backup cannot be read.
+	}
+	if err := FS.Remove(path); err != nil {
+		o.err = fmt.Errorf("deleting backup file '%s': %w", path, err)
+		o.warn("PerformClean", "Remove", o.err, "")
+		return o
+	}
+	o.message = fmt.Sprintf("  - Deleted backup: %s", path)
+	o.done = true
+	return o
+}
+
+// backupOutcome is the result of restoring or cleaning one backup.
+type backupOutcome struct {
+	message  string    // Line for the operation's messages; may be empty.
+	done     bool      // Whether the backup was restored or deleted.
+	kept     bool      // Whether the backup was deliberately left alone.
+	err      error     // The problem that stopped the backup from being handled.
+	warnings []Warning // Warnings to report, in order.
+
+	// For the OnFileRestored and OnBackupDeleted callbacks: the file restored or the
+	// backup deleted, with the hashes of its content before and after.
+	path                  string
+	hashBefore, hashAfter string
+}
+
+// warn records a warning to report once the backup has been handled.
+func (o *backupOutcome) warn(op, stage string, err error, action string) {
+	o.warnings = append(o.warnings, Warning{Op: op, Stage: stage, Err: err, Action: action})
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package photonsr
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl, which makes a file share the extents of another
+// (btrfs, XFS with reflink, bcachefs, overlays of those).
+const ficlone = 0x40049409
+
+// CloneFile makes dst a copy-on-write clone of src; a new dst gets the permissions
+// of src. It fails, removing dst, if the file system cannot clone src into dst.
+func CloneFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := FS.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	closeErr := out.Close()
+	if errno != 0 {
+		FS.Remove(dst)
+		return &os.LinkError{Op: "clone", Old: src, New: dst, Err: errno}
+	}
+	return closeErr
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package photonsr
+
+import (
+	"errors"
+	"os"
+)
+
+// CloneFile makes dst a copy-on-write clone of src. Cloning is only implemented
+// on Linux; elsewhere it always fails and callers copy instead.
+func CloneFile(src, dst string) error {
+	return &os.LinkError{Op: "clone", Old: src, New: dst, Err: errors.ErrUnsupported}
+}
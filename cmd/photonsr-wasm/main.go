@@ -0,0 +1,97 @@
+//go:build js && wasm
+
+// Command photonsr-wasm exposes the matching engine of PhotonSR to JavaScript, for a
+// browser-based rule tester that matches exactly as the CLI does. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o photonsr.wasm ./cmd/photonsr-wasm
+//
+// and load it with the wasm_exec.js of the same Go release. It defines a global
+// photonsr object:
+//
+//	photonsr.findMatches(content, old)
+//	    -> [{start, end, line, column, jsStart, jsEnd}, ...]
+//	photonsr.apply(content, [{old, new, regexp, ignoreCase}, ...])
+//	    -> {content, replacements}
+//
+// start and end are byte offsets in the UTF-8 encoding of content, as reported by
+// the CLI; jsStart and jsEnd are the same offsets in the JavaScript string, for
+// highlighting. apply applies the rules one after the other, like a rules file; a
+// rule with regexp true is a regular expression, as with -regex, and one with
+// ignoreCase true matches regardless of case, as with -ignore-case.
+// Invalid arguments return {error}.
+package main
+
+import (
+	"syscall/js"
+	"unicode/utf8"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+func main() {
+	js.Global().Set("photonsr", js.ValueOf(map[string]any{
+		"findMatches": js.FuncOf(findMatches),
+		"apply":       js.FuncOf(apply),
+	}))
+	select {} // Keep the functions callable.
+}
+
+// findMatches implements photonsr.findMatches(content, old).
+func findMatches(this js.Value, args []js.Value) any {
+	if len(args) != 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+		return jsError("findMatches expects (content, old), both strings")
+	}
+	content := []byte(args[0].String())
+	matches := photonsr.FindMatches(content, photonsr.Rule{Old: args[1].String()})
+	out := make([]any, len(matches))
+	offset, units := 0, 0 // Byte offset and UTF-16 offset reached so far.
+	for i, m := range matches {
+		units += utf16Len(content[offset:m.Start])
+		jsStart := units
+		units += utf16Len(content[m.Start:m.End])
+		offset = m.End
+		out[i] = map[string]any{
+			"start": m.Start, "end": m.End, "line": m.Line, "column": m.Column,
+			"jsStart": jsStart, "jsEnd": units,
+		}
+	}
+	return js.ValueOf(out)
+}
+
+// apply implements photonsr.apply(content, rules).
+func apply(this js.Value, args []js.Value) any {
+	if len(args) != 2 || args[0].Type() != js.TypeString || !js.Global().Get("Array").Call("isArray", args[1]).Bool() {
+		return jsError("apply expects (content, rules): a string and an array of {old, new}")
+	}
+	var rules []photonsr.Rule
+	for i := 0; i < args[1].Length(); i++ {
+		r := args[1].Index(i)
+		if r.Type() != js.TypeObject || r.Get("old").Type() != js.TypeString || r.Get("new").Type() != js.TypeString {
+			return jsError("apply: each rule must be an object with string old and new")
+		}
+		rules = append(rules, photonsr.Rule{Old: r.Get("old").String(), New: r.Get("new").String(), Regexp: r.Get("regexp").Truthy(), IgnoreCase: r.Get("ignoreCase").Truthy()})
+	}
+	content, count := photonsr.ApplyAll([]byte(args[0].String()), rules)
+	return js.ValueOf(map[string]any{"content": string(content), "replacements": count})
+}
+
+// utf16Len returns the length of the UTF-8 text b in UTF-16 code units, the unit of
+// JavaScript string offsets.
+func utf16Len(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+		b = b[size:]
+	}
+	return n
+}
+
+// jsError returns the {error} object of an invalid call.
+func jsError(message string) js.Value {
+	return js.ValueOf(map[string]any{"error": message})
+}
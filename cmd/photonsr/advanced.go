@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Wizard Advanced Options ---
+
+// Rows of the advanced options screen, in display order. Ignore case and dry run
+// have no row: the wizard asks for the first right after the old text, and the
+// summary previews the replacement as a dry run (p).
+const (
+	advancedRegex = iota
+	advancedJobs
+	advancedMaxSize
+	advancedExclude
+	advancedSkipBinary
+	advancedOrder
+	advancedRowCount
+)
+
+// maxWizardJobs caps the number of parallel jobs selectable in the wizard.
+const maxWizardJobs = 64
+
+// advancedOrders are the processing orders offered by the wizard, cycled in this order.
+var advancedOrders = []string{photonsr.OrderPath, photonsr.OrderSizeDesc, photonsr.OrderMtime, photonsr.OrderInode}
+
+// advancedOptions holds the replacement settings of the wizard's advanced options
+// screen. The zero value matches the CLI defaults.
+type advancedOptions struct {
+	regex      bool   // The old text is a regular expression and the new text may use its groups.
+	jobs       int    // Files processed concurrently; 0 means 1.
+	maxSize    string // Size limit as typed (e.g. "50M"); empty means no limit.
+	exclude    string // Comma-separated name patterns of files to leave alone, as typed.
+	skipBinary bool   // Leave files that look binary alone.
+	order      string // One of advancedOrders; empty means OrderPath.
+}
+
+// isDefault reports whether no advanced option was changed.
+func (a advancedOptions) isDefault() bool {
+	return a == advancedOptions{}
+}
+
+// apply copies the settings into opts. The size limit was validated when it was
+// entered, so parsing it again cannot fail. Excluded names become skip rules.
+func (a advancedOptions) apply(opts *photonsr.ReplaceOptions) {
+	opts.UseRegex = a.regex
+	opts.Jobs = a.jobs
+	opts.SkipBinary = a.skipBinary
+	opts.Order = a.order
+	if a.maxSize != "" {
+		opts.MaxFileSize, _ = photonsr.ParseSize(a.maxSize)
+	}
+	for _, pattern := range excludePatterns(a.exclude) {
+		opts.Rules = append(opts.Rules, photonsr.RuleSpec{Pattern: pattern, Skip: true})
+	}
+}
+
+// excludePatterns splits the comma-separated name patterns of s.
+func excludePatterns(s string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// excludedName reports whether the file named name matches one of the
+// comma-separated patterns of exclude.
+func excludedName(exclude, name string) bool {
+	for _, pattern := range excludePatterns(exclude) {
+		if matched, _ := photonsr.MatchesPattern(name, pattern); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// textRow reports whether row is edited as text rather than adjusted.
+func textRow(row int) bool {
+	return row == advancedMaxSize || row == advancedExclude
+}
+
+// text returns the value of the text row as typed.
+func (a advancedOptions) text(row int) string {
+	if row == advancedExclude {
+		return a.exclude
+	}
+	return a.maxSize
+}
+
+// setText checks value, typed for the text row, and stores it. The error is a
+// message for the user.
+func (a *advancedOptions) setText(row int, value string) error {
+	switch row {
+	case advancedMaxSize:
+		if value != "" {
+			if _, err := photonsr.ParseSize(value); err != nil {
+				return errors.New(tr("err.bad_size", err))
+			}
+		}
+		a.maxSize = value
+	case advancedExclude:
+		for _, pattern := range excludePatterns(value) {
+			if err := photonsr.ValidatePattern(pattern); err != nil {
+				return errors.New(tr("err.bad_exclude", err))
+			}
+		}
+		a.exclude = value
+	}
+	return nil
+}
+
+// adjust changes the value of row by one step in the direction of delta.
+func (a *advancedOptions) adjust(row, delta int) {
+	switch row {
+	case advancedRegex:
+		a.regex = !a.regex
+	case advancedJobs:
+		jobs := a.jobs
+		if jobs == 0 {
+			jobs = 1
+		}
+		jobs = clampInt(jobs+delta, 1, maxWizardJobs)
+		if jobs == 1 {
+			jobs = 0
+		}
+		a.jobs = jobs
+	case advancedSkipBinary:
+		a.skipBinary = !a.skipBinary
+	case advancedOrder:
+		i := 0
+		for j, o := range advancedOrders {
+			if o == a.order {
+				i = j
+			}
+		}
+		i = (i + delta + len(advancedOrders)) % len(advancedOrders)
+		a.order = advancedOrders[i]
+		if a.order == photonsr.OrderPath {
+			a.order = ""
+		}
+	}
+}
+
+// value returns the display value of row.
+func (a advancedOptions) value(row int) string {
+	switch row {
+	case advancedRegex:
+		return yesNo(a.regex)
+	case advancedJobs:
+		if a.jobs == 0 {
+			return "1"
+		}
+		return fmt.Sprint(a.jobs)
+	case advancedMaxSize:
+		if a.maxSize == "" {
+			return tr("advanced.no_limit")
+		}
+		return a.maxSize
+	case advancedExclude:
+		if a.exclude == "" {
+			return tr("advanced.none")
+		}
+		return a.exclude
+	case advancedSkipBinary:
+		return yesNo(a.skipBinary)
+	case advancedOrder:
+		if a.order == "" {
+			return photonsr.OrderPath
+		}
+		return a.order
+	}
+	return ""
+}
+
+// advancedLabels maps each row to its message catalog key.
+var advancedLabels = [advancedRowCount]string{
+	advancedRegex:      "advanced.regex",
+	advancedJobs:       "advanced.jobs",
+	advancedMaxSize:    "advanced.max_size",
+	advancedExclude:    "advanced.exclude",
+	advancedSkipBinary: "advanced.skip_binary",
+	advancedOrder:      "advanced.order",
+}
+
+// summary describes the changed settings in one line for the confirmation screen.
+func (a advancedOptions) summary() string {
+	if a.isDefault() {
+		return tr("advanced.defaults")
+	}
+	var parts []string
+	if a.regex {
+		parts = append(parts, tr("advanced.regex"))
+	}
+	if a.jobs != 0 {
+		parts = append(parts, tr("advanced.jobs")+": "+a.value(advancedJobs))
+	}
+	if a.maxSize != "" {
+		parts = append(parts, tr("advanced.max_size")+": "+a.maxSize)
+	}
+	if a.exclude != "" {
+		parts = append(parts, tr("advanced.exclude")+": "+a.exclude)
+	}
+	if a.skipBinary {
+		parts = append(parts, tr("advanced.skip_binary"))
+	}
+	if a.order != "" {
+		parts = append(parts, tr("advanced.order")+": "+a.order)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// view renders the rows with cursor highlighted. While the size limit is being
+// edited, input (the rendered text input) replaces its value.
+func (a advancedOptions) view(cursor int, input string) string {
+	var b strings.Builder
+	for row := 0; row < advancedRowCount; row++ {
+		prefix := "  "
+		if row == cursor {
+			prefix = "> "
+		}
+		value := a.value(row)
+		if row == cursor && input != "" {
+			value = input
+		}
+		b.WriteString(fmt.Sprintf("%s%-24s %s\n", prefix, tr(advancedLabels[row]), value))
+	}
+	return b.String()
+}
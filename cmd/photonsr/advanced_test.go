@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+func TestAdvancedOptionsApply(t *testing.T) {
+	a := advancedOptions{regex: true, jobs: 4, maxSize: "1K", exclude: "vendor, *.lock,", skipBinary: true}
+	var opts photonsr.ReplaceOptions
+	a.apply(&opts)
+	want := photonsr.ReplaceOptions{
+		UseRegex: true, Jobs: 4, MaxFileSize: 1024, SkipBinary: true,
+		Rules: []photonsr.RuleSpec{{Pattern: "vendor", Skip: true}, {Pattern: "*.lock", Skip: true}},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("apply = %+v, want %+v", opts, want)
+	}
+}
+
+func TestSpliceLine(t *testing.T) {
+	tests := []struct {
+		rule  photonsr.Rule
+		want  string
+		after [][2]int
+	}{
+		{photonsr.Rule{Old: "a", New: "xyz"}, "xyzbxyz", [][2]int{{0, 3}, {4, 7}}},
+		{photonsr.Rule{Old: `(\w)b`, New: "[$1]", Regexp: true}, "[a]a", [][2]int{{0, 3}}},
+		{photonsr.Rule{Old: "(", New: "", Regexp: true}, "aba", nil},
+	}
+	for _, tt := range tests {
+		changed, _, after := spliceLine([]byte("aba"), tt.rule)
+		if string(changed) != tt.want || !reflect.DeepEqual(after, tt.after) {
+			t.Errorf("spliceLine(%q) = %q %v, want %q %v", tt.rule.Old, changed, after, tt.want, tt.after)
+		}
+	}
+}
+
+func TestAdvancedOptionsSetText(t *testing.T) {
+	var a advancedOptions
+	if err := a.setText(advancedMaxSize, "nonsense"); err == nil || a.maxSize != "" {
+		t.Errorf("setText accepted the size \"nonsense\" (%v)", err)
+	}
+	if err := a.setText(advancedExclude, "ok,["); err == nil || a.exclude != "" {
+		t.Errorf("setText accepted the pattern \"[\" (%v)", err)
+	}
+	if err := a.setText(advancedExclude, "*.lock"); err != nil || !excludedName(a.exclude, "go.lock") || excludedName(a.exclude, "go.mod") {
+		t.Errorf("setText(%q) = %v, excluding %q", "*.lock", err, a.exclude)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Log Anonymization ---
+
+// "photonsr anonymize" pseudonymizes the personal data in log files: every email
+// address, IP address or match of -anonymize-regex is replaced by a token derived
+// from an HMAC of the value, so the same value gets the same token in every file
+// and the logs can still be correlated. Files are streamed line by line, so large
+// and rotated logs are handled in bounded memory. Tokens keep the shape of what
+// they replace: addresses stay addresses (in ranges reserved for documentation and
+// future use) and emails stay emails, so log parsers keep working; values already
+// in those ranges (or "anon-" tokens) are left alone, so a second run changes
+// nothing.
+
+// anonymizeKeyEnv holds the pseudonymization key when -anonymize-key is not given.
+const anonymizeKeyEnv = "PHOTONSR_ANONYMIZE_KEY"
+
+// Kinds of values recognized by "photonsr anonymize".
+const (
+	AnonymizeEmail = "email"
+	AnonymizeIP    = "ip"
+	anonymizeRegex = "regex" // Matches of -anonymize-regex.
+)
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)+`)
+	ipv4Pattern   = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern   = regexp.MustCompile(`(?i)[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}`) // Candidates; netip.ParseAddr decides.
+	anonIPv4Range = netip.MustParsePrefix("240.0.0.0/4")                           // Reserved for future use.
+	anonIPv6Range = netip.MustParsePrefix("2001:db8::/32")                         // Reserved for documentation.
+)
+
+// anonymizeEmailDomain is the domain of pseudonymized email addresses.
+const anonymizeEmailDomain = "example.invalid"
+
+// anonymizer pseudonymizes values in log files. It implements StreamTransform.
+type anonymizer struct {
+	key   []byte
+	kinds map[string]bool
+	regex *regexp.Regexp // -anonymize-regex; its first group, if any, is the value.
+
+	mu   sync.Mutex
+	seen map[string]map[string]bool // Distinct values pseudonymized, by kind.
+}
+
+// newAnonymizer validates the options of "photonsr anonymize". Without a key, a
+// random one is used: tokens are then consistent within the run only.
+func newAnonymizer(kinds, regex string, key []byte) (*anonymizer, error) {
+	a := &anonymizer{key: key, kinds: map[string]bool{}, seen: map[string]map[string]bool{}}
+	for _, kind := range strings.Split(kinds, ",") {
+		switch kind = strings.TrimSpace(kind); kind {
+		case "":
+		case AnonymizeEmail, AnonymizeIP:
+			a.kinds[kind] = true
+		default:
+			return nil, fmt.Errorf("unknown -anonymize kind '%s' (expected email or ip): %w", kind, photonsr.ErrInvalidOption)
+		}
+	}
+	if regex != "" {
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("-anonymize-regex: %v: %w", err, photonsr.ErrInvalidOption)
+		}
+		a.regex = re
+	}
+	if len(a.kinds) == 0 && a.regex == nil {
+		return nil, fmt.Errorf("nothing to anonymize: give -anonymize kinds or -anonymize-regex: %w", photonsr.ErrInvalidOption)
+	}
+	if len(a.key) == 0 {
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return nil, fmt.Errorf("generating anonymization key: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// loadAnonymizeKey reads the key of -anonymize-key, or $PHOTONSR_ANONYMIZE_KEY, or
+// returns nil if neither is set.
+func loadAnonymizeKey(path string) ([]byte, error) {
+	if path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading anonymization key file '%s': %w", path, err)
+		}
+		return bytes.TrimSpace(key), nil
+	}
+	if key := os.Getenv(anonymizeKeyEnv); key != "" {
+		return []byte(key), nil
+	}
+	return nil, nil
+}
+
+// Applies reports whether the file named name can hold log lines: compressed
+// rotations (.gz, .bz2, .xz, .zst, .zip) are left alone.
+func (a *anonymizer) Applies(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".bz2", ".xz", ".zst", ".zip":
+		return false
+	}
+	return true
+}
+
+// Apply pseudonymizes content held in memory.
+func (a *anonymizer) Apply(path string, content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if _, err := a.ApplyStream(&out, bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ApplyStream pseudonymizes src line by line into dst.
+func (a *anonymizer) ApplyStream(dst io.Writer, src io.Reader) (int, error) {
+	r := bufio.NewReaderSize(src, 64<<10)
+	w := bufio.NewWriterSize(dst, 64<<10)
+	count := 0
+	for {
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			var rest []byte
+			rest, err = r.ReadBytes('\n')
+			line = append(append([]byte{}, line...), rest...)
+		}
+		if len(line) > 0 {
+			out, n := a.line(line)
+			count += n
+			if _, werr := w.Write(out); werr != nil {
+				return count, werr
+			}
+		}
+		if err == io.EOF {
+			return count, w.Flush()
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// line pseudonymizes one line and returns it with the number of values replaced.
+func (a *anonymizer) line(line []byte) ([]byte, int) {
+	count := 0
+	if a.regex != nil {
+		line = a.regex.ReplaceAllFunc(line, func(m []byte) []byte {
+			sub := a.regex.FindSubmatchIndex(m)
+			if len(sub) < 4 || sub[2] < 0 {
+				sub = []int{0, len(m), 0, len(m)}
+			}
+			if bytes.HasPrefix(m[sub[2]:], []byte("anon-")) {
+				return m // Already a pseudonym.
+			}
+			count++
+			out := append([]byte{}, m[:sub[2]]...)
+			out = append(out, a.token(anonymizeRegex, string(m[sub[2]:sub[3]]))...)
+			return append(out, m[sub[3]:]...)
+		})
+	}
+	if a.kinds[AnonymizeEmail] {
+		line = emailPattern.ReplaceAllFunc(line, func(m []byte) []byte {
+			if bytes.HasSuffix(bytes.ToLower(m), []byte("@"+anonymizeEmailDomain)) {
+				return m
+			}
+			count++
+			return []byte(a.token(AnonymizeEmail, strings.ToLower(string(m))))
+		})
+	}
+	if a.kinds[AnonymizeIP] {
+		for _, re := range []*regexp.Regexp{ipv4Pattern, ipv6Pattern} {
+			line = re.ReplaceAllFunc(line, func(m []byte) []byte {
+				addr, err := netip.ParseAddr(string(m))
+				if err != nil || anonIPv4Range.Contains(addr) || anonIPv6Range.Contains(addr) {
+					return m
+				}
+				count++
+				return []byte(a.token(AnonymizeIP, addr.String()))
+			})
+		}
+	}
+	return line, count
+}
+
+// token returns the pseudonym of a value of the given kind.
+func (a *anonymizer) token(kind, value string) string {
+	a.mu.Lock()
+	if a.seen[kind] == nil {
+		a.seen[kind] = map[string]bool{}
+	}
+	a.seen[kind][value] = true
+	a.mu.Unlock()
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(kind + ":" + value))
+	sum := mac.Sum(nil)
+	switch kind {
+	case AnonymizeEmail:
+		return "user-" + hex.EncodeToString(sum[:5]) + "@" + anonymizeEmailDomain
+	case AnonymizeIP:
+		if strings.Contains(value, ":") {
+			b := anonIPv6Range.Addr().As16()
+			copy(b[4:], sum[:12])
+			return netip.AddrFrom16(b).String()
+		}
+		return netip.AddrFrom4([4]byte{0xf0 | sum[0]&0x0f, sum[1], sum[2], sum[3]}).String()
+	}
+	return "anon-" + hex.EncodeToString(sum[:5])
+}
+
+// summary describes the distinct values pseudonymized, by kind.
+func (a *anonymizer) summary() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var parts []string
+	total := 0
+	for kind, values := range a.seen {
+		parts = append(parts, fmt.Sprintf("%s: %d", kind, len(values)))
+		total += len(values)
+	}
+	sort.Strings(parts)
+	if total == 0 {
+		return "No values to pseudonymize were found."
+	}
+	return fmt.Sprintf("Pseudonymized %d distinct value(s) (%s).", total, strings.Join(parts, ", "))
+}
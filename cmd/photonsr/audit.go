@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Audit Log ---
+
+// auditGenesisHash is the PrevHash of the first record in an audit log.
+const auditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// auditKeyEnv names the environment variable that may hold the HMAC signing key.
+const auditKeyEnv = "PHOTONSR_AUDIT_KEY"
+
+// auditFile describes a single file touched by an audited run.
+type auditFile struct {
+	Path       string `json:"path"`                    // Path of the file.
+	HashBefore string `json:"sha256_before,omitempty"` // SHA-256 of the content before the run.
+	HashAfter  string `json:"sha256_after,omitempty"`  // SHA-256 of the content after the run.
+}
+
+// auditRecord is one line of the append-only, hash-chained audit log.
+type auditRecord struct {
+	Seq           int               `json:"seq"`                 // Position of the record in the log (1-based).
+	Time          string            `json:"time"`                // RFC 3339 timestamp of the run.
+	User          string            `json:"user"`                // Account that executed the run.
+	Host          string            `json:"host"`                // Machine the run was executed on.
+	Version       string            `json:"version"`             // PhotonSR version that performed the run.
+	Operation     string            `json:"operation"`           // "replace", "restore" or "clean".
+	Options       map[string]string `json:"options"`             // Effective options of the run.
+	Files         []auditFile       `json:"files,omitempty"`     // Files modified, restored or deleted, with content hashes.
+	Details       []string          `json:"details,omitempty"`   // Per-file messages for restore/clean runs.
+	ItemsAffected int               `json:"items_affected"`      // Number of files modified, restored, or cleaned.
+	Error         string            `json:"error,omitempty"`     // Error reported by the run, if any.
+	PrevHash      string            `json:"prev_hash"`           // Hash of the previous record in the chain.
+	Hash          string            `json:"hash"`                // SHA-256 over this record (Hash and Signature empty).
+	Signature     string            `json:"signature,omitempty"` // Optional HMAC-SHA256 of Hash.
+}
+
+// auditRecorder collects the files modified during a run for the audit log.
+type auditRecorder struct {
+	files []auditFile
+}
+
+// recordModification is suitable as ReplaceOptions.OnFileModified and
+// RestoreOptions.OnFileRestored.
+func (r *auditRecorder) recordModification(path, hashBefore, hashAfter string) {
+	r.files = append(r.files, auditFile{Path: path, HashBefore: hashBefore, HashAfter: hashAfter})
+}
+
+// recordDeletion is suitable as CleanOptions.OnBackupDeleted.
+func (r *auditRecorder) recordDeletion(path, hash string) {
+	r.files = append(r.files, auditFile{Path: path, HashBefore: hash})
+}
+
+// computeRecordHash returns the chain hash of a record, ignoring its Hash and Signature fields.
+func computeRecordHash(rec auditRecord) (string, error) {
+	rec.Hash = ""
+	rec.Signature = ""
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", fmt.Errorf("encoding audit record: %w", err)
+	}
+	return photonsr.SHA256Hex(data), nil
+}
+
+// signRecordHash returns the hex HMAC-SHA256 of hash using key.
+func signRecordHash(hash string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// loadAuditKey returns the signing key from keyPath, or from PHOTONSR_AUDIT_KEY.
+// A nil key means records are hash-chained but not signed.
+func loadAuditKey(keyPath string) ([]byte, error) {
+	if keyPath != "" {
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading audit key file '%s': %w", keyPath, err)
+		}
+		return bytes.TrimSpace(key), nil
+	}
+	if key := os.Getenv(auditKeyEnv); key != "" {
+		return []byte(key), nil
+	}
+	return nil, nil
+}
+
+// readAuditRecords parses every record in the audit log at logPath.
+// A missing log yields no records and no error.
+func readAuditRecords(logPath string) ([]auditRecord, error) {
+	f, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log '%s': %w", logPath, err)
+	}
+	defer f.Close()
+
+	var records []auditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec auditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("parsing audit log '%s' line %d: %w", logPath, lineNo, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit log '%s': %w", logPath, err)
+	}
+	return records, nil
+}
+
+// appendAuditRecord chains rec to the last record of the log at logPath, signs it
+// if key is non-nil, and appends it as a single JSON line. The log stays locked from
+// reading the last record to the append, so that concurrent runs cannot chain two
+// records to the same one.
+func appendAuditRecord(logPath string, rec auditRecord, key []byte) (err error) {
+	f, err := photonsr.FS.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log '%s' for append: %w", logPath, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if err := lockAuditLog(f); err != nil {
+		return fmt.Errorf("locking audit log '%s': %w", logPath, err)
+	}
+	defer unlockAuditLog(f)
+
+	existing, err := readAuditRecords(logPath)
+	if err != nil {
+		return err
+	}
+	rec.Seq = len(existing) + 1
+	rec.PrevHash = auditGenesisHash
+	if len(existing) > 0 {
+		rec.PrevHash = existing[len(existing)-1].Hash
+	}
+	sort.Slice(rec.Files, func(i, j int) bool { return rec.Files[i].Path < rec.Files[j].Path })
+
+	hash, err := computeRecordHash(rec)
+	if err != nil {
+		return err
+	}
+	rec.Hash = hash
+	if key != nil {
+		rec.Signature = signRecordHash(hash, key)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding audit record: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("appending to audit log '%s': %w", logPath, err)
+	}
+	return nil
+}
+
+// verifyAuditLog checks the hash chain (and signatures, if key is non-nil) of the
+// log at logPath. It returns the number of records verified.
+func verifyAuditLog(logPath string, key []byte) (int, error) {
+	records, err := readAuditRecords(logPath)
+	if err != nil {
+		return 0, err
+	}
+	prev := auditGenesisHash
+	for i, rec := range records {
+		if rec.Seq != i+1 {
+			return i, fmt.Errorf("record %d: sequence number is %d (records missing or reordered)", i+1, rec.Seq)
+		}
+		if rec.PrevHash != prev {
+			return i, fmt.Errorf("record %d: chain broken (prev_hash does not match previous record)", rec.Seq)
+		}
+		hash, err := computeRecordHash(rec)
+		if err != nil {
+			return i, err
+		}
+		if hash != rec.Hash {
+			return i, fmt.Errorf("record %d: content does not match its hash (record modified)", rec.Seq)
+		}
+		if key != nil && !hmac.Equal([]byte(rec.Signature), []byte(signRecordHash(rec.Hash, key))) {
+			return i, fmt.Errorf("record %d: invalid or missing signature", rec.Seq)
+		}
+		prev = rec.Hash
+	}
+	return len(records), nil
+}
+
+// newAuditRecord fills in the who/when part of an audit record.
+func newAuditRecord(operation string, options map[string]string) auditRecord {
+	rec := auditRecord{
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Version:   version,
+		Operation: operation,
+		Options:   options,
+	}
+	if u, err := user.Current(); err == nil {
+		rec.User = u.Username
+	} else {
+		rec.User = os.Getenv("USER")
+	}
+	if host, err := os.Hostname(); err == nil {
+		rec.Host = host
+	}
+	return rec
+}
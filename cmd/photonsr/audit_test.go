@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeTestAuditLog appends n records to a new audit log, signed with key if it is
+// non-nil, and returns the log's path.
+func writeTestAuditLog(t *testing.T, n int, key []byte) string {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < n; i++ {
+		rec := newAuditRecord("replace", map[string]string{"old": "foo", "new": "bar"})
+		rec.Files = []auditFile{{Path: "b.txt", HashBefore: "1", HashAfter: "2"}, {Path: "a.txt", HashBefore: "3", HashAfter: "4"}}
+		rec.ItemsAffected = len(rec.Files)
+		if err := appendAuditRecord(logPath, rec, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return logPath
+}
+
+func TestAuditLog(t *testing.T) {
+	key := []byte("secret")
+	logPath := writeTestAuditLog(t, 3, key)
+	if n, err := verifyAuditLog(logPath, key); err != nil || n != 3 {
+		t.Fatalf("verifyAuditLog = %d, %v; want 3 records verified", n, err)
+	}
+	if n, err := verifyAuditLog(logPath, nil); err != nil || n != 3 {
+		t.Errorf("verifyAuditLog without a key = %d, %v; want 3 records verified", n, err)
+	}
+	if _, err := verifyAuditLog(logPath, []byte("other")); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("verifyAuditLog with another key = %v, want a signature error", err)
+	}
+
+	records, err := readAuditRecords(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rec := range records {
+		if rec.Seq != i+1 {
+			t.Errorf("record %d has sequence number %d", i+1, rec.Seq)
+		}
+		if rec.Files[0].Path != "a.txt" {
+			t.Errorf("record %d lists %q first, want the files sorted", i+1, rec.Files[0].Path)
+		}
+	}
+	if records[0].PrevHash != auditGenesisHash || records[1].PrevHash != records[0].Hash {
+		t.Error("the records are not chained")
+	}
+}
+
+func TestAuditLogTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		want   string // Part of the verification error.
+	}{
+		{"record modified", func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"items_affected":2`), []byte(`"items_affected":1`), 1)
+			return lines
+		}, "record 2: content does not match its hash"},
+		{"record removed", func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		}, "record 2: sequence number is 3"},
+		{"records swapped", func(lines [][]byte) [][]byte {
+			lines[0], lines[1] = lines[1], lines[0]
+			return lines
+		}, "record 1: sequence number is 2"},
+		{"last record removed", func(lines [][]byte) [][]byte {
+			return lines[:2]
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := writeTestAuditLog(t, 3, nil)
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")))
+			if err := os.WriteFile(logPath, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err = verifyAuditLog(logPath, nil)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("verifyAuditLog = %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("verifyAuditLog = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditLogSignatureRemoved(t *testing.T) {
+	key := []byte("secret")
+	logPath := writeTestAuditLog(t, 2, key)
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := readAuditRecords(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`,"signature":"`+records[1].Signature+`"`), nil, 1)
+	if err := os.WriteFile(logPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyAuditLog(logPath, key); err == nil || !strings.Contains(err.Error(), "record 2: invalid or missing signature") {
+		t.Errorf("verifyAuditLog = %v, want record 2's signature missing", err)
+	}
+}
+
+func TestAuditLogConcurrentAppends(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	const writers, appends = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*appends)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < appends; j++ {
+				errs <- appendAuditRecord(logPath, newAuditRecord("clean", nil), nil)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if verified, err := verifyAuditLog(logPath, nil); err != nil || verified != writers*appends {
+		t.Errorf("verifyAuditLog = %d, %v; want %d records verified", verified, err, writers*appends)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockAuditLog blocks until it holds an exclusive lock on the audit log open as f.
+// The lock is advisory: it only excludes other PhotonSR runs appending to the log.
+func lockAuditLog(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockAuditLog releases the lock taken by lockAuditLog.
+func unlockAuditLog(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
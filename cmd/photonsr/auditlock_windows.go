@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// auditLockOffsetHigh is the high 32 bits of the offset of the byte locked by
+// lockAuditLog, 2^62, far past the end of any log: Windows locks are mandatory, and
+// locking the records themselves would keep readAuditRecords from reading them.
+const auditLockOffsetHigh = 1 << 30
+
+// lockAuditLog blocks until it holds an exclusive lock on the audit log open as f.
+func lockAuditLog(f *os.File) error {
+	ol := windows.Overlapped{OffsetHigh: auditLockOffsetHigh}
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &ol)
+}
+
+// unlockAuditLog releases the lock taken by lockAuditLog.
+func unlockAuditLog(f *os.File) error {
+	ol := windows.Overlapped{OffsetHigh: auditLockOffsetHigh}
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &ol)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Backup Naming and Conflict Handling ---
+
+// stdinReader is shared by interactive CLI prompts so buffered input is not lost between them.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptBackupConflict asks on the terminal how to resolve a backup conflict for
+// path. It is used as ReplaceOptions.ResolveBackupConflict in CLI mode; without an
+// answer (e.g. stdin closed) the existing backup is kept.
+func promptBackupConflict(path string) string {
+	for {
+		fmt.Fprintf(promptOut, "Backup '%s' already exists. [o]verwrite, [s]kip (keep existing), [v]ersion (keep both)? ", photonsr.BackupPathFor(path))
+		answer, err := stdinReader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "o", "overwrite":
+			return photonsr.BackupPolicyOverwrite
+		case "s", "skip":
+			return photonsr.BackupPolicySkip
+		case "v", "version":
+			return photonsr.BackupPolicyVersion
+		}
+		if err != nil {
+			fmt.Fprintln(promptOut)
+			return photonsr.BackupPolicySkip
+		}
+	}
+}
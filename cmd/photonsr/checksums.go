@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Checksum Manifest ---
+
+// writeChecksumManifest writes the SHA-256 digests of the modified files to path in
+// the format of sha256sum, so the result can be checked with "sha256sum -c path"
+// from the directory the run was started in. Files are listed by path.
+func writeChecksumManifest(path string, files []auditFile) error {
+	sorted := append([]auditFile(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	var b strings.Builder
+	for _, f := range sorted {
+		// sha256sum marks names containing a newline or backslash by a leading
+		// backslash and escapes those characters.
+		name := f.Path
+		prefix := ""
+		if strings.ContainsAny(name, "\\\n") {
+			prefix = "\\"
+			name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+		}
+		fmt.Fprintf(&b, "%s%s  %s\n", prefix, f.HashAfter, name)
+	}
+	if err := photonsr.WriteFileAtomic(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("writing checksum manifest '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/termenv"
+)
+
+// --- Terminal Detection and Colors ---
+
+// Color modes accepted by -color.
+const (
+	ColorAuto   = "auto"   // Color only on terminals, unless $NO_COLOR is set.
+	ColorAlways = "always" // Color even when the output is piped.
+	ColorNever  = "never"  // Plain text only.
+)
+
+// Renderers styling CLI output written to stdout and stderr; configureColor sets
+// their color profiles.
+var (
+	stdoutRenderer = lipgloss.NewRenderer(os.Stdout)
+	stderrRenderer = lipgloss.NewRenderer(os.Stderr)
+)
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fd := f.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// configureColor applies a -color mode to the CLI renderers and to the default
+// renderer used by the wizard. In auto mode each stream is colored only if it is a
+// terminal, following $NO_COLOR and $CLICOLOR_FORCE as termenv does.
+func configureColor(mode string) error {
+	var profile termenv.Profile
+	switch mode {
+	case ColorAuto:
+		return nil // The renderers detect their output lazily.
+	case ColorAlways:
+		profile = termenv.ANSI256
+	case ColorNever:
+		profile = termenv.Ascii
+	default:
+		return fmt.Errorf("unknown -color mode '%s' (expected auto, always or never): %w", mode, photonsr.ErrInvalidOption)
+	}
+	stdoutRenderer.SetColorProfile(profile)
+	stderrRenderer.SetColorProfile(profile)
+	lipgloss.SetColorProfile(profile)
+	return nil
+}
+
+// paint renders s in color for r. Each line is rendered on its own, so line breaks
+// are kept and lines are not padded to a common width.
+func paint(r *lipgloss.Renderer, color, s string) string {
+	if r.ColorProfile() == termenv.Ascii {
+		return s
+	}
+	style := r.NewStyle().Foreground(lipgloss.Color(color))
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = style.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// colorizeMessage colors a CLI result line for r: the removed and added lines of a
+// change listing red and green. Other lines are returned unchanged.
+func colorizeMessage(r *lipgloss.Renderer, msg string) string {
+	// Change lines carry their marker after a 5-column line number ("%5d - ").
+	trimmed := strings.TrimLeft(msg, " ")
+	digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+	if digits == 0 || len(trimmed) < digits+3 || trimmed[digits] != ' ' || trimmed[digits+2] != ' ' {
+		return msg
+	}
+	switch trimmed[digits+1] {
+	case '-':
+		return paint(r, "9", msg)
+	case '+':
+		return paint(r, "10", msg)
+	}
+	return msg
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Containerized Execution ---
+
+// -in-container <image> runs the operation in a throwaway container of image instead
+// of in this process, for rules authored by others: the container has no network,
+// no capabilities, a read-only root file system, and runs as the calling user (or
+// nobody), so the engine can reach nothing but the target directory, which is
+// bind-mounted at its own path, and the input files named by flags, mounted
+// read-only. On Linux this executable is mounted into the container, so any image
+// that can run it will do (e.g. "gcr.io/distroless/static" for a CGO_ENABLED=0
+// build); elsewhere the image must provide photonsr on its PATH. The state
+// directory is a temporary one inside the container: retry lists, history and
+// stats of the run are not kept.
+
+// containerRuntimeEnv names the container engine to use instead of the first of
+// docker and podman found on the PATH.
+const containerRuntimeEnv = "PHOTONSR_CONTAINER_RUNTIME"
+
+// containerBinary is where this executable is mounted in the container.
+const containerBinary = "/usr/local/bin/photonsr"
+
+// containerInputFlags name files the operation reads; they are mounted read-only.
+var containerInputFlags = []string{"rules", "manifest", "header", "ip-map", "anonymize-key"}
+
+// containerRejectedFlags write outside the target directory.
+var containerRejectedFlags = []string{"audit", "checksums", "out"}
+
+// containerTextFlags give the old and new text; the resolved texts are passed instead.
+var containerTextFlags = map[string]bool{
+	"old": true, "new": true, "old-stdin": true, "new-stdin": true, "old-hex": true, "new-hex": true,
+}
+
+// containerEnv are passed on to the container when set.
+var containerEnv = []string{anonymizeKeyEnv, "PHOTONSR_LANG", "LC_ALL", "LC_MESSAGES", "LANG"}
+
+// containerRuntime returns the container engine to run.
+func containerRuntime() (string, error) {
+	if name := strings.TrimSpace(os.Getenv(containerRuntimeEnv)); name != "" {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("container runtime '%s' ($%s): %w", name, containerRuntimeEnv, err)
+		}
+		return path, nil
+	}
+	for _, name := range []string{"docker", "podman"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("-in-container needs docker or podman on the PATH (or $%s)", containerRuntimeEnv)
+}
+
+// containerCommand returns the command running the operation of fs, with subcommand
+// and its positional args, in image, on the target directory dir and the texts
+// oldText and newText already resolved.
+func containerCommand(fs *flag.FlagSet, image, subcommand string, args []string, dir, oldText, newText string) (*exec.Cmd, error) {
+	for _, name := range containerRejectedFlags {
+		if fs.Lookup(name).Value.String() != "" {
+			return nil, fmt.Errorf("-%s cannot be used with -in-container: %w", name, photonsr.ErrInvalidOption)
+		}
+	}
+	if target := fs.Lookup("progress-json").Value.String(); target != "" && target != progressStderr {
+		return nil, fmt.Errorf("-progress-json can only stream to stderr with -in-container: %w", photonsr.ErrInvalidOption)
+	}
+	switch subcommand {
+	case "stats", "runs", "retry", "multi":
+		return nil, fmt.Errorf("%s cannot run with -in-container: %w", subcommand, photonsr.ErrInvalidOption)
+	}
+	engine, err := containerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving directory '%s': %w", dir, err)
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid < 0 { // Windows.
+		uid, gid = 65534, 65534
+	}
+	run := []string{"run", "--rm", "-i",
+		"--network", "none", "--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+		"--read-only", "--tmpfs", "/tmp", "--pids-limit", "512",
+		"--user", fmt.Sprintf("%d:%d", uid, gid),
+		"-v", abs + ":" + abs, "-w", abs,
+		"-e", photonsr.StateDirEnv + "=/tmp/photonsr",
+	}
+	entrypoint := "photonsr"
+	if runtime.GOOS == "linux" {
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("locating photonsr: %w", err)
+		}
+		run = append(run, "-v", exe+":"+containerBinary+":ro")
+		entrypoint = containerBinary
+	}
+	for _, env := range containerEnv {
+		if _, ok := os.LookupEnv(env); ok {
+			run = append(run, "-e", env)
+		}
+	}
+
+	inputs := map[string]bool{}
+	for _, name := range containerInputFlags {
+		inputs[name] = true
+	}
+	given := map[string]bool{} // On the command line or in the environment (see applyEnvDefaults).
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	var flags []string
+	var mountErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if !(given[f.Name] || flagFromEnv(f.Name)) || containerTextFlags[f.Name] || f.Name == "in-container" || f.Name == "dir" {
+			return
+		}
+		value := f.Value.String()
+		if inputs[f.Name] && value != "" {
+			path, err := filepath.Abs(value)
+			if err != nil {
+				mountErr = fmt.Errorf("resolving -%s '%s': %w", f.Name, value, err)
+				return
+			}
+			if !photonsr.Within(abs, path) {
+				run = append(run, "-v", path+":"+path+":ro")
+			}
+			value = path
+		}
+		flags = append(flags, "-"+f.Name+"="+value)
+	})
+	if mountErr != nil {
+		return nil, mountErr
+	}
+	flags = append(flags, "-dir="+abs)
+	if oldText != "" {
+		flags = append(flags, "-old-hex="+hex.EncodeToString([]byte(oldText)))
+	}
+	if newText != "" {
+		flags = append(flags, "-new-hex="+hex.EncodeToString([]byte(newText)))
+	}
+
+	run = append(run, "--entrypoint", entrypoint, image)
+	if subcommand != "" {
+		run = append(run, subcommand)
+	}
+	run = append(run, args...)
+	run = append(run, flags...)
+	cmd := exec.Command(engine, run...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd, nil
+}
+
+// runInContainer runs cmd and returns the exit status of the operation.
+func runInContainer(cmd *exec.Cmd) int {
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	default:
+		fmt.Fprintf(os.Stderr, "Error: running %s: %v\n", filepath.Base(cmd.Path), err)
+		return 1
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- CSV Column Scope ---
+
+// With -csv-column, -old is replaced by -new only inside one column of CSV and TSV
+// files. The column is given by its 1-based number or by its name in the header
+// row, which is then left alone. Fields are rewritten in place: the delimiters,
+// line endings and quoting of the file are kept, and a field is only quoted anew
+// if its new value needs it.
+
+// csvColumn replaces text within one column of delimited files. It implements
+// Transform.
+type csvColumn struct {
+	old, new  string
+	name      string // Column name; "" if given by number.
+	index     int    // 0-based column index if given by number.
+	delimiter byte   // Field delimiter; 0 to choose by extension.
+}
+
+// newCSVColumn validates -csv-column and -csv-delimiter.
+func newCSVColumn(column, delimiter, oldText, newText string) (*csvColumn, error) {
+	c := &csvColumn{old: oldText, new: newText}
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return nil, fmt.Errorf("-csv-column %d: columns are numbered from 1: %w", n, photonsr.ErrInvalidOption)
+		}
+		c.index = n - 1
+	} else {
+		c.name = strings.TrimSpace(column)
+	}
+	switch {
+	case delimiter == "":
+	case delimiter == `\t` || delimiter == "tab":
+		c.delimiter = '\t'
+	case len(delimiter) == 1 && delimiter != `"` && delimiter != "\n" && delimiter != "\r":
+		c.delimiter = delimiter[0]
+	default:
+		return nil, fmt.Errorf("-csv-delimiter '%s' must be a single character other than a quote or line break: %w", delimiter, photonsr.ErrInvalidOption)
+	}
+	return c, nil
+}
+
+// delimiterFor returns the delimiter of the file named name: -csv-delimiter, or a
+// tab for .tsv and .tab files and a comma otherwise.
+func (c *csvColumn) delimiterFor(name string) byte {
+	if c.delimiter != 0 {
+		return c.delimiter
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tsv", ".tab":
+		return '\t'
+	}
+	return ','
+}
+
+// Applies reports whether the file named name is delimited: any file with
+// -csv-delimiter, otherwise .csv, .tsv and .tab files.
+func (c *csvColumn) Applies(name string) bool {
+	if c.delimiter != 0 {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".tsv", ".tab":
+		return true
+	}
+	return false
+}
+
+// Apply replaces the old text in the column's fields of content.
+func (c *csvColumn) Apply(path string, content []byte) ([]byte, error) {
+	delim := c.delimiterFor(filepath.Base(path))
+	index := c.index
+	if c.name != "" {
+		index = -1 // Known once the header row has been read.
+	}
+	var out []byte
+	var header []string
+	last, record, field := 0, 0, 0
+	for i := 0; ; {
+		start, end, next := i, i, i
+		quoted := i < len(content) && content[i] == '"'
+		if quoted {
+			j := i + 1
+			for {
+				k := bytes.IndexByte(content[j:], '"')
+				if k < 0 {
+					return nil, fmt.Errorf("'%s': record %d has an unterminated quoted field: %w", path, record+1, photonsr.ErrNotApplicable)
+				}
+				j += k + 1
+				if j < len(content) && content[j] == '"' {
+					j++
+					continue
+				}
+				break
+			}
+			end = j
+		} else {
+			for end < len(content) && content[end] != delim && content[end] != '\n' {
+				end++
+			}
+			if end > start && end < len(content) && content[end] == '\n' && content[end-1] == '\r' {
+				end--
+			}
+		}
+
+		// What follows the field: a delimiter, a line break or the end of the file.
+		endOfRecord := true
+		switch {
+		case end == len(content):
+			next = end
+		case content[end] == delim:
+			next, endOfRecord = end+1, false
+		case content[end] == '\n':
+			next = end + 1
+		case content[end] == '\r' && end+1 < len(content) && content[end+1] == '\n':
+			next = end + 2
+		default:
+			return nil, fmt.Errorf("'%s': record %d has text after a closing quote: %w", path, record+1, photonsr.ErrNotApplicable)
+		}
+
+		value := string(content[start:end])
+		if quoted {
+			value = strings.ReplaceAll(value[1:len(value)-1], `""`, `"`)
+		}
+		switch {
+		case index < 0:
+			if field == 0 {
+				value = strings.TrimPrefix(value, "\ufeff")
+			}
+			header = append(header, strings.TrimSpace(value))
+		case field == index && strings.Contains(value, c.old):
+			replaced := strings.ReplaceAll(value, c.old, c.new)
+			out = append(out, content[last:start]...)
+			out = append(out, quoteCSVField(replaced, delim, quoted)...)
+			last = end
+		}
+
+		if endOfRecord {
+			if index < 0 {
+				for k, name := range header {
+					if strings.EqualFold(name, c.name) {
+						index = k
+						break
+					}
+				}
+				if index < 0 {
+					return nil, fmt.Errorf("'%s' has no column '%s': %w", path, c.name, photonsr.ErrNotApplicable)
+				}
+			}
+			record, field = record+1, 0
+		} else {
+			field++
+		}
+		if end == len(content) || endOfRecord && next == len(content) {
+			break
+		}
+		i = next
+	}
+	if out == nil {
+		return content, nil
+	}
+	return append(out, content[last:]...), nil
+}
+
+// quoteCSVField encodes a field value, quoting it if it was quoted or needs quotes.
+func quoteCSVField(value string, delim byte, quoted bool) string {
+	if !quoted && !strings.ContainsAny(value, string(delim)+"\"\r\n") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Environment Defaults ---
+
+// Every CLI flag can be given a per-user or per-container default through an
+// environment variable named after it: -backup is $PHOTONSR_BACKUP, -max-size is
+// $PHOTONSR_MAX_SIZE. The precedence is built-in default < environment < command
+// line, so a flag on the command line always wins.
+const envPrefix = "PHOTONSR_"
+
+// envExemptFlags are never read from the environment: they choose the operation
+// or its text, act once and exit, or their variable already has another meaning
+// ($PHOTONSR_AUDIT_KEY and $PHOTONSR_ANONYMIZE_KEY hold the key itself,
+// $PHOTONSR_LANG also accepts locales).
+var envExemptFlags = map[string]bool{
+	"old": true, "new": true, "old-stdin": true, "new-stdin": true, "old-hex": true, "new-hex": true,
+	"restore": true, "clean": true, "wizard": true,
+	"version": true, "check-update": true, "audit-verify": true,
+	"audit-key": true, "anonymize-key": true, "lang": true, "accessible": true,
+}
+
+// envFlags maps the flags whose value came from the environment to their variable.
+var envFlags = map[string]string{}
+
+// envVarFor returns the environment variable of the named flag.
+func envVarFor(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvDefaults sets the flags of fs that have a non-empty environment variable
+// to its value. It must run before fs is parsed so that the command line overrides
+// it. Boolean variables accept the values of EnvBool and "0", "false", "no", "off".
+func applyEnvDefaults(fs *flag.FlagSet) error {
+	var firstErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if firstErr != nil || envExemptFlags[f.Name] {
+			return
+		}
+		env := envVarFor(f.Name)
+		value := strings.TrimSpace(os.Getenv(env))
+		if value == "" {
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			switch strings.ToLower(value) {
+			case "0", "false", "no", "off":
+				value = "false"
+			default:
+				if !photonsr.EnvBool(env) {
+					firstErr = fmt.Errorf("$%s: invalid boolean '%s' (use 1/0, true/false, yes/no or on/off): %w", env, value, photonsr.ErrInvalidOption)
+					return
+				}
+				value = "true"
+			}
+		}
+		if err := f.Value.Set(value); err != nil {
+			firstErr = fmt.Errorf("$%s: invalid value '%s' for -%s: %w", env, value, f.Name, photonsr.ErrInvalidOption)
+			return
+		}
+		f.DefValue = value // Shown by -help as the effective default.
+		envFlags[f.Name] = env
+	})
+	return firstErr
+}
+
+// flagFromEnv reports whether the named flag took its value from the environment.
+func flagFromEnv(name string) bool {
+	_, ok := envFlags[name]
+	return ok
+}
+
+// envDefaultsSummary describes the flags set from the environment for -verbose,
+// e.g. "PHOTONSR_BACKUP=true, PHOTONSR_JOBS=8", or "" if there are none.
+func envDefaultsSummary() string {
+	var parts []string
+	for name, env := range envFlags {
+		if f := flag.Lookup(name); f != nil && !flagWasSet(name) {
+			parts = append(parts, env+"="+f.Value.String())
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Error Taxonomy ---
+
+// Stable error codes reported in JSON output. They are part of the CLI contract
+// and must not be renamed.
+const (
+	CodePermission       = "permission"
+	CodeNotFound         = "not_found"
+	CodeBinarySkipped    = "binary_skipped"
+	CodeTooLarge         = "too_large"
+	CodeChangedDuringRun = "changed_during_run"
+	CodeInterrupted      = "interrupted"
+	CodeRulesViolated    = "rules_violated"   // "photonsr verify" or "lint" found violations.
+	CodeOutsideDir       = "outside_dir"      // Kept out of the run by -confine.
+	CodeNotApplicable    = "not_applicable"   // Left alone by the operation, e.g. an unknown format.
+	CodeImmutable        = "immutable"        // Marked immutable or append-only (chattr +i, +a).
+	CodeOpenForWrite     = "open_for_write"   // Open for writing by another process (-skip-open).
+	CodeInvalidRename    = "invalid_rename"   // rename-files or move-files planned invalid names or collisions.
+	CodeSecurityContext  = "security_context" // SELinux context could not be kept.
+	CodeInvalidOptions   = "invalid_options"  // Rejected by an options Validate method.
+	CodePolicy           = "policy"           // Refused or skipped by the administrator's policy.
+	CodeIO               = "io"               // Any other failure.
+)
+
+// errorCodes maps each sentinel to its code and CLI exit status, in the order
+// they are checked.
+var errorCodes = []struct {
+	err      error
+	code     string
+	exitCode int
+}{
+	{photonsr.ErrInterrupted, CodeInterrupted, exitInterrupted},
+	{photonsr.ErrPermission, CodePermission, 3},
+	{photonsr.ErrNotFound, CodeNotFound, 4},
+	{photonsr.ErrChangedDuringRun, CodeChangedDuringRun, 5},
+	{photonsr.ErrTooLarge, CodeTooLarge, 6},
+	{photonsr.ErrBinarySkipped, CodeBinarySkipped, 7},
+	{photonsr.ErrRulesViolated, CodeRulesViolated, 8},
+	{photonsr.ErrOutsideDir, CodeOutsideDir, 9},
+	{photonsr.ErrImmutable, CodeImmutable, 10},
+	{photonsr.ErrOpenForWrite, CodeOpenForWrite, 1},
+	{photonsr.ErrSecurityContext, CodeSecurityContext, 11},
+	{photonsr.ErrNotApplicable, CodeNotApplicable, 1},
+	{photonsr.ErrEmptyOldText, CodeInvalidOptions, 2},
+	{photonsr.ErrNotDirectory, CodeInvalidOptions, 2},
+	{photonsr.ErrInvalidOption, CodeInvalidOptions, 2},
+	{photonsr.ErrInvalidRename, CodeInvalidRename, 2},
+	{photonsr.ErrPolicy, CodePolicy, 12},
+	{filepath.ErrBadPattern, CodeInvalidOptions, 2},
+}
+
+// errorCode returns the stable code classifying err ("" for a nil error).
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeIO
+}
+
+// exitCodeFor returns the exit status for an operation that failed with err:
+// 130 when interrupted, 2 for invalid options, 3 for permission problems, 4 for missing files, 5 for files
+// changed during the run, 6 and 7 for the size and binary limits, 8 for rule
+// violations found by verify, 9 for paths outside the directory under -confine,
+// 10 for immutable files, 11 for SELinux contexts that could not be kept, 12 for
+// runs refused by the administrator's policy, 1 for anything else.
+func exitCodeFor(err error) int {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.exitCode
+		}
+	}
+	return 1
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Go Module Rename ---
+
+// "photonsr go-mod-rename old/module new/module" renames the Go module in -dir:
+// the module directive of its go.mod and every import of the module or one of its
+// packages in the .go files below it. The edits run as a replacement with rules,
+// so -backup, -sandbox, -confine and the journal apply as usual. go.sum only holds
+// the checksums of dependencies and needs no edit of its own; -tidy runs
+// "go mod tidy" afterwards to bring it and the requirements up to date.
+
+// moduleRename is a requested module rename.
+type moduleRename struct {
+	oldPath string
+	newPath string
+	line    string // The module directive of go.mod, as written.
+}
+
+// checkModulePath reports whether p can be written as a module path. It checks the
+// characters only; whether the path resolves is left to the go command.
+func checkModulePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("empty module path: %w", photonsr.ErrInvalidOption)
+	}
+	if strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/") || strings.Contains(p, "//") {
+		return fmt.Errorf("module path '%s' has an empty element: %w", p, photonsr.ErrInvalidOption)
+	}
+	for _, r := range p {
+		if r <= ' ' || r == '"' || r == '`' || r == '\\' || r == 0x7f {
+			return fmt.Errorf("module path '%s' contains %q: %w", p, r, photonsr.ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// readModuleDirective returns the module path declared by the go.mod file at path
+// and the line declaring it, without its comment.
+func readModuleDirective(path string) (module, line string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("reading '%s': %w", path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		text, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(text)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		module = fields[1]
+		if unquoted, err := strconv.Unquote(module); err == nil {
+			module = unquoted
+		}
+		return module, strings.TrimSpace(text), nil
+	}
+	return "", "", fmt.Errorf("'%s' has no module directive: %w", path, photonsr.ErrInvalidOption)
+}
+
+// newModuleRename checks that the go.mod file in dir declares oldPath and returns
+// the rename to newPath.
+func newModuleRename(dir, oldPath, newPath string) (*moduleRename, error) {
+	for _, p := range []string{oldPath, newPath} {
+		if err := checkModulePath(p); err != nil {
+			return nil, err
+		}
+	}
+	if oldPath == newPath {
+		return nil, fmt.Errorf("the old and new module paths are both '%s': %w", oldPath, photonsr.ErrInvalidOption)
+	}
+	module, line, err := readModuleDirective(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	if module != oldPath {
+		return nil, fmt.Errorf("go.mod in '%s' declares module '%s', not '%s': %w", dir, module, oldPath, photonsr.ErrInvalidOption)
+	}
+	return &moduleRename{oldPath: oldPath, newPath: newPath, line: line}, nil
+}
+
+// rules returns the replacement rules of the rename. Imports are matched with
+// their opening quote and the character after the path, so "example.com/app" does
+// not also rewrite "example.com/application".
+func (m *moduleRename) rules() []photonsr.RuleSpec {
+	return []photonsr.RuleSpec{
+		{Old: m.line, New: strings.Replace(m.line, m.oldPath, m.newPath, 1), Pattern: "go.mod", HasNew: true},
+		{Old: `"` + m.oldPath + `"`, New: `"` + m.newPath + `"`, Pattern: "*.go", HasNew: true},
+		{Old: `"` + m.oldPath + `/`, New: `"` + m.newPath + `/`, Pattern: "*.go", HasNew: true},
+	}
+}
+
+// runGoModTidy runs "go mod tidy" in dir and returns its combined output.
+func runGoModTidy(ctx context.Context, dir string) (string, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return "", fmt.Errorf("running go mod tidy: the go command is not on PATH: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "go", "mod", "tidy")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			return "", fmt.Errorf("running go mod tidy: %w", err)
+		}
+		return "", fmt.Errorf("running go mod tidy: %s", msg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- License Headers ---
+
+// "photonsr license-headers -header HEADER.txt" keeps the license header at the
+// top of every source file in -dir in line with a template. Headers made from the
+// template get their year range extended to -year and their holder set to
+// -holder; files without a header get one, written in the comment syntax of their
+// language. Files whose header differs from the template, generated files and
+// files in languages without a known comment syntax are left alone.
+
+// Placeholders of a header template.
+const (
+	yearPlaceholder   = "{year}"
+	holderPlaceholder = "{holder}"
+)
+
+// commentStyle is how a language writes comments: lines starting with line, or a
+// block from start to end whose inner lines begin with middle.
+type commentStyle struct {
+	line   string
+	start  string
+	middle string
+	end    string
+}
+
+var (
+	slashComments = commentStyle{line: "//"}
+	hashComments  = commentStyle{line: "#"}
+	dashComments  = commentStyle{line: "--"}
+	starComments  = commentStyle{start: "/*", middle: " * ", end: " */"}
+	xmlComments   = commentStyle{start: "<!--", middle: "  ", end: "-->"}
+)
+
+// commentStyles maps file extensions to the comment syntax of their language.
+var commentStyles = map[string]commentStyle{
+	".go": slashComments, ".c": slashComments, ".h": slashComments, ".cc": slashComments,
+	".cpp": slashComments, ".hpp": slashComments, ".cs": slashComments, ".java": slashComments,
+	".js": slashComments, ".jsx": slashComments, ".mjs": slashComments, ".cjs": slashComments,
+	".ts": slashComments, ".tsx": slashComments, ".kt": slashComments, ".kts": slashComments,
+	".scala": slashComments, ".swift": slashComments, ".rs": slashComments, ".dart": slashComments,
+	".proto": slashComments, ".groovy": slashComments, ".zig": slashComments,
+	".py": hashComments, ".sh": hashComments, ".bash": hashComments, ".zsh": hashComments,
+	".rb": hashComments, ".pl": hashComments, ".pm": hashComments, ".r": hashComments,
+	".yaml": hashComments, ".yml": hashComments, ".toml": hashComments, ".tf": hashComments,
+	".ps1": hashComments, ".cmake": hashComments, ".nix": hashComments, ".ex": hashComments,
+	".exs": hashComments, ".jl": hashComments, ".mk": hashComments,
+	".sql": dashComments, ".lua": dashComments, ".hs": dashComments, ".elm": dashComments,
+	".css": starComments, ".scss": starComments, ".less": starComments,
+	".html": xmlComments, ".htm": xmlComments, ".xml": xmlComments, ".svg": xmlComments, ".vue": xmlComments,
+}
+
+// commentStyleNames maps file names without a telling extension to their syntax.
+var commentStyleNames = map[string]commentStyle{
+	"Makefile": hashComments, "Dockerfile": hashComments, "CMakeLists.txt": hashComments,
+	"Containerfile": hashComments, "Jenkinsfile": slashComments,
+}
+
+// commentStyleFor returns the comment syntax of the file named name.
+func commentStyleFor(name string) (commentStyle, bool) {
+	if style, ok := commentStyleNames[name]; ok {
+		return style, true
+	}
+	style, ok := commentStyles[strings.ToLower(filepath.Ext(name))]
+	return style, ok
+}
+
+// format writes text lines as a comment.
+func (s commentStyle) format(text []string) []string {
+	var out []string
+	if s.line != "" {
+		for _, t := range text {
+			out = append(out, strings.TrimRight(s.line+" "+t, " "))
+		}
+		return out
+	}
+	out = append(out, s.start)
+	for _, t := range text {
+		out = append(out, strings.TrimRight(s.middle+t, " "))
+	}
+	return append(out, strings.TrimLeft(s.end, " "))
+}
+
+// leadingComment finds the first comment of lines at or after from, skipping blank
+// lines. It returns the text of the comment without its markers and, for each text
+// line, the index in lines it comes from; both are empty if no comment follows.
+func (s commentStyle) leadingComment(lines []string, from int) (text []string, index []int) {
+	i := from
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if s.line != "" {
+		for ; i < len(lines); i++ {
+			t := strings.TrimLeft(lines[i], " \t")
+			if !strings.HasPrefix(t, s.line) {
+				break
+			}
+			text = append(text, strings.TrimPrefix(strings.TrimPrefix(t, s.line), " "))
+			index = append(index, i)
+		}
+		return text, index
+	}
+	if i >= len(lines) || !strings.HasPrefix(strings.TrimLeft(lines[i], " \t"), s.start) {
+		return nil, nil
+	}
+	end := strings.TrimSpace(s.end)
+	for first := i; i < len(lines); i++ {
+		t := strings.TrimLeft(lines[i], " \t")
+		if i == first {
+			t = strings.TrimPrefix(t, s.start)
+		}
+		last := strings.Contains(t, end)
+		if last {
+			t = t[:strings.Index(t, end)]
+		}
+		t = strings.TrimLeft(t, " \t")
+		if strings.Contains(s.middle, "*") {
+			t = strings.TrimPrefix(strings.TrimPrefix(t, "*"), " ")
+		}
+		t = strings.TrimRight(t, " \t")
+		if t != "" || len(text) > 0 {
+			text = append(text, t)
+			index = append(index, i)
+		}
+		if last {
+			break
+		}
+	}
+	for len(text) > 0 && text[len(text)-1] == "" {
+		text, index = text[:len(text)-1], index[:len(index)-1]
+	}
+	return text, index
+}
+
+// prologueLen returns the number of leading lines that must stay above a header:
+// a "#!" interpreter line, an XML declaration or doctype, a Python encoding line.
+func prologueLen(lines []string, style commentStyle) int {
+	i := 0
+	if i < len(lines) && strings.HasPrefix(lines[i], "#!") {
+		i++
+	}
+	for i < len(lines) && (strings.HasPrefix(lines[i], "<?xml") || strings.HasPrefix(strings.ToUpper(lines[i]), "<!DOCTYPE")) {
+		i++
+	}
+	if style == hashComments && i < len(lines) && strings.HasPrefix(lines[i], "#") &&
+		(strings.Contains(lines[i], "coding:") || strings.Contains(lines[i], "coding=")) {
+		i++
+	}
+	return i
+}
+
+// headerTemplate is a license header template: plain text lines, without comment
+// markers, with at most one {year} and one {holder} placeholder. It implements
+// Transform.
+type headerTemplate struct {
+	lines   []string       // Template lines, placeholders included.
+	pattern *regexp.Regexp // Matches the comment text of a header made from the template.
+	year    string         // Year headers must cover, e.g. "2026".
+	holder  string         // Copyright holder; required if the template has {holder}.
+}
+
+// loadHeaderTemplate reads a header template file for the given year and holder.
+func loadHeaderTemplate(path, year, holder string) (*headerTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading header template: %w", err)
+	}
+	text := strings.Trim(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("header template '%s' is empty: %w", path, photonsr.ErrInvalidOption)
+	}
+	if strings.Count(text, yearPlaceholder) > 1 || strings.Count(text, holderPlaceholder) > 1 {
+		return nil, fmt.Errorf("header template '%s' uses %s or %s more than once: %w", path, yearPlaceholder, holderPlaceholder, photonsr.ErrInvalidOption)
+	}
+	if !regexp.MustCompile(`^\d{4}$`).MatchString(year) {
+		return nil, fmt.Errorf("invalid year '%s' (expected four digits, e.g. 2026): %w", year, photonsr.ErrInvalidOption)
+	}
+	if strings.Contains(text, holderPlaceholder) && strings.TrimSpace(holder) == "" {
+		return nil, fmt.Errorf("header template '%s' has %s: give the copyright holder with -holder: %w", path, holderPlaceholder, photonsr.ErrInvalidOption)
+	}
+
+	t := &headerTemplate{year: year, holder: strings.TrimSpace(holder)}
+	var expr []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		t.lines = append(t.lines, line)
+		quoted := regexp.QuoteMeta(line)
+		quoted = strings.Replace(quoted, regexp.QuoteMeta(yearPlaceholder), `(?P<year>\d{4}(?:\s*(?:-|–|,)\s*\d{4})*)`, 1)
+		quoted = strings.Replace(quoted, regexp.QuoteMeta(holderPlaceholder), `(?P<holder>.+?)`, 1)
+		expr = append(expr, quoted)
+	}
+	t.pattern = regexp.MustCompile(`^` + strings.Join(expr, `\n`) + `(?:\n|$)`)
+	return t, nil
+}
+
+// Applies reports whether the template can be written into the file named name.
+func (t *headerTemplate) Applies(name string) bool {
+	_, ok := commentStyleFor(name)
+	return ok
+}
+
+// render returns the template lines for a new header.
+func (t *headerTemplate) render() []string {
+	lines := make([]string, len(t.lines))
+	for i, line := range t.lines {
+		line = strings.Replace(line, yearPlaceholder, t.year, 1)
+		lines[i] = strings.Replace(line, holderPlaceholder, t.holder, 1)
+	}
+	return lines
+}
+
+// Apply updates or inserts the header of one file.
+func (t *headerTemplate) Apply(path string, content []byte) ([]byte, error) {
+	style, ok := commentStyleFor(filepath.Base(path))
+	if !ok {
+		return nil, fmt.Errorf("'%s': no known comment syntax: %w", path, photonsr.ErrNotApplicable)
+	}
+	eol := ""
+	if bytes.Contains(content, []byte("\r\n")) {
+		eol = "\r"
+	}
+	lines := strings.Split(string(content), "\n")
+	plain := make([]string, len(lines)) // Without the "\r" of CRLF line endings.
+	for i, line := range lines {
+		plain[i] = strings.TrimSuffix(line, "\r")
+	}
+	prologue := prologueLen(plain, style)
+	text, index := style.leadingComment(plain, prologue)
+	joined := strings.Join(text, "\n")
+
+	if m := t.pattern.FindStringSubmatchIndex(joined); m != nil {
+		for _, field := range []struct {
+			name string
+			next func(string) string
+		}{
+			{"year", func(years string) string { return extendYears(years, t.year) }},
+			{"holder", func(holder string) string { return t.holder }},
+		} {
+			g := t.pattern.SubexpIndex(field.name)
+			if g < 0 || m[2*g] < 0 {
+				continue
+			}
+			old := joined[m[2*g]:m[2*g+1]]
+			if next := field.next(old); next != old {
+				i := index[strings.Count(joined[:m[2*g]], "\n")]
+				lines[i] = strings.Replace(lines[i], old, next, 1)
+			}
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+
+	lower := strings.ToLower(joined)
+	switch {
+	case strings.Contains(joined, "Code generated") && strings.Contains(joined, "DO NOT EDIT"):
+		return nil, fmt.Errorf("'%s' is generated: %w", path, photonsr.ErrNotApplicable)
+	case strings.Contains(lower, "copyright") || strings.Contains(lower, "spdx-license-identifier"):
+		return nil, fmt.Errorf("'%s' has a license header that does not match the template: %w", path, photonsr.ErrNotApplicable)
+	}
+
+	header := style.format(t.render())
+	if prologue >= len(plain) || plain[prologue] != "" {
+		header = append(header, "")
+	}
+	for i := range header {
+		header[i] += eol
+	}
+	out := append([]string{}, lines[:prologue]...)
+	out = append(out, header...)
+	out = append(out, lines[prologue:]...)
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// extendYears extends a year or year list of a header to cover year: "2019"
+// becomes "2019-2026", "2019-2023" becomes "2019-2026" and "2019, 2021" becomes
+// "2019, 2021, 2026". Lists that already reach year are returned unchanged.
+func extendYears(years, year string) string {
+	last := years[len(years)-4:]
+	if last >= year {
+		return years
+	}
+	rest := strings.TrimRight(years[:len(years)-4], " \t")
+	switch {
+	case rest == "":
+		return years + "-" + year
+	case strings.HasSuffix(rest, "-") || strings.HasSuffix(rest, "–"):
+		return years[:len(years)-4] + year
+	}
+	return years + ", " + year
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Wizard Match Heatmap ---
+
+// From the replace summary, t opens the match heatmap: the target directory as a
+// collapsible tree of its directories and matching files, each with the matches
+// below it and a bar showing its share of all matches, so it is easy to see where in
+// the tree the old text is concentrated. Space skips the highlighted file or
+// directory, with everything below it, or includes it again; Enter narrows the
+// operation to the highlighted directory, which becomes the target directory; Esc
+// returns. The counts come from the preview listing and share its limits.
+//
+// Skipped paths are kept in the model. When the replacement starts, the files
+// outside them become its ReplaceOptions.AllowedPaths (see AllowedPaths), so files
+// the listing did not reach are not skipped by accident. The summary offers to save
+// them in the project configuration (see saveExclusions) for later runs of the rule.
+
+// heatmapBarWidth is the width of the bar of the directory with all the matches.
+const heatmapBarWidth = 16
+
+// heatNode is a directory or file of the heatmap tree.
+type heatNode struct {
+	name     string      // Name shown; the path for the root.
+	path     string      // Path of the directory or file.
+	depth    int         // Nesting below the root.
+	file     bool        // True for a file.
+	files    int         // Matching files at or below the node.
+	matches  int         // Occurrences of the old text at or below the node.
+	children []*heatNode // Subdirectories and files with matches, most matches first.
+	expanded bool        // True if the children are shown.
+}
+
+// heatmapState is the match heatmap opened from the replace summary.
+type heatmapState struct {
+	gen       int       // Generation; listings requested earlier are discarded.
+	loading   bool      // True until the listing arrives.
+	root      *heatNode // Tree of the target directory; nil until loaded.
+	truncated bool      // True if a preview limit stopped the listing early.
+	err       error     // Error that stopped the listing, if any.
+	cursor    int       // Highlighted row.
+	offset    int       // First row shown.
+}
+
+// heatmapMsg carries the listing the heatmap is built from.
+type heatmapMsg struct {
+	gen     int
+	listing previewMsg
+}
+
+// openHeatmap shows the match heatmap of the pending replacement. The listing of the
+// live preview is used if it is complete and current; otherwise one is started.
+func (m *model) openHeatmap() tea.Cmd {
+	req, ok := m.previewRequest()
+	if !ok {
+		return nil
+	}
+	m.step = stepMatchHeatmap
+	h := &m.heatmap
+	h.gen++
+	h.cursor, h.offset = 0, 0
+	if p := m.preview; p.gen > 0 && p.request == req && !p.loading && p.err == nil {
+		h.load(req.dir, previewMsg{files: p.files, scanned: p.scanned, truncated: p.truncated})
+		return nil
+	}
+	h.loading, h.root, h.err = true, nil, nil
+	gen := h.gen
+	return func() tea.Msg { return heatmapMsg{gen: gen, listing: listPreview(req, gen)} }
+}
+
+// handleHeatmap builds the tree from a finished listing.
+func (m *model) handleHeatmap(msg heatmapMsg) {
+	if msg.gen == m.heatmap.gen && m.step == stepMatchHeatmap {
+		m.heatmap.load(m.targetDir, msg.listing)
+	}
+}
+
+// load builds the tree of dir from listing.
+func (h *heatmapState) load(dir string, listing previewMsg) {
+	h.loading = false
+	h.err, h.truncated = listing.err, listing.truncated
+	h.root = buildHeatTree(dir, listing.files)
+}
+
+// buildHeatTree returns the tree of dir holding files, with the counts of each
+// directory summed over everything below it. The root and its first level are
+// expanded.
+func buildHeatTree(dir string, files []previewFile) *heatNode {
+	root := &heatNode{name: dir, path: dir, expanded: true}
+	for _, f := range files {
+		matches := max(f.matches, 0)
+		node := root
+		node.files++
+		node.matches += matches
+		if rel := photonsr.RelPath(dir, filepath.Dir(f.path)); rel != "." {
+			node = root.descend(strings.Split(rel, string(filepath.Separator)), matches)
+		}
+		name := filepath.Base(f.path)
+		node.children = append(node.children, &heatNode{name: name, path: filepath.Join(node.path, name), depth: node.depth + 1, file: true, files: 1, matches: matches})
+	}
+	root.sortChildren()
+	for _, c := range root.children {
+		c.expanded = true
+	}
+	return root
+}
+
+// descend returns the directory below n that parts name, creating the missing
+// directories, and adds a file with matches to the counts of each on the way.
+func (n *heatNode) descend(parts []string, matches int) *heatNode {
+	node := n
+	for _, part := range parts {
+		var child *heatNode
+		for _, c := range node.children {
+			if c.name == part && !c.file {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &heatNode{name: part, path: filepath.Join(node.path, part), depth: node.depth + 1}
+			node.children = append(node.children, child)
+		}
+		node = child
+		node.files++
+		node.matches += matches
+	}
+	return node
+}
+
+// sortChildren orders the children of n and all below it by matches, then by name.
+func (n *heatNode) sortChildren() {
+	sort.Slice(n.children, func(i, j int) bool {
+		a, b := n.children[i], n.children[j]
+		if a.matches != b.matches {
+			return a.matches > b.matches
+		}
+		return a.name < b.name
+	})
+	for _, c := range n.children {
+		c.sortChildren()
+	}
+}
+
+// rows returns the nodes shown, in display order.
+func (h heatmapState) rows() []*heatNode {
+	var rows []*heatNode
+	var add func(n *heatNode)
+	add = func(n *heatNode) {
+		rows = append(rows, n)
+		if n.expanded {
+			for _, c := range n.children {
+				add(c)
+			}
+		}
+	}
+	if h.root != nil {
+		add(h.root)
+	}
+	return rows
+}
+
+// selected returns the highlighted directory, or nil before the tree is loaded.
+func (h heatmapState) selected() *heatNode {
+	rows := h.rows()
+	if h.cursor >= len(rows) {
+		return nil
+	}
+	return rows[h.cursor]
+}
+
+// move moves the highlight by delta rows.
+func (h *heatmapState) move(delta, pageSize int) {
+	h.cursor = clampInt(h.cursor+delta, 0, len(h.rows())-1)
+	h.offset = scrollOffset(h.offset, h.cursor, pageSize)
+}
+
+// collapse collapses the highlighted directory, or highlights its parent if it is
+// collapsed already.
+func (h *heatmapState) collapse(pageSize int) {
+	n := h.selected()
+	if n == nil {
+		return
+	}
+	if n.expanded && len(n.children) > 0 {
+		n.expanded = false
+		return
+	}
+	rows := h.rows()
+	for i := h.cursor - 1; i >= 0; i-- {
+		if rows[i].depth < n.depth {
+			h.move(i-h.cursor, pageSize)
+			return
+		}
+	}
+}
+
+// updateHeatmap handles a key of the heatmap step.
+func (m model) updateHeatmap(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	h := &m.heatmap
+	page := m.pageSize()
+	switch msg.String() {
+	case "up", "k":
+		h.move(-1, page)
+	case "down", "j":
+		h.move(1, page)
+	case "pgup":
+		h.move(-page, page)
+	case "pgdown":
+		h.move(page, page)
+	case "home", "g":
+		h.move(-len(h.rows()), page)
+	case "end", "G":
+		h.move(len(h.rows()), page)
+	case "right", "l":
+		if n := h.selected(); n != nil {
+			n.expanded = true
+		}
+	case "left", "h":
+		h.collapse(page)
+	case " ", "x":
+		if n := h.selected(); n != nil && n.depth > 0 {
+			m.toggleSkipped(n.path)
+		}
+	case "enter":
+		if n := h.selected(); n != nil && n.path != m.targetDir {
+			m.narrowTo(n.path)
+		}
+		m.step = stepConfirmOperation
+	}
+	return m, nil
+}
+
+// toggleSkipped skips the file or directory at path, or includes it again.
+func (m *model) toggleSkipped(path string) {
+	if m.skippedPaths[path] {
+		delete(m.skippedPaths, path)
+		return
+	}
+	if m.skippedPaths == nil {
+		m.skippedPaths = map[string]bool{}
+	}
+	m.skippedPaths[path] = true
+}
+
+// narrowTo makes dir, a subdirectory of the target directory, the target directory
+// of the pending replacement. Skipped paths outside dir are forgotten.
+func (m *model) narrowTo(dir string) {
+	var conflicts []string
+	for _, path := range m.backupConflicts {
+		if photonsr.Within(dir, path) {
+			conflicts = append(conflicts, path)
+		}
+	}
+	m.backupConflicts = conflicts
+	for path := range m.skippedPaths {
+		if !photonsr.Within(dir, path) {
+			delete(m.skippedPaths, path)
+		}
+	}
+	m.noticeMessages = append(m.noticeMessages, tr("heatmap.narrowed", photonsr.RelPath(m.targetDir, dir)))
+	m.targetDir = dir
+}
+
+// saveSkippedPaths saves the skipped paths as exclusions of the pending replacement
+// in the project configuration.
+func (m *model) saveSkippedPaths() {
+	paths := make([]string, 0, len(m.skippedPaths))
+	for path := range m.skippedPaths {
+		paths = append(paths, path)
+	}
+	configPath, added, err := saveExclusions(m.targetDir, m.oldText, m.newText, paths)
+	if err != nil {
+		m.errorMessage = tr("err.save_exclusions", err)
+		return
+	}
+	m.errorMessage = ""
+	m.noticeMessages = append(m.noticeMessages, tr("heatmap.saved", added, configPath))
+}
+
+// skippedPathList returns the skipped paths relative to the target directory,
+// sorted and separated by commas.
+func (m model) skippedPathList() string {
+	var paths []string
+	for path := range m.skippedPaths {
+		paths = append(paths, photonsr.RelPath(m.targetDir, path))
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ", ")
+}
+
+// skippedMatches returns the matches at or below n that are skipped.
+func (n *heatNode) skippedMatches(skipped map[string]bool) int {
+	if skipped[n.path] {
+		return n.matches
+	}
+	total := 0
+	for _, c := range n.children {
+		total += c.skippedMatches(skipped)
+	}
+	return total
+}
+
+// view renders the heatmap in width columns with pageSize rows of directories; the
+// directories in skipped, and everything below them, are shown as skipped.
+func (h heatmapState) view(width, pageSize int, skipped map[string]bool) string {
+	var b strings.Builder
+	switch {
+	case h.err != nil:
+		return tr("preview.error", h.err) + "\n"
+	case h.loading || h.root == nil:
+		return tr("heatmap.loading") + "\n"
+	case h.root.files == 0:
+		return tr("heatmap.empty") + "\n"
+	}
+	status := tr("heatmap.count", h.root.matches, h.root.files)
+	if n := h.root.skippedMatches(skipped); n > 0 {
+		status = tr("heatmap.count_skipped", h.root.matches-n, h.root.matches, h.root.files)
+	}
+	if h.truncated {
+		status += " " + tr("preview.truncated")
+	}
+	b.WriteString(status + "\n\n")
+
+	// Heat colors from the largest share of the matches down.
+	hot := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	warm := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	mild := lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	skippedStyle := lipgloss.NewStyle().Faint(true).Strikethrough(true)
+	rows := h.rows()
+	// Rows come parent first, so a row is skipped if its directory is or the last
+	// row one level up was.
+	inSkipped := make([]bool, len(rows))
+	var levels []bool
+	for i, n := range rows {
+		levels = append(levels[:n.depth], skipped[n.path] || n.depth > 0 && levels[n.depth-1])
+		inSkipped[i] = levels[n.depth]
+	}
+	counts := make([]string, len(rows))
+	countWidth := 0
+	for i, n := range rows {
+		counts[i] = tr("heatmap.row", n.matches, n.files)
+		countWidth = max(countWidth, len([]rune(counts[i])))
+	}
+	nameWidth := max(width-heatmapBarWidth-countWidth-8, 10)
+	end := min(h.offset+pageSize, len(rows))
+	for i := h.offset; i < end; i++ {
+		n := rows[i]
+		prefix := "  "
+		if i == h.cursor {
+			prefix = "> "
+		}
+		marker := "  "
+		if len(n.children) > 0 {
+			marker = "▸ "
+			if n.expanded {
+				marker = "▾ "
+			}
+		}
+		name := n.name
+		if n.depth > 0 && !n.file {
+			name += string(filepath.Separator)
+		}
+		label := strings.Repeat("  ", n.depth) + marker + photonsr.TruncateMiddle(name, nameWidth-2*n.depth-2)
+		var share float64
+		if h.root.matches > 0 {
+			share = float64(n.matches) / float64(h.root.matches)
+		} else {
+			share = float64(n.files) / float64(h.root.files)
+		}
+		bar := strings.Repeat("█", int(share*heatmapBarWidth+0.5))
+		switch {
+		case share >= 0.5:
+			bar = hot.Render(bar)
+		case share >= 0.2:
+			bar = warm.Render(bar)
+		default:
+			bar = mild.Render(bar)
+		}
+		pad := strings.Repeat(" ", max(nameWidth-len([]rune(label)), 0))
+		row := fmt.Sprintf("%s%s  %*s", label, pad, countWidth, counts[i])
+		if inSkipped[i] {
+			row, bar = skippedStyle.Render(row), tr("heatmap.skipped")
+		}
+		b.WriteString(fmt.Sprintf("%s%s  %s\n", prefix, row, bar))
+	}
+	if len(rows) > pageSize {
+		b.WriteString(tr("page.position", h.offset+1, end, len(rows)) + "\n")
+	}
+	return b.String()
+}
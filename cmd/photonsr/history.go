@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Run History ---
+
+// Every CLI operation is recorded in the state directory: the options it ran with,
+// its counts, the files it modified, skipped or failed on, and its error. "photonsr
+// runs" lists the recorded runs, newest first, and "photonsr runs diff <id1> <id2>"
+// compares two of them, which shows regressions when the same migration is
+// replayed on a newer tree. Paths are kept relative to the target directory, so runs
+// on different checkouts of a project compare file by file. Only the newest
+// historyLimit runs are kept.
+
+const (
+	historyDir   = "history"             // Run records in the state directory.
+	historyLimit = 200                   // Runs kept; older records are removed.
+	noHistoryEnv = "PHOTONSR_NO_HISTORY" // Set to a true value to stop recording.
+)
+
+// historyRecord is the record of one CLI run.
+type historyRecord struct {
+	ID            string            `json:"id"`                   // Run identifier (see NewRunID).
+	Time          string            `json:"time"`                 // RFC 3339 time the run ended.
+	Operation     string            `json:"operation"`            // Operation name (see operationNames).
+	Dir           string            `json:"dir"`                  // Absolute target directory.
+	Options       map[string]string `json:"options"`              // Values of retryOptionFlags.
+	ItemsAffected int               `json:"items_affected"`       // Files modified, restored, cleaned, ...
+	FilesScanned  int               `json:"files_scanned"`        // Files examined.
+	Modified      []string          `json:"modified,omitempty"`   // Modified files, relative to Dir.
+	Failed        []failedFile      `json:"failed,omitempty"`     // Files that failed, relative to Dir.
+	Skipped       []failedFile      `json:"skipped,omitempty"`    // Files left alone, relative to Dir.
+	Error         string            `json:"error,omitempty"`      // Error the run ended with, if any.
+	ErrorCode     string            `json:"error_code,omitempty"` // Stable code of Error (see errorCode).
+}
+
+// historyPath returns the path of the record of run id.
+func historyPath(id string) (string, error) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return "", err
+	}
+	if id == "" || filepath.Base(id) != id {
+		return "", fmt.Errorf("invalid run id '%s'", id)
+	}
+	return filepath.Join(dir, historyDir, id+".json"), nil
+}
+
+// relativeFiles returns files with their paths relative to dir.
+func relativeFiles(dir string, files []failedFile) []failedFile {
+	out := make([]failedFile, len(files))
+	for i, f := range files {
+		f.Path = filepath.ToSlash(photonsr.RelPath(dir, f.Path))
+		out[i] = f
+	}
+	return out
+}
+
+// recordHistory saves rec and removes the oldest records beyond historyLimit. Like
+// the usage statistics, the history is best effort: problems are ignored.
+func recordHistory(rec historyRecord) {
+	if photonsr.EnvBool(noHistoryEnv) || rec.Operation == "" {
+		return
+	}
+	path, err := historyPath(rec.ID)
+	if err != nil || photonsr.FS.MkdirAll(filepath.Dir(path), 0o700) != nil {
+		return
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil || photonsr.WriteFileAtomic(path, append(data, '\n'), 0o600) != nil {
+		return
+	}
+	ids, err := historyIDs()
+	if err != nil {
+		return
+	}
+	for len(ids) > historyLimit {
+		if old, err := historyPath(ids[0]); err == nil {
+			photonsr.FS.Remove(old)
+		}
+		ids = ids[1:]
+	}
+}
+
+// historyIDs returns the ids of the recorded runs, oldest first.
+func historyIDs() ([]string, error) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, historyDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading run history: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && e.Type().IsRegular() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids) // Run ids start with their time.
+	return ids, nil
+}
+
+// loadHistoryRecord reads the record of run id.
+func loadHistoryRecord(id string) (historyRecord, error) {
+	var rec historyRecord
+	path, err := historyPath(id)
+	if err != nil {
+		return rec, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rec, fmt.Errorf("no recorded run '%s' (see photonsr runs)", id)
+	}
+	if err != nil {
+		return rec, fmt.Errorf("reading run record '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, fmt.Errorf("parsing run record '%s': %w", path, err)
+	}
+	return rec, nil
+}
+
+// writeRunList writes one line per recorded run to w, newest first.
+func writeRunList(w io.Writer) error {
+	ids, err := historyIDs()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(w, "No runs recorded yet.")
+		return nil
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		rec, err := loadHistoryRecord(ids[i])
+		if err != nil {
+			return err
+		}
+		status := "ok"
+		if rec.Error != "" {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%s  %-12s %s=%d scanned=%d errors=%d %-6s %s\n", rec.ID, rec.Operation,
+			operationVerb(rec.Operation), rec.ItemsAffected, rec.FilesScanned, len(rec.Failed), status, rec.Dir)
+	}
+	return nil
+}
+
+// runDiff is the comparison of two recorded runs by "photonsr runs diff".
+type runDiff struct {
+	Base          historyRecord        `json:"base"`                            // Earlier run.
+	Next          historyRecord        `json:"next"`                            // Later run.
+	Options       map[string][2]string `json:"options,omitempty"`               // Options that differ: base and next value.
+	OnlyBase      []string             `json:"modified_only_in_base,omitempty"` // Modified by base only: missed by next.
+	OnlyNext      []string             `json:"modified_only_in_next,omitempty"` // Modified by next only.
+	NewFailures   []failedFile         `json:"new_failures,omitempty"`          // Failed in next only.
+	FixedFailures []failedFile         `json:"fixed_failures,omitempty"`        // Failed in base only.
+	StillFailing  []failedFile         `json:"still_failing,omitempty"`         // Failed in both, as in next.
+	Regressions   int                  `json:"regressions"`                     // Files missed by next and new failures, plus 1 if only next ended with an error.
+}
+
+// diffRuns compares run base with the later run next.
+func diffRuns(base, next historyRecord) runDiff {
+	d := runDiff{Base: base, Next: next}
+	var names []string
+	for name := range base.Options {
+		names = append(names, name)
+	}
+	for name := range next.Options {
+		if _, ok := base.Options[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if base.Options[name] != next.Options[name] {
+			if d.Options == nil {
+				d.Options = map[string][2]string{}
+			}
+			d.Options[name] = [2]string{base.Options[name], next.Options[name]}
+		}
+	}
+	d.OnlyBase = missingFrom(base.Modified, next.Modified)
+	d.OnlyNext = missingFrom(next.Modified, base.Modified)
+
+	baseFailed := map[string]bool{}
+	for _, f := range base.Failed {
+		baseFailed[f.Path] = true
+	}
+	nextFailed := map[string]bool{}
+	for _, f := range next.Failed {
+		nextFailed[f.Path] = true
+		if baseFailed[f.Path] {
+			d.StillFailing = append(d.StillFailing, f)
+		} else {
+			d.NewFailures = append(d.NewFailures, f)
+		}
+	}
+	for _, f := range base.Failed {
+		if !nextFailed[f.Path] {
+			d.FixedFailures = append(d.FixedFailures, f)
+		}
+	}
+	d.Regressions = len(d.OnlyBase) + len(d.NewFailures)
+	if next.Error != "" && base.Error == "" {
+		d.Regressions++
+	}
+	return d
+}
+
+// missingFrom returns the paths of a that are not in b, in the order of a.
+func missingFrom(a, b []string) []string {
+	in := map[string]bool{}
+	for _, p := range b {
+		in[p] = true
+	}
+	var missing []string
+	for _, p := range a {
+		if !in[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// lines returns the comparison as the text printed by "photonsr runs diff".
+func (d runDiff) lines() []string {
+	b, n := d.Base, d.Next
+	lines := []string{
+		fmt.Sprintf("Base: %s  %s in %s (%s)", b.ID, b.Operation, b.Dir, b.Time),
+		fmt.Sprintf("Next: %s  %s in %s (%s)", n.ID, n.Operation, n.Dir, n.Time),
+	}
+	if b.Operation != n.Operation {
+		lines = append(lines, "Warning: the runs are different operations.")
+	}
+	if len(d.Options) > 0 {
+		var names []string
+		for name := range d.Options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines = append(lines, "Options that differ:")
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("  - -%s: %q -> %q", name, d.Options[name][0], d.Options[name][1]))
+		}
+	}
+	count := func(label string, x, y int) string {
+		return fmt.Sprintf("%-15s %d -> %d (%+d)", label+":", x, y, y-x)
+	}
+	lines = append(lines,
+		count("Files scanned", b.FilesScanned, n.FilesScanned),
+		count("Items affected", b.ItemsAffected, n.ItemsAffected),
+		count("Files failed", len(b.Failed), len(n.Failed)),
+		count("Files skipped", len(b.Skipped), len(n.Skipped)),
+	)
+	if b.Error != n.Error {
+		lines = append(lines, fmt.Sprintf("Error: %s -> %s", orNone(b.Error), orNone(n.Error)))
+	}
+	lines = append(lines, photonsr.ListFiles(fmt.Sprintf("Modified by base only (%d):", len(d.OnlyBase)), d.OnlyBase, nil)...)
+	lines = append(lines, photonsr.ListFiles(fmt.Sprintf("Modified by next only (%d):", len(d.OnlyNext)), d.OnlyNext, nil)...)
+	failures := func(header string, files []failedFile) []string {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, fmt.Sprintf("%s: %s", f.Path, f.Error))
+		}
+		return photonsr.ListFiles(fmt.Sprintf(header, len(files)), paths, nil)
+	}
+	lines = append(lines, failures("New failures (%d):", d.NewFailures)...)
+	lines = append(lines, failures("Fixed failures (%d):", d.FixedFailures)...)
+	lines = append(lines, failures("Still failing (%d):", d.StillFailing)...)
+	if d.Regressions == 0 {
+		lines = append(lines, "No regressions.")
+	} else {
+		lines = append(lines, fmt.Sprintf("%d regression(s).", d.Regressions))
+	}
+	return lines
+}
+
+// orNone returns s, or "none" if it is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// newHistoryRecord returns the record of a run in dir ending now, with the paths of
+// the files made relative to dir.
+func newHistoryRecord(id, operation, dir string, items, scanned int, modified []string, failed, skipped []failedFile, err error) historyRecord {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	rec := historyRecord{
+		ID: id, Time: time.Now().UTC().Format(time.RFC3339), Operation: operation, Dir: dir,
+		ItemsAffected: items, FilesScanned: scanned,
+		Failed: relativeFiles(dir, failed), Skipped: relativeFiles(dir, skipped),
+	}
+	for _, path := range modified {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		rec.Modified = append(rec.Modified, filepath.ToSlash(photonsr.RelPath(dir, path)))
+	}
+	if err != nil {
+		rec.Error, rec.ErrorCode = err.Error(), errorCode(err)
+	}
+	return rec
+}
+
+// runsCommand runs "photonsr runs" with args, "list" (the default) or "diff <id1>
+// <id2>", writing in format (text or json) to w, and returns the exit status: 1 if
+// the diff found regressions or on errors, 2 for bad arguments.
+func runsCommand(w io.Writer, args []string, format string) int {
+	if format != outputText && format != outputJSON {
+		fmt.Fprintf(os.Stderr, "Error: runs supports -output text or json.\n")
+		return 2
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	switch {
+	case len(args) == 0 || len(args) == 1 && args[0] == "list":
+		if format == outputText {
+			if err := writeRunList(w); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+		ids, err := historyIDs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		records := []historyRecord{}
+		for i := len(ids) - 1; i >= 0; i-- {
+			rec, err := loadHistoryRecord(ids[i])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			records = append(records, rec)
+		}
+		if err := enc.Encode(records); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: encoding run list: %v\n", err)
+			return 1
+		}
+		return 0
+	case len(args) == 3 && args[0] == "diff":
+		base, err := loadHistoryRecord(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		next, err := loadHistoryRecord(args[2])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		d := diffRuns(base, next)
+		if format == outputJSON {
+			if err := enc.Encode(d); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: encoding run diff: %v\n", err)
+				return 1
+			}
+		} else {
+			for _, line := range d.lines() {
+				fmt.Fprintln(w, line)
+			}
+		}
+		if d.Regressions > 0 {
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "Error: usage: photonsr runs [list] | photonsr runs diff <id1> <id2>")
+	return 2
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// --- Message Catalog ---
+
+// catalogs holds the user-facing strings of the CLI summaries and the TUI, keyed by
+// language and then by message key. English is the reference and fallback language.
+var catalogs = map[string]map[string]string{
+	"en": messagesEN,
+	"id": messagesID,
+}
+
+// currentLang is the language used by tr. It is set once at startup by setLanguage.
+var currentLang = "en"
+
+// tr returns the message for key in the current language, falling back to English
+// and then to the key itself. With args, the message is used as a fmt format string.
+func tr(key string, args ...any) string {
+	msg, ok := catalogs[currentLang][key]
+	if !ok {
+		if msg, ok = messagesEN[key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// availableLanguages returns the supported language codes, sorted.
+func availableLanguages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// normalizeLanguage reduces a locale such as "id_ID.UTF-8" or "en-US" to its
+// language code ("id", "en").
+func normalizeLanguage(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+// detectLanguage picks the language from PHOTONSR_LANG, then the usual POSIX
+// locale variables. Unsupported or unset locales yield English.
+func detectLanguage() string {
+	for _, env := range []string{"PHOTONSR_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(env); value != "" {
+			if lang := normalizeLanguage(value); catalogs[lang] != nil {
+				return lang
+			}
+			if env == "PHOTONSR_LANG" {
+				continue
+			}
+			// The first POSIX variable that is set decides, as with setlocale.
+			break
+		}
+	}
+	return "en"
+}
+
+// setLanguage selects the language used by tr. An empty lang means detectLanguage.
+func setLanguage(lang string) error {
+	if lang == "" {
+		currentLang = detectLanguage()
+		return nil
+	}
+	code := normalizeLanguage(lang)
+	if catalogs[code] == nil {
+		return fmt.Errorf("unsupported language '%s' (available: %s)", lang, strings.Join(availableLanguages(), ", "))
+	}
+	currentLang = code
+	return nil
+}
+
+// yesNo renders a boolean as a localized "Yes"/"No".
+func yesNo(b bool) string {
+	if b {
+		return tr("common.yes")
+	}
+	return tr("common.no")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- INI and TOML Key Scope ---
+
+// With -key, -old is replaced by -new only in the value of one key of INI-style
+// files (.ini, .conf, .cfg, .properties, .env) and TOML files. The key is written
+// "section.name", or just "name" for keys above the first section; the section may
+// itself contain dots ("tool.poetry.name"). Only the value is rewritten: the key,
+// the separator, quotes, inline comments and every other line are kept as they are.
+
+// configKey replaces text in the value of one key of config files. It implements
+// Transform.
+type configKey struct {
+	old, new string
+	section  string // "" for keys above the first section.
+	name     string
+}
+
+// newConfigKey validates -key.
+func newConfigKey(key, oldText, newText string) (*configKey, error) {
+	key = strings.TrimSpace(key)
+	c := &configKey{old: oldText, new: newText, name: key}
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		c.section, c.name = key[:i], key[i+1:]
+	}
+	if c.name == "" || strings.ContainsAny(c.name, "=[]") {
+		return nil, fmt.Errorf("-key '%s' must be \"section.name\" or \"name\": %w", key, photonsr.ErrInvalidOption)
+	}
+	return c, nil
+}
+
+// Applies reports whether the file named name is an INI-style or TOML file.
+func (c *configKey) Applies(name string) bool {
+	if strings.HasPrefix(name, ".env") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ini", ".conf", ".cfg", ".properties", ".env", ".toml":
+		return true
+	}
+	return false
+}
+
+// Apply replaces the old text in the values of the key in content.
+func (c *configKey) Apply(path string, content []byte) ([]byte, error) {
+	var out []byte
+	section, last := "", 0
+	for pos := 0; pos < len(content); {
+		end := bytes.IndexByte(content[pos:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += pos
+		}
+		line := string(bytes.TrimRight(content[pos:end], "\r"))
+		trimmed := strings.TrimSpace(line)
+		next := end + 1
+
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';':
+		case trimmed[0] == '[':
+			name := strings.Trim(trimmed, "[]")
+			if i := strings.IndexAny(trimmed, "#;"); i > 0 && strings.Contains(trimmed[:i], "]") {
+				name = strings.Trim(strings.TrimSpace(trimmed[:i]), "[]") // [section] # comment
+			}
+			section = strings.TrimSpace(name)
+		default:
+			eq := strings.IndexByte(line, '=')
+			if eq < 0 {
+				break
+			}
+			key := strings.TrimSpace(line[:eq])
+			key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+			key = strings.Trim(key, `"'`)
+			start, stop := valueSpan(content, pos+eq+1, end)
+			if stop > end {
+				next = stop // A multi-line string; continue after it.
+				if i := bytes.IndexByte(content[stop:], '\n'); i >= 0 {
+					next = stop + i + 1
+				} else {
+					next = len(content)
+				}
+			}
+			if section != c.section || key != c.name {
+				break
+			}
+			value := string(content[start:stop])
+			if !strings.Contains(value, c.old) {
+				break
+			}
+			out = append(out, content[last:start]...)
+			out = append(out, strings.ReplaceAll(value, c.old, c.new)...)
+			last = stop
+		}
+		pos = next
+	}
+	if out == nil {
+		return content, nil
+	}
+	return append(out, content[last:]...), nil
+}
+
+// valueSpan returns the bounds of the value that starts after the separator at
+// from, on the line ending at eol: a quoted string without its quotes (a TOML
+// multi-line string may continue past eol), or the text up to an inline comment,
+// without surrounding blanks.
+func valueSpan(content []byte, from, eol int) (int, int) {
+	start := from
+	for start < eol && (content[start] == ' ' || content[start] == '\t') {
+		start++
+	}
+	rest := content[start:]
+	for _, quote := range []string{`"""`, `'''`} {
+		if bytes.HasPrefix(rest, []byte(quote)) {
+			if i := bytes.Index(content[start+3:], []byte(quote)); i >= 0 {
+				return start + 3, start + 3 + i
+			}
+			return start + 3, len(content)
+		}
+	}
+	if start < eol && (content[start] == '"' || content[start] == '\'') {
+		quote := content[start]
+		for i := start + 1; i < eol; i++ {
+			switch {
+			case content[i] == '\\' && quote == '"':
+				i++
+			case content[i] == quote:
+				return start + 1, i
+			}
+		}
+		return start + 1, eol // Unterminated: the rest of the line.
+	}
+	stop := eol
+	for i := start; i < eol; i++ {
+		if (content[i] == '#' || content[i] == ';') && (i == start || content[i-1] == ' ' || content[i-1] == '\t') {
+			stop = i
+			break
+		}
+	}
+	for stop > start && (content[stop-1] == ' ' || content[stop-1] == '\t' || content[stop-1] == '\r') {
+		stop--
+	}
+	return start, stop
+}
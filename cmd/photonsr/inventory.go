@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- String Inventory ---
+
+// "photonsr inventory <expression>" counts the strings a regular expression matches
+// across the files of -dir that match -pattern, e.g. the string literals of a code
+// base with '"[^"]+"', to plan which ones to migrate next. With a capture group,
+// the group is counted instead of the whole match ('"([^"]+)"' counts the text
+// between the quotes). The report lists the most frequent strings, -top of them,
+// with their occurrences and the number of files containing them; -csv writes
+// every string to a file for a spreadsheet. Binary files are left out, and -max-size
+// leaves larger files unread. Nothing is modified.
+
+// writeInventoryCSV writes entries to the CSV file at path, with a header row:
+// string, count, files and first_file.
+func writeInventoryCSV(path string, entries []photonsr.InventoryEntry) error {
+	err := photonsr.WriteFileAtomicFrom(path, 0o644, func(w io.Writer) error {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"string", "count", "files", "first_file"})
+		for _, e := range entries {
+			cw.Write([]string{e.Text, strconv.Itoa(e.Count), strconv.Itoa(e.Files), filepath.ToSlash(e.First)})
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return fmt.Errorf("writing inventory CSV '%s': %w", path, err)
+	}
+	return nil
+}
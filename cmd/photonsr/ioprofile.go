@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- I/O Profiles ---
+
+// Storage kinds, used as -io-profile values. IOProfileAuto detects the kind of the
+// target directory's storage.
+const (
+	IOProfileAuto    = "auto"
+	IOProfileHDD     = "hdd"
+	IOProfileSSD     = "ssd"
+	IOProfileNetwork = "network"
+)
+
+// ioProfile tunes replacement for a kind of storage.
+type ioProfile struct {
+	Name      string // Storage kind (IOProfileHDD, IOProfileSSD or IOProfileNetwork).
+	Jobs      int    // Worker count, unless -jobs is given explicitly.
+	ReadAhead int    // Read buffer, in bytes, for streamed files.
+	Order     string // Order in which files are dispatched to workers.
+}
+
+// ioProfileFor returns the tuning for a storage kind. Spinning disks get a single
+// worker, large reads and inode order (close to on-disk layout, fewer seeks); SSDs
+// one worker per CPU; network file systems extra workers to hide latency.
+func ioProfileFor(kind string) (ioProfile, bool) {
+	switch kind {
+	case IOProfileHDD:
+		return ioProfile{Name: kind, Jobs: 1, ReadAhead: 1 << 20, Order: photonsr.OrderInode}, true
+	case IOProfileSSD:
+		return ioProfile{Name: kind, Jobs: runtime.NumCPU(), ReadAhead: 64 << 10, Order: photonsr.OrderPath}, true
+	case IOProfileNetwork:
+		return ioProfile{Name: kind, Jobs: 2 * runtime.NumCPU(), ReadAhead: 256 << 10, Order: photonsr.OrderPath}, true
+	}
+	return ioProfile{}, false
+}
+
+// resolveIOProfile returns the profile named by an -io-profile value for dir.
+// With IOProfileAuto, detected reports whether the storage kind could be determined;
+// unknown storage is treated as an SSD.
+func resolveIOProfile(name, dir string) (profile ioProfile, detected bool, err error) {
+	if name == IOProfileAuto {
+		kind := detectStorageKind(dir)
+		detected = kind != ""
+		if !detected {
+			kind = IOProfileSSD
+		}
+		profile, _ = ioProfileFor(kind)
+		return profile, detected, nil
+	}
+	profile, ok := ioProfileFor(name)
+	if !ok {
+		return ioProfile{}, false, fmt.Errorf("unknown I/O profile '%s' (expected auto, hdd, ssd or network)", name)
+	}
+	return profile, false, nil
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Magic numbers (statfs f_type) of network file systems.
+var networkFSTypes = map[int64]bool{
+	0x6969:     true, // NFS
+	0x517B:     true, // SMB
+	0xFF534D42: true, // CIFS
+	0xFE534D42: true, // SMB2
+	0x564C:     true, // NCP
+	0x73757245: true, // Coda
+	0x65735546: true, // FUSE (sshfs and most remote mounts)
+	0x00C36400: true, // Ceph
+	0x01161970: true, // GFS2
+}
+
+// detectStorageKind returns the storage kind holding dir, or "" if unknown.
+func detectStorageKind(dir string) string {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &fs); err == nil && networkFSTypes[int64(fs.Type)] {
+		return IOProfileNetwork
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(dir, &st); err != nil {
+		return ""
+	}
+	major, minor := (st.Dev>>8)&0xfff|(st.Dev>>32)&^0xfff, st.Dev&0xff|(st.Dev>>12)&^0xff
+	// The device may be a partition; its queue settings live on the parent disk.
+	dev, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		return ""
+	}
+	for _, candidate := range []string{dev, filepath.Dir(dev)} {
+		data, err := os.ReadFile(filepath.Join(candidate, "queue", "rotational"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(data)) == "1" {
+			return IOProfileHDD
+		}
+		return IOProfileSSD
+	}
+	return ""
+}
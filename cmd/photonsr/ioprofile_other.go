@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// detectStorageKind returns the storage kind holding dir, or "" if unknown.
+// Detection is only implemented on Linux.
+func detectStorageKind(dir string) string {
+	return ""
+}
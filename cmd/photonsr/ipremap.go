@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- IP Address Renumbering ---
+
+// With -preset cidr, -old is a network (e.g. 10.1.0.0/16) and every IPv4 or IPv6
+// address inside it is rewritten: into the network given by -new at the same
+// offset (10.1.2.3 becomes 10.9.2.3 for -new 10.9.0.0/16), or through the table of
+// -ip-map. Addresses are recognized as whole tokens, also with a port or prefix
+// length after them; version numbers such as 10.1.2.3.4 are not addresses.
+
+// PresetCIDR renumbers the addresses of a network.
+const PresetCIDR = "cidr"
+
+// ipRemap rewrites the addresses of a network. It implements Transform.
+type ipRemap struct {
+	from  netip.Prefix
+	to    netip.Prefix              // Target network of the offset mapping, if no table.
+	table map[netip.Addr]netip.Addr // Lookup table of -ip-map, if any.
+
+	mu       sync.Mutex
+	replaced int                 // Addresses rewritten.
+	unmapped map[netip.Addr]bool // Addresses of the network missing from the table.
+}
+
+// newIPRemap validates -old, -new and -ip-map for -preset cidr. Exactly one of
+// newValue and mapFile must be given.
+func newIPRemap(oldValue, newValue, mapFile string) (*ipRemap, error) {
+	from, err := netip.ParsePrefix(strings.TrimSpace(oldValue))
+	if err != nil {
+		return nil, fmt.Errorf("-old '%s' is not a network in CIDR notation (e.g. 10.1.0.0/16): %w", oldValue, photonsr.ErrInvalidOption)
+	}
+	m := &ipRemap{from: from.Masked(), unmapped: map[netip.Addr]bool{}}
+	switch {
+	case newValue != "" && mapFile != "":
+		return nil, fmt.Errorf("give either -new or -ip-map, not both: %w", photonsr.ErrInvalidOption)
+	case newValue != "":
+		to, err := netip.ParsePrefix(strings.TrimSpace(newValue))
+		if err != nil {
+			return nil, fmt.Errorf("-new '%s' is not a network in CIDR notation: %w", newValue, photonsr.ErrInvalidOption)
+		}
+		if to.Addr().Is4() != from.Addr().Is4() || to.Bits() != from.Bits() {
+			return nil, fmt.Errorf("-new %s must be the same size and IP version as -old %s: %w", to, from, photonsr.ErrInvalidOption)
+		}
+		m.to = to.Masked()
+	case mapFile != "":
+		if m.table, err = readIPMap(mapFile, m.from); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("give the new network with -new or a table of addresses with -ip-map: %w", photonsr.ErrInvalidOption)
+	}
+	return m, nil
+}
+
+// readIPMap reads an -ip-map file: one "old new" address pair per line, blank
+// lines and lines starting with # ignored. Every old address must lie in network.
+func readIPMap(path string, network netip.Prefix) (map[netip.Addr]netip.Addr, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading -ip-map: %w", err)
+	}
+	defer f.Close()
+	table := map[netip.Addr]netip.Addr{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected an old and a new address: %w", path, n, photonsr.ErrInvalidOption)
+		}
+		oldAddr, err1 := netip.ParseAddr(fields[0])
+		newAddr, err2 := netip.ParseAddr(fields[1])
+		switch {
+		case err1 != nil || err2 != nil:
+			return nil, fmt.Errorf("%s:%d: invalid address: %w", path, n, photonsr.ErrInvalidOption)
+		case !network.Contains(oldAddr):
+			return nil, fmt.Errorf("%s:%d: %s is not in %s: %w", path, n, oldAddr, network, photonsr.ErrInvalidOption)
+		case oldAddr.Is4() != newAddr.Is4():
+			return nil, fmt.Errorf("%s:%d: %s and %s are of different IP versions: %w", path, n, oldAddr, newAddr, photonsr.ErrInvalidOption)
+		}
+		if prev, ok := table[oldAddr]; ok && prev != newAddr {
+			return nil, fmt.Errorf("%s:%d: %s is mapped twice: %w", path, n, oldAddr, photonsr.ErrInvalidOption)
+		}
+		table[oldAddr] = newAddr
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading -ip-map: %w", err)
+	}
+	if len(table) == 0 {
+		return nil, fmt.Errorf("-ip-map '%s' has no addresses: %w", path, photonsr.ErrInvalidOption)
+	}
+	return table, nil
+}
+
+// mapAddr returns the new address of addr, which lies in m.from.
+func (m *ipRemap) mapAddr(addr netip.Addr) (netip.Addr, bool) {
+	if m.table != nil {
+		mapped, ok := m.table[addr]
+		return mapped, ok
+	}
+	// Network bits from the target, host bits from the address.
+	a, t := addr.AsSlice(), m.to.Addr().AsSlice()
+	bits := m.from.Bits()
+	for i := range a {
+		switch {
+		case bits >= 8:
+			a[i] = t[i]
+			bits -= 8
+		case bits > 0:
+			mask := byte(0xff << (8 - bits))
+			a[i] = t[i]&mask | a[i]&^mask
+			bits = 0
+		}
+	}
+	mapped, _ := netip.AddrFromSlice(a)
+	return mapped, true
+}
+
+// Applies reports true: addresses are rewritten in files of any type.
+func (m *ipRemap) Applies(name string) bool {
+	return true
+}
+
+// isAddrChar reports whether b can occur in a textual IPv4 or IPv6 address.
+func isAddrChar(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'f' || b >= 'A' && b <= 'F' || b == '.' || b == ':'
+}
+
+// Apply rewrites the addresses of the network in content.
+func (m *ipRemap) Apply(path string, content []byte) ([]byte, error) {
+	var out []byte
+	replaced := 0
+	var unmapped []netip.Addr
+	last := 0
+	for i := 0; i < len(content); {
+		if !isAddrChar(content[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(content) && isAddrChar(content[i]) {
+			i++
+		}
+		if start > 0 && isHostChar(content[start-1]) || i < len(content) && isHostChar(content[i]) {
+			continue // Part of a word, e.g. a hash or an identifier.
+		}
+		addr, end, ok := parseAddrToken(string(content[start:i]))
+		if !ok || !m.from.Contains(addr) {
+			continue
+		}
+		mapped, ok := m.mapAddr(addr)
+		if !ok {
+			unmapped = append(unmapped, addr)
+			continue
+		}
+		out = append(out, content[last:start]...)
+		out = append(out, mapped.String()...)
+		last = start + end
+		replaced++
+	}
+	if replaced > 0 || len(unmapped) > 0 {
+		m.mu.Lock()
+		m.replaced += replaced
+		for _, addr := range unmapped {
+			m.unmapped[addr] = true
+		}
+		m.mu.Unlock()
+	}
+	if replaced == 0 {
+		return content, nil
+	}
+	return append(out, content[last:]...), nil
+}
+
+// parseAddrToken parses the address at the start of a run of address characters,
+// which may be followed by a ":port" (IPv4) or end in a sentence's full stop. It
+// returns the address and its length in token.
+func parseAddrToken(token string) (netip.Addr, int, bool) {
+	trimmed := strings.TrimRight(token, ".:")
+	if addr, err := netip.ParseAddr(trimmed); err == nil {
+		return addr, len(trimmed), true
+	}
+	if i := strings.IndexByte(trimmed, ':'); i > 0 && strings.Contains(trimmed[:i], ".") {
+		if addr, err := netip.ParseAddr(trimmed[:i]); err == nil && addr.Is4() {
+			return addr, i, true
+		}
+	}
+	return netip.Addr{}, 0, false
+}
+
+// summary describes the rewritten and unmapped addresses.
+func (m *ipRemap) summary() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lines := []string{fmt.Sprintf("Rewrote %d address(es) of %s.", m.replaced, m.from)}
+	if len(m.unmapped) > 0 {
+		addrs := make([]netip.Addr, 0, len(m.unmapped))
+		for addr := range m.unmapped {
+			addrs = append(addrs, addr)
+		}
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+		names := make([]string, len(addrs))
+		for i, addr := range addrs {
+			names[i] = addr.String()
+		}
+		lines = append(lines, fmt.Sprintf("%d address(es) of %s are not in the -ip-map table and were left alone: %s", len(addrs), m.from, strings.Join(names, ", ")))
+	}
+	return lines
+}
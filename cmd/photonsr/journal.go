@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Run Journal and Crash Recovery ---
+
+// findInterruptedRuns returns the run workspaces in dir whose process is no longer
+// running. Workspaces of runs still in progress on this machine are ignored.
+func findInterruptedRuns(dir string) ([]photonsr.InterruptedRun, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("checking '%s' for interrupted runs: %w", dir, err)
+	}
+	host, _ := os.Hostname()
+	var runs []photonsr.InterruptedRun
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), photonsr.RunWorkspacePrefix) {
+			continue
+		}
+		workspace := filepath.Join(dir, e.Name())
+		begin, writes, err := readJournal(filepath.Join(workspace, photonsr.JournalFileName))
+		if err != nil {
+			return nil, err
+		}
+		if begin.Host == host && begin.PID != os.Getpid() && processAlive(begin.PID) {
+			continue
+		}
+		if begin.Op == "" {
+			// A run that is just starting has not written its first entry yet.
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) < time.Minute {
+				continue
+			}
+		}
+		runs = append(runs, photonsr.InterruptedRun{Workspace: workspace, Operation: begin.Operation, Started: begin.Time, Writes: writes})
+	}
+	return runs, nil
+}
+
+// readJournal parses a run journal. A journal cut short by a crash is read up to its
+// last complete line; a missing journal yields an empty run.
+func readJournal(path string) (begin photonsr.JournalEntry, writes []photonsr.JournalEntry, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return begin, nil, nil
+	}
+	if err != nil {
+		return begin, nil, fmt.Errorf("opening run journal '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e photonsr.JournalEntry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			break
+		}
+		switch e.Op {
+		case "begin":
+			begin = e
+		case "write", "move", "remove", "rmdir":
+			writes = append(writes, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return begin, nil, fmt.Errorf("reading run journal '%s': %w", path, err)
+	}
+	return begin, writes, nil
+}
+
+// promptRecovery asks on the terminal what to do with an interrupted run. Without an
+// answer (e.g. stdin closed) the run is left alone.
+func promptRecovery(r photonsr.InterruptedRun) string {
+	for {
+		fmt.Fprintf(promptOut, "Found an %s.\n[r]oll back all its changes, [d]iscard leftovers and keep files as they are, or [i]gnore for now? ", r.Describe())
+		answer, err := stdinReader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "r", "rollback":
+			return photonsr.RecoverRollback
+		case "d", "discard":
+			return photonsr.RecoverDiscard
+		case "i", "ignore":
+			return photonsr.RecoverIgnore
+		}
+		if err != nil {
+			fmt.Fprintln(promptOut)
+			return photonsr.RecoverIgnore
+		}
+	}
+}
+
+// stdinIsTerminal reports whether standard input is an interactive terminal.
+func stdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// handleInterruptedRuns resolves interrupted runs in dir before a CLI operation,
+// according to mode (see the Recover* constants). It exits if a recovery fails.
+func handleInterruptedRuns(dir, mode string) {
+	runs, err := findInterruptedRuns(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	for _, r := range runs {
+		choice := mode
+		if choice == photonsr.RecoverAsk {
+			if !stdinIsTerminal() {
+				fmt.Fprintf(os.Stderr, "Warning: found an %s. Run again with -recover=rollback or -recover=discard to resolve it.\n", r.Describe())
+				continue
+			}
+			choice = promptRecovery(r)
+		}
+		if choice == photonsr.RecoverIgnore {
+			continue
+		}
+		messages, err := photonsr.RecoverRun(r, choice)
+		for _, msg := range messages {
+			fmt.Fprintln(infoOut, msg)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: recovering interrupted run: %v\n", err)
+			exit(1)
+		}
+	}
+}
@@ -0,0 +1,1806 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	tea "github.com/charmbracelet/bubbletea" // Bubble Tea TUI framework
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Global variables to be injected by ldflags during the build process.
+var (
+	version = "dev"     // Application version.
+	commit  = "none"    // Git commit hash.
+	date    = "unknown" // Build date.
+	builtBy = "unknown" // Who or what built the binary (e.g., "goreleaser").
+)
+
+// --- Command-Line Flags ---
+
+var (
+	dirFlag            = flag.String("dir", ".", "Target directory for operations (default: current directory). \"auto\" uses the project root (.git, go.mod or package.json) above the current directory.")
+	patternFlag        = flag.String("pattern", "*", "Filename pattern (e.g., *.txt) for -replace operation (default: *).")
+	oldTextFlag        = flag.String("old", "", "Text to be replaced (required for -replace operation).")
+	newTextFlag        = flag.String("new", "", "Text to replace with (for -replace operation).")
+	oldStdinFlag       = flag.Bool("old-stdin", false, "Read the text to be replaced verbatim from standard input (quotes, newlines and all).")
+	newStdinFlag       = flag.Bool("new-stdin", false, "Read the replacement text verbatim from standard input.")
+	oldHexFlag         = flag.String("old-hex", "", "Text to be replaced as hexadecimal bytes (e.g. 'DEADBEEF' or '0a 09').")
+	newHexFlag         = flag.String("new-hex", "", "Replacement text as hexadecimal bytes.")
+	regexFlag          = flag.Bool("regex", false, "Treat -old as a Go regular expression; -new may refer to its groups as $1 or ${name} (use ${1}x before letters, $$ for a literal $).")
+	ignoreCaseFlag     = flag.Bool("ignore-case", false, "Match -old (and -rules texts) regardless of case; -new is inserted exactly as given.")
+	nearFlag           = flag.String("near", "", "Replace only the matches of -old with another text close by: 'TERM' (same line) or 'TERM,within=N<lines|chars>', e.g. 'http:,within=3lines'.")
+	notPrecededByFlag  = flag.String("not-preceded-by", "", "Leave out the matches of -old directly after this text (e.g. -old cat -not-preceded-by con).")
+	notFollowedByFlag  = flag.String("not-followed-by", "", "Leave out the matches of -old directly before this text (e.g. -old cat -not-followed-by e).")
+	anchorFlag         = flag.String("anchor", "", "Replace only the matches of -old at the start or end of a line or file: bol, eol, bof or eof.")
+	lineModeFlag       = flag.Bool("line-mode", false, "Replace every line holding a match of -old as a whole by -new; an empty -new deletes the line.")
+	sameLengthFlag     = flag.Bool("same-length", false, "Refuse to run unless the old and new text have the same length in bytes (keeps offsets in binary files intact).")
+	backupFlag         = flag.Bool("backup", false, "Create .bak backup files before replacing text.")
+	backupPolicyFlag   = flag.String("backup-conflict", photonsr.BackupPolicyOverwrite, "What to do when a .bak already exists: overwrite, skip (keep existing), version (archive as .bak.N), or ask.")
+	restoreFlag        = flag.Bool("restore", false, "Restore files from .bak backups.")
+	forceFlag          = flag.Bool("force", false, "With -restore, overwrite files even if they changed after their backup was made (with -to, files already there).")
+	toFlag             = flag.String("to", "", "With -restore, copy the backed-up originals into this directory, at their paths relative to -dir, instead of restoring them in place.")
+	cleanFlag          = flag.Bool("clean", false, "Delete all .bak backup files in the target directory.")
+	olderThanFlag      = flag.String("older-than", "", "With -clean, only delete backups older than this age (e.g. 7d, 2w, 36h).")
+	orphansFlag        = flag.Bool("orphans", false, "With -clean, only delete backups whose original file no longer exists.")
+	redundantFlag      = flag.Bool("redundant", false, "With -clean, only delete backups identical to their original file (with -orphans, orphaned ones too).")
+	wizardFlag         = flag.Bool("wizard", false, "Run in interactive wizard (TUI) mode.")
+	accessibleFlag     = flag.Bool("accessible", photonsr.EnvBool("PHOTONSR_ACCESSIBLE"), "Screen-reader friendly wizard: numbered choices, no animation, no colors, no alternate screen.")
+	showVersion        = flag.Bool("version", false, "Show application version and exit.")
+	checkUpdateFlag    = flag.Bool("check-update", false, "Check for a newer release ($PHOTONSR_UPDATE_CHANNEL: stable or prerelease) and exit.")
+	auditFlag          = flag.String("audit", "", "Append a hash-chained audit record of this run to the given log file.")
+	auditKeyFlag       = flag.String("audit-key", "", "File holding the HMAC key used to sign audit records (default: $"+auditKeyEnv+").")
+	scopeFlag          = flag.String("scope", "", "Restrict replacement to a file scope: git-diff[:ref] processes only files changed relative to ref (default HEAD).")
+	manifestFlag       = flag.String("manifest", "", "Restrict replacement to the files listed (one per line, relative to -dir) in this manifest.")
+	verifyManifestFlag = flag.Bool("verify", false, "With -manifest, fail before changing anything unless every listed file exists and will be processed.")
+	diffBaseFlag       = flag.String("diff-base", "", "Also compare against a git ref (e.g. git:HEAD) and flag files that already have uncommitted changes.")
+	checksumsFlag      = flag.String("checksums", "", "After a replacement, write the SHA-256 checksums of the modified files to this file (sha256sum format).")
+	rulesFlag          = flag.String("rules", "", "YAML file of rules (old or forbidden text, new text, pattern, message, severity, skip). Replaces per file type in one walk, or with verify or lint lists the text that must not occur in -dir.")
+	auditVerifyFlag    = flag.Bool("audit-verify", false, "Verify the hash chain (and signatures) of the -audit log and exit.")
+
+	keepBackupsFlag   = flag.Int("keep-backups", 0, "Retention: keep at most N backups (.bak and .bak.N) per file (0 = unlimited).")
+	maxBackupAgeFlag  = flag.String("max-backup-age", "", "Retention: remove backups older than this age (e.g. 30d).")
+	maxBackupSizeFlag = flag.String("max-backup-size", "", "Retention: cap the total size of backups (e.g. 500M), removing the oldest first.")
+
+	jobsFlag           = flag.Int("jobs", 1, "Number of files to process concurrently during replacement, and of backups during -restore and -clean.")
+	maxMemFlag         = flag.String("max-mem", "", "Memory budget for file contents held at once during replacement (e.g. 1G); larger files are streamed.")
+	ioProfileFlag      = flag.String("io-profile", "", "Tune replacement for the storage: auto (detect), hdd, ssd or network. Sets -jobs unless given explicitly.")
+	verboseFlag        = flag.Bool("verbose", false, "Print additional details about how the operation runs.")
+	maxSizeFlag        = flag.String("max-size", "", "Skip files larger than this during replacement (e.g. 50M).")
+	emptyFlag          = flag.String("empty", "files,dirs", "tidy: what to remove: files (zero-byte files), dirs (empty directories) or both.")
+	excludeFlag        = flag.String("exclude", "", "tidy: comma-separated name patterns of files and directories to keep (e.g. \"cache,*.lock\").")
+	dryRunFlag         = flag.Bool("dry-run", false, "Write nothing: list the files a replacement would modify, with their replacement counts, or what tidy would remove.")
+	diffFlag           = flag.Bool("diff", false, "Write nothing: print a unified diff of the changes a replacement would make, to page or save as a .patch (apply with \"patch -p1\" in -dir).")
+	undoFlag           = flag.String("undo", "", "tidy: recreate the files and directories removed by the tidy run with this id.")
+	reposFlag          = flag.String("repos", "", "multi: file listing the repositories, one per line: git URLs, cloned into -dir (or pulled if already there), or paths of local checkouts.")
+	dupesLinkFlag      = flag.String("dupes-link", "", "dupes: replace each duplicate by a link to the first copy: hard or symlink (default: report only).")
+	layoutFlag         = flag.String("layout", "", "move-files: where to move each file, relative to -dir, e.g. \"{ext}/{name}\" or \"{yyyy}/{mm}/\" (placeholders: {name} {stem} {ext} {dir} {yyyy} {mm} {dd}).")
+	reviewFlag         = flag.Bool("review", false, "rename-files, move-files: review the planned renames in an interactive table, excluding rows, before anything is renamed.")
+	targetOSFlag       = flag.String("target-os", "", "System whose file naming rules rename-files checks new names against: linux, darwin, windows or portable (default: this system).")
+	skipOpenFlag       = flag.Bool("skip-open", false, "Skip files that another process has open for writing (e.g. active logs), reporting the process.")
+	immutableFlag      = flag.String("immutable", photonsr.ImmutableSkip, "Files marked immutable or append-only (chattr +i, +a): skip (report as skipped) or error (report as failed).")
+	skipBinaryFlag     = flag.Bool("skip-binary", false, "Skip files that look binary (contain a NUL byte near the start) during replacement.")
+	orderFlag          = flag.String("order", "", "Order in which files are processed: path (default), size-desc (largest first) or mtime (most recently changed first).")
+	sortByFlag         = flag.String("sort-by", photonsr.SortByPath, "Order of reported files: path (deterministic) or completion.")
+	langFlag           = flag.String("lang", "", "Language for messages (en, id). Default: $PHOTONSR_LANG or the system locale.")
+	quietFlag          = flag.Bool("quiet", false, "Print errors only: no progress, per-file listing, warnings or success message (the exit status tells the result).")
+	summaryFlag        = flag.Bool("summary", false, "Print the result as one line, e.g. \"modified=12 scanned=340 errors=0 duration=2.3s\", instead of the per-file listing.")
+	noPagerFlag        = flag.Bool("no-pager", false, "Print long results straight to the terminal instead of through $PAGER (default \"less -R\").")
+	colorFlag          = flag.String("color", ColorAuto, "Colored output: auto (only on terminals, honoring $NO_COLOR), always or never.")
+	outputFlag         = flag.String("output", outputText, "Result format for CLI operations: text, json, ndjson (one JSON object per line), psobject (ndjson with CRLF line endings, for PowerShell) or markdown (a summary to paste into a pull request).")
+	cpuProfileFlag     = flag.String("cpuprofile", "", "Write a CPU profile of the run to this file (for bug reports about slow runs).")
+	memProfileFlag     = flag.String("memprofile", "", "Write a heap profile to this file when the run ends.")
+	confineFlag        = flag.Bool("confine", false, "Never read or write outside -dir: skip files and backups that resolve elsewhere (e.g. through symlinks). Implied by -sandbox.")
+	headerFlag         = flag.String("header", "", "License header template for license-headers: plain text lines with {year} and {holder} placeholders.")
+	yearFlag           = flag.String("year", strconv.Itoa(time.Now().Year()), "Year license headers must cover (license-headers).")
+	holderFlag         = flag.String("holder", "", "Copyright holder written into license headers, replacing the existing one (license-headers).")
+	presetFlag         = flag.String("preset", "", "Interpret -old and -new for a kind of value: url (URLs or host names, matched as whole names and validated) cidr (renumber the addresses of a network) or number (-old and -new are sample numbers such as 1.234,56 and 1,234.56).")
+	ipMapFlag          = flag.String("ip-map", "", "With -preset cidr, file of \"old new\" address pairs to use instead of -new.")
+	numberScopeFlag    = flag.String("number-scope", "", "With -preset number, convert only numbers inside the text matching this regular expression.")
+	csvColumnFlag      = flag.String("csv-column", "", "Replace only within this column of CSV/TSV files: a 1-based number or a header name.")
+	csvDelimiterFlag   = flag.String("csv-delimiter", "", "Field delimiter for -csv-column (e.g. \";\" or tab); also selects every file matching -pattern. Default: comma, tab for .tsv.")
+	keyFlag            = flag.String("key", "", "Replace only in the value of this key of INI/TOML/.env files: \"section.name\", or \"name\" above the first section.")
+	sqlFlag            = flag.Bool("sql", false, "Treat .sql files as SQL dumps: stream them statement by statement, never matching across statements.")
+	sqlTablesFlag      = flag.String("sql-tables", "", "Comma-separated tables: with -sql, replace only in their INSERT, REPLACE and COPY statements. Implies -sql.")
+	anonymizeFlag      = flag.String("anonymize", AnonymizeEmail+","+AnonymizeIP, "Kinds of values pseudonymized by anonymize: email and/or ip, comma-separated (\"\" for none).")
+	anonymizeRegexFlag = flag.String("anonymize-regex", "", "Regular expression whose matches (or first group) anonymize also pseudonymizes, e.g. 'user=(\\S+)'.")
+	anonymizeKeyFlag   = flag.String("anonymize-key", "", "File holding the key of anonymize's pseudonyms (default: $"+anonymizeKeyEnv+"); without one, pseudonyms hold for one run only.")
+	urlCheckFlag       = flag.String("url-check", URLCheckNone, "With -preset url, check the new value before replacing: none, dns (resolves) or http (answers a HEAD request).")
+	tidyFlag           = flag.Bool("tidy", false, "After go-mod-rename, run 'go mod tidy' in -dir to update go.sum and the requirements.")
+	sandboxFlag        = flag.Bool("sandbox", false, "Run the operation on a temporary copy of -dir and show what it changed there; the real files are not touched.")
+	fuzzyFlag          = flag.Int("fuzzy", 0, "scan: also list near-misses of -old within this many edits (typos, spacing variants), e.g. 2.")
+	topFlag            = flag.Int("top", 20, "inventory: number of most frequent strings listed (0: all of them).")
+	csvFlag            = flag.String("csv", "", "inventory: also write every string found, with its counts, to this CSV file.")
+	progressJSONFlag   = flag.String("progress-json", "", "Stream progress as newline-delimited JSON events (file started, modified, skipped, error, heartbeat, done) to \"stderr\" or this named pipe or file.")
+	readOnlyFlag       = flag.Bool("read-only", false, "Write nothing at all: every change to the file system is discarded and listed instead, so not even a bug can modify files.")
+	inContainerFlag    = flag.String("in-container", "", "Run the operation in a throwaway container of this image (docker or podman): no network, no capabilities, read-only except -dir.")
+	outFlag            = flag.String("out", "", "Write a transformed copy of -dir into this new or empty directory, keeping relative paths; the sources are not touched.")
+	outLinkFlag        = flag.String("out-link", OutLinkAuto, "How -out places files: auto (reflink clone, else hard link, else copy), reflink (clone, else copy) or copy.")
+	preserveOwnerFlag  = flag.Bool("preserve-owner", false, "When run as root, give rewritten files and backups the owner and group of the original instead of root.")
+	durabilityFlag     = flag.String("durability", photonsr.DurabilityNone, "Flush writes to disk: none (leave it to the OS), dsync (file data before each rename) or fsync (file and its directory).")
+	recoverFlag        = flag.String("recover", photonsr.RecoverAsk, "What to do with runs that were interrupted in -dir: ask, rollback, discard (keep files, delete leftovers), or ignore.")
+)
+
+// --- Helper Functions ---
+
+// flagWasSet reports whether the named flag was given on the command line.
+func flagWasSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// sizeFlag returns the size given to the flag name, such as "50M", or 0 if it was
+// not given. A malformed size is reported and ends the program.
+func sizeFlag(name, value string) int64 {
+	if value == "" {
+		return 0
+	}
+	size, err := photonsr.ParseSize(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -%s: %v\n", name, err)
+		exit(1)
+	}
+	return size
+}
+
+// exitInterrupted is the exit status of a CLI operation stopped by SIGINT or SIGTERM
+// (128 + SIGINT, as shells report an interrupted command).
+const exitInterrupted = 130
+
+// subcommands lists the operations selected by a leading word (e.g. "photonsr prune")
+// rather than by a flag. All flags remain available after the subcommand.
+var subcommands = map[string]bool{
+	"prune":           true,
+	"retry":           true,
+	"verify":          true,
+	"lint":            true,
+	"stats":           true,
+	"go-mod-rename":   true,
+	"license-headers": true,
+	"anonymize":       true,
+	"rename-files":    true,
+	"move-files":      true,
+	"dupes":           true,
+	"tidy":            true,
+	"scan":            true,
+	"backup-diff":     true,
+	"backup-check":    true,
+	"inventory":       true,
+	"runs":            true,
+	"multi":           true,
+}
+
+// --- Main Function ---
+
+// cliRun holds one run of the command line: the operation and the arguments it was
+// given, then the results the operation reports.
+type cliRun struct {
+	subcommand    string
+	retryID       string   // retry <run-id>
+	diffPath      string   // backup-diff [path], backup-check [path]
+	inventoryExpr string   // inventory <expression>
+	runsArgs      []string // runs [list | diff <id1> <id2>]
+	moduleArgs    []string // go-mod-rename <old> <new>
+
+	oldText, newText string
+	retention        photonsr.RetentionPolicy
+	auditKey         []byte
+	readOnly         *photonsr.ReadOnlyFS // Set with -read-only; holds what the run did not write.
+	retryPaths       map[string]bool      // Set with retry: the files that failed in the recorded run.
+	sb               *sandbox             // Set with -sandbox or -out; *dirFlag then points into it.
+	progress         *progressStream      // Set with -progress-json.
+
+	actionVerb        string
+	operationMessages []string
+	operationError    error
+	itemsAffected     int // Number of files modified, restored, or cleaned
+	filesScanned      int // For replacement: number of files matching pattern that were scanned
+	modifiedFilePaths []string
+	started           time.Time
+	recorder          *auditRecorder
+	sampleDiffs       map[string][]string // With -output markdown: changed lines of modified files.
+	patches           map[string]string   // With -diff: unified diffs of the files that would be modified.
+	runID             string              // Identifies the run in the history and for "photonsr retry".
+	retryRunID        string              // Set when this run's failures were recorded for "photonsr retry".
+	failedFiles       []failedFile
+	skippedFiles      []failedFile
+	violationsFound   []photonsr.Violation
+	repoResults       []photonsr.RepoResult // For multi.
+	intendedWrites    []photonsr.IntendedWrite
+}
+
+func main() {
+	r := parseCommandLine()
+	r.configure()
+	switch r.subcommand {
+	case "stats":
+		runStats()
+	case "runs":
+		runRuns(r.runsArgs)
+	}
+	r.loadRetry()
+	r.resolveText()
+
+	runWizard := r.wantsWizard()
+	r.checkFlagCombinations(runWizard)
+	if runWizard {
+		runInteractiveWizard()
+	}
+
+	// --- CLI Mode Logic ---
+	ctx := interruptContext()
+	r.prepare()
+	r.dispatch(ctx)
+	r.finish()
+	r.report()
+	exit(0) // Runs the exit hooks (profiles) on success, too.
+}
+
+// parseCommandLine reads the subcommand, its words and the flags from the command line.
+func parseCommandLine() *cliRun {
+	r := &cliRun{
+		recorder:    &auditRecorder{},
+		sampleDiffs: map[string][]string{},
+		patches:     map[string]string{},
+		runID:       photonsr.NewRunID(),
+	}
+	args := os.Args[1:]
+	if len(args) > 0 && subcommands[args[0]] {
+		r.subcommand, args = args[0], args[1:]
+	}
+	if r.subcommand == "retry" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		r.retryID, args = args[0], args[1:]
+	}
+	if (r.subcommand == "backup-diff" || r.subcommand == "backup-check") && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		r.diffPath, args = args[0], args[1:]
+	}
+	if r.subcommand == "inventory" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		r.inventoryExpr, args = args[0], args[1:]
+	}
+	for r.subcommand == "runs" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		r.runsArgs, args = append(r.runsArgs, args[0]), args[1:]
+	}
+	for r.subcommand == "go-mod-rename" && len(args) > 0 && len(r.moduleArgs) < 2 && !strings.HasPrefix(args[0], "-") {
+		r.moduleArgs, args = append(r.moduleArgs, args[0]), args[1:]
+	}
+	if err := applyEnvDefaults(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(2)
+	}
+	flag.CommandLine.Parse(args)
+	if r.subcommand == "retry" && r.retryID == "" {
+		r.retryID = flag.Arg(0)
+	}
+	if (r.subcommand == "backup-diff" || r.subcommand == "backup-check") && r.diffPath == "" {
+		r.diffPath = flag.Arg(0)
+	}
+	if r.subcommand == "inventory" && r.inventoryExpr == "" && flag.NArg() > 0 {
+		r.inventoryExpr = flag.Arg(0)
+		flag.CommandLine.Parse(flag.Args()[1:]) // Flags may also follow the expression.
+	}
+	if r.subcommand == "runs" {
+		r.runsArgs = append(r.runsArgs, flag.Args()...)
+	}
+	if r.subcommand == "go-mod-rename" {
+		for rest := flag.Args(); len(rest) > 0; rest = flag.Args() { // Flags may also follow the paths.
+			if len(r.moduleArgs) == 2 {
+				r.moduleArgs = append(r.moduleArgs, rest...)
+				break
+			}
+			r.moduleArgs = append(r.moduleArgs, rest[0])
+			flag.CommandLine.Parse(rest[1:])
+		}
+		if len(r.moduleArgs) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: go-mod-rename requires the old and the new module path (photonsr go-mod-rename old/module new/module).")
+			exit(1)
+		}
+	}
+	return r
+}
+
+// configure applies the flags that set up the program rather than an operation,
+// and handles those that end it, such as -version and -audit-verify.
+func (r *cliRun) configure() {
+	handleInfoFlags()
+	if *readOnlyFlag {
+		r.readOnly = photonsr.NewReadOnlyFS()
+		photonsr.FS = r.readOnly
+	} else {
+		startUpdateCheck() // Its cache is the only thing it writes; not worth listing.
+	}
+
+	if err := startProfiling(*cpuProfileFlag, *memProfileFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+
+	if *auditFlag != "" {
+		var err error
+		r.auditKey, err = loadAuditKey(*auditKeyFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+	if *auditVerifyFlag {
+		if *auditFlag == "" {
+			fmt.Fprintln(os.Stderr, "Error: -audit-verify requires -audit <log file>.")
+			exit(1)
+		}
+		verified, err := verifyAuditLog(*auditFlag, r.auditKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Audit log verification failed after %d valid record(s): %v\n", verified, err)
+			exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "Audit log OK: %d record(s) verified.\n", verified)
+		exit(0)
+	}
+
+	r.retention = photonsr.RetentionPolicy{KeepLast: *keepBackupsFlag}
+	if *maxBackupAgeFlag != "" {
+		age, err := photonsr.ParseAge(*maxBackupAgeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -max-backup-age: %v\n", err)
+			exit(1)
+		}
+		r.retention.MaxAge = age
+	}
+	r.retention.MaxTotalSize = sizeFlag("max-backup-size", *maxBackupSizeFlag)
+
+	configureOutput()
+
+	if !photonsr.ValidRecoverMode(*recoverFlag) {
+		fmt.Fprintf(os.Stderr, "Error: unknown -recover mode '%s' (expected ask, rollback, discard or ignore).\n", *recoverFlag)
+		exit(1)
+	}
+	if !photonsr.ValidDurability(*durabilityFlag) {
+		fmt.Fprintf(os.Stderr, "Error: unknown -durability mode '%s' (expected none, dsync or fsync).\n", *durabilityFlag)
+		exit(2)
+	}
+	photonsr.Durability = *durabilityFlag
+	if r.readOnly != nil { // The null device and temporary names cannot be flushed.
+		photonsr.Durability = photonsr.DurabilityNone
+	}
+	if *preserveOwnerFlag {
+		if os.Geteuid() == 0 {
+			photonsr.PreserveOwner = true
+		} else {
+			fmt.Fprintln(warnOut, "Note: -preserve-owner has no effect unless run as root.")
+		}
+	}
+}
+
+// handleInfoFlags prints what -version or -check-update asks for and exits.
+func handleInfoFlags() {
+	if *showVersion {
+		fmt.Printf("PhotonSR version: %s\n", version)
+		fmt.Printf("Commit: %s\n", commit)
+		fmt.Printf("Built at: %s\n", date)
+		fmt.Printf("Built by: %s\n", builtBy)
+		exit(0)
+	}
+	if *checkUpdateFlag {
+		notice, err := checkUpdateNow()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		fmt.Println(notice)
+		exit(0)
+	}
+}
+
+// configureOutput applies -lang, -color, -output, -quiet and -summary.
+func configureOutput() {
+	if err := setLanguage(*langFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -lang: %v\n", err)
+		exit(1)
+	}
+
+	if err := configureColor(*colorFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(2)
+	}
+
+	switch *outputFlag {
+	case outputText:
+	case outputJSON, outputNDJSON, outputPSObject, outputMarkdown:
+		infoOut, promptOut = os.Stderr, os.Stderr
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -output format '%s' (expected text, json, ndjson, psobject or markdown).\n", *outputFlag)
+		exit(1)
+	}
+
+	if *quietFlag || *summaryFlag {
+		if *quietFlag && *summaryFlag {
+			fmt.Fprintln(os.Stderr, "Error: -quiet and -summary are mutually exclusive.")
+			exit(2)
+		}
+		if *outputFlag != outputText {
+			fmt.Fprintf(os.Stderr, "Error: -quiet and -summary apply to text output, not -output %s.\n", *outputFlag)
+			exit(2)
+		}
+		infoOut, promptOut = io.Discard, os.Stderr
+		if *quietFlag {
+			warnOut = io.Discard
+		}
+	}
+}
+
+// runStats prints the usage statistics and exits.
+func runStats() {
+	stats, err := loadUsageStats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	switch *outputFlag {
+	case outputJSON:
+		err = writeUsageStatsJSON(os.Stdout, stats)
+	case outputNDJSON, outputPSObject:
+		err = writeUsageStatsNDJSON(os.Stdout, stats, *outputFlag == outputPSObject)
+	default:
+		writeUsageStats(os.Stdout, stats)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	exit(0)
+}
+
+// runRuns lists or compares the recorded runs and exits.
+func runRuns(args []string) {
+	var out strings.Builder
+	status := runsCommand(&out, args, *outputFlag)
+	writePaged(out.String(), !*noPagerFlag && *outputFlag == outputText)
+	exit(status)
+}
+
+// loadRetry applies the options of the run retried by "photonsr retry", which
+// re-runs a recorded replacement, restricted to the files that failed.
+func (r *cliRun) loadRetry() {
+	if r.subcommand != "retry" {
+		return
+	}
+	if r.retryID == "" {
+		fmt.Fprintln(os.Stderr, "Error: retry requires a run id (photonsr retry <run-id>).")
+		exit(1)
+	}
+	rec, err := loadRunRecord(r.retryID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	for name, value := range rec.Options {
+		// Text given on the command line in any input mode replaces the recorded text.
+		if group := strings.TrimSuffix(name, "-hex"); textArgFlags[group] != nil && textFlagGiven(group) {
+			continue
+		}
+		if !flagWasSet(name) {
+			flag.Set(name, value)
+		}
+	}
+	r.retryPaths = map[string]bool{}
+	for _, f := range rec.Failed {
+		r.retryPaths[photonsr.CanonicalPath(f.Path)] = true
+	}
+	fmt.Fprintf(infoOut, "Retrying %d failed file(s) from run %s.\n", len(rec.Failed), r.retryID)
+}
+
+// resolveText reads the old and new text from their flags or standard input.
+func (r *cliRun) resolveText() {
+	var err error
+	r.oldText, r.newText, err = resolveTextArgs(
+		textArg{name: "old", text: *oldTextFlag, fromStdin: *oldStdinFlag, hex: *oldHexFlag},
+		textArg{name: "new", text: *newTextFlag, fromStdin: *newStdinFlag, hex: *newHexFlag},
+		os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+}
+
+// wantsWizard reports whether the wizard runs: with -wizard, or when no operation is given.
+func (r *cliRun) wantsWizard() bool {
+	runWizard := *wizardFlag
+	if r.subcommand == "" && !*wizardFlag && !*restoreFlag && !*cleanFlag && r.oldText == "" && *rulesFlag == "" && len(flag.Args()) == 0 {
+		runWizard = true
+	}
+	return runWizard
+}
+
+// checkFlagCombinations rejects flags that do not apply to the operation or
+// cannot be combined.
+func (r *cliRun) checkFlagCombinations(runWizard bool) {
+	if runWizard && *sandboxFlag {
+		fmt.Fprintln(os.Stderr, "Error: -sandbox applies to CLI operations, not the wizard (try its tutorial instead).")
+		exit(2)
+	}
+	if runWizard && *progressJSONFlag != "" {
+		fmt.Fprintln(os.Stderr, "Error: -progress-json applies to CLI operations, not the wizard.")
+		exit(2)
+	}
+	if runWizard && *inContainerFlag != "" {
+		fmt.Fprintln(os.Stderr, "Error: -in-container applies to CLI operations, not the wizard.")
+		exit(2)
+	}
+	if *readOnlyFlag {
+		switch {
+		case runWizard:
+			fmt.Fprintln(os.Stderr, "Error: -read-only applies to CLI operations, not the wizard.")
+			exit(2)
+		case *sandboxFlag || *outFlag != "":
+			fmt.Fprintln(os.Stderr, "Error: -read-only cannot be combined with -sandbox or -out, which write a copy of -dir.")
+			exit(2)
+		case r.subcommand == "multi" || *tidyFlag:
+			fmt.Fprintln(os.Stderr, "Error: -read-only cannot stop git or the go command from writing; it does not apply to multi or -tidy.")
+			exit(2)
+		}
+	}
+	if *outFlag != "" {
+		switch {
+		case runWizard:
+			fmt.Fprintln(os.Stderr, "Error: -out applies to CLI operations, not the wizard.")
+			exit(2)
+		case *sandboxFlag:
+			fmt.Fprintln(os.Stderr, "Error: -out and -sandbox cannot be combined; both leave the sources alone.")
+			exit(2)
+		case *backupFlag:
+			fmt.Fprintln(os.Stderr, "Error: -backup has nothing to protect with -out: the sources are not touched.")
+			exit(2)
+		case !validOutLink(*outLinkFlag):
+			fmt.Fprintf(os.Stderr, "Error: invalid -out-link '%s' (expected auto, reflink or copy).\n", *outLinkFlag)
+			exit(2)
+		case *cleanFlag || *restoreFlag || (r.subcommand != "" && r.subcommand != "go-mod-rename" && r.subcommand != "license-headers" && r.subcommand != "anonymize" && r.subcommand != "rename-files" && r.subcommand != "move-files"):
+			fmt.Fprintln(os.Stderr, "Error: -out applies to replacements, go-mod-rename, license-headers, anonymize, rename-files and move-files.")
+			exit(2)
+		}
+	}
+	if *toFlag != "" {
+		switch {
+		case !*restoreFlag:
+			fmt.Fprintln(os.Stderr, "Error: -to applies to -restore.")
+			exit(2)
+		case *sandboxFlag:
+			fmt.Fprintln(os.Stderr, "Error: -to and -sandbox cannot be combined; both leave -dir alone.")
+			exit(2)
+		}
+	}
+	if *diffFlag {
+		switch {
+		case *cleanFlag || *restoreFlag || (r.subcommand != "" && r.subcommand != "go-mod-rename" && r.subcommand != "license-headers" && r.subcommand != "anonymize"):
+			fmt.Fprintln(os.Stderr, "Error: -diff applies to replacements, go-mod-rename, license-headers and anonymize.")
+			exit(2)
+		case *sandboxFlag || *outFlag != "":
+			fmt.Fprintln(os.Stderr, "Error: -diff modifies nothing; it cannot be used with -sandbox or -out.")
+			exit(2)
+		case *outputFlag != outputText || *summaryFlag:
+			fmt.Fprintln(os.Stderr, "Error: -diff prints a patch; it cannot be used with -output or -summary.")
+			exit(2)
+		}
+		*dryRunFlag = true
+		if !*quietFlag {
+			infoOut = os.Stderr // Keep stdout to the patch.
+		}
+	}
+}
+
+// runInteractiveWizard runs the wizard and exits.
+func runInteractiveWizard() {
+	// Without a terminal on stdout (e.g. output captured by a script), the wizard
+	// runs plain: no colors, animation or alternate screen.
+	plain := *accessibleFlag || !isTerminal(os.Stdout)
+	var programOpts []tea.ProgramOption
+	if plain {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	} else {
+		programOpts = append(programOpts, tea.WithAltScreen())
+	}
+	program := tea.NewProgram(newWizardModel(wizardConfig{Accessible: plain}), programOpts...)
+	final, err := program.Run()
+	if m, ok := final.(model); ok {
+		m.endTutorial() // Quitting mid-tutorial leaves no sample files behind.
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running interactive wizard: %v\n", err)
+		exit(1)
+	}
+	exit(0)
+}
+
+// interruptContext returns a context done on SIGINT or SIGTERM, which stop the
+// operation once the files in flight are written; the report is still produced. A
+// second signal terminates immediately.
+func interruptContext() context.Context {
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stopSignals()
+		fmt.Fprintln(os.Stderr, tr("cli.interrupted"))
+	}()
+	return ctx
+}
+
+// prepare resolves -dir and sets up what the operation runs in: a progress
+// stream, a container, a sandbox or an output tree.
+func (r *cliRun) prepare() {
+	r.started = time.Now()
+	if *verboseFlag {
+		if summary := envDefaultsSummary(); summary != "" {
+			fmt.Fprintf(infoOut, "Defaults from the environment: %s.\n", summary)
+		}
+	}
+
+	if *dirFlag == autoDir {
+		root, marker, err := findProjectRoot(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -dir auto: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		*dirFlag = root
+		fmt.Fprintf(infoOut, "Project root: %s (found %s).\n", root, marker)
+	}
+
+	if *progressJSONFlag != "" && *inContainerFlag == "" { // The container streams its own.
+		var err error
+		if r.progress, err = openProgressStream(*progressJSONFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		exitHooks = append(exitHooks, r.progress.close)
+	}
+
+	if *inContainerFlag != "" {
+		if err := photonsr.ValidateDir(*dirFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		cmd, err := containerCommand(flag.CommandLine, *inContainerFlag, r.subcommand, r.moduleArgs, *dirFlag, r.oldText, r.newText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		fmt.Fprintf(infoOut, "Running in a container of %s.\n", *inContainerFlag)
+		exit(runInContainer(cmd))
+	}
+
+	if r.subcommand != "" || *cleanFlag || *restoreFlag || r.oldText != "" || *rulesFlag != "" {
+		r.prepareDir()
+	}
+}
+
+// prepareDir checks -dir, replaces it by a sandbox or output tree if asked to,
+// and handles the interrupted runs left in it.
+func (r *cliRun) prepareDir() {
+	if err := photonsr.ValidateDir(*dirFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitCodeFor(err))
+	}
+	if *sandboxFlag {
+		if r.subcommand == "verify" || r.subcommand == "lint" || r.subcommand == "backup-diff" || r.subcommand == "backup-check" || r.subcommand == "inventory" {
+			fmt.Fprintf(os.Stderr, "Error: -sandbox is for operations that change files; %s never does.\n", r.subcommand)
+			exit(2)
+		}
+		if r.subcommand == "multi" {
+			fmt.Fprintln(os.Stderr, "Error: -sandbox cannot copy the repositories of multi, which may be outside -dir.")
+			exit(2)
+		}
+		var err error
+		if r.sb, err = newSandbox(*dirFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		exitHooks = append(exitHooks, r.sb.remove)
+		*confineFlag = true // Copied links may still point at the real tree.
+		fmt.Fprintf(infoOut, "Sandbox: copied %d file(s) (%s) of %s to %s; the real files are not touched.\n", r.sb.files, photonsr.FormatSize(r.sb.bytes), r.sb.realDir, r.sb.dir)
+		*dirFlag = r.sb.dir
+	} else if *outFlag != "" {
+		var err error
+		if r.sb, err = newOutputTree(*dirFlag, *outFlag, *outLinkFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		*confineFlag = true // Copied links may still point at the sources.
+		fmt.Fprintf(infoOut, "Output: copied %d file(s) (%s) of %s to %s; the sources are not touched.\n", r.sb.files, photonsr.FormatSize(r.sb.bytes), r.sb.realDir, r.sb.dir)
+		if r.sb.cloned > 0 || r.sb.linked > 0 {
+			fmt.Fprintf(infoOut, "Output: %d file(s) cloned by reflink and %d hard-linked instead of copied.\n", r.sb.cloned, r.sb.linked)
+		}
+		*dirFlag = r.sb.dir
+	}
+	if r.subcommand != "verify" && r.subcommand != "lint" && r.subcommand != "backup-diff" && r.subcommand != "backup-check" && r.subcommand != "inventory" { // Read-only; leftovers are not their business.
+		handleInterruptedRuns(*dirFlag, *recoverFlag)
+	}
+}
+
+// dispatch runs the operation selected by the subcommand or flags.
+func (r *cliRun) dispatch(ctx context.Context) {
+	switch {
+	case r.subcommand == "prune":
+		r.prune()
+	case r.subcommand == "verify" || r.subcommand == "lint":
+		r.verify(ctx)
+	case r.subcommand == "scan":
+		r.scan(ctx)
+	case r.subcommand == "inventory":
+		r.inventory(ctx)
+	case r.subcommand == "backup-diff":
+		r.backupDiff(ctx)
+	case r.subcommand == "backup-check":
+		r.backupCheck(ctx)
+	case r.subcommand == "tidy" && *undoFlag != "":
+		r.tidyUndo()
+	case r.subcommand == "tidy":
+		r.tidy()
+	case r.subcommand == "dupes":
+		r.dupes(ctx)
+	case r.subcommand == "rename-files" || r.subcommand == "move-files":
+		r.rename()
+	case *cleanFlag:
+		r.clean(ctx)
+	case *restoreFlag:
+		r.restore(ctx)
+	case r.oldText != "" || *rulesFlag != "" || r.subcommand == "go-mod-rename" || r.subcommand == "license-headers" || r.subcommand == "anonymize" || r.subcommand == "multi":
+		r.replace(ctx)
+	default:
+		if len(flag.Args()) > 0 {
+			fmt.Fprintln(os.Stderr, tr("cli.unknown_args"))
+		}
+		fmt.Fprintln(os.Stderr, tr("cli.no_operation"))
+		flag.Usage()
+		exit(1)
+	}
+}
+
+// prune applies the retention policy to the backups in -dir.
+func (r *cliRun) prune() {
+	if r.retention.IsZero() {
+		fmt.Fprintln(os.Stderr, "Error: prune requires a retention limit: -keep-backups, -max-backup-age and/or -max-backup-size.")
+		exit(1)
+	}
+	r.actionVerb = "pruned"
+	fmt.Fprintln(infoOut, tr("cli.progress.prune"))
+	r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformPrune(photonsr.PruneOptions{Dir: *dirFlag, Policy: r.retention, OnWarning: printWarning})
+}
+
+// verify checks -dir against the rules of verify or lint.
+func (r *cliRun) verify(ctx context.Context) {
+	if *rulesFlag == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s requires a rules file (photonsr %s -rules rules.yaml).\n", r.subcommand, r.subcommand)
+		exit(2)
+	}
+	rules, err := loadRules(*rulesFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitCodeFor(err))
+	}
+	r.actionVerb = "verified"
+	if r.subcommand == "lint" {
+		r.actionVerb = "linted"
+	}
+	verifyOpts := photonsr.VerifyOptions{Dir: *dirFlag, Pattern: *patternFlag, Rules: rules, RulesFile: *rulesFlag, SkipBinary: *skipBinaryFlag, OnWarning: printWarning}
+	verifyOpts.MaxFileSize = sizeFlag("max-size", *maxSizeFlag)
+	violations, checked, err := photonsr.PerformVerifyCtx(ctx, verifyOpts)
+	r.filesScanned, r.violationsFound, r.operationError = checked, violations, err
+	// verify fails on any occurrence; lint only on those of error-severity rules.
+	files := map[string]bool{}
+	failing := 0
+	bySeverity := map[string]int{}
+	for _, v := range violations {
+		bySeverity[v.Severity]++
+		if r.subcommand == "verify" || v.Severity == photonsr.SeverityError {
+			failing++
+			files[v.Path] = true
+		}
+		r.operationMessages = append(r.operationMessages, v.Format(rules[v.Rule-1].Old))
+	}
+	if r.subcommand == "lint" && len(violations) > 0 {
+		r.operationMessages = append(r.operationMessages, fmt.Sprintf("%d error(s), %d warning(s), %d info.", bySeverity[photonsr.SeverityError], bySeverity[photonsr.SeverityWarning], bySeverity[photonsr.SeverityInfo]))
+	}
+	if failing > 0 && r.operationError == nil {
+		r.operationError = fmt.Errorf("%d occurrence(s) in %d file(s): %w", failing, len(files), photonsr.ErrRulesViolated)
+	} else if len(violations) == 0 && r.operationError == nil {
+		r.operationMessages = append(r.operationMessages, fmt.Sprintf("Checked %d file(s) against %d rule(s): no violations.", checked, len(rules)))
+	}
+}
+
+// scan counts the occurrences of -old, or of every string, in -dir.
+func (r *cliRun) scan(ctx context.Context) {
+	r.actionVerb = "reported"
+	fmt.Fprintln(infoOut, tr("cli.progress.scan"))
+	scanOpts := photonsr.ScanOptions{Dir: *dirFlag, Pattern: *patternFlag, Term: r.oldText, Fuzzy: *fuzzyFlag, OnWarning: printWarning}
+	if *fuzzyFlag > 0 && r.oldText == "" {
+		fmt.Fprintln(os.Stderr, "Error: -fuzzy lists near-misses of a text; give it with -old.")
+		exit(2)
+	}
+	scanOpts.MaxFileSize = sizeFlag("max-size", *maxSizeFlag)
+	if *scopeFlag != "" {
+		allowed, err := photonsr.ResolveScope(*dirFlag, *scopeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		scanOpts.AllowedPaths = allowed
+	}
+	r.operationMessages, r.itemsAffected, r.filesScanned, r.operationError = photonsr.PerformScanCtx(ctx, scanOpts)
+}
+
+// inventory counts the strings matching an expression in -dir.
+func (r *cliRun) inventory(ctx context.Context) {
+	r.actionVerb = "counted"
+	fmt.Fprintln(infoOut, tr("cli.progress.inventory"))
+	if r.inventoryExpr == "" {
+		r.inventoryExpr = r.oldText // Also accepted with -old.
+	}
+	invOpts := photonsr.InventoryOptions{Dir: *dirFlag, Pattern: *patternFlag, Expr: r.inventoryExpr, Top: *topFlag, OnWarning: printWarning}
+	invOpts.MaxFileSize = sizeFlag("max-size", *maxSizeFlag)
+	if *scopeFlag != "" {
+		allowed, err := photonsr.ResolveScope(*dirFlag, *scopeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		invOpts.AllowedPaths = allowed
+	}
+	var entries []photonsr.InventoryEntry
+	r.operationMessages, entries, r.filesScanned, r.operationError = photonsr.PerformInventoryCtx(ctx, invOpts)
+	r.itemsAffected = len(entries)
+	if *csvFlag != "" && r.operationMessages != nil {
+		if err := writeInventoryCSV(*csvFlag, entries); err != nil {
+			if r.operationError == nil {
+				r.operationError = err
+			}
+		} else {
+			r.operationMessages = append(r.operationMessages, fmt.Sprintf("%d string(s) written to %s.", len(entries), *csvFlag))
+		}
+	}
+}
+
+// backupDiff compares the backups with their files.
+func (r *cliRun) backupDiff(ctx context.Context) {
+	r.actionVerb = "compared"
+	fmt.Fprintln(infoOut, tr("cli.progress.backup_diff"))
+	if r.diffPath == "" {
+		r.diffPath = *dirFlag
+	}
+	r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformBackupDiffCtx(ctx, photonsr.BackupDiffOptions{Path: r.diffPath, OnWarning: printWarning})
+}
+
+// backupCheck lists the orphaned and redundant backups.
+func (r *cliRun) backupCheck(ctx context.Context) {
+	r.actionVerb = "checked"
+	fmt.Fprintln(infoOut, tr("cli.progress.backup_check"))
+	if r.diffPath == "" {
+		r.diffPath = *dirFlag
+	}
+	var redundant int
+	r.operationMessages, r.itemsAffected, redundant, r.operationError = photonsr.PerformBackupCheckCtx(ctx, photonsr.BackupCheckOptions{Path: r.diffPath, OnWarning: printWarning})
+	if info, err := os.Stat(r.diffPath); err == nil && info.IsDir() && redundant > 0 {
+		r.operationMessages = append(r.operationMessages, fmt.Sprintf("Delete the redundant backups with: photonsr -clean -redundant -dir %s", r.diffPath))
+	}
+}
+
+// tidyUndo recreates what a tidy run removed.
+func (r *cliRun) tidyUndo() {
+	r.actionVerb = "undone"
+	fmt.Fprintln(infoOut, tr("cli.progress.tidy_undo"))
+	r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformTidyUndo(*undoFlag)
+}
+
+// tidy removes empty files and directories.
+func (r *cliRun) tidy() {
+	r.actionVerb = "tidied"
+	tidyOpts := photonsr.TidyOptions{Dir: *dirFlag, DryRun: *dryRunFlag, OnWarning: printWarning}
+	if err := tidyOpts.ParseKinds(*emptyFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitCodeFor(err))
+	}
+	fmt.Fprintln(infoOut, tr("cli.progress.tidy"))
+	for _, pattern := range strings.Split(*excludeFlag, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			tidyOpts.Exclude = append(tidyOpts.Exclude, pattern)
+		}
+	}
+	r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformTidy(tidyOpts)
+}
+
+// dupes reports or links duplicate files.
+func (r *cliRun) dupes(ctx context.Context) {
+	r.actionVerb = "deduped"
+	fmt.Fprintln(infoOut, tr("cli.progress.dupes"))
+	dupesOpts := photonsr.DupesOptions{Dir: *dirFlag, Pattern: *patternFlag, Link: *dupesLinkFlag, OnWarning: printWarning}
+	dupesOpts.MaxFileSize = sizeFlag("max-size", *maxSizeFlag)
+	if *scopeFlag != "" {
+		allowed, err := photonsr.ResolveScope(*dirFlag, *scopeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		dupesOpts.AllowedPaths = allowed
+	}
+	r.operationMessages, r.itemsAffected, r.filesScanned, r.operationError = photonsr.PerformDupesCtx(ctx, dupesOpts)
+}
+
+// rename renames or moves files for rename-files and move-files.
+func (r *cliRun) rename() {
+	r.actionVerb = "renamed"
+	progressKey := "cli.progress.rename"
+	renameOpts := photonsr.RenameOptions{
+		Dir: *dirFlag, Pattern: *patternFlag, OldText: r.oldText, NewText: r.newText,
+		TargetOS: *targetOSFlag, OnWarning: printWarning,
+	}
+	if r.subcommand == "move-files" {
+		if *layoutFlag == "" {
+			fmt.Fprintln(os.Stderr, "Error: move-files requires -layout (e.g. -layout \"{ext}/{name}\").")
+			exit(2)
+		}
+		r.actionVerb, progressKey = "moved", "cli.progress.move"
+		renameOpts.Layout = *layoutFlag
+	}
+	if !*reviewFlag {
+		fmt.Fprintln(infoOut, tr(progressKey))
+		r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformRename(renameOpts)
+	} else if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintf(os.Stderr, "Error: -review needs a terminal; run %s without it to rename as planned.\n", r.subcommand)
+		exit(2)
+	} else {
+		r.operationMessages, r.itemsAffected, r.operationError = performRenameReview(renameOpts)
+	}
+}
+
+// clean deletes backups.
+func (r *cliRun) clean(ctx context.Context) {
+	r.actionVerb = "cleaned"
+	fmt.Fprintln(infoOut, tr("cli.progress.clean"))
+	cleanOpts := photonsr.CleanOptions{Dir: *dirFlag, OrphansOnly: *orphansFlag, RedundantOnly: *redundantFlag, Jobs: *jobsFlag, OnProgress: r.progress.backups, OnWarning: printWarning}
+	if *olderThanFlag != "" {
+		age, err := photonsr.ParseAge(*olderThanFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -older-than: %v\n", err)
+			exit(1)
+		}
+		cleanOpts.OlderThan = age
+	}
+	if *auditFlag != "" {
+		cleanOpts.OnBackupDeleted = r.recorder.recordDeletion
+	}
+	r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformCleanCtx(ctx, cleanOpts)
+}
+
+// restore restores files from their backups.
+func (r *cliRun) restore(ctx context.Context) {
+	r.actionVerb = "restored"
+	fmt.Fprintln(infoOut, tr("cli.progress.restore"))
+	restoreOpts := photonsr.RestoreOptions{Dir: *dirFlag, Force: *forceFlag, Confine: *confineFlag, To: *toFlag, Jobs: *jobsFlag, OnProgress: r.progress.backups, OnWarning: printWarning}
+	if *auditFlag != "" {
+		restoreOpts.OnFileRestored = r.recorder.recordModification
+	}
+	r.operationMessages, r.itemsAffected, r.operationError = photonsr.PerformRestoreCtx(ctx, restoreOpts)
+}
+
+// replace runs a replacement, including those of go-mod-rename, license-headers,
+// anonymize and multi.
+func (r *cliRun) replace(ctx context.Context) {
+	r.actionVerb = "modified"
+	opts, t := r.replaceOptions(ctx)
+	fmt.Fprintln(infoOut, tr("cli.progress.replace"))
+	r.runOptions(&opts)
+	replacementCounts := map[string]int{}
+	opts.OnFileReplacements = func(path string, count int) { replacementCounts[path] = count }
+	if *auditFlag != "" || *checksumsFlag != "" {
+		opts.OnFileModified = r.recorder.recordModification
+	}
+	if *outputFlag == outputMarkdown {
+		opts.OnFileDiff = func(path string, lines []string) { r.sampleDiffs[path] = lines }
+	}
+	if *diffFlag {
+		opts.OnFilePatch = func(path, patch string) { r.patches[path] = patch }
+	}
+	opts.BackupPolicy = *backupPolicyFlag
+	if opts.BackupPolicy == photonsr.BackupPolicyAsk {
+		opts.ResolveBackupConflict = promptBackupConflict
+	}
+	var conflictMessages []string
+	opts.OnBackupConflict = func(path, resolution string) {
+		conflictMessages = append(conflictMessages, fmt.Sprintf("  - %s: %s", path, resolution))
+	}
+	opts.OnFileError = func(path string, err error) {
+		if abs, absErr := filepath.Abs(path); absErr == nil {
+			path = abs
+		}
+		r.failedFiles = append(r.failedFiles, failedFile{Path: path, Code: errorCode(err), Error: err.Error()})
+	}
+	opts.OnFileSkipped = func(path string, reason error) {
+		r.skippedFiles = append(r.skippedFiles, failedFile{Path: path, Code: errorCode(reason), Error: reason.Error()})
+	}
+	r.restrictReplace(&opts)
+	adminPolicy, err := loadPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	realDir := *dirFlag
+	if r.sb != nil {
+		realDir = r.sb.realDir
+	}
+	policyNotices, err := adminPolicy.enforce(&opts, realDir, r.sb != nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitCodeFor(err))
+	}
+	for _, notice := range policyNotices {
+		fmt.Fprintln(infoOut, notice)
+	}
+	r.progress.watch(&opts)
+	dirtyFiles, diffBaseRef := diffBaseFiles() // Collected before anything is written.
+
+	if r.subcommand == "multi" {
+		if *diffBaseFlag != "" {
+			fmt.Fprintln(os.Stderr, "Error: -diff-base compares one repository; it cannot be used with multi.")
+			exit(2)
+		}
+		fmt.Fprintf(infoOut, "Applying the replacement to the repositories of %s.\n", *reposFlag)
+		r.repoResults, r.modifiedFilePaths, r.operationError = photonsr.PerformMultiCtx(ctx, photonsr.MultiOptions{ReposFile: *reposFlag, Workspace: *dirFlag, Replace: opts})
+		for _, repo := range r.repoResults {
+			r.filesScanned += repo.Scanned
+		}
+		r.operationMessages = append(r.operationMessages, multiMessages(r.repoResults)...)
+	} else {
+		r.modifiedFilePaths, r.filesScanned, r.operationError = photonsr.PerformReplacementCtx(ctx, opts)
+	}
+	r.itemsAffected = len(r.modifiedFilePaths)
+	if adminPolicy != nil && adminPolicy.RequireDryRun && ((r.sb != nil && !r.sb.output) || opts.DryRun) && r.operationError == nil {
+		if abs, err := filepath.Abs(realDir); err == nil {
+			if err := recordDryRun(dryRunKey(abs, opts)); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not record the dry run: %v\n", err)
+			}
+		}
+	}
+	if *checksumsFlag != "" {
+		if err := writeChecksumManifest(*checksumsFlag, r.recorder.files); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if r.operationError == nil {
+				r.operationError = err
+			}
+		} else {
+			r.operationMessages = append(r.operationMessages, fmt.Sprintf("Checksums of %d modified file(s) written to %s.", len(r.recorder.files), *checksumsFlag))
+		}
+	}
+
+	if r.itemsAffected > 0 {
+		r.listModified(opts, replacementCounts, dirtyFiles, diffBaseRef)
+	}
+	if len(r.skippedFiles) > 0 {
+		r.operationMessages = append(r.operationMessages, fmt.Sprintf("Skipped %d file(s):", len(r.skippedFiles)))
+		for _, f := range r.skippedFiles {
+			r.operationMessages = append(r.operationMessages, fmt.Sprintf("  - %s", f.Error))
+		}
+	}
+	if len(conflictMessages) > 0 {
+		r.operationMessages = append(r.operationMessages, "Existing backups encountered:")
+		r.operationMessages = append(r.operationMessages, conflictMessages...)
+	}
+	if len(r.failedFiles) > 0 && r.sb == nil && t.rename == nil && opts.Transform == nil && r.subcommand != "multi" && r.readOnly == nil && !opts.DryRun { // A sandbox is gone by the time a retry could run; subcommands are simply run again.
+		r.saveRetryList(opts)
+	}
+	if t.rename != nil && r.itemsAffected > 0 && r.operationError == nil && !opts.DryRun {
+		r.goModTidy(ctx)
+	}
+	r.operationMessages = append(r.operationMessages, t.summary(r.itemsAffected)...)
+	if r.retryID != "" && len(r.failedFiles) == 0 && r.operationError == nil && !opts.DryRun {
+		if err := removeRunRecord(r.retryID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if opts.ShouldBackup && !r.retention.IsZero() && !opts.DryRun {
+		pruneMessages, pruned, pruneErr := photonsr.PerformPrune(photonsr.PruneOptions{Dir: *dirFlag, Policy: r.retention, OnWarning: printWarning})
+		if pruned > 0 {
+			r.operationMessages = append(r.operationMessages, fmt.Sprintf("Retention policy pruned %d backup(s):", pruned))
+			r.operationMessages = append(r.operationMessages, pruneMessages...)
+		}
+		if pruneErr != nil && r.operationError == nil {
+			r.operationError = pruneErr
+		}
+	}
+	if r.operationError == nil && r.itemsAffected == 0 {
+		r.explainNoChanges(opts)
+	}
+}
+
+// replaceTransforms holds the transformations a replacement may run instead of
+// replacing -old by -new, for what they add to its report.
+type replaceTransforms struct {
+	rename  *moduleRename
+	anon    *anonymizer
+	urlRW   *urlRewrite
+	remap   *ipRemap
+	numbers *numberConversion
+}
+
+// summary returns the messages the transformations add after itemsAffected files were changed.
+func (t replaceTransforms) summary(itemsAffected int) []string {
+	var messages []string
+	if t.urlRW != nil && itemsAffected > 0 {
+		messages = append(messages, "Occurrences replaced by scheme: "+t.urlRW.schemeCounts())
+	}
+	if t.remap != nil {
+		messages = append(messages, t.remap.summary()...)
+	}
+	if t.numbers != nil {
+		messages = append(messages, t.numbers.summary())
+	}
+	if t.anon != nil {
+		messages = append(messages, t.anon.summary())
+	}
+	return messages
+}
+
+// replaceOptions builds the options of a replacement from -old, -new, -rules, the
+// subcommand and the flags that choose what is replaced.
+func (r *cliRun) replaceOptions(ctx context.Context) (photonsr.ReplaceOptions, replaceTransforms) {
+	var t replaceTransforms
+	opts := photonsr.ReplaceOptions{
+		Dir: *dirFlag, Pattern: *patternFlag,
+		OldText: r.oldText, NewText: r.newText,
+		ShouldBackup: *backupFlag, Confine: *confineFlag,
+		Jobs: *jobsFlag, SortBy: *sortByFlag,
+		OnWarning: printWarning,
+	}
+	if *rulesFlag != "" {
+		rules, err := loadRules(*rulesFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.Rules, opts.RulesFile = rules, *rulesFlag
+		if r.sb != nil {
+			opts.RulesFile = r.sb.pathFor(*rulesFlag)
+		}
+		if err := opts.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -rules %s: %v\n", *rulesFlag, err)
+			exit(exitCodeFor(err))
+		}
+	}
+	r.transformOptions(&opts, &t)
+	r.presetOptions(ctx, &opts, &t)
+	matchOptions(&opts)
+	return opts, t
+}
+
+// diffBaseFiles returns the files that already differ from the -diff-base ref, and
+// the ref, or nothing without -diff-base.
+func diffBaseFiles() (map[string]bool, string) {
+	if *diffBaseFlag == "" {
+		return nil, ""
+	}
+	ref, err := photonsr.ParseGitRefSpec(*diffBaseFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	dirtyFiles, err := photonsr.GitChangedFiles(*dirFlag, ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: determining files changed relative to %s: %v\n", ref, err)
+		exit(1)
+	}
+	return dirtyFiles, ref
+}
+
+// transformOptions sets up the transformation of the subcommand or of -csv-column,
+// -key or -sql, which replaces opts.OldText and opts.NewText.
+func (r *cliRun) transformOptions(opts *photonsr.ReplaceOptions, t *replaceTransforms) {
+	if r.subcommand == "go-mod-rename" {
+		if r.oldText != "" || *rulesFlag != "" {
+			fmt.Fprintln(os.Stderr, "Error: go-mod-rename takes its text from the module paths; -old and -rules cannot be used with it.")
+			exit(2)
+		}
+		var err error
+		if t.rename, err = newModuleRename(*dirFlag, r.moduleArgs[0], r.moduleArgs[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.Rules = t.rename.rules()
+		fmt.Fprintf(infoOut, "Renaming module %s to %s.\n", t.rename.oldPath, t.rename.newPath)
+	}
+	if r.subcommand == "license-headers" {
+		if *headerFlag == "" {
+			fmt.Fprintln(os.Stderr, "Error: license-headers requires a header template (photonsr license-headers -header HEADER.txt).")
+			exit(2)
+		}
+		tmpl, err := loadHeaderTemplate(*headerFlag, *yearFlag, *holderFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.Transform = tmpl
+		fmt.Fprintf(infoOut, "Updating license headers from %s for %s.\n", *headerFlag, *yearFlag)
+	}
+	if *csvColumnFlag != "" {
+		if *presetFlag != "" || opts.OldText == "" {
+			fmt.Fprintln(os.Stderr, "Error: -csv-column replaces -old by -new within a column; it requires -old and cannot be combined with -preset.")
+			exit(2)
+		}
+		column, err := newCSVColumn(*csvColumnFlag, *csvDelimiterFlag, opts.OldText, opts.NewText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.OldText, opts.NewText, opts.Transform = "", "", column
+	}
+	if *keyFlag != "" {
+		if *presetFlag != "" || *csvColumnFlag != "" || opts.OldText == "" {
+			fmt.Fprintln(os.Stderr, "Error: -key replaces -old by -new within one value; it requires -old and cannot be combined with -preset or -csv-column.")
+			exit(2)
+		}
+		key, err := newConfigKey(*keyFlag, opts.OldText, opts.NewText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.OldText, opts.NewText, opts.Transform = "", "", key
+	}
+	if *sqlFlag || *sqlTablesFlag != "" {
+		if *presetFlag != "" || *csvColumnFlag != "" || *keyFlag != "" || opts.OldText == "" {
+			fmt.Fprintln(os.Stderr, "Error: -sql replaces -old by -new in SQL dumps; it requires -old and cannot be combined with -preset, -csv-column or -key.")
+			exit(2)
+		}
+		dump, err := newSQLDump(opts.OldText, opts.NewText, *sqlTablesFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.OldText, opts.NewText, opts.Transform = "", "", dump
+	}
+	if r.subcommand == "anonymize" {
+		key, err := loadAnonymizeKey(*anonymizeKeyFlag)
+		if err == nil {
+			t.anon, err = newAnonymizer(*anonymizeFlag, *anonymizeRegexFlag, key)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.Transform = t.anon
+		if key == nil {
+			fmt.Fprintf(infoOut, "No -anonymize-key or $%s: pseudonyms are consistent within this run only.\n", anonymizeKeyEnv)
+		}
+	}
+}
+
+// presetOptions sets up the transformation of -preset.
+func (r *cliRun) presetOptions(ctx context.Context, opts *photonsr.ReplaceOptions, t *replaceTransforms) {
+	switch *presetFlag {
+	case "":
+	case PresetURL:
+		if opts.OldText == "" || opts.NewText == "" {
+			fmt.Fprintln(os.Stderr, "Error: -preset url requires the old and the new URL or host name (-old and -new).")
+			exit(2)
+		}
+		var err error
+		if t.urlRW, err = newURLRewrite(opts.OldText, opts.NewText); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -preset url: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		if err := t.urlRW.checkReachable(ctx, *urlCheckFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -url-check %s: %v\n", *urlCheckFlag, err)
+			exit(exitCodeFor(err))
+		}
+		opts.OldText, opts.NewText, opts.Transform = "", "", t.urlRW
+	case PresetCIDR:
+		var err error
+		if t.remap, err = newIPRemap(opts.OldText, opts.NewText, *ipMapFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -preset cidr: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.OldText, opts.NewText, opts.Transform = "", "", t.remap
+		if *ipMapFlag != "" && opts.RulesFile == "" { // Lists the old addresses too.
+			opts.RulesFile = *ipMapFlag
+			if r.sb != nil {
+				opts.RulesFile = r.sb.pathFor(*ipMapFlag)
+			}
+		}
+	case PresetNumber:
+		var err error
+		if t.numbers, err = newNumberConversion(opts.OldText, opts.NewText, *numberScopeFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -preset number: %v\n", err)
+			exit(exitCodeFor(err))
+		}
+		opts.OldText, opts.NewText, opts.Transform = "", "", t.numbers
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -preset '%s' (expected url, cidr or number).\n", *presetFlag)
+		exit(2)
+	}
+}
+
+// matchOptions applies the flags that choose which matches of -old are replaced,
+// which apply to -old only, not to a transformation.
+func matchOptions(opts *photonsr.ReplaceOptions) {
+	if *regexFlag && opts.Transform != nil {
+		fmt.Fprintln(os.Stderr, "Error: -regex applies to -old and -new; it cannot be combined with a preset or another transformation.")
+		exit(2)
+	}
+	opts.UseRegex = *regexFlag
+	opts.IgnoreCase = *ignoreCaseFlag
+	if *nearFlag != "" {
+		if opts.Transform != nil {
+			fmt.Fprintln(os.Stderr, "Error: -near applies to -old; it cannot be combined with a preset or another transformation.")
+			exit(2)
+		}
+		near, err := parseNear(*nearFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -near: %v\n", err)
+			exit(2)
+		}
+		opts.Near = near
+	}
+	if (*notPrecededByFlag != "" || *notFollowedByFlag != "") && opts.Transform != nil {
+		fmt.Fprintln(os.Stderr, "Error: -not-preceded-by and -not-followed-by apply to -old; they cannot be combined with a preset or another transformation.")
+		exit(2)
+	}
+	opts.NotPrecededBy, opts.NotFollowedBy = *notPrecededByFlag, *notFollowedByFlag
+	if *lineModeFlag && opts.Transform != nil {
+		fmt.Fprintln(os.Stderr, "Error: -line-mode applies to -old and -new; it cannot be combined with a preset or another transformation.")
+		exit(2)
+	}
+	opts.LineMode = *lineModeFlag
+	if *anchorFlag != "" && opts.Transform != nil {
+		fmt.Fprintln(os.Stderr, "Error: -anchor applies to -old; it cannot be combined with a preset or another transformation.")
+		exit(2)
+	}
+	opts.Anchor = *anchorFlag
+}
+
+// runOptions applies the flags that choose how a replacement runs to opts.
+func (r *cliRun) runOptions(opts *photonsr.ReplaceOptions) {
+	if *ioProfileFlag != "" {
+		profile, detected, err := resolveIOProfile(*ioProfileFlag, *dirFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -io-profile: %v\n", err)
+			exit(1)
+		}
+		if !flagWasSet("jobs") && !flagFromEnv("jobs") {
+			opts.Jobs = profile.Jobs
+		}
+		opts.ReadAhead = profile.ReadAhead
+		opts.Order = profile.Order
+		if *verboseFlag {
+			source := "requested"
+			if *ioProfileFlag == IOProfileAuto {
+				source = "detected"
+				if !detected {
+					source = "storage not detected, assumed"
+				}
+			}
+			fmt.Fprintf(infoOut, "I/O profile: %s (%s): %d job(s), %d KiB read-ahead, %s order.\n", profile.Name, source, opts.Jobs, opts.ReadAhead>>10, opts.Order)
+		}
+	}
+	if *orderFlag != "" {
+		opts.Order = *orderFlag
+	}
+	opts.MaxMemory = sizeFlag("max-mem", *maxMemFlag)
+	opts.MaxFileSize = sizeFlag("max-size", *maxSizeFlag)
+	opts.SkipBinary = *skipBinaryFlag
+	opts.Immutable = *immutableFlag
+	opts.SkipOpen = *skipOpenFlag
+	opts.SameLength = *sameLengthFlag
+	opts.DryRun = *dryRunFlag
+	if opts.DryRun {
+		if r.subcommand == "multi" || *checksumsFlag != "" {
+			fmt.Fprintln(os.Stderr, "Error: -dry-run modifies nothing; it cannot be used with multi or -checksums.")
+			exit(2)
+		}
+		r.actionVerb = "previewed"
+	}
+}
+
+// restrictReplace limits a replacement to the files of -scope, -manifest and retry,
+// less those excluded for it.
+func (r *cliRun) restrictReplace(opts *photonsr.ReplaceOptions) {
+	if *scopeFlag != "" {
+		allowed, err := photonsr.ResolveScope(*dirFlag, *scopeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		opts.AllowedPaths = allowed
+		fmt.Fprintf(infoOut, "Scope '%s': %d candidate file(s).\n", *scopeFlag, len(allowed))
+	}
+	if *manifestFlag != "" {
+		entries, err := readManifest(*manifestFlag, *dirFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		if *verifyManifestFlag {
+			if problems := verifyManifest(entries, *dirFlag, *patternFlag); len(problems) > 0 {
+				fmt.Fprintf(os.Stderr, "Error: manifest verification failed for %d of %d file(s); nothing was changed:\n", len(problems), len(entries))
+				for _, p := range problems {
+					fmt.Fprintf(os.Stderr, "  - %s\n", p)
+				}
+				exit(1)
+			}
+			fmt.Fprintf(infoOut, "Manifest verified: all %d listed file(s) present and in scope.\n", len(entries))
+		}
+		manifestPaths := manifestAllowedPaths(entries)
+		if opts.AllowedPaths != nil {
+			for path := range manifestPaths {
+				if !opts.AllowedPaths[path] {
+					delete(manifestPaths, path)
+				}
+			}
+		}
+		opts.AllowedPaths = manifestPaths
+	} else if *verifyManifestFlag {
+		fmt.Fprintln(os.Stderr, "Error: -verify requires -manifest <file>.")
+		exit(1)
+	}
+	if r.retryPaths != nil {
+		if opts.AllowedPaths != nil {
+			for path := range r.retryPaths {
+				if !opts.AllowedPaths[path] {
+					delete(r.retryPaths, path)
+				}
+			}
+		}
+		opts.AllowedPaths = r.retryPaths
+	}
+	if saved, configPath, err := photonsr.ApplySavedExclusions(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	} else if saved > 0 {
+		fmt.Fprintf(infoOut, "Skipping %d path(s) excluded for this replacement in %s.\n", saved, configPath)
+	}
+}
+
+// listModified prepends the files a replacement modified to its messages, with their
+// counts in a dry run and a mark on those that already differed from -diff-base.
+func (r *cliRun) listModified(opts photonsr.ReplaceOptions, counts map[string]int, dirtyFiles map[string]bool, diffBaseRef string) {
+	header := tr("cli.modified_header")
+	if opts.DryRun {
+		header = tr("cli.dry_run_header")
+	}
+	detailedMessages := []string{header}
+	stackedCount := 0
+	for _, f := range r.modifiedFilePaths {
+		line := fmt.Sprintf("  - %s", f)
+		if opts.DryRun {
+			line += fmt.Sprintf(" (%d replacement(s))", counts[f])
+		}
+		if dirtyFiles[photonsr.CanonicalPath(f)] {
+			stackedCount++
+			line += fmt.Sprintf("  [!] already differed from %s (replacement stacks on uncommitted edits)", diffBaseRef)
+		}
+		detailedMessages = append(detailedMessages, line)
+	}
+	if diffBaseRef != "" {
+		detailedMessages = append(detailedMessages, fmt.Sprintf("%d of %d modified file(s) already differed from %s.", stackedCount, r.itemsAffected, diffBaseRef))
+	}
+	// Prepend these messages to any messages returned by PerformReplacement (e.g., "no files found" if itemsAffected is 0)
+	r.operationMessages = append(detailedMessages, r.operationMessages...)
+}
+
+// saveRetryList records the files a replacement failed on for "photonsr retry".
+func (r *cliRun) saveRetryList(opts photonsr.ReplaceOptions) {
+	options := map[string]string{}
+	for _, name := range retryOptionFlags {
+		options[name] = flag.Lookup(name).Value.String()
+	}
+	recordTextOption(options, "old", opts.OldText)
+	recordTextOption(options, "new", opts.NewText)
+	rec := runRecord{ID: r.runID, Time: time.Now().UTC().Format(time.RFC3339), Options: options, Failed: r.failedFiles}
+	if err := saveRunRecord(rec); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save retry list: %v\n", err)
+	} else {
+		r.retryRunID = rec.ID
+		r.operationMessages = append(r.operationMessages, fmt.Sprintf("%d file(s) failed. Retry only those with: photonsr retry %s", len(r.failedFiles), rec.ID))
+	}
+}
+
+// goModTidy runs "go mod tidy" after go-mod-rename with -tidy, or suggests it.
+func (r *cliRun) goModTidy(ctx context.Context) {
+	if *tidyFlag {
+		fmt.Fprintln(infoOut, "Running go mod tidy...")
+		if r.sb != nil && r.sb.linked > 0 { // The go command rewrites go.sum in place.
+			for _, name := range []string{"go.mod", "go.sum"} {
+				if err := unshare(filepath.Join(*dirFlag, name)); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					exit(1)
+				}
+			}
+		}
+		if out, err := runGoModTidy(ctx, *dirFlag); err != nil {
+			r.operationError = err
+		} else {
+			r.operationMessages = append(r.operationMessages, "go mod tidy completed.")
+			if out != "" {
+				r.operationMessages = append(r.operationMessages, strings.Split(out, "\n")...)
+			}
+		}
+	} else {
+		r.operationMessages = append(r.operationMessages, "Run 'go mod tidy' (or use -tidy) to update go.sum and the requirements.")
+	}
+}
+
+// explainNoChanges adds why a replacement modified nothing to its messages.
+func (r *cliRun) explainNoChanges(opts photonsr.ReplaceOptions) {
+	if r.filesScanned > 0 {
+		// This message might already be part of operationMessages from PerformReplacement if it handles this logic.
+		// Let's ensure it's clear.
+		hasNoMatchMsg := false
+		for _, msg := range r.operationMessages {
+			if strings.Contains(msg, "Old text not found") || strings.Contains(msg, "No files matched the criteria") {
+				hasNoMatchMsg = true
+				break
+			}
+		}
+		if !hasNoMatchMsg && opts.Transform != nil {
+			r.operationMessages = append(r.operationMessages, tr("cli.up_to_date"))
+		} else if !hasNoMatchMsg {
+			r.operationMessages = append(r.operationMessages, tr("cli.old_not_found"))
+			if r.subcommand == "" {
+				for _, s := range suggestOldText(opts) {
+					r.operationMessages = append(r.operationMessages, tr("cli.did_you_mean", s.Text, opts.OldText, s.Count))
+				}
+			}
+		}
+	} else { // filesScanned == 0
+		hasNoFilesFoundMsg := false
+		for _, msg := range r.operationMessages {
+			if strings.Contains(msg, "No files found") {
+				hasNoFilesFoundMsg = true
+				break
+			}
+		}
+		if !hasNoFilesFoundMsg {
+			r.operationMessages = append(r.operationMessages, tr("cli.no_files_found"))
+		}
+	}
+}
+
+// finish records the run: the changes in a sandbox or output tree, the end of the
+// progress stream, the writes -read-only held back, the history and the audit log.
+func (r *cliRun) finish() {
+	if r.sb != nil {
+		r.reportCopy()
+	}
+
+	r.progress.finish(r.itemsAffected, r.operationError)
+
+	if r.readOnly != nil {
+		r.intendedWrites = r.readOnly.Intended()
+		r.operationMessages = append(r.operationMessages, photonsr.ReadOnlyMessages(r.intendedWrites)...)
+	}
+
+	r.record()
+}
+
+// reportCopy adds what the operation changed in the sandbox or output tree to its
+// messages, and warns about changes to the real files during the run.
+func (r *cliRun) reportCopy() {
+	if r.sb.output {
+		r.operationMessages = append(r.operationMessages, fmt.Sprintf("Transformed copy of %s written to %s.", r.sb.realDir, r.sb.dir))
+		switch changed, err := r.sb.untouched(); {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: checking %s: %v\n", r.sb.realDir, err)
+		case len(changed) > 0:
+			fmt.Fprintf(os.Stderr, "Warning: %d path(s) in %s changed during the run, by another process; the copy may not reflect them:\n", len(changed), r.sb.realDir)
+			for _, name := range changed {
+				fmt.Fprintf(os.Stderr, "  - %s\n", name)
+			}
+		}
+	} else {
+		changes, err := r.sb.changes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: comparing sandbox with %s: %v\n", r.sb.realDir, err)
+		} else if len(changes) > 0 {
+			r.operationMessages = append(r.operationMessages, "Changes in the sandbox (not applied to the real files):")
+			r.operationMessages = append(r.operationMessages, changes...)
+		} else {
+			r.operationMessages = append(r.operationMessages, "The operation changed nothing in the sandbox.")
+		}
+		switch changed, err := r.sb.untouched(); {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: checking %s: %v\n", r.sb.realDir, err)
+		case len(changed) > 0:
+			fmt.Fprintf(os.Stderr, "Warning: %d path(s) in %s changed during the run, by another process (the sandboxed operation only touched its copy):\n", len(changed), r.sb.realDir)
+			for _, name := range changed {
+				fmt.Fprintf(os.Stderr, "  - %s\n", name)
+			}
+		default:
+			r.operationMessages = append(r.operationMessages, fmt.Sprintf("Verified: all %d entries of %s are unchanged.", len(r.sb.before), r.sb.realDir))
+		}
+	}
+}
+
+// record adds the run to the usage statistics, the history and the -audit log.
+func (r *cliRun) record() {
+	recordUsage(operationNames[r.actionVerb], r.filesScanned, r.itemsAffected, r.operationError != nil, time.Since(r.started))
+	rec := newHistoryRecord(r.runID, operationNames[r.actionVerb], *dirFlag, r.itemsAffected, r.filesScanned, r.modifiedFilePaths, r.failedFiles, r.skippedFiles, r.operationError)
+	if r.sb != nil { // The paths are relative to the copy, and so to the real directory.
+		rec.Dir = r.sb.realDir
+	}
+	rec.Options = map[string]string{}
+	for _, name := range retryOptionFlags {
+		rec.Options[name] = flag.Lookup(name).Value.String()
+	}
+	recordTextOption(rec.Options, "old", r.oldText)
+	recordTextOption(rec.Options, "new", r.newText)
+	recordHistory(rec)
+
+	if *auditFlag != "" {
+		options := map[string]string{}
+		flag.VisitAll(func(f *flag.Flag) { options[f.Name] = f.Value.String() })
+		rec := newAuditRecord(operationNames[r.actionVerb], options)
+		rec.Files = r.recorder.files
+		rec.ItemsAffected = r.itemsAffected
+		if r.actionVerb != "modified" {
+			rec.Details = r.operationMessages
+		}
+		if r.operationError != nil {
+			rec.Error = r.operationError.Error()
+		}
+		if err := appendAuditRecord(*auditFlag, rec, r.auditKey); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing audit record: %v\n", err)
+			exit(1)
+		}
+	}
+}
+
+// report prints the result in the format requested and exits on failure.
+func (r *cliRun) report() {
+	switch {
+	case machineReadable(*outputFlag) || *outputFlag == outputMarkdown:
+		r.writeReport()
+	case *diffFlag:
+		r.writePatch()
+	case *quietFlag || *summaryFlag:
+		r.writeSummary()
+	default:
+		r.writeText()
+	}
+}
+
+// writeReport writes the result as -output requests and exits.
+func (r *cliRun) writeReport() {
+	report := runReport{
+		Operation: operationNames[r.actionVerb], Dir: *dirFlag,
+		ItemsAffected: r.itemsAffected, FilesScanned: r.filesScanned,
+		ModifiedFiles: r.modifiedFilePaths, Messages: r.operationMessages,
+		RunID: r.runID, RetryRunID: r.retryRunID, FileErrors: r.failedFiles, SkippedFiles: r.skippedFiles,
+		Violations: r.violationsFound, Repos: r.repoResults,
+		ReadOnly: r.readOnly != nil, IntendedWrites: r.intendedWrites,
+	}
+	if r.sb != nil {
+		report.Dir, report.Sandbox = r.sb.realDir, r.sb.dir
+		if r.sb.output {
+			report.Sandbox, report.Output = "", r.sb.dir
+		}
+	}
+	if r.operationError != nil {
+		report.Error = r.operationError.Error()
+		report.ErrorCode = errorCode(r.operationError)
+	}
+	var err error
+	switch *outputFlag {
+	case outputJSON:
+		err = writeJSONReport(os.Stdout, report)
+	case outputMarkdown:
+		err = writeMarkdownReport(os.Stdout, report, r.oldText, r.newText, r.sampleDiffs)
+	default:
+		err = writeNDJSONReport(os.Stdout, report, *outputFlag == outputPSObject)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	if r.operationError != nil {
+		exit(exitCodeFor(r.operationError))
+	}
+	exit(0)
+}
+
+// writePatch writes the patch of -diff to standard output, and nothing else, so
+// that it can be saved or piped as is, and exits.
+func (r *cliRun) writePatch() {
+	var patch strings.Builder
+	for _, f := range r.modifiedFilePaths {
+		patch.WriteString(r.patches[f])
+	}
+	writePaged(colorizeDiff(stdoutRenderer, patch.String()), !*noPagerFlag)
+	if r.operationError != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", r.operationError)
+		exit(exitCodeFor(r.operationError))
+	}
+	if !*quietFlag {
+		if r.itemsAffected > 0 {
+			fmt.Fprint(os.Stderr, tr("cli.success.previewed", r.itemsAffected))
+		} else {
+			fmt.Fprintln(os.Stderr, tr("cli.no_changes"))
+		}
+	}
+	exit(0)
+}
+
+// writeSummary prints the one line of -summary, or nothing with -quiet, and exits.
+func (r *cliRun) writeSummary() {
+	if *summaryFlag {
+		errorCount := len(r.failedFiles)
+		if r.operationError != nil && errorCount == 0 {
+			errorCount = 1
+		}
+		fmt.Println(summaryLine(r.actionVerb, r.itemsAffected, r.filesScanned, len(r.skippedFiles), len(r.violationsFound), errorCount, time.Since(r.started)))
+	}
+	if r.operationError != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", r.operationError)
+		exit(exitCodeFor(r.operationError))
+	}
+	exit(0)
+}
+
+// writeText prints the messages of the operation and its result.
+func (r *cliRun) writeText() {
+	var out strings.Builder
+	for _, msg := range r.operationMessages {
+		// Avoid printing duplicate "no files found" messages if already handled by core logic.
+		// This simple check might need refinement if messages become more complex.
+		isSummaryMsgFromCore := (strings.Contains(msg, "No .bak files found") || strings.Contains(msg, "No files found")) && r.itemsAffected == 0
+		if !(isSummaryMsgFromCore && r.actionVerb != "modified" && r.actionVerb != "previewed") { // For replace, detail messages are more critical
+			fmt.Fprintln(&out, colorizeMessage(stdoutRenderer, msg))
+		}
+	}
+	writePaged(out.String(), !*noPagerFlag)
+
+	if r.operationError != nil {
+		fmt.Fprint(os.Stderr, paint(stderrRenderer, "9", tr("cli.completed_with_errors", r.operationError)))
+		if r.itemsAffected > 0 {
+			fmt.Fprint(os.Stderr, tr("cli.partial_success."+r.actionVerb, r.itemsAffected))
+		}
+		exit(exitCodeFor(r.operationError))
+	} else {
+		// Success messages
+		if r.itemsAffected > 0 {
+			fmt.Fprint(os.Stdout, tr("cli.success."+r.actionVerb, r.itemsAffected))
+		} else if (r.actionVerb == "modified" || r.actionVerb == "previewed") && r.filesScanned > 0 {
+			// Message about "Old text not found..." should have been in operationMessages
+			fmt.Fprintln(os.Stdout, tr("cli.no_changes"))
+		} else if (r.actionVerb == "cleaned" || r.actionVerb == "restored") && r.itemsAffected == 0 {
+			// Message about "No .bak files found..." should have been in operationMessages
+			// if the core function added it.
+			// If operationMessages is empty, means the core func didn't add it.
+			if len(r.operationMessages) == 0 || (len(r.operationMessages) == 1 && r.operationMessages[0] == "") {
+				fmt.Fprint(os.Stdout, tr("cli.no_backups."+r.actionVerb))
+			} else {
+				fmt.Fprintln(os.Stdout, tr("cli.completed"))
+			}
+		} else if r.actionVerb == "compared" || r.actionVerb == "checked" {
+			if len(r.operationMessages) == 1 && strings.Contains(r.operationMessages[0], "No .bak files found") {
+				fmt.Fprint(os.Stdout, tr("cli.no_backups."+r.actionVerb))
+			} else if r.actionVerb == "checked" {
+				fmt.Fprint(os.Stdout, tr("cli.no_redundant"))
+			} else {
+				fmt.Fprint(os.Stdout, tr("cli.no_differences"))
+			}
+		} else if (r.actionVerb == "modified" || r.actionVerb == "previewed") && r.filesScanned == 0 {
+			// "No files found matching pattern"
+			fmt.Fprintln(os.Stdout, tr("cli.completed"))
+		} else {
+			fmt.Fprintln(os.Stdout, tr("cli.completed_successfully")) // General fallback
+		}
+	}
+}
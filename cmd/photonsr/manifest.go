@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- File Manifests ---
+
+// readManifest reads a manifest of file paths, one per line. Blank lines and lines
+// starting with '#' are ignored; relative paths are resolved against dir.
+func readManifest(manifestPath, dir string) ([]string, error) {
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening manifest '%s': %w", manifestPath, err)
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		path := filepath.FromSlash(line)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		entries = append(entries, path)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading manifest '%s': %w", manifestPath, err)
+	}
+	return entries, nil
+}
+
+// manifestAllowedPaths converts manifest entries into a ReplaceOptions.AllowedPaths set.
+func manifestAllowedPaths(entries []string) map[string]bool {
+	allowed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		allowed[photonsr.CanonicalPath(entry)] = true
+	}
+	return allowed
+}
+
+// verifyManifest checks that every manifest entry is a regular file inside dir that
+// the replacement would process with pattern. It returns one problem description
+// per entry that would not be processed.
+func verifyManifest(entries []string, dir, pattern string) []string {
+	var problems []string
+	root := photonsr.CanonicalPath(dir)
+	for _, entry := range entries {
+		info, err := os.Stat(entry)
+		switch {
+		case os.IsNotExist(err):
+			problems = append(problems, fmt.Sprintf("%s: missing", entry))
+			continue
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", entry, err))
+			continue
+		case info.IsDir():
+			problems = append(problems, fmt.Sprintf("%s: is a directory", entry))
+			continue
+		}
+		if rel, err := filepath.Rel(root, photonsr.CanonicalPath(entry)); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			problems = append(problems, fmt.Sprintf("%s: outside target directory '%s'", entry, dir))
+			continue
+		}
+		if matched, err := photonsr.MatchesPattern(info.Name(), pattern); err != nil || !matched {
+			problems = append(problems, fmt.Sprintf("%s: does not match pattern '%s'", entry, pattern))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Markdown Run Summary ---
+
+// "-output markdown" prints the result of a run as a summary meant to be pasted into
+// the description of the pull request that follows a bulk edit: the counts, a table
+// of the files modified, failed and skipped, and, for replacements, sample diffs of
+// the first files in collapsed <details> blocks. Progress messages go to stderr, as
+// for the JSON formats.
+
+// Limits of the sample diffs of a markdown summary.
+const (
+	markdownDiffFiles = 10 // Files with a sample diff.
+	markdownFileRows  = 50 // Rows of the table of files.
+)
+
+// writeMarkdownReport writes report to w as Markdown. oldText and newText, if set,
+// are the replacement of the run, and diffs the sample diffs of its modified files.
+func writeMarkdownReport(w io.Writer, report runReport, oldText, newText string, diffs map[string][]string) error {
+	var b strings.Builder
+	dir := report.Dir // Where the operation ran, which file paths are in.
+	switch {
+	case report.Output != "":
+		dir = report.Output
+	case report.Sandbox != "":
+		dir = report.Sandbox
+	}
+	rel := func(path string) string { return filepath.ToSlash(photonsr.RelPath(dir, path)) }
+	fmt.Fprintf(&b, "## PhotonSR %s in %s\n\n", report.Operation, markdownCode(report.Dir))
+	if report.Output != "" {
+		fmt.Fprintf(&b, "Written to %s.\n\n", markdownCode(report.Output))
+	}
+	if oldText != "" {
+		fmt.Fprintf(&b, "Replaced %s with %s.\n\n", markdownCode(oldText), markdownCode(newText))
+	}
+
+	b.WriteString("| | |\n|---|---:|\n")
+	verb := operationVerb(report.Operation)
+	if verb == "" {
+		verb = "affected"
+	}
+	if report.FilesScanned > 0 {
+		fmt.Fprintf(&b, "| Files scanned | %s |\n", photonsr.FormatCount(report.FilesScanned))
+	}
+	fmt.Fprintf(&b, "| Files %s | %s |\n", verb, photonsr.FormatCount(report.ItemsAffected))
+	if len(report.FileErrors) > 0 {
+		fmt.Fprintf(&b, "| Files failed | %s |\n", photonsr.FormatCount(len(report.FileErrors)))
+	}
+	if len(report.SkippedFiles) > 0 {
+		fmt.Fprintf(&b, "| Files skipped | %s |\n", photonsr.FormatCount(len(report.SkippedFiles)))
+	}
+	if len(report.Violations) > 0 {
+		fmt.Fprintf(&b, "| Violations | %s |\n", photonsr.FormatCount(len(report.Violations)))
+	}
+	if report.RunID != "" {
+		fmt.Fprintf(&b, "| Run | %s |\n", markdownCode(report.RunID))
+	}
+	if report.Error != "" {
+		fmt.Fprintf(&b, "\n**Error:** %s\n", markdownCell(report.Error))
+	}
+
+	if len(report.Repos) > 0 {
+		b.WriteString("\n### Repositories\n\n| Repository | Modified | Scanned | Failed | Error |\n|---|---:|---:|---:|---|\n")
+		for _, r := range report.Repos {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", markdownCell(markdownCode(r.Repo)), photonsr.FormatCount(r.Modified), photonsr.FormatCount(r.Scanned), photonsr.FormatCount(r.Failed), markdownCell(r.Error))
+		}
+	}
+
+	type fileRow struct{ path, status, detail string }
+	var rows []fileRow
+	for _, path := range report.ModifiedFiles {
+		rows = append(rows, fileRow{rel(path), "modified", ""})
+	}
+	for _, f := range report.FileErrors {
+		rows = append(rows, fileRow{rel(f.Path), "failed", f.Error})
+	}
+	for _, f := range report.SkippedFiles {
+		rows = append(rows, fileRow{rel(f.Path), "skipped", f.Error})
+	}
+	if len(rows) > 0 {
+		b.WriteString("\n### Files\n\n| File | Status | Detail |\n|---|---|---|\n")
+		for i, r := range rows {
+			if i == markdownFileRows {
+				fmt.Fprintf(&b, "| ... and %s more | | |\n", photonsr.FormatCount(len(rows)-i))
+				break
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(markdownCode(r.path)), r.status, markdownCell(r.detail))
+		}
+	}
+
+	shown := 0
+	for _, path := range report.ModifiedFiles {
+		lines := diffs[path]
+		if len(lines) == 0 {
+			continue
+		}
+		if shown == 0 {
+			b.WriteString("\n### Sample diffs\n")
+		}
+		if shown == markdownDiffFiles {
+			fmt.Fprintf(&b, "\nDiffs of the other modified files are not shown.\n")
+			break
+		}
+		shown++
+		body := strings.Join(lines, "\n")
+		fence := markdownFence(body)
+		fmt.Fprintf(&b, "\n<details>\n<summary><code>%s</code></summary>\n\n%sdiff\n%s\n%s\n\n</details>\n",
+			htmlEscape(rel(path)), fence, body, fence)
+	}
+
+	if len(report.Messages) > 0 && verb != "modified" {
+		b.WriteString("\n### Details\n\n```\n")
+		for _, msg := range report.Messages {
+			b.WriteString(strings.ReplaceAll(msg, "```", "` ` `") + "\n")
+		}
+		b.WriteString("```\n")
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("writing Markdown report: %w", err)
+	}
+	return nil
+}
+
+// markdownCode returns s as an inline code span, delimited by more backticks than
+// s contains in a row.
+func markdownCode(s string) string {
+	s = strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+	ticks := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return ticks + " " + s + " " + ticks
+	}
+	return ticks + s + ticks
+}
+
+// markdownCell returns s on one line, with the characters that end a table cell
+// escaped.
+func markdownCell(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ", "|", `\|`).Replace(s)
+}
+
+// markdownFence returns a code fence longer than any run of backticks in body.
+func markdownFence(body string) string {
+	return strings.Repeat("`", max(3, longestRun(body, '`')+1))
+}
+
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// htmlEscape escapes s for use in HTML text.
+func htmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
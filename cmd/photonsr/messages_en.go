@@ -0,0 +1,249 @@
+package main
+
+// messagesEN is the English message catalog; every key used with tr must exist here.
+var messagesEN = map[string]string{
+	"common.yes": "Yes",
+	"common.no":  "No",
+
+	// CLI progress and summaries.
+	"cli.progress.replace":          "Performing text replacement...",
+	"cli.progress.restore":          "Restoring from backup files...",
+	"cli.progress.clean":            "Cleaning backup files...",
+	"cli.progress.prune":            "Pruning backup files...",
+	"cli.progress.rename":           "Renaming files...",
+	"cli.progress.move":             "Moving files...",
+	"cli.progress.dupes":            "Looking for duplicate files...",
+	"cli.progress.tidy":             "Looking for empty files and directories...",
+	"cli.progress.tidy_undo":        "Recreating removed files and directories...",
+	"cli.progress.scan":             "Scanning file contents...",
+	"cli.progress.backup_check":     "Checking backups against their files...",
+	"cli.progress.backup_diff":      "Comparing backups with their files...",
+	"cli.progress.inventory":        "Counting matched strings...",
+	"cli.no_operation":              "No operation specified. Use -wizard for interactive mode, or provide operation flags (e.g., -old, -restore, -clean, -version).",
+	"cli.unknown_args":              "Error: Unknown arguments provided. Use flags to specify operations.",
+	"cli.interrupted":               "Interrupt received: finishing the files in progress and writing the report (press Ctrl+C again to abort immediately)...",
+	"cli.completed_with_errors":     "\nOperation completed with errors: %v\n",
+	"cli.partial_success.modified":  "However, %d file(s) were successfully modified before the error occurred.\n",
+	"cli.partial_success.restored":  "However, %d file(s) were successfully restored before the error occurred.\n",
+	"cli.partial_success.cleaned":   "However, %d file(s) were successfully cleaned before the error occurred.\n",
+	"cli.partial_success.pruned":    "However, %d file(s) were successfully pruned before the error occurred.\n",
+	"cli.partial_success.renamed":   "However, %d file(s) were successfully renamed before the error occurred.\n",
+	"cli.partial_success.moved":     "However, %d file(s) were successfully moved before the error occurred.\n",
+	"cli.partial_success.deduped":   "However, %d duplicate(s) were replaced by links before the error occurred.\n",
+	"cli.partial_success.tidied":    "However, %d empty file(s) and director(ies) were removed before the error occurred.\n",
+	"cli.partial_success.undone":    "However, %d file(s) and director(ies) were recreated before the error occurred.\n",
+	"cli.partial_success.reported":  "However, %d file(s) containing the text were found before the error occurred.\n",
+	"cli.partial_success.previewed": "However, %d file(s) would have been modified before the error occurred.\n",
+	"cli.partial_success.compared":  "However, %d backup(s) were found to differ from their file before the error occurred.\n",
+	"cli.partial_success.checked":   "However, %d orphaned or redundant backup(s) were found before the error occurred.\n",
+	"cli.partial_success.counted":   "However, %d distinct string(s) were counted before the error occurred.\n",
+	"cli.success.modified":          "\nSuccessfully modified %d file(s).\n",
+	"cli.success.restored":          "\nSuccessfully restored %d file(s).\n",
+	"cli.success.cleaned":           "\nSuccessfully cleaned %d file(s).\n",
+	"cli.success.pruned":            "\nSuccessfully pruned %d file(s).\n",
+	"cli.success.renamed":           "\nSuccessfully renamed %d file(s).\n",
+	"cli.success.moved":             "\nSuccessfully moved %d file(s).\n",
+	"cli.success.deduped":           "\nSuccessfully replaced %d duplicate(s) by links.\n",
+	"cli.success.tidied":            "\nSuccessfully removed %d empty file(s) and director(ies).\n",
+	"cli.success.undone":            "\nSuccessfully recreated %d file(s) and director(ies).\n",
+	"cli.success.reported":          "\nScan complete: %d file(s) contain the text.\n",
+	"cli.success.previewed":         "\nDry run: %d file(s) would be modified. Nothing was written.\n",
+	"cli.success.compared":          "\nBackup diff: restoring would change %d file(s).\n",
+	"cli.success.checked":           "\nBackup check: %d orphaned or redundant backup(s) found.\n",
+	"cli.success.counted":           "\nInventory complete: %d distinct string(s) found.\n",
+	"cli.no_redundant":              "\nNo orphaned or redundant backups found.\n",
+	"cli.no_differences":            "\nEvery backup is identical to its file; restoring would change nothing.\n",
+	"cli.no_changes":                "\nOperation completed. No files required changes.",
+	"cli.no_backups.restored":       "\nNo .bak files found to restore.\n",
+	"cli.no_backups.cleaned":        "\nNo .bak files found to clean.\n",
+	"cli.no_backups.checked":        "\nNo .bak files found to check.\n",
+	"cli.no_backups.compared":       "\nNo .bak files found to compare.\n",
+	"cli.completed":                 "\nOperation completed.",
+	"cli.completed_successfully":    "\nOperation completed successfully.",
+	"cli.old_not_found":             "Old text not found in any matching files, or files were already up-to-date.",
+	"cli.did_you_mean":              "Did you mean %q instead of %q? It occurs %d time(s).",
+	"cli.up_to_date":                "All matching files are already up to date.",
+	"cli.no_files_found":            "No files found matching the pattern in the specified directory.",
+	"cli.modified_header":           "Successfully modified files:",
+	"cli.dry_run_header":            "Files that would be modified (dry run):",
+
+	// TUI menus.
+	"menu.title":              "What would you like to do?",
+	"action.replace":          "Replace Text in Files",
+	"action.replace.desc":     "Search and replace text recursively.",
+	"action.restore":          "Restore Files from .bak",
+	"action.restore.desc":     "Restore original files from .bak backups.",
+	"action.clean":            "Clean .bak Backup Files",
+	"action.clean.desc":       "Delete all .bak backup files.",
+	"action.exit":             "Exit",
+	"action.exit.desc":        "Exit the application.",
+	"ignore_case.title":       "Match the old text regardless of case?",
+	"ignore_case.yes.desc":    "Also replace it in other cases (\"Foo\", \"FOO\"); the new text is inserted as typed.",
+	"ignore_case.no.desc":     "Only replace the text exactly as typed.",
+	"backup.title":            "Create .bak backups before replacing text?",
+	"backup.yes.desc":         "Create .bak files (recommended).",
+	"backup.no.desc":          "Do not create backups (use with caution).",
+	"conflict.title":          "What should happen to existing backups?",
+	"conflict.count":          "%d matching file(s) already have a .bak backup.",
+	"conflict.overwrite":      "Overwrite existing backups",
+	"conflict.overwrite.desc": "Replace the existing .bak files with fresh backups.",
+	"conflict.skip":           "Keep existing backups",
+	"conflict.skip.desc":      "Keep the existing .bak files; no new backup for those files.",
+	"conflict.version":        "Keep both (versioned)",
+	"conflict.version.desc":   "Archive existing backups as .bak.N, then create fresh ones.",
+
+	"recover.title":         "How should the interrupted run be handled?",
+	"recover.found":         "An interrupted %s run (started %s, %d file(s) being rewritten) was found in this directory.",
+	"recover.rollback":      "Roll back",
+	"recover.rollback.desc": "Restore every file the run touched to its original content.",
+	"recover.discard":       "Clean up",
+	"recover.discard.desc":  "Keep the files as they are and delete the run's leftovers.",
+	"recover.ignore":        "Leave for now",
+	"recover.ignore.desc":   "Do nothing; you will be asked again next time.",
+
+	// TUI validation and errors.
+	"err.dir_missing":      "Directory '%s' does not exist.",
+	"err.dir_access":       "Error accessing directory '%s': %v",
+	"err.not_dir":          "Path '%s' is not a directory.",
+	"err.bad_pattern":      "Invalid file pattern syntax: %v",
+	"err.old_empty":        "Text to replace cannot be empty for 'Replace' action.",
+	"err.conflict_check":   "Checking for existing backups failed: %v",
+	"err.operation_failed": "Operation failed: %v",
+	"err.recover_check":    "Checking for interrupted runs failed: %v",
+	"err.recover_failed":   "Recovering the interrupted run failed: %v",
+	"err.bad_size":         "Invalid size: %v",
+	"err.bad_exclude":      "Invalid exclude pattern: %v",
+	"err.save_exclusions":  "Cannot save the exclusions: %v",
+	"err.prefix":           "Error: ",
+
+	// TUI results.
+	"result.modified":              "Successfully modified %d file(s).",
+	"result.dry_run":               "Dry run: %d file(s) would be modified. Nothing was written.",
+	"result.backup_diff":           "Backup diff: restoring would change %d file(s).",
+	"result.backup_check":          "Backup check: %d orphaned and %d redundant backup(s).",
+	"result.backup_diff_none":      "No backup differs from its file; restoring would change nothing.",
+	"result.would_modify":          "  - Would modify: %s (%d replacement(s))",
+	"result.old_not_found":         "Old text not found in any matching files, or files were already up-to-date.",
+	"result.did_you_mean":          "Did you mean %q instead of %q? It occurs %d time(s).",
+	"result.no_files":              "No files found matching the pattern in the specified directory.",
+	"result.restored":              "Successfully restored %d file(s).",
+	"result.no_restore":            "No .bak files found to restore.",
+	"result.cleaned":               "Successfully cleaned %d backup file(s).",
+	"result.no_clean":              "No .bak files found to clean.",
+	"result.skipped_header":        "Skipped (changed after backup; enable force overwrite to restore):",
+	"result.skipped_limits_header": "Skipped (size limit or binary file):",
+	"result.conflicts_header":      "Existing backups:",
+	"result.warnings_header":       "Warnings:",
+	"result.interrupted":           "Stopped: %v.",
+	"result.fallback":              "Operation completed. No specific actions to report.",
+	"result.header":                "Operation Complete:",
+	"result.none":                  "The operation finished, but no specific result messages were generated.",
+
+	// TUI screens.
+	"view.goodbye":            "Exiting PhotonSR. Goodbye!\n",
+	"view.processing":         "Processing... please wait.",
+	"view.stopping":           "Stopping... files being rewritten are completed first.",
+	"view.stop_hint":          "(Press Esc or Ctrl+C to stop)",
+	"prompt.dir":              "Enter target directory (default: current directory '.'):",
+	"prompt.pattern":          "Enter file pattern (e.g., *.txt, default *):",
+	"prompt.old":              "Enter text to replace:",
+	"prompt.new":              "Enter new text (leave empty to delete old text):",
+	"hint.confirm_input":      "(Press Enter to confirm, Esc to go back)",
+	"hint.dir_input":          "(Press Enter to confirm, ↑/↓ for suggestions, Tab to browse, Esc to go back)",
+	"hint.picker":             "(↑/↓ PgUp/PgDn move, → open, ← parent, Enter select, Esc cancel)",
+	"hint.scroll":             "(↑/↓ PgUp/PgDn to scroll, Enter to return to the main menu)",
+	"hint.dry_run":            "(↑/↓ PgUp/PgDn to scroll, Enter to apply the replacement, Esc to return to the summary)",
+	"hint.backup_check":       "(↑/↓ PgUp/PgDn to scroll, Enter to delete the %d redundant backup(s), Esc to return to the summary)",
+	"hint.backup_check_none":  "(↑/↓ PgUp/PgDn to scroll, Enter or Esc to return to the summary)",
+	"hint.backup_diff":        "(↑/↓ PgUp/PgDn to scroll, Enter to go on with the operation, Esc to return to the summary)",
+	"quickpick.title":         "Recent and suggested directories (↑/↓ to pick):",
+	"quickpick.recent":        "recently used",
+	"quickpick.cwd":           "current directory",
+	"quickpick.project_root":  "project root, found %s",
+	"quickpick.git_root":      "git root",
+	"suggest.title":           "Common extensions (↑/↓ to pick):",
+	"hint.pattern_input":      "(Press Enter to confirm, ↑/↓ for suggestions, Esc to go back)",
+	"picker.title":            "Browse for target directory:",
+	"picker.loading":          "Loading... %d folders (%d entries read)",
+	"picker.error":            "Could not list directory: %v",
+	"picker.empty":            "(no subdirectories)",
+	"picker.position":         "%d of %d",
+	"page.position":           "Lines %d-%d of %d",
+	"preview.title":           "Preview",
+	"preview.loading":         "Looking for matching files...",
+	"preview.updating":        "(updating...)",
+	"preview.count":           "%d matching file(s), %d scanned",
+	"preview.truncated":       "(listing stopped early)",
+	"preview.error":           "Cannot preview: %v",
+	"preview.read_error":      "Cannot read the file: %v",
+	"preview.more":            "...",
+	"hint.preview":            "(Ctrl+N/Ctrl+P: show another file)",
+	"review.title":            "Review renames (checked against %s naming rules)",
+	"review.unchanged":        "(unchanged)",
+	"review.problems":         "%d included rename(s) cannot be done; exclude them or press Esc.",
+	"review.blocked":          "Cannot rename yet: %d included rename(s) have problems.",
+	"review.ready":            "%d of %d file(s) included.",
+	"hint.review":             "(↑/↓ PgUp/PgDn move, Space exclude/include, Enter rename, Esc cancel)",
+	"hint.proceed":            "Press Enter to proceed, Esc to go back.",
+	"hint.menu":               "(Press Enter to return to the main menu)",
+	"hint.menu_or_back":       "(Press Enter to return to the main menu or Esc to go back)",
+	"confirm.title":           "Confirm Operation Summary:",
+	"confirm.action":          "  Action: %s\n",
+	"confirm.dir":             "  Directory: %s\n",
+	"confirm.pattern":         "  Pattern: %s\n",
+	"confirm.old":             "  Old Text: '%s'\n",
+	"confirm.new":             "  New Text: '%s'\n",
+	"confirm.ignore_case":     "  Ignore Case: %s\n",
+	"confirm.backup":          "  Create Backups: %s\n",
+	"confirm.existing":        "  Existing Backups: %d (%s)\n",
+	"confirm.force_restore":   "  Force Overwrite Newer Files: %s (press f to toggle)\n",
+	"scan.title":              "Scope:",
+	"scan.loading":            "Scanning the target directory...",
+	"scan.error":              "Scan failed: %v",
+	"scan.files":              "Matching files: %s (%s)",
+	"scan.largest":            "Largest: %s",
+	"scan.extensions":         "By extension: %s",
+	"scan.no_ext":             "(none)",
+	"scan.other_ext":          "others %s",
+	"confirm.advanced":        "  Advanced Options: %s (press a to change)\n",
+	"confirm.heatmap":         "  Matches by Directory: press t to show or narrow the directory\n",
+	"confirm.dry_run":         "  Preview First: press p for a dry run listing the files that would change\n",
+	"confirm.backup_check":    "  Redundant Only: press r to list orphaned and redundant backups, then delete only the redundant ones\n",
+	"confirm.backup_diff":     "  Compare First: press d to see how each backup differs from its file\n",
+	"heatmap.title":           "Matches by directory in %s",
+	"heatmap.loading":         "Counting matches...",
+	"heatmap.empty":           "No file matches.",
+	"heatmap.count":           "%d match(es) in %d file(s)",
+	"heatmap.count_skipped":   "%d of %d match(es) included, in %d file(s)",
+	"heatmap.skipped":         "(skipped)",
+	"confirm.skipped":         "  Skipped: %s (press s to skip them in later runs too)\n",
+	"heatmap.saved":           "Saved %d new exclusion(s) for this replacement in %s.",
+	"heatmap.row":             "%d in %d file(s)",
+	"heatmap.narrowed":        "Narrowed to %s.",
+	"hint.heatmap":            "(↑/↓ move, →/← expand/collapse, Space skip/include, Enter narrow to the directory, Esc to go back)",
+	"advanced.title":          "Advanced Options:",
+	"advanced.regex":          "Old text is a regex",
+	"advanced.jobs":           "Parallel jobs",
+	"advanced.max_size":       "Skip files larger than",
+	"advanced.exclude":        "Exclude files named",
+	"advanced.skip_binary":    "Skip binary files",
+	"advanced.order":          "Processing order",
+	"advanced.no_limit":       "no limit",
+	"advanced.none":           "none",
+	"advanced.defaults":       "defaults",
+	"hint.advanced":           "(↑/↓ select, ←/→ or Space change, Enter to edit the text or finish, Esc to go back)",
+	"action.tutorial":         "Tutorial",
+	"action.tutorial.desc":    "Learn PhotonSR on a sandbox of sample files",
+	"tutorial.title":          "Tutorial",
+	"tutorial.intro":          "This tutorial works on sample files in a temporary directory, so none of your files are touched:\n  %s\n\nYou will replace the British spelling \"colour\" with \"color\", look at what will change, keep backups, and then undo the change.\n",
+	"tutorial.hint.pattern":   "Step 1 of 6: the file pattern selects files by name. \"*\" means every file; \"*.txt\" would only take text files.",
+	"tutorial.hint.old":       "Step 2 of 6: the text to find. It is matched exactly, including upper and lower case.",
+	"tutorial.hint.new":       "Step 3 of 6: the text to put in its place.",
+	"tutorial.hint.backup":    "Step 4 of 6: choose Yes. Each changed file then gets a .bak copy, which makes the change undoable.",
+	"tutorial.hint.confirm":   "Step 5 of 6: review before anything changes. The scope lists the matching files; on a wide terminal the preview beside it shows every line that will change (Ctrl+N/Ctrl+P to switch files). Press Enter to run.",
+	"tutorial.hint.undo":      "Step 6 of 6: the files were changed. Press u to undo it by restoring them from their .bak backups.",
+	"tutorial.hint.no_backup": "Without backups there is nothing to restore from. In real use, keep backups for anything you could not fix by hand. Press Enter to finish.",
+	"tutorial.hint.done":      "Every file is back to its original text. That is the whole cycle: preview, replace with backups, undo. Press Enter to finish; the sample files are deleted.",
+	"hint.tutorial_start":     "Press Enter to start, Esc to go back.",
+	"err.tutorial_setup":      "Could not create the tutorial files: %v",
+}
@@ -0,0 +1,249 @@
+package main
+
+// messagesID is the Indonesian (Bahasa Indonesia) message catalog.
+var messagesID = map[string]string{
+	"common.yes": "Ya",
+	"common.no":  "Tidak",
+
+	// CLI progress and summaries.
+	"cli.progress.replace":          "Mengganti teks...",
+	"cli.progress.restore":          "Memulihkan dari file cadangan...",
+	"cli.progress.clean":            "Membersihkan file cadangan...",
+	"cli.progress.prune":            "Merapikan file cadangan...",
+	"cli.progress.rename":           "Mengganti nama file...",
+	"cli.progress.move":             "Memindahkan file...",
+	"cli.progress.dupes":            "Mencari file duplikat...",
+	"cli.progress.tidy":             "Mencari file dan direktori kosong...",
+	"cli.progress.tidy_undo":        "Membuat ulang file dan direktori yang dihapus...",
+	"cli.progress.scan":             "Memindai isi file...",
+	"cli.progress.backup_check":     "Memeriksa cadangan terhadap file-nya...",
+	"cli.progress.backup_diff":      "Membandingkan cadangan dengan file-nya...",
+	"cli.progress.inventory":        "Menghitung string yang cocok...",
+	"cli.no_operation":              "Tidak ada operasi yang ditentukan. Gunakan -wizard untuk mode interaktif, atau berikan flag operasi (mis. -old, -restore, -clean, -version).",
+	"cli.unknown_args":              "Error: Argumen tidak dikenal. Gunakan flag untuk menentukan operasi.",
+	"cli.interrupted":               "Interupsi diterima: menyelesaikan file yang sedang diproses dan menulis laporan (tekan Ctrl+C lagi untuk berhenti seketika)...",
+	"cli.completed_with_errors":     "\nOperasi selesai dengan error: %v\n",
+	"cli.partial_success.modified":  "Namun, %d file berhasil diubah sebelum error terjadi.\n",
+	"cli.partial_success.restored":  "Namun, %d file berhasil dipulihkan sebelum error terjadi.\n",
+	"cli.partial_success.cleaned":   "Namun, %d file berhasil dibersihkan sebelum error terjadi.\n",
+	"cli.partial_success.pruned":    "Namun, %d file berhasil dirapikan sebelum error terjadi.\n",
+	"cli.partial_success.renamed":   "Namun, %d file berhasil diganti namanya sebelum error terjadi.\n",
+	"cli.partial_success.moved":     "Namun, %d file berhasil dipindahkan sebelum error terjadi.\n",
+	"cli.partial_success.deduped":   "Namun, %d duplikat berhasil diganti dengan tautan sebelum error terjadi.\n",
+	"cli.partial_success.tidied":    "Namun, %d file dan direktori kosong berhasil dihapus sebelum error terjadi.\n",
+	"cli.partial_success.undone":    "Namun, %d file dan direktori berhasil dibuat ulang sebelum error terjadi.\n",
+	"cli.partial_success.reported":  "Namun, %d file yang berisi teks tersebut ditemukan sebelum error terjadi.\n",
+	"cli.partial_success.previewed": "Namun, %d file akan diubah sebelum error terjadi.\n",
+	"cli.partial_success.compared":  "Namun, %d cadangan ditemukan berbeda dari file-nya sebelum error terjadi.\n",
+	"cli.partial_success.checked":   "Namun, %d cadangan yatim atau berlebih ditemukan sebelum error terjadi.\n",
+	"cli.partial_success.counted":   "Namun, %d string berbeda dihitung sebelum error terjadi.\n",
+	"cli.success.modified":          "\nBerhasil mengubah %d file.\n",
+	"cli.success.restored":          "\nBerhasil memulihkan %d file.\n",
+	"cli.success.cleaned":           "\nBerhasil membersihkan %d file.\n",
+	"cli.success.pruned":            "\nBerhasil merapikan %d file.\n",
+	"cli.success.renamed":           "\nBerhasil mengganti nama %d file.\n",
+	"cli.success.moved":             "\nBerhasil memindahkan %d file.\n",
+	"cli.success.deduped":           "\nBerhasil mengganti %d duplikat dengan tautan.\n",
+	"cli.success.tidied":            "\nBerhasil menghapus %d file dan direktori kosong.\n",
+	"cli.success.undone":            "\nBerhasil membuat ulang %d file dan direktori.\n",
+	"cli.success.reported":          "\nPemindaian selesai: %d file berisi teks tersebut.\n",
+	"cli.success.previewed":         "\nUji coba: %d file akan diubah. Tidak ada yang ditulis.\n",
+	"cli.success.compared":          "\nPerbandingan cadangan: pemulihan akan mengubah %d file.\n",
+	"cli.success.checked":           "\nPemeriksaan cadangan: %d cadangan yatim atau berlebih ditemukan.\n",
+	"cli.success.counted":           "\nInventaris selesai: %d string berbeda ditemukan.\n",
+	"cli.no_redundant":              "\nTidak ada cadangan yatim atau berlebih.\n",
+	"cli.no_differences":            "\nSemua cadangan identik dengan file-nya; pemulihan tidak akan mengubah apa pun.\n",
+	"cli.no_changes":                "\nOperasi selesai. Tidak ada file yang perlu diubah.",
+	"cli.no_backups.restored":       "\nTidak ada file .bak untuk dipulihkan.\n",
+	"cli.no_backups.cleaned":        "\nTidak ada file .bak untuk dibersihkan.\n",
+	"cli.no_backups.checked":        "\nTidak ada file .bak untuk diperiksa.\n",
+	"cli.no_backups.compared":       "\nTidak ada file .bak untuk dibandingkan.\n",
+	"cli.completed":                 "\nOperasi selesai.",
+	"cli.completed_successfully":    "\nOperasi berhasil diselesaikan.",
+	"cli.old_not_found":             "Teks lama tidak ditemukan di file yang cocok, atau file sudah diperbarui.",
+	"cli.did_you_mean":              "Mungkin maksud Anda %q, bukan %q? Teks itu muncul %d kali.",
+	"cli.up_to_date":                "Semua file yang cocok sudah mutakhir.",
+	"cli.no_files_found":            "Tidak ada file yang cocok dengan pola di direktori yang ditentukan.",
+	"cli.modified_header":           "File yang berhasil diubah:",
+	"cli.dry_run_header":            "File yang akan diubah (uji coba):",
+
+	// TUI menus.
+	"menu.title":              "Apa yang ingin Anda lakukan?",
+	"action.replace":          "Ganti Teks di File",
+	"action.replace.desc":     "Cari dan ganti teks secara rekursif.",
+	"action.restore":          "Pulihkan File dari .bak",
+	"action.restore.desc":     "Pulihkan file asli dari cadangan .bak.",
+	"action.clean":            "Bersihkan File Cadangan .bak",
+	"action.clean.desc":       "Hapus semua file cadangan .bak.",
+	"action.exit":             "Keluar",
+	"action.exit.desc":        "Keluar dari aplikasi.",
+	"ignore_case.title":       "Cocokkan teks lama tanpa membedakan huruf besar/kecil?",
+	"ignore_case.yes.desc":    "Ganti juga dalam huruf lain (\"Foo\", \"FOO\"); teks baru disisipkan apa adanya.",
+	"ignore_case.no.desc":     "Hanya ganti teks yang persis seperti diketik.",
+	"backup.title":            "Buat cadangan .bak sebelum mengganti teks?",
+	"backup.yes.desc":         "Buat file .bak (disarankan).",
+	"backup.no.desc":          "Jangan buat cadangan (hati-hati).",
+	"conflict.title":          "Apa yang harus dilakukan dengan cadangan yang sudah ada?",
+	"conflict.count":          "%d file yang cocok sudah memiliki cadangan .bak.",
+	"conflict.overwrite":      "Timpa cadangan yang ada",
+	"conflict.overwrite.desc": "Ganti file .bak yang ada dengan cadangan baru.",
+	"conflict.skip":           "Pertahankan cadangan yang ada",
+	"conflict.skip.desc":      "Pertahankan file .bak yang ada; tidak ada cadangan baru untuk file tersebut.",
+	"conflict.version":        "Simpan keduanya (berversi)",
+	"conflict.version.desc":   "Arsipkan cadangan lama sebagai .bak.N, lalu buat cadangan baru.",
+
+	"recover.title":         "Bagaimana run yang terputus harus ditangani?",
+	"recover.found":         "Ditemukan run %s yang terputus (dimulai %s, %d file sedang ditulis ulang) di direktori ini.",
+	"recover.rollback":      "Kembalikan",
+	"recover.rollback.desc": "Kembalikan setiap file yang disentuh run ke isi aslinya.",
+	"recover.discard":       "Bersihkan",
+	"recover.discard.desc":  "Biarkan file apa adanya dan hapus sisa-sisa run.",
+	"recover.ignore":        "Biarkan dulu",
+	"recover.ignore.desc":   "Jangan lakukan apa pun; Anda akan ditanya lagi lain kali.",
+
+	// TUI validation and errors.
+	"err.dir_missing":      "Direktori '%s' tidak ada.",
+	"err.dir_access":       "Gagal mengakses direktori '%s': %v",
+	"err.not_dir":          "Path '%s' bukan direktori.",
+	"err.bad_pattern":      "Sintaks pola file tidak valid: %v",
+	"err.old_empty":        "Teks yang diganti tidak boleh kosong untuk aksi 'Ganti'.",
+	"err.conflict_check":   "Gagal memeriksa cadangan yang ada: %v",
+	"err.operation_failed": "Operasi gagal: %v",
+	"err.recover_check":    "Pemeriksaan run yang terputus gagal: %v",
+	"err.recover_failed":   "Pemulihan run yang terputus gagal: %v",
+	"err.bad_size":         "Ukuran tidak valid: %v",
+	"err.bad_exclude":      "Pola pengecualian tidak valid: %v",
+	"err.save_exclusions":  "Tidak dapat menyimpan pengecualian: %v",
+	"err.prefix":           "Error: ",
+
+	// TUI results.
+	"result.modified":              "Berhasil mengubah %d file.",
+	"result.dry_run":               "Uji coba: %d file akan diubah. Tidak ada yang ditulis.",
+	"result.backup_diff":           "Perbandingan cadangan: pemulihan akan mengubah %d file.",
+	"result.backup_check":          "Pemeriksaan cadangan: %d cadangan yatim dan %d cadangan berlebih.",
+	"result.backup_diff_none":      "Tidak ada cadangan yang berbeda dari file-nya; pemulihan tidak akan mengubah apa pun.",
+	"result.would_modify":          "  - Akan diubah: %s (%d penggantian)",
+	"result.old_not_found":         "Teks lama tidak ditemukan di file yang cocok, atau file sudah diperbarui.",
+	"result.did_you_mean":          "Mungkin maksud Anda %q, bukan %q? Teks itu muncul %d kali.",
+	"result.no_files":              "Tidak ada file yang cocok dengan pola di direktori yang ditentukan.",
+	"result.restored":              "Berhasil memulihkan %d file.",
+	"result.no_restore":            "Tidak ada file .bak untuk dipulihkan.",
+	"result.cleaned":               "Berhasil membersihkan %d file cadangan.",
+	"result.no_clean":              "Tidak ada file .bak untuk dibersihkan.",
+	"result.skipped_header":        "Dilewati (berubah setelah dicadangkan; aktifkan timpa paksa untuk memulihkan):",
+	"result.skipped_limits_header": "Dilewati (batas ukuran atau file biner):",
+	"result.conflicts_header":      "Cadangan yang sudah ada:",
+	"result.warnings_header":       "Peringatan:",
+	"result.interrupted":           "Dihentikan: %v.",
+	"result.fallback":              "Operasi selesai. Tidak ada tindakan khusus untuk dilaporkan.",
+	"result.header":                "Operasi Selesai:",
+	"result.none":                  "Operasi selesai, tetapi tidak ada pesan hasil.",
+
+	// TUI screens.
+	"view.goodbye":            "Keluar dari PhotonSR. Sampai jumpa!\n",
+	"view.processing":         "Memproses... harap tunggu.",
+	"view.stopping":           "Menghentikan... file yang sedang ditulis diselesaikan terlebih dahulu.",
+	"view.stop_hint":          "(Tekan Esc atau Ctrl+C untuk berhenti)",
+	"prompt.dir":              "Masukkan direktori target (bawaan: direktori saat ini '.'):",
+	"prompt.pattern":          "Masukkan pola file (mis. *.txt, bawaan *):",
+	"prompt.old":              "Masukkan teks yang akan diganti:",
+	"prompt.new":              "Masukkan teks baru (kosongkan untuk menghapus teks lama):",
+	"hint.confirm_input":      "(Tekan Enter untuk konfirmasi, Esc untuk kembali)",
+	"hint.dir_input":          "(Tekan Enter untuk konfirmasi, ↑/↓ untuk saran, Tab untuk menjelajah, Esc untuk kembali)",
+	"hint.picker":             "(↑/↓ PgUp/PgDn pindah, → buka, ← induk, Enter pilih, Esc batal)",
+	"hint.scroll":             "(↑/↓ PgUp/PgDn untuk menggulir, Enter untuk kembali ke menu utama)",
+	"hint.dry_run":            "(↑/↓ PgUp/PgDn untuk menggulir, Enter untuk menerapkan penggantian, Esc untuk kembali ke ringkasan)",
+	"hint.backup_check":       "(↑/↓ PgUp/PgDn untuk menggulir, Enter untuk menghapus %d cadangan berlebih, Esc untuk kembali ke ringkasan)",
+	"hint.backup_check_none":  "(↑/↓ PgUp/PgDn untuk menggulir, Enter atau Esc untuk kembali ke ringkasan)",
+	"hint.backup_diff":        "(↑/↓ PgUp/PgDn untuk menggulir, Enter untuk melanjutkan operasi, Esc untuk kembali ke ringkasan)",
+	"quickpick.title":         "Direktori terbaru dan saran (↑/↓ untuk memilih):",
+	"quickpick.recent":        "baru dipakai",
+	"quickpick.cwd":           "direktori saat ini",
+	"quickpick.project_root":  "akar proyek, ditemukan %s",
+	"quickpick.git_root":      "akar git",
+	"suggest.title":           "Ekstensi umum (↑/↓ untuk memilih):",
+	"hint.pattern_input":      "(Tekan Enter untuk konfirmasi, ↑/↓ untuk saran, Esc untuk kembali)",
+	"picker.title":            "Jelajahi direktori target:",
+	"picker.loading":          "Memuat... %d folder (%d entri dibaca)",
+	"picker.error":            "Tidak dapat membaca direktori: %v",
+	"picker.empty":            "(tidak ada subdirektori)",
+	"picker.position":         "%d dari %d",
+	"page.position":           "Baris %d-%d dari %d",
+	"preview.title":           "Pratinjau",
+	"preview.loading":         "Mencari file yang cocok...",
+	"preview.updating":        "(memperbarui...)",
+	"preview.count":           "%d file cocok, %d diperiksa",
+	"preview.truncated":       "(daftar dihentikan lebih awal)",
+	"preview.error":           "Tidak dapat menampilkan pratinjau: %v",
+	"preview.read_error":      "Tidak dapat membaca file: %v",
+	"preview.more":            "...",
+	"hint.preview":            "(Ctrl+N/Ctrl+P: tampilkan file lain)",
+	"review.title":            "Tinjau penggantian nama (diperiksa dengan aturan nama %s)",
+	"review.unchanged":        "(tidak berubah)",
+	"review.problems":         "%d penggantian nama yang disertakan tidak dapat dilakukan; kecualikan atau tekan Esc.",
+	"review.blocked":          "Belum dapat mengganti nama: %d penggantian nama yang disertakan bermasalah.",
+	"review.ready":            "%d dari %d file disertakan.",
+	"hint.review":             "(↑/↓ PgUp/PgDn pindah, Spasi kecualikan/sertakan, Enter ganti nama, Esc batal)",
+	"hint.proceed":            "Tekan Enter untuk melanjutkan, Esc untuk kembali.",
+	"hint.menu":               "(Tekan Enter untuk kembali ke menu utama)",
+	"hint.menu_or_back":       "(Tekan Enter untuk kembali ke menu utama atau Esc untuk kembali)",
+	"confirm.title":           "Ringkasan Konfirmasi Operasi:",
+	"confirm.action":          "  Aksi: %s\n",
+	"confirm.dir":             "  Direktori: %s\n",
+	"confirm.pattern":         "  Pola: %s\n",
+	"confirm.old":             "  Teks Lama: '%s'\n",
+	"confirm.new":             "  Teks Baru: '%s'\n",
+	"confirm.ignore_case":     "  Abaikan Besar/Kecil Huruf: %s\n",
+	"confirm.backup":          "  Buat Cadangan: %s\n",
+	"confirm.existing":        "  Cadangan yang Ada: %d (%s)\n",
+	"confirm.force_restore":   "  Timpa Paksa File yang Lebih Baru: %s (tekan f untuk mengubah)\n",
+	"scan.title":              "Cakupan:",
+	"scan.loading":            "Memindai direktori target...",
+	"scan.error":              "Pemindaian gagal: %v",
+	"scan.files":              "File yang cocok: %s (%s)",
+	"scan.largest":            "Terbesar: %s",
+	"scan.extensions":         "Per ekstensi: %s",
+	"scan.no_ext":             "(tanpa)",
+	"scan.other_ext":          "lainnya %s",
+	"confirm.advanced":        "  Opsi Lanjutan: %s (tekan a untuk mengubah)\n",
+	"confirm.heatmap":         "  Kecocokan per Direktori: tekan t untuk melihat atau mempersempit direktori\n",
+	"confirm.dry_run":         "  Pratinjau Dulu: tekan p untuk uji coba yang menampilkan file yang akan berubah\n",
+	"confirm.backup_check":    "  Hanya yang Berlebih: tekan r untuk menampilkan cadangan yatim dan berlebih, lalu hapus hanya yang berlebih\n",
+	"confirm.backup_diff":     "  Bandingkan Dulu: tekan d untuk melihat perbedaan tiap cadangan dengan file-nya\n",
+	"heatmap.title":           "Kecocokan per direktori di %s",
+	"heatmap.loading":         "Menghitung kecocokan...",
+	"heatmap.empty":           "Tidak ada file yang cocok.",
+	"heatmap.count":           "%d kecocokan di %d file",
+	"heatmap.count_skipped":   "%d dari %d kecocokan disertakan, di %d file",
+	"heatmap.skipped":         "(dilewati)",
+	"confirm.skipped":         "  Dilewati: %s (tekan s untuk juga melewatinya di run berikutnya)\n",
+	"heatmap.saved":           "%d pengecualian baru untuk penggantian ini disimpan di %s.",
+	"heatmap.row":             "%d di %d file",
+	"heatmap.narrowed":        "Dipersempit ke %s.",
+	"hint.heatmap":            "(↑/↓ pindah, →/← buka/tutup, Spasi lewati/sertakan, Enter persempit ke direktori, Esc untuk kembali)",
+	"advanced.title":          "Opsi Lanjutan:",
+	"advanced.regex":          "Teks lama adalah regex",
+	"advanced.jobs":           "Job paralel",
+	"advanced.max_size":       "Lewati file lebih besar dari",
+	"advanced.exclude":        "Kecualikan file bernama",
+	"advanced.skip_binary":    "Lewati file biner",
+	"advanced.order":          "Urutan pemrosesan",
+	"advanced.no_limit":       "tanpa batas",
+	"advanced.none":           "tidak ada",
+	"advanced.defaults":       "bawaan",
+	"hint.advanced":           "(↑/↓ pilih, ←/→ atau Spasi ubah, Enter untuk mengubah teks atau selesai, Esc untuk kembali)",
+	"action.tutorial":         "Tutorial",
+	"action.tutorial.desc":    "Pelajari PhotonSR pada sandbox berisi berkas contoh",
+	"tutorial.title":          "Tutorial",
+	"tutorial.intro":          "Tutorial ini memakai berkas contoh di direktori sementara, jadi tidak ada berkas Anda yang tersentuh:\n  %s\n\nAnda akan mengganti ejaan Inggris \"colour\" dengan \"color\", melihat apa yang akan berubah, menyimpan cadangan, lalu membatalkan perubahan.\n",
+	"tutorial.hint.pattern":   "Langkah 1 dari 6: pola berkas memilih berkas berdasarkan nama. \"*\" berarti semua berkas; \"*.txt\" hanya mengambil berkas teks.",
+	"tutorial.hint.old":       "Langkah 2 dari 6: teks yang dicari. Dicocokkan persis, termasuk huruf besar dan kecil.",
+	"tutorial.hint.new":       "Langkah 3 dari 6: teks penggantinya.",
+	"tutorial.hint.backup":    "Langkah 4 dari 6: pilih Ya. Setiap berkas yang diubah lalu mendapat salinan .bak, sehingga perubahan dapat dibatalkan.",
+	"tutorial.hint.confirm":   "Langkah 5 dari 6: tinjau sebelum ada yang berubah. Cakupan menampilkan berkas yang cocok; pada terminal lebar, pratinjau di sampingnya menampilkan setiap baris yang akan berubah (Ctrl+N/Ctrl+P untuk berpindah berkas). Tekan Enter untuk menjalankan.",
+	"tutorial.hint.undo":      "Langkah 6 dari 6: berkas telah diubah. Tekan u untuk membatalkannya dengan memulihkan dari cadangan .bak.",
+	"tutorial.hint.no_backup": "Tanpa cadangan tidak ada yang bisa dipulihkan. Dalam pemakaian nyata, simpan cadangan untuk apa pun yang tidak bisa Anda perbaiki secara manual. Tekan Enter untuk selesai.",
+	"tutorial.hint.done":      "Semua berkas kembali ke teks aslinya. Itulah siklus lengkapnya: pratinjau, ganti dengan cadangan, batalkan. Tekan Enter untuk selesai; berkas contoh akan dihapus.",
+	"hint.tutorial_start":     "Tekan Enter untuk mulai, Esc untuk kembali.",
+	"err.tutorial_setup":      "Tidak dapat membuat berkas tutorial: %v",
+}
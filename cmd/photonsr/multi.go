@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Multi-Repository Batch ---
+
+// "photonsr multi -repos repos.txt -rules rules.yaml" applies one replacement to
+// many repositories, the "fix this string across 40 services" task. The repos file
+// lists one repository per line: a git URL, cloned into the target directory (-dir)
+// or, if already cloned there, updated with a fast-forward pull; or the path of a
+// local checkout, relative to the repos file, used as it is. Each repository is then
+// processed like a replacement with the same options, in turn, leaving its version
+// control directory alone and honoring its own saved exclusions (see
+// ApplySavedExclusions). A repository that cannot be cloned,
+// updated or processed is reported and the batch goes on with the next one.
+
+// multiMessages summarizes results, one line per repository.
+func multiMessages(results []photonsr.RepoResult) []string {
+	changed, failed := 0, 0
+	var lines []string
+	for _, r := range results {
+		var line string
+		switch {
+		case r.Error != "" && r.Scanned == 0:
+			line = fmt.Sprintf("  - %s: error: %s", r.Repo, r.Error)
+		case r.Modified > 0:
+			line = fmt.Sprintf("  - %s (%s): %s of %s file(s) modified", r.Repo, r.Source, photonsr.FormatCount(r.Modified), photonsr.FormatCount(r.Scanned))
+		default:
+			line = fmt.Sprintf("  - %s (%s): no change in %s file(s)", r.Repo, r.Source, photonsr.FormatCount(r.Scanned))
+		}
+		if r.Failed > 0 {
+			line += fmt.Sprintf(", %s failed", photonsr.FormatCount(r.Failed))
+		}
+		if r.Error != "" {
+			failed++
+		}
+		if r.Modified > 0 {
+			changed++
+		}
+		lines = append(lines, line)
+	}
+	header := fmt.Sprintf("Repositories: %d (%d changed, %d with errors):", len(results), changed, failed)
+	return append([]string{header}, lines...)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Co-occurrence Filter ---
+
+// -near 'TERM,within=N<unit>' limits the replacement of -old to the matches that
+// have TERM close by, e.g. -old timeout -near 'http:,within=3lines' changes only
+// the timeouts within three lines of an "http:". The unit is lines or chars; N
+// lines counts the line breaks between the match and TERM, N chars the
+// characters between them, so 0 lines is the same line. Without ",within=", TERM
+// must be on the same line. TERM is literal and follows -ignore-case. The last
+// ",within=" is the separator, so TERM may contain commas.
+
+// parseNear parses the value of -near.
+func parseNear(s string) (*photonsr.Near, error) {
+	term, within := s, ""
+	if i := strings.LastIndex(strings.ToLower(s), ",within="); i >= 0 {
+		term, within = s[:i], s[i+len(",within="):]
+	}
+	if term == "" {
+		return nil, fmt.Errorf("no term in %q (expected TERM or TERM,within=N<lines|chars>): %w", s, photonsr.ErrInvalidOption)
+	}
+	near := &photonsr.Near{Term: term, Lines: true}
+	if within == "" {
+		return near, nil
+	}
+	digits := strings.TrimLeft(within, "0123456789")
+	n, err := strconv.Atoi(within[:len(within)-len(digits)])
+	if err != nil {
+		return nil, fmt.Errorf("invalid distance %q (expected e.g. 3lines or 40chars): %w", within, photonsr.ErrInvalidOption)
+	}
+	near.Within = n
+	switch strings.ToLower(strings.TrimSpace(digits)) {
+	case "line", "lines":
+	case "char", "chars", "characters":
+		near.Lines = false
+	default:
+		return nil, fmt.Errorf("invalid unit in %q (expected lines or chars): %w", within, photonsr.ErrInvalidOption)
+	}
+	return near, nil
+}
+
+// formatNear returns near as -near accepts it.
+func formatNear(near *photonsr.Near) string {
+	unit := "chars"
+	if near.Lines {
+		unit = "lines"
+	}
+	return fmt.Sprintf("%s,within=%d%s", near.Term, near.Within, unit)
+}
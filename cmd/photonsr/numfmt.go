@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Number Format Conversion ---
+
+// With -preset number, -old and -new are sample numbers showing a number format,
+// e.g. "1.234,56" (German) and "1,234.56" (English), and the numbers written in the
+// old format are rewritten in the new one. Only numbers that use the old format's
+// grouping or decimal separator are converted, so integers, versions (1.2.3) and
+// addresses are left alone. -number-scope limits the conversion to the text
+// matched by a regular expression, e.g. 'price: \S+'.
+
+// PresetNumber converts numbers between locale formats.
+const PresetNumber = "number"
+
+// numberFormat is a number format: its digit grouping and decimal separators.
+type numberFormat struct {
+	group   string
+	decimal string
+}
+
+// sampleNumber is the shape of a -preset number sample: grouped digits and a
+// fraction, with one grouping and one decimal separator.
+var sampleNumber = regexp.MustCompile(`^\d{1,3}(\D+)\d{3}(?:\D+\d{3})*(\D+)\d+$`)
+
+// parseNumberFormat reads the format shown by a sample such as "1.234,56".
+func parseNumberFormat(sample string) (numberFormat, error) {
+	m := sampleNumber.FindStringSubmatch(sample)
+	if m == nil || m[1] == m[2] || strings.Count(sample, m[2]) != 1 {
+		return numberFormat{}, fmt.Errorf("'%s' is not a sample number with grouping and decimals, e.g. 1.234,56 or 1,234.56: %w", sample, photonsr.ErrInvalidOption)
+	}
+	for _, sep := range m[1:] {
+		r, size := utf8.DecodeRuneInString(sep)
+		if size != len(sep) || unicode.IsLetter(r) || r == '-' || r == '+' {
+			return numberFormat{}, fmt.Errorf("'%s' in sample '%s' is not a number separator: %w", sep, sample, photonsr.ErrInvalidOption)
+		}
+	}
+	return numberFormat{group: m[1], decimal: m[2]}, nil
+}
+
+// numberConversion rewrites numbers from one format to another. It implements
+// Transform.
+type numberConversion struct {
+	from, to numberFormat
+	number   *regexp.Regexp // Numbers in the from format.
+	scope    *regexp.Regexp // Text the conversion is limited to; nil for everything.
+
+	mu        sync.Mutex
+	converted int
+}
+
+// newNumberConversion validates -old, -new and -number-scope for -preset number.
+func newNumberConversion(oldSample, newSample, scope string) (*numberConversion, error) {
+	from, err := parseNumberFormat(oldSample)
+	if err != nil {
+		return nil, fmt.Errorf("-old: %w", err)
+	}
+	to, err := parseNumberFormat(newSample)
+	if err != nil {
+		return nil, fmt.Errorf("-new: %w", err)
+	}
+	if from == to {
+		return nil, fmt.Errorf("-old and -new show the same number format: %w", photonsr.ErrInvalidOption)
+	}
+	g, d := regexp.QuoteMeta(from.group), regexp.QuoteMeta(from.decimal)
+	c := &numberConversion{
+		from:   from,
+		to:     to,
+		number: regexp.MustCompile(`\d{1,3}(?:` + g + `\d{3})+(?:` + d + `\d+)?|\d+` + d + `\d+`),
+	}
+	if scope != "" {
+		if c.scope, err = regexp.Compile(scope); err != nil {
+			return nil, fmt.Errorf("-number-scope: %v: %w", err, photonsr.ErrInvalidOption)
+		}
+	}
+	return c, nil
+}
+
+// Applies reports true: numbers are converted in files of any type.
+func (c *numberConversion) Applies(name string) bool {
+	return true
+}
+
+// Apply converts the numbers of content, or of the parts matching the scope.
+func (c *numberConversion) Apply(path string, content []byte) ([]byte, error) {
+	text := string(content)
+	count := 0
+	if c.scope == nil {
+		text, count = c.convert(text)
+	} else {
+		text = c.scope.ReplaceAllStringFunc(text, func(s string) string {
+			s, n := c.convert(s)
+			count += n
+			return s
+		})
+	}
+	if count == 0 {
+		return content, nil
+	}
+	c.mu.Lock()
+	c.converted += count
+	c.mu.Unlock()
+	return []byte(text), nil
+}
+
+// convert rewrites the numbers of s that stand on their own: not part of a word, a
+// longer number or a dotted version.
+func (c *numberConversion) convert(s string) (string, int) {
+	var out strings.Builder
+	count, last := 0, 0
+	for _, m := range c.number.FindAllStringIndex(s, -1) {
+		if !c.standsAlone(s, m[0], m[1]) {
+			continue
+		}
+		out.WriteString(s[last:m[0]])
+		out.WriteString(c.reformat(s[m[0]:m[1]]))
+		last = m[1]
+		count++
+	}
+	if count == 0 {
+		return s, 0
+	}
+	out.WriteString(s[last:])
+	return out.String(), count
+}
+
+// standsAlone reports whether s[start:end] is delimited as a number on its own.
+func (c *numberConversion) standsAlone(s string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:start])
+	if start > 0 && (unicode.IsLetter(before) || unicode.IsDigit(before) || before == '_' ||
+		strings.HasSuffix(s[:start], c.from.group) || strings.HasSuffix(s[:start], c.from.decimal) || before == '.' || before == ',') {
+		return false
+	}
+	after, _ := utf8.DecodeRuneInString(s[end:])
+	if end < len(s) && (unicode.IsLetter(after) || unicode.IsDigit(after) || after == '_') {
+		return false
+	}
+	// A separator followed by a digit continues the token (1.234.5 or 1,5,6).
+	rest := s[end:]
+	for _, sep := range []string{c.from.group, c.from.decimal, ".", ","} {
+		if strings.HasPrefix(rest, sep) {
+			next, _ := utf8.DecodeRuneInString(rest[len(sep):])
+			if unicode.IsDigit(next) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reformat writes a number of the from format in the to format, keeping whether it
+// was grouped.
+func (c *numberConversion) reformat(n string) string {
+	integer, fraction, hasFraction := strings.Cut(n, c.from.decimal)
+	grouped := strings.Contains(integer, c.from.group)
+	integer = strings.ReplaceAll(integer, c.from.group, "")
+	if grouped {
+		var b strings.Builder
+		for i, d := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				b.WriteString(c.to.group)
+			}
+			b.WriteRune(d)
+		}
+		integer = b.String()
+	}
+	if hasFraction {
+		return integer + c.to.decimal + fraction
+	}
+	return integer
+}
+
+// summary describes the conversion for the result of the run.
+func (c *numberConversion) summary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("Converted %d number(s) from %q grouping and %q decimals to %q and %q.", c.converted, c.from.group, c.from.decimal, c.to.group, c.to.decimal)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Output Directory Mode ---
+
+// With -out the operation writes a transformed copy of -dir instead of editing it:
+// the tree is mirrored into the output directory, with the same relative paths,
+// permissions and modification times, and the operation then runs on the mirror.
+// The sources are never opened for writing and are checked to be unchanged
+// afterwards, as with -sandbox, but the copy is kept.
+//
+// Copying a large tree to change a few files in it would be wasteful, so -out-link
+// decides how files are placed in the mirror. Reflink clones share the data of the
+// source until either is written, and are always safe. Hard links share the file
+// itself: PhotonSR replaces the files it modifies with new ones, so the sources stay
+// intact, but a tool that later edits a linked file of the mirror in place edits the
+// source too.
+
+// Modes of -out-link.
+const (
+	OutLinkAuto    = "auto"    // Reflink clone, else hard link, else copy.
+	OutLinkReflink = "reflink" // Reflink clone, else copy.
+	OutLinkCopy    = "copy"    // Always copy.
+)
+
+// validOutLink reports whether mode is a known -out-link mode.
+func validOutLink(mode string) bool {
+	return mode == OutLinkAuto || mode == OutLinkReflink || mode == OutLinkCopy
+}
+
+// newOutputTree mirrors dir into out, placing files as link (an OutLink mode) says.
+// out must not exist or be an empty directory, and must be neither inside dir nor
+// one of its parents.
+func newOutputTree(dir, out, link string) (*sandbox, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving '%s': %w", dir, err)
+	}
+	abs, err := filepath.Abs(out)
+	if err != nil {
+		return nil, fmt.Errorf("resolving '%s': %w", out, err)
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil && filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("-out '%s' is inside -dir '%s': %w", out, dir, photonsr.ErrInvalidOption)
+	}
+	if rel, err := filepath.Rel(abs, dir); err == nil && filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("-dir '%s' is inside -out '%s': %w", dir, out, photonsr.ErrInvalidOption)
+	}
+	switch entries, err := os.ReadDir(abs); {
+	case os.IsNotExist(err):
+		if err := photonsr.FS.MkdirAll(abs, 0o755); err != nil {
+			return nil, fmt.Errorf("creating output directory: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("reading output directory: %w", err)
+	case len(entries) > 0:
+		return nil, fmt.Errorf("output directory '%s' is not empty: %w", out, photonsr.ErrInvalidOption)
+	}
+
+	before, err := treeStamps(dir)
+	if err != nil {
+		return nil, fmt.Errorf("scanning '%s': %w", dir, err)
+	}
+	sb := &sandbox{realDir: dir, dir: abs, before: before, output: true, link: link}
+	if err := sb.copyTree("output directory"); err != nil {
+		return nil, err
+	}
+	return sb, nil
+}
+
+// placeFile puts a copy of the regular file src at dst, with its modification
+// time, cloning or hard-linking it when sb.link allows and the file system can.
+func (sb *sandbox) placeFile(src, dst string, stamp fileStamp) error {
+	if sb.link == OutLinkAuto || sb.link == OutLinkReflink {
+		if photonsr.CloneFile(src, dst) == nil {
+			sb.cloned++
+			return photonsr.FS.Chtimes(dst, stamp.modTime, stamp.modTime)
+		}
+	}
+	if sb.link == OutLinkAuto && photonsr.FS.Link(src, dst) == nil {
+		sb.linked++ // Shares the modification time of src.
+		return nil
+	}
+	if err := photonsr.CopyFile(src, dst); err != nil {
+		return err
+	}
+	return photonsr.FS.Chtimes(dst, stamp.modTime, stamp.modTime)
+}
+
+// unshare replaces the file at path, if it exists, with a copy of its own that no
+// hard link shares, before something that writes files in place (such as the go
+// command) changes it.
+func unshare(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if err := photonsr.CopyFile(path, path); err != nil { // Written to a new file, then renamed.
+		return err
+	}
+	return photonsr.FS.Chtimes(path, info.ModTime(), info.ModTime())
+}
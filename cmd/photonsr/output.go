@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- CLI Output ---
+
+// Output formats accepted by -output.
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputNDJSON   = "ndjson"   // One JSON object per line: a record per file, then the summary.
+	outputPSObject = "psobject" // ndjson with CRLF line endings, for PowerShell's ConvertFrom-Json.
+	outputMarkdown = "markdown" // A summary for pull-request descriptions (see writeMarkdownReport).
+)
+
+// machineReadable reports whether format is one of the JSON output formats.
+func machineReadable(format string) bool {
+	return format == outputJSON || format == outputNDJSON || format == outputPSObject
+}
+
+// infoOut receives progress and informational CLI messages. It is switched to
+// stderr for machine-readable output formats so stdout carries only the report.
+var infoOut io.Writer = os.Stdout
+
+// promptOut receives interactive questions. It follows infoOut, except that -quiet
+// and -summary move questions to stderr instead of hiding them.
+var promptOut io.Writer = os.Stdout
+
+// summaryLine is the result of a run as printed by -summary, e.g.
+// "modified=12 scanned=340 errors=0 duration=2.3s". The first field is named after
+// the operation's verb; scanned, skipped and violations appear when they apply.
+func summaryLine(verb string, items, scanned, skipped, violations, errors int, duration time.Duration) string {
+	fields := []string{fmt.Sprintf("%s=%d", verb, items)}
+	if scanned > 0 || verb == "modified" || verb == "previewed" {
+		fields = append(fields, fmt.Sprintf("scanned=%d", scanned))
+	}
+	if skipped > 0 {
+		fields = append(fields, fmt.Sprintf("skipped=%d", skipped))
+	}
+	if verb == "verified" || verb == "linted" {
+		fields = append(fields, fmt.Sprintf("violations=%d", violations))
+	}
+	fields = append(fields, fmt.Sprintf("errors=%d", errors), fmt.Sprintf("duration=%.1fs", duration.Seconds()))
+	return strings.Join(fields, " ")
+}
+
+// operationNames maps a CLI action verb to the name of its operation.
+var operationNames = map[string]string{
+	"modified":  "replace",
+	"restored":  "restore",
+	"cleaned":   "clean",
+	"pruned":    "prune",
+	"verified":  "verify",
+	"linted":    "lint",
+	"renamed":   "rename-files",
+	"moved":     "move-files",
+	"deduped":   "dupes",
+	"tidied":    "tidy",
+	"undone":    "tidy-undo",
+	"reported":  "scan",
+	"previewed": "dry-run",
+	"compared":  "backup-diff",
+	"checked":   "backup-check",
+	"counted":   "inventory",
+}
+
+// operationVerb returns the action verb of operation, the inverse of operationNames.
+func operationVerb(operation string) string {
+	for verb, name := range operationNames {
+		if name == operation {
+			return verb
+		}
+	}
+	return ""
+}
+
+// runReport is the machine-readable summary of a CLI run (-output json).
+type runReport struct {
+	Operation     string   `json:"operation"`                // "replace", "restore", "clean", "prune", "verify", "lint", "rename-files", "move-files", "dupes", "tidy", "tidy-undo", "scan", "dry-run", "backup-diff", "backup-check" or "inventory".
+	Dir           string   `json:"dir"`                      // Target directory.
+	ItemsAffected int      `json:"items_affected"`           // Number of files modified, restored, cleaned, or pruned.
+	FilesScanned  int      `json:"files_scanned,omitempty"`  // For replace, verify and lint: files checked.
+	ModifiedFiles []string `json:"modified_files,omitempty"` // For replace: modified files, in -sort-by order.
+	Messages      []string `json:"messages,omitempty"`       // Human-readable detail messages.
+	Error         string   `json:"error,omitempty"`          // First error encountered, if any.
+	ErrorCode     string   `json:"error_code,omitempty"`     // Stable code of Error (see errorCode).
+	RunID         string   `json:"run_id"`                   // Run in the history (see "photonsr runs").
+	RetryRunID    string   `json:"retry_run_id,omitempty"`   // Run to pass to "photonsr retry" when files failed.
+	Sandbox       string   `json:"sandbox,omitempty"`        // With -sandbox: the temporary copy the operation ran on (deleted on exit).
+	Output        string   `json:"output,omitempty"`         // With -out: the directory holding the transformed copy.
+	ReadOnly      bool     `json:"read_only,omitempty"`      // With -read-only: nothing was written.
+
+	FileErrors   []failedFile          `json:"file_errors,omitempty"`   // For replace: files that could not be processed.
+	SkippedFiles []failedFile          `json:"skipped_files,omitempty"` // For replace: files left alone by -max-size or -skip-binary.
+	Violations   []photonsr.Violation  `json:"violations,omitempty"`    // For verify and lint: occurrences of old or forbidden text.
+	Repos        []photonsr.RepoResult `json:"repos,omitempty"`         // For multi: the outcome of each repository.
+
+	IntendedWrites []photonsr.IntendedWrite `json:"intended_writes,omitempty"` // With -read-only: the changes discarded, in order.
+}
+
+// writeJSONReport writes report to w as indented JSON.
+func writeJSONReport(w io.Writer, report runReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("encoding JSON report: %w", err)
+	}
+	return nil
+}
+
+// writeNDJSONReport writes report to w as newline-delimited JSON: one record per
+// modified file, file error, skipped file, violation and message, then the summary
+// (the report without its lists). Each record is an object whose "type" is
+// "modified", "file_error", "skipped", "violation", "message" or "summary". Line
+// breaks inside values are escaped by the encoder, so a record is always exactly
+// one line; crlf ends the lines with "\r\n" instead of "\n".
+func writeNDJSONReport(w io.Writer, report runReport, crlf bool) error {
+	write := newNDJSONWriter(w, crlf).write
+	for _, path := range report.ModifiedFiles {
+		if err := write("modified", struct {
+			Path string `json:"path"`
+		}{path}); err != nil {
+			return err
+		}
+	}
+	for _, f := range report.FileErrors {
+		if err := write("file_error", f); err != nil {
+			return err
+		}
+	}
+	for _, f := range report.SkippedFiles {
+		if err := write("skipped", f); err != nil {
+			return err
+		}
+	}
+	for _, v := range report.Violations {
+		if err := write("violation", v); err != nil {
+			return err
+		}
+	}
+	for _, msg := range report.Messages {
+		if err := write("message", struct {
+			Text string `json:"text"`
+		}{msg}); err != nil {
+			return err
+		}
+	}
+	summary := report
+	summary.ModifiedFiles, summary.Messages, summary.FileErrors, summary.SkippedFiles, summary.Violations = nil, nil, nil, nil, nil
+	return write("summary", summary)
+}
+
+// ndjsonWriter writes the records of the ndjson and psobject output formats.
+type ndjsonWriter struct {
+	w   io.Writer
+	eol string
+}
+
+// newNDJSONWriter returns a writer of records to w, ending lines with "\r\n" if
+// crlf is set.
+func newNDJSONWriter(w io.Writer, crlf bool) *ndjsonWriter {
+	if crlf {
+		return &ndjsonWriter{w: w, eol: "\r\n"}
+	}
+	return &ndjsonWriter{w: w, eol: "\n"}
+}
+
+// write writes v, which must encode as a JSON object, as one line with a leading
+// "type" field set to kind.
+func (n *ndjsonWriter) write(kind string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding NDJSON record: %w", err)
+	}
+	line := `{"type":"` + kind + `"`
+	if len(data) > 2 {
+		line += "," + string(data[1:len(data)-1])
+	}
+	if _, err := io.WriteString(n.w, line+"}"+n.eol); err != nil {
+		return fmt.Errorf("writing NDJSON record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// --- Pager ---
+
+// Long CLI results, like the per-file listing of a large replacement or the changes
+// of a sandboxed run, are shown through a pager when stdout is a terminal and the
+// text does not fit on the screen. The pager is $PAGER, or "less -R" so that colors
+// are kept; an empty $PAGER or "cat" turns paging off, as does -no-pager.
+
+// defaultPager is the pager command used when $PAGER is not set.
+const defaultPager = "less -R"
+
+// pagerCommand returns the pager command line, or nil if paging is turned off.
+func pagerCommand() []string {
+	pager, set := os.LookupEnv("PAGER")
+	if !set {
+		pager = defaultPager
+	}
+	fields := strings.Fields(pager)
+	if len(fields) == 0 || fields[0] == "cat" {
+		return nil
+	}
+	return fields
+}
+
+// terminalHeight returns the number of rows of the terminal f, or 0 if f is not a
+// terminal or its size is unknown.
+func terminalHeight(f *os.File) int {
+	if !isTerminal(f) {
+		return 0
+	}
+	_, height, err := term.GetSize(f.Fd())
+	if err != nil {
+		return 0
+	}
+	return height
+}
+
+// writePaged writes text to stdout, through the pager if enabled is set, stdout is a
+// terminal and text has more lines than the terminal. If the pager cannot be
+// started, text is written to stdout directly.
+func writePaged(text string, enabled bool) {
+	height := terminalHeight(os.Stdout)
+	command := pagerCommand()
+	if !enabled || height == 0 || command == nil || strings.Count(text, "\n") < height {
+		fmt.Fprint(os.Stdout, text)
+		return
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, set := os.LookupEnv("LESS"); !set {
+		// Keep the text on the screen after quitting, and pass colors through.
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		if _, started := err.(*exec.ExitError); !started {
+			fmt.Fprint(os.Stdout, text)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Directory Picker and Paged Views ---
+
+// dirBatchSize is the number of directory entries read per background step, so that
+// directories with hundreds of thousands of entries never block the UI.
+const dirBatchSize = 1000
+
+// dirPicker browses subdirectories for the target directory step. Entries are
+// loaded in the background in batches; only the visible page is rendered.
+type dirPicker struct {
+	dir     string   // Directory being listed.
+	entries []string // Names of its subdirectories; sorted once loading ends.
+	scanned int      // Number of directory entries examined so far.
+	cursor  int      // Index of the highlighted entry.
+	offset  int      // Index of the first visible entry.
+	loading bool     // True while batches are still being read.
+	err     error    // Error that stopped loading, if any.
+	gen     int      // Generation; batches from an earlier listing are discarded.
+}
+
+// dirBatchMsg carries one batch of subdirectory names read by readDirBatchCmd.
+type dirBatchMsg struct {
+	gen     int      // Generation of the listing the batch belongs to.
+	f       *os.File // Open directory to continue reading from; nil once done.
+	names   []string // Subdirectory names in this batch.
+	scanned int      // Number of entries examined in this batch.
+	err     error    // Error that stopped loading, if any.
+}
+
+// openDirCmd starts listing dir in the background.
+func openDirCmd(dir string, gen int) tea.Cmd {
+	return func() tea.Msg {
+		f, err := os.Open(dir)
+		if err != nil {
+			return dirBatchMsg{gen: gen, err: err}
+		}
+		return readDirBatch(f, gen)
+	}
+}
+
+// readDirBatchCmd reads the next batch from an already open directory.
+func readDirBatchCmd(f *os.File, gen int) tea.Cmd {
+	return func() tea.Msg { return readDirBatch(f, gen) }
+}
+
+// readDirBatch reads up to dirBatchSize entries from f. The file is closed when the
+// listing is complete or fails.
+func readDirBatch(f *os.File, gen int) dirBatchMsg {
+	entries, err := f.ReadDir(dirBatchSize)
+	msg := dirBatchMsg{gen: gen, f: f, scanned: len(entries)}
+	for _, e := range entries {
+		if e.IsDir() {
+			msg.names = append(msg.names, e.Name())
+		}
+	}
+	if err != nil || len(entries) == 0 {
+		f.Close()
+		msg.f = nil
+		if err != nil && !errors.Is(err, io.EOF) {
+			msg.err = err
+		}
+	}
+	return msg
+}
+
+// open resets the picker to list dir and returns the command that loads it.
+func (p *dirPicker) open(dir string) tea.Cmd {
+	p.gen++
+	p.dir = filepath.Clean(dir)
+	p.entries = nil
+	p.scanned = 0
+	p.cursor = 0
+	p.offset = 0
+	p.loading = true
+	p.err = nil
+	return openDirCmd(p.dir, p.gen)
+}
+
+// handleBatch merges a loaded batch and returns the command for the next one.
+func (p *dirPicker) handleBatch(msg dirBatchMsg) tea.Cmd {
+	if msg.gen != p.gen {
+		if msg.f != nil {
+			msg.f.Close()
+		}
+		return nil
+	}
+	p.entries = append(p.entries, msg.names...)
+	p.scanned += msg.scanned
+	p.err = msg.err
+	if msg.f != nil {
+		return readDirBatchCmd(msg.f, msg.gen)
+	}
+	p.loading = false
+	// Keep the highlight on the same name if the user already moved it.
+	current := ""
+	if p.cursor > 0 && p.cursor < len(p.entries) {
+		current = p.entries[p.cursor]
+	}
+	sort.Strings(p.entries)
+	if current != "" {
+		p.cursor = sort.SearchStrings(p.entries, current)
+	}
+	return nil
+}
+
+// selected returns the path of the highlighted subdirectory, or the listed
+// directory itself when it has none.
+func (p *dirPicker) selected() string {
+	if p.cursor < len(p.entries) {
+		return filepath.Join(p.dir, p.entries[p.cursor])
+	}
+	return p.dir
+}
+
+// move moves the cursor by delta entries, keeping it inside a page of pageSize rows.
+func (p *dirPicker) move(delta, pageSize int) {
+	p.cursor = clampInt(p.cursor+delta, 0, len(p.entries)-1)
+	p.offset = scrollOffset(p.offset, p.cursor, pageSize)
+}
+
+// view renders the visible page of the picker.
+func (p *dirPicker) view(pageSize, width int) string {
+	var b strings.Builder
+	b.WriteString(photonsr.TruncateMiddle(p.dir, width) + "\n")
+	offset := scrollOffset(p.offset, p.cursor, pageSize)
+	end := offset + pageSize
+	if end > len(p.entries) {
+		end = len(p.entries)
+	}
+	for i := offset; i < end; i++ {
+		prefix := "  "
+		if i == p.cursor {
+			prefix = "> "
+		}
+		b.WriteString(prefix + photonsr.TruncateMiddle(p.entries[i]+string(filepath.Separator), width-2) + "\n")
+	}
+	switch {
+	case p.loading:
+		b.WriteString(tr("picker.loading", len(p.entries), p.scanned) + "\n")
+	case p.err != nil:
+		b.WriteString(tr("picker.error", p.err) + "\n")
+	case len(p.entries) == 0:
+		b.WriteString(tr("picker.empty") + "\n")
+	default:
+		b.WriteString(tr("picker.position", p.cursor+1, len(p.entries)) + "\n")
+	}
+	return b.String()
+}
+
+// pageLines renders the lines visible at offset in a view of pageSize rows,
+// followed by a position indicator when not everything fits.
+func pageLines(lines []string, offset, pageSize, width int) string {
+	var b strings.Builder
+	end := offset + pageSize
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for _, line := range lines[offset:end] {
+		b.WriteString(photonsr.TruncateMiddle(line, width) + "\n")
+	}
+	if len(lines) > pageSize {
+		b.WriteString(tr("page.position", offset+1, end, len(lines)) + "\n")
+	}
+	return b.String()
+}
+
+// scrollOffset returns the offset that keeps cursor visible in a page of pageSize rows.
+func scrollOffset(offset, cursor, pageSize int) int {
+	if cursor < offset {
+		return cursor
+	}
+	if cursor >= offset+pageSize {
+		return cursor - pageSize + 1
+	}
+	return offset
+}
+
+// clampInt limits v to [lo, hi]; hi below lo yields lo.
+func clampInt(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	"gopkg.in/yaml.v3"
+)
+
+// --- Administrator Policy ---
+
+// On shared machines an administrator can restrict what replacements may do with a
+// policy file at a fixed system location (policyPath), which the CLI and the
+// wizard enforce whatever their flags: a maximum number of files a run may modify,
+// paths that are never modified, mandatory backups, and a mandatory dry run
+// (-dry-run or -sandbox) of the same replacement shortly before the real one. The policy
+// guards against mistakes, not against users determined to get around it: anyone
+// who can run another build of PhotonSR, or edit the state directory, can.
+
+// policyPath is the location of the policy file.
+var policyPath = defaultPolicyPath()
+
+// defaultPolicyPath returns /etc/photonsr/policy.yaml, or its equivalent under
+// %ProgramData% on Windows.
+func defaultPolicyPath() string {
+	if runtime.GOOS == "windows" {
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			dir = `C:\ProgramData`
+		}
+		return filepath.Join(dir, "photonsr", "policy.yaml")
+	}
+	return "/etc/photonsr/policy.yaml"
+}
+
+// defaultDryRunMaxAge is how recent the dry run required by a policy must be,
+// unless the policy says otherwise.
+const defaultDryRunMaxAge = "24h"
+
+// policy is the layout of the policy file:
+//
+//	max_files: 200
+//	forbidden_paths:
+//	  - /etc
+//	  - /srv/shared/legal
+//	  - "*.pem"
+//	require_backup: true
+//	require_dry_run: true
+//	dry_run_max_age: 4h
+type policy struct {
+	MaxFiles       int      `yaml:"max_files"`       // If > 0, runs that would modify more files are refused.
+	ForbiddenPaths []string `yaml:"forbidden_paths"` // Absolute paths, with everything below them, and name patterns.
+	RequireBackup  bool     `yaml:"require_backup"`  // Runs always back files up, as with -backup.
+	RequireDryRun  bool     `yaml:"require_dry_run"` // Runs need a recent dry run of the same replacement.
+	DryRunMaxAge   string   `yaml:"dry_run_max_age"` // How recent, e.g. "4h" (default 24h).
+
+	dryRunMaxAge time.Duration
+}
+
+// loadPolicy reads the policy file, or returns nil if there is none.
+func loadPolicy() (*policy, error) {
+	data, err := os.ReadFile(policyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading policy '%s': %w", policyPath, err)
+	}
+	p := &policy{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil && len(bytes.TrimSpace(data)) > 0 {
+		return nil, fmt.Errorf("parsing policy '%s': %w", policyPath, err)
+	}
+	if p.MaxFiles < 0 {
+		return nil, fmt.Errorf("policy '%s': max_files must not be negative", policyPath)
+	}
+	for _, f := range p.ForbiddenPaths {
+		if !filepath.IsAbs(f) {
+			if err := photonsr.ValidatePattern(f); err != nil {
+				return nil, fmt.Errorf("policy '%s': forbidden path: %w", policyPath, err)
+			}
+		}
+	}
+	if p.DryRunMaxAge == "" {
+		p.DryRunMaxAge = defaultDryRunMaxAge
+	}
+	if p.dryRunMaxAge, err = photonsr.ParseAge(p.DryRunMaxAge); err != nil {
+		return nil, fmt.Errorf("policy '%s': dry_run_max_age: %w", policyPath, err)
+	}
+	return p, nil
+}
+
+// enforce applies the policy to opts, a replacement of the files of realDir. With
+// sandboxed, opts.Dir is a copy of realDir (-sandbox or -out): the real files are
+// not touched, so neither backups nor a previous dry run are required, as for a
+// dry run itself (opts.DryRun). A nil policy allows everything.
+// Returns:
+//   - []string: Notices of what the policy changed in opts.
+//   - error: An error wrapping ErrPolicy if the run is refused.
+func (p *policy) enforce(opts *photonsr.ReplaceOptions, realDir string, sandboxed bool) ([]string, error) {
+	if p == nil {
+		return nil, nil
+	}
+	realAbs, err := filepath.Abs(realDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving directory '%s': %w", realDir, err)
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving directory '%s': %w", opts.Dir, err)
+	}
+	opts.Forbidden = nil
+	for _, f := range p.ForbiddenPaths {
+		if !filepath.IsAbs(f) {
+			opts.Forbidden = append(opts.Forbidden, f)
+			continue
+		}
+		f = filepath.Clean(f)
+		switch {
+		case f == realAbs || photonsr.Within(f, realAbs):
+			return nil, fmt.Errorf("'%s' is below the forbidden path '%s': %w", realDir, f, photonsr.ErrPolicy)
+		case photonsr.Within(realAbs, f): // Where the run sees it, in a copy.
+			opts.Forbidden = append(opts.Forbidden, filepath.Join(dir, photonsr.RelPath(realAbs, f)))
+		}
+	}
+	opts.MaxModified = p.MaxFiles
+	untouched := sandboxed || opts.DryRun
+	var notices []string
+	if p.RequireBackup && !untouched && !opts.ShouldBackup {
+		opts.ShouldBackup = true
+		notices = append(notices, "Backups are required by the administrator's policy: -backup is on.")
+	}
+	if p.RequireDryRun && !untouched {
+		at, err := lastDryRun(dryRunKey(realAbs, *opts))
+		if err != nil {
+			return nil, err
+		}
+		if at.IsZero() || time.Since(at) > p.dryRunMaxAge {
+			return nil, fmt.Errorf("the policy requires a dry run of this replacement (the same options with -dry-run or -sandbox) within %s first: %w", p.DryRunMaxAge, photonsr.ErrPolicy)
+		}
+	}
+	return notices, nil
+}
+
+// dryRunKey identifies the replacement opts of the files of dir, the absolute real
+// directory, for the dry-run requirement: the same directory, pattern, texts, rules
+// and kind of transformation.
+func dryRunKey(dir string, opts photonsr.ReplaceOptions) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%+v\x00%T", dir, opts.Pattern, opts.OldText, opts.NewText, opts.Rules, opts.Transform)
+	if opts.UseRegex {
+		fmt.Fprint(h, "\x00regex")
+	}
+	if opts.IgnoreCase {
+		fmt.Fprint(h, "\x00ignore-case")
+	}
+	if opts.Near != nil {
+		fmt.Fprint(h, "\x00near\x00", formatNear(opts.Near))
+	}
+	if opts.NotPrecededBy != "" || opts.NotFollowedBy != "" {
+		fmt.Fprintf(h, "\x00guards\x00%s\x00%s", opts.NotPrecededBy, opts.NotFollowedBy)
+	}
+	if opts.LineMode {
+		fmt.Fprint(h, "\x00line-mode")
+	}
+	if opts.Anchor != "" {
+		fmt.Fprint(h, "\x00anchor\x00", opts.Anchor)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// dryRunPath returns the path of the record of the last dry run of key.
+func dryRunPath(key string) (string, error) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dry-runs", key), nil
+}
+
+// recordDryRun notes that the replacement of key had a dry run now.
+func recordDryRun(key string) error {
+	path, err := dryRunPath(key)
+	if err != nil {
+		return err
+	}
+	if err := photonsr.FS.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating dry-run record directory: %w", err)
+	}
+	return photonsr.WriteFileAtomic(path, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0o600)
+}
+
+// lastDryRun returns when the replacement of key last had a dry run, or the zero
+// time if it never did.
+func lastDryRun(key string) (time.Time, error) {
+	path, err := dryRunPath(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading dry-run record: %w", err)
+	}
+	at, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, nil // Unreadable: as if there had been none.
+	}
+	return at, nil
+}
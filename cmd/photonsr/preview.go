@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Wizard Live Preview ---
+
+// splitPaneMinWidth is the terminal width from which the wizard shows the live
+// preview next to the current step.
+const splitPaneMinWidth = 120
+
+// Limits that keep the preview responsive on large trees.
+const (
+	previewMaxScan      = 20000                  // Files examined before the listing stops.
+	previewMaxFiles     = 1000                   // Matching files listed before the listing stops.
+	previewMaxRead      = 4 << 20                // Files larger than this are listed but not searched.
+	previewMaxDiffLines = 40                     // Changed lines shown for the selected file.
+	previewDebounce     = 150 * time.Millisecond // Pause in typing before the preview is refreshed.
+)
+
+// previewRequest is everything the preview depends on; a new listing starts
+// whenever it changes.
+type previewRequest struct {
+	dir        string
+	pattern    string
+	rule       photonsr.Rule
+	maxSize    int64
+	skipBinary bool
+	exclude    string // Comma-separated name patterns of files left out.
+}
+
+// previewFile is one file of the preview listing.
+type previewFile struct {
+	path    string // Path of the file.
+	matches int    // Occurrences of the old text; -1 when it was not searched.
+}
+
+// previewState is the preview shown in the right pane of the split layout.
+type previewState struct {
+	request   previewRequest // Request the listing belongs to.
+	gen       int            // Generation; results of an earlier request are discarded.
+	loading   bool           // True until the listing for request arrives.
+	files     []previewFile  // Matching files, in walk order.
+	scanned   int            // Files examined.
+	truncated bool           // True if a limit stopped the listing early.
+	err       error          // Error that stopped the listing, if any.
+	selected  int            // Index into files of the file whose changes are shown.
+	diff      []previewLine  // Changed lines of the selected file.
+}
+
+// previewLine is a line of the changes shown for the selected file: a line before
+// the replacement and the same line after it. Lines without a number are notes.
+type previewLine struct {
+	number int      // 1-based line number; 0 for a note.
+	added  bool     // The line after the replacement.
+	text   string   // Content, with tabs expanded.
+	spans  [][2]int // Byte ranges of text holding the matches, or their replacements.
+}
+
+// previewTickMsg fires when typing has paused long enough to refresh the preview.
+type previewTickMsg struct{ gen int }
+
+// previewMsg carries a finished listing.
+type previewMsg struct {
+	gen       int
+	files     []previewFile
+	scanned   int
+	truncated bool
+	err       error
+}
+
+// previewDiffMsg carries the changed lines of one file.
+type previewDiffMsg struct {
+	gen   int
+	path  string
+	lines []previewLine
+}
+
+// splitPane reports whether the current step is shown next to the live preview.
+func (m model) splitPane() bool {
+	_, ok := m.previewRequest()
+	return ok && m.width >= splitPaneMinWidth
+}
+
+// previewRequest returns the preview request for the wizard's current state. While
+// a text input is active its live value is used, so the preview follows typing.
+func (m model) previewRequest() (previewRequest, bool) {
+	if m.selectedAction != actionReplace || m.targetDir == "" || m.step < stepEnterPattern || m.step > stepAdvancedOptions {
+		return previewRequest{}, false
+	}
+	req := previewRequest{dir: m.targetDir, pattern: m.filePattern, rule: photonsr.Rule{Old: m.oldText, New: m.newText, Regexp: m.advanced.regex, IgnoreCase: m.ignoreCase}}
+	switch m.step {
+	case stepEnterPattern:
+		req.pattern = strings.TrimSpace(m.inputs[0].Value())
+	case stepEnterOldText:
+		req.rule.Old = m.inputs[0].Value()
+	case stepConfirmIgnoreCase:
+		if selected, ok := m.caseChoice.SelectedItem().(item); ok {
+			req.rule.IgnoreCase = selected.id == "yes"
+		}
+	case stepEnterNewText:
+		req.rule.New = m.inputs[0].Value()
+	}
+	if req.pattern == "" {
+		req.pattern = "*"
+	}
+	var opts photonsr.ReplaceOptions
+	m.advanced.apply(&opts)
+	req.maxSize, req.skipBinary, req.exclude = opts.MaxFileSize, opts.SkipBinary, m.advanced.exclude
+	return req, true
+}
+
+// schedulePreview starts a debounced refresh if the preview request changed.
+func (m *model) schedulePreview() tea.Cmd {
+	if m.width < splitPaneMinWidth {
+		return nil
+	}
+	req, ok := m.previewRequest()
+	if !ok || (req == m.preview.request && m.preview.gen > 0) {
+		return nil
+	}
+	m.preview.gen++
+	m.preview.request = req
+	m.preview.loading = true
+	gen := m.preview.gen
+	return tea.Tick(previewDebounce, func(time.Time) tea.Msg { return previewTickMsg{gen: gen} })
+}
+
+// handlePreviewTick starts the listing if no newer request arrived meanwhile.
+func (m *model) handlePreviewTick(msg previewTickMsg) tea.Cmd {
+	if msg.gen != m.preview.gen {
+		return nil
+	}
+	req, gen := m.preview.request, m.preview.gen
+	return func() tea.Msg { return listPreview(req, gen) }
+}
+
+// handlePreview stores a finished listing and loads the changes of its first file.
+func (m *model) handlePreview(msg previewMsg) tea.Cmd {
+	if msg.gen != m.preview.gen {
+		return nil
+	}
+	p := &m.preview
+	p.loading = false
+	p.files, p.scanned, p.truncated, p.err = msg.files, msg.scanned, msg.truncated, msg.err
+	p.selected = 0
+	p.diff = nil
+	return p.diffCmd()
+}
+
+// handlePreviewDiff stores the changed lines of the selected file.
+func (m *model) handlePreviewDiff(msg previewDiffMsg) {
+	p := &m.preview
+	if msg.gen == p.gen && p.selected < len(p.files) && p.files[p.selected].path == msg.path {
+		p.diff = msg.lines
+	}
+}
+
+// selectPreviewFile moves the preview selection by delta files.
+func (m *model) selectPreviewFile(delta int) tea.Cmd {
+	p := &m.preview
+	if len(p.files) == 0 {
+		return nil
+	}
+	p.selected = clampInt(p.selected+delta, 0, len(p.files)-1)
+	p.diff = nil
+	return p.diffCmd()
+}
+
+// diffCmd returns the command that computes the changes of the selected file.
+func (p *previewState) diffCmd() tea.Cmd {
+	if p.selected >= len(p.files) || p.files[p.selected].matches <= 0 {
+		return nil
+	}
+	path, rule, gen := p.files[p.selected].path, p.request.rule, p.gen
+	return func() tea.Msg { return previewDiffMsg{gen: gen, path: path, lines: previewDiff(path, rule)} }
+}
+
+// listPreview walks req.dir and lists the files a replacement would consider,
+// counting the occurrences of the old text in each.
+func listPreview(req previewRequest, gen int) previewMsg {
+	msg := previewMsg{gen: gen}
+	if err := photonsr.ValidatePattern(req.pattern); err != nil {
+		msg.err = err
+		return msg
+	}
+	msg.err = filepath.Walk(req.dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			return nil
+		}
+		if photonsr.IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || photonsr.IsInternalEntry(info) || !info.Mode().IsRegular() {
+			return nil
+		}
+		if matched, _ := photonsr.MatchesPattern(info.Name(), req.pattern); !matched || excludedName(req.exclude, info.Name()) {
+			return nil
+		}
+		if msg.scanned >= previewMaxScan || len(msg.files) >= previewMaxFiles {
+			msg.truncated = true
+			return filepath.SkipAll
+		}
+		msg.scanned++
+		if req.maxSize > 0 && info.Size() > req.maxSize {
+			return nil
+		}
+		file := previewFile{path: path, matches: -1}
+		if (req.rule.Old != "" || req.skipBinary) && info.Size() <= previewMaxRead {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			if req.skipBinary && bytes.IndexByte(content[:min(len(content), photonsr.BinarySniffLen)], 0) >= 0 {
+				return nil
+			}
+			if req.rule.Old != "" {
+				file.matches = len(photonsr.FindMatches(content, req.rule))
+				if file.matches == 0 {
+					return nil
+				}
+			}
+		}
+		msg.files = append(msg.files, file)
+		return nil
+	})
+	return msg
+}
+
+// previewDiff returns each line of path that rule changes, before and after, with
+// the matches and their replacements marked.
+func previewDiff(path string, rule photonsr.Rule) []previewLine {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return []previewLine{{text: tr("preview.read_error", err)}}
+	}
+	lines := bytes.Split(content, []byte("\n"))
+	var out []previewLine
+	last := 0
+	for _, match := range photonsr.FindMatches(content, rule) {
+		if match.Line == last {
+			continue
+		}
+		last = match.Line
+		if len(out) >= 2*previewMaxDiffLines {
+			out = append(out, previewLine{text: tr("preview.more")})
+			break
+		}
+		line := lines[match.Line-1]
+		changed, before, after := spliceLine(line, rule)
+		text, spans := expandTabs(string(line), before)
+		out = append(out, previewLine{number: match.Line, text: text, spans: spans})
+		text, spans = expandTabs(string(changed), after)
+		out = append(out, previewLine{number: match.Line, added: true, text: text, spans: spans})
+	}
+	return out
+}
+
+// spliceLine returns line with the matches of rule replaced, along with the byte
+// ranges of the matches in line and of their replacements in the result. The
+// replacement of a regular expression match is expanded with its groups, as a
+// run expands it; the wizard's rules have no guards, so the expression finds the
+// same matches as FindMatches.
+func spliceLine(line []byte, rule photonsr.Rule) (changed []byte, before, after [][2]int) {
+	var re *regexp.Regexp
+	var groups [][]int
+	if rule.Regexp {
+		expr := rule.Old
+		if rule.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		var err error
+		if re, err = regexp.Compile(expr); err != nil {
+			return line, nil, nil
+		}
+		groups = re.FindAllSubmatchIndex(line, -1)
+	} else {
+		for _, m := range photonsr.FindMatches(line, rule) {
+			groups = append(groups, []int{m.Start, m.End})
+		}
+	}
+	prev := 0
+	for _, g := range groups {
+		changed = append(changed, line[prev:g[0]]...)
+		start := len(changed)
+		if re != nil {
+			changed = re.Expand(changed, []byte(rule.New), line, g)
+		} else {
+			changed = append(changed, rule.New...)
+		}
+		before = append(before, [2]int{g[0], g[1]})
+		after = append(after, [2]int{start, len(changed)})
+		prev = g[1]
+	}
+	return append(changed, line[prev:]...), before, after
+}
+
+// expandTabs replaces the tabs of text by four spaces, moving the byte ranges
+// spans along.
+func expandTabs(text string, spans [][2]int) (string, [][2]int) {
+	if !strings.Contains(text, "\t") {
+		return text, spans
+	}
+	var b strings.Builder
+	moved := make([][2]int, len(spans))
+	prev := 0
+	for i, s := range spans {
+		b.WriteString(strings.ReplaceAll(text[prev:s[0]], "\t", "    "))
+		moved[i][0] = b.Len()
+		b.WriteString(strings.ReplaceAll(text[s[0]:s[1]], "\t", "    "))
+		moved[i][1] = b.Len()
+		prev = s[1]
+	}
+	b.WriteString(strings.ReplaceAll(text[prev:], "\t", "    "))
+	return b.String(), moved
+}
+
+// excerpt returns the part of text that fits in width columns: all of it if it
+// can, otherwise a window around the first span whose ends do not cut through a
+// word, with "…" where text was left out. The spans are clipped and moved to the
+// excerpt.
+func excerpt(text string, spans [][2]int, width int) (string, [][2]int) {
+	r := []rune(text)
+	if width <= 0 || len(r) <= width {
+		return text, spans
+	}
+	// Byte offset of each rune, and of the end of text.
+	offsets := make([]int, 0, len(r)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+	runeAt := func(offset int) int {
+		for i, o := range offsets {
+			if o >= offset {
+				return i
+			}
+		}
+		return len(r)
+	}
+
+	avail := max(width-2, 1) // Room for an ellipsis at both ends.
+	first, last := 0, 0
+	if len(spans) > 0 {
+		first, last = runeAt(spans[0][0]), runeAt(spans[0][1])
+	}
+	start, end := first, first+avail
+	if last-first < avail {
+		start = max(first-(avail-(last-first))/3, 0) // A third of the context before the match.
+		end = min(start+avail, len(r))
+		start = max(end-avail, 0)
+		for start > 0 && start < first && isWordRune(r[start-1]) && isWordRune(r[start]) {
+			start++
+		}
+		for end < len(r) && end > last && isWordRune(r[end-1]) && isWordRune(r[end]) {
+			end--
+		}
+	}
+	end = min(end, len(r))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	shift := b.Len() - offsets[start]
+	b.WriteString(string(r[start:end]))
+	if end < len(r) {
+		b.WriteString("…")
+	}
+	var moved [][2]int
+	for _, s := range spans {
+		from, to := max(s[0], offsets[start]), min(s[1], offsets[end])
+		if from < to || s[0] == s[1] && s[0] >= offsets[start] && s[0] <= offsets[end] {
+			moved = append(moved, [2]int{from + shift, to + shift})
+		}
+	}
+	return b.String(), moved
+}
+
+// isWordRune reports whether r is part of a word, which excerpt does not cut.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// renderPreviewLine renders l in width columns: a dim line number, marker and
+// context, with the matches or replacements highlighted.
+func renderPreviewLine(l previewLine, width int) string {
+	if l.number == 0 {
+		return photonsr.TruncateMiddle(l.text, width)
+	}
+	marker, color := "-", lipgloss.Color("9")
+	if l.added {
+		marker, color = "+", lipgloss.Color("10")
+	}
+	dim := lipgloss.NewStyle().Faint(true)
+	highlight := lipgloss.NewStyle().Foreground(color).Bold(true)
+	text, spans := excerpt(l.text, l.spans, width-8) // After "%5d - ".
+	var b strings.Builder
+	b.WriteString(dim.Render(fmt.Sprintf("%5d ", l.number)))
+	b.WriteString(lipgloss.NewStyle().Foreground(color).Render(marker) + " ")
+	prev := 0
+	for _, s := range spans {
+		b.WriteString(dim.Render(text[prev:s[0]]))
+		b.WriteString(highlight.Render(text[s[0]:s[1]]))
+		prev = s[1]
+	}
+	b.WriteString(dim.Render(text[prev:]))
+	return b.String()
+}
+
+// view renders the preview pane in width columns and height rows.
+func (p previewState) view(width, height int) string {
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().Bold(true).Render(tr("preview.title")) + "\n")
+	switch {
+	case p.err != nil:
+		b.WriteString(photonsr.TruncateMiddle(tr("preview.error", p.err), width) + "\n")
+		return b.String()
+	case p.loading && p.files == nil:
+		b.WriteString(tr("preview.loading") + "\n")
+		return b.String()
+	}
+	status := tr("preview.count", len(p.files), p.scanned)
+	if p.truncated {
+		status += " " + tr("preview.truncated")
+	}
+	if p.loading {
+		status += " " + tr("preview.updating")
+	}
+	b.WriteString(status + "\n")
+
+	listRows := height / 3
+	if listRows < 3 {
+		listRows = 3
+	}
+	offset := scrollOffset(0, p.selected, listRows)
+	for i := offset; i < len(p.files) && i < offset+listRows; i++ {
+		prefix := "  "
+		if i == p.selected {
+			prefix = "> "
+		}
+		name := p.files[i].path
+		if rel, err := filepath.Rel(p.request.dir, name); err == nil {
+			name = rel
+		}
+		if p.files[i].matches > 0 {
+			name = fmt.Sprintf("%s (%d)", name, p.files[i].matches)
+		}
+		b.WriteString(prefix + photonsr.TruncateMiddle(name, width-2) + "\n")
+	}
+	if len(p.diff) > 0 {
+		b.WriteString("\n")
+		diffRows := height - listRows - 4
+		for i, line := range p.diff {
+			if i >= diffRows {
+				break
+			}
+			b.WriteString(renderPreviewLine(line, width) + "\n")
+		}
+	}
+	if len(p.files) > 1 {
+		b.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render(tr("hint.preview")))
+	}
+	return b.String()
+}
+
+// contentWidth returns the width available to the wizard steps: half the terminal
+// when the split layout can be used, otherwise all of it.
+func (m model) contentWidth() int {
+	if m.width >= splitPaneMinWidth {
+		return m.width / 2
+	}
+	return m.width
+}
+
+// renderSplit places left, the current step, next to the preview pane.
+func (m model) renderSplit(left string) string {
+	leftWidth := m.contentWidth()
+	rightWidth := m.width - leftWidth - 4
+	height := m.height - 2
+	if height < 10 {
+		height = 10
+	}
+	leftPane := lipgloss.NewStyle().Width(leftWidth).Render(left)
+	rightPane := lipgloss.NewStyle().Width(rightWidth).PaddingLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		Render(m.preview.view(rightWidth-1, height))
+	return lipgloss.JoinHorizontal(lipgloss.Top, leftPane, rightPane)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// processAlive reports whether a process with the given pid exists.
+// On Windows, FindProcess fails for processes that have exited.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// --- Profiling ---
+
+// exitHooks run, last registered first, before the process exits through exit.
+var exitHooks []func()
+
+// exit runs the exit hooks (e.g. flushing profiles) and terminates with code.
+// Code paths that may run after profiling has started must use exit, not os.Exit.
+func exit(code int) {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+	os.Exit(code)
+}
+
+// startProfiling starts a CPU profile written to cpuPath and arranges for a heap
+// profile to be written to memPath when the process exits. Empty paths disable the
+// corresponding profile.
+func startProfiling(cpuPath, memPath string) error {
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return fmt.Errorf("creating CPU profile '%s': %w", cpuPath, err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("starting CPU profile: %w", err)
+		}
+		exitHooks = append(exitHooks, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if memPath != "" {
+		exitHooks = append(exitHooks, func() {
+			if err := writeHeapProfile(memPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		})
+	}
+	return nil
+}
+
+// writeHeapProfile writes a heap profile of the live objects to path.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating memory profile '%s': %w", path, err)
+	}
+	defer f.Close()
+	runtime.GC() // Report up-to-date statistics.
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("writing memory profile '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Progress Stream ---
+
+// -progress-json writes the progress of a CLI run as newline-delimited JSON, one
+// event per line, for external UIs and CI dashboards to render live: each file a
+// replacement starts, modifies, skips or fails on, a heartbeat with the counts so
+// far every progressHeartbeatInterval, and a final "done" event. The stream goes to
+// standard error or to a named pipe (or file), so it never mixes with the report
+// on standard output. Operations other than replacements send heartbeats and
+// "done" only; those of -restore and -clean also count the backups handled
+// ("processed") out of those found ("total").
+//
+//	{"event":"started","time":"2024-05-01T10:00:00.120Z","path":"src/a.go"}
+//	{"event":"modified","time":"2024-05-01T10:00:00.125Z","path":"src/a.go"}
+//	{"event":"heartbeat","time":"2024-05-01T10:00:01.000Z","started":1,"modified":1,"skipped":0,"errors":0,"elapsed_ms":880}
+
+// progressStderr is the -progress-json value that selects standard error.
+const progressStderr = "stderr"
+
+// progressHeartbeatInterval is the time between two heartbeat events.
+const progressHeartbeatInterval = time.Second
+
+// Events of the -progress-json stream.
+const (
+	ProgressStarted   = "started"   // A file is being processed.
+	ProgressModified  = "modified"  // A file was rewritten.
+	ProgressSkipped   = "skipped"   // A file was deliberately left alone.
+	ProgressError     = "error"     // A file could not be processed.
+	ProgressHeartbeat = "heartbeat" // The counts so far; sent while the run lasts.
+	ProgressDone      = "done"      // The run ended; the last event.
+)
+
+// progressEvent is one line of the -progress-json stream.
+type progressEvent struct {
+	Event string `json:"event"`
+	Time  string `json:"time"`            // RFC 3339, in milliseconds.
+	Path  string `json:"path,omitempty"`  // For file events.
+	Code  string `json:"code,omitempty"`  // For skipped, error and done: the error code (see errorCode).
+	Error string `json:"error,omitempty"` // For skipped, error and done: why.
+
+	*progressCounts // For heartbeat and done.
+}
+
+// progressCounts are the counts of a run reported by heartbeat and done events.
+type progressCounts struct {
+	Started       int   `json:"started"`
+	Modified      int   `json:"modified"`
+	Skipped       int   `json:"skipped"`
+	Errors        int   `json:"errors"`
+	Processed     int   `json:"processed,omitempty"`      // For -restore and -clean: backups handled so far.
+	Total         int   `json:"total,omitempty"`          // For -restore and -clean: backups found.
+	ItemsAffected *int  `json:"items_affected,omitempty"` // For done: as in the report.
+	ElapsedMS     int64 `json:"elapsed_ms"`
+}
+
+// progressStream writes the -progress-json events of a run. It is safe for
+// concurrent use, and a nil *progressStream writes nothing.
+type progressStream struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer // nil for standard error.
+	err     error     // First write error; nothing more is written after it.
+	started time.Time
+	counts  progressCounts
+	stop    chan struct{} // Closed to end the heartbeats.
+	ended   bool
+}
+
+// openProgressStream starts the stream to target, progressStderr or the path of a
+// named pipe or file. Opening a named pipe waits for its reader.
+func openProgressStream(target string) (*progressStream, error) {
+	p := &progressStream{w: os.Stderr, started: time.Now(), stop: make(chan struct{})}
+	if target != progressStderr {
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening -progress-json '%s': %w", target, err)
+		}
+		p.w, p.closer = f, f
+	}
+	go p.heartbeats()
+	return p, nil
+}
+
+// heartbeats sends a heartbeat every progressHeartbeatInterval until p ends.
+func (p *progressStream) heartbeats() {
+	ticker := time.NewTicker(progressHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.emit(progressEvent{Event: ProgressHeartbeat})
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// emit writes e, counting file events. Heartbeat and done events get the counts.
+func (p *progressStream) emit(e progressEvent) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended || p.err != nil {
+		return
+	}
+	switch e.Event {
+	case ProgressStarted:
+		p.counts.Started++
+	case ProgressModified:
+		p.counts.Modified++
+	case ProgressSkipped:
+		p.counts.Skipped++
+	case ProgressError:
+		p.counts.Errors++
+	case ProgressHeartbeat, ProgressDone:
+		counts := p.counts
+		counts.ElapsedMS = time.Since(p.started).Milliseconds()
+		e.progressCounts = &counts
+	}
+	e.Time = time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	line, err := json.Marshal(e)
+	if err == nil {
+		_, err = p.w.Write(append(line, '\n'))
+	}
+	p.err = err
+}
+
+// watch makes the callbacks of opts also send the file events of the replacement,
+// keeping the callbacks already set.
+func (p *progressStream) watch(opts *photonsr.ReplaceOptions) {
+	if p == nil {
+		return
+	}
+	onStarted, onModified, onSkipped, onError := opts.OnFileStarted, opts.OnFileModified, opts.OnFileSkipped, opts.OnFileError
+	opts.OnFileStarted = func(path string) {
+		p.emit(progressEvent{Event: ProgressStarted, Path: path})
+		if onStarted != nil {
+			onStarted(path)
+		}
+	}
+	opts.OnFileModified = func(path, hashBefore, hashAfter string) {
+		p.emit(progressEvent{Event: ProgressModified, Path: path})
+		if onModified != nil {
+			onModified(path, hashBefore, hashAfter)
+		}
+	}
+	opts.OnFileSkipped = func(path string, reason error) {
+		p.emit(progressEvent{Event: ProgressSkipped, Path: path, Code: errorCode(reason), Error: reason.Error()})
+		if onSkipped != nil {
+			onSkipped(path, reason)
+		}
+	}
+	opts.OnFileError = func(path string, err error) {
+		p.emit(progressEvent{Event: ProgressError, Path: path, Code: errorCode(err), Error: err.Error()})
+		if onError != nil {
+			onError(path, err)
+		}
+	}
+}
+
+// backups records that done of the total backups of a restore or clean have been
+// handled, for the next heartbeat. It is the OnProgress callback of those operations.
+func (p *progressStream) backups(done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts.Processed, p.counts.Total = done, total
+}
+
+// finish sends the done event of a run that affected itemsAffected items and ended
+// with err, and closes the stream.
+func (p *progressStream) finish(itemsAffected int, err error) {
+	if p == nil {
+		return
+	}
+	done := progressEvent{Event: ProgressDone}
+	if err != nil {
+		done.Code, done.Error = errorCode(err), err.Error()
+	}
+	p.mu.Lock()
+	if p.ended {
+		p.mu.Unlock()
+		return
+	}
+	p.counts.ItemsAffected = &itemsAffected
+	p.mu.Unlock()
+	close(p.stop)
+	p.emit(done)
+	p.close()
+}
+
+// close ends the stream without a done event, as when the process exits early.
+func (p *progressStream) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.ended = true
+	if p.closer != nil {
+		p.closer.Close()
+	}
+}
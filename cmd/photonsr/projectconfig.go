@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	"gopkg.in/yaml.v3"
+)
+
+// --- Project Configuration ---
+
+// A project can keep settings in a .photonsr.yaml file at its root. PhotonSR looks
+// for it in the target directory and then in each parent directory. For now it
+// holds the exclusions saved from the wizard: files and directories that a
+// replacement of one old text by one new text leaves alone. Runs of the same
+// replacement below the project, from the CLI or the wizard, skip them
+// automatically.
+
+// saveExclusions adds paths, which are in the target directory dir, to the saved
+// exclusion of the replacement of oldText by newText in the project configuration
+// of dir.
+// Returns:
+//   - string: The path of the project configuration.
+//   - int: The number of paths that were not saved before.
+//   - error: An error if the configuration cannot be read or written.
+func saveExclusions(dir, oldText, newText string, paths []string) (string, int, error) {
+	configPath, _, err := photonsr.FindProjectConfig(dir)
+	if err != nil {
+		return "", 0, err
+	}
+	config, err := photonsr.LoadProjectConfig(configPath)
+	if err != nil {
+		return configPath, 0, err
+	}
+	ex := config.Exclusion(oldText, newText)
+	if ex == nil {
+		config.Exclusions = append(config.Exclusions, photonsr.SavedExclusion{Old: oldText, New: newText})
+		ex = &config.Exclusions[len(config.Exclusions)-1]
+	}
+	saved := map[string]bool{}
+	for _, p := range ex.Paths {
+		saved[p] = true
+	}
+	added := 0
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return configPath, 0, fmt.Errorf("resolving '%s': %w", path, err)
+		}
+		rel := filepath.ToSlash(photonsr.RelPath(filepath.Dir(configPath), abs))
+		if !saved[rel] {
+			saved[rel] = true
+			ex.Paths = append(ex.Paths, rel)
+			added++
+		}
+	}
+	sort.Strings(ex.Paths)
+	var data bytes.Buffer
+	enc := yaml.NewEncoder(&data)
+	enc.SetIndent(2)
+	err = enc.Encode(config)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		return configPath, 0, fmt.Errorf("encoding project configuration: %w", err)
+	}
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(configPath); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := photonsr.WriteFileAtomic(configPath, data.Bytes(), perm); err != nil {
+		return configPath, 0, fmt.Errorf("writing project configuration: %w", err)
+	}
+	return configPath, added, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Recent Directories ---
+
+// recentDirsFile is the name of the list of recently used target directories in
+// the state directory.
+const recentDirsFile = "recent-dirs.json"
+
+// maxRecentDirs is the number of recently used directories remembered.
+const maxRecentDirs = 10
+
+// Kinds of quick-pick entries offered in the wizard's directory step.
+const (
+	quickPickRecent  = "quickpick.recent"
+	quickPickCWD     = "quickpick.cwd"
+	quickPickGitRoot = "quickpick.git_root"
+	quickPickProject = "quickpick.project_root" // Takes the marker found as argument.
+)
+
+// quickPick is a directory offered for selection without typing it.
+type quickPick struct {
+	path string // Absolute path of the directory.
+	kind string // One of the quickPick* constants; also its message catalog key.
+	note string // Argument of the kind's message, if it takes one.
+}
+
+// label returns the description shown next to the directory.
+func (p quickPick) label() string {
+	if p.note != "" {
+		return tr(p.kind, p.note)
+	}
+	return tr(p.kind)
+}
+
+// recentDirsPath returns the path of the recently used directories list.
+func recentDirsPath() (string, error) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, recentDirsFile), nil
+}
+
+// loadRecentDirs returns the recently used target directories, most recent first.
+// A missing list is not an error.
+func loadRecentDirs() ([]string, error) {
+	path, err := recentDirsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading recent directories: %w", err)
+	}
+	var dirs []string
+	if err := json.Unmarshal(data, &dirs); err != nil {
+		return nil, fmt.Errorf("parsing recent directories '%s': %w", path, err)
+	}
+	return dirs, nil
+}
+
+// rememberRecentDir moves dir to the front of the recently used directories,
+// keeping at most maxRecentDirs entries.
+func rememberRecentDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving '%s': %w", dir, err)
+	}
+	dirs, err := loadRecentDirs()
+	if err != nil {
+		return err
+	}
+	updated := []string{abs}
+	for _, d := range dirs {
+		if d != abs && len(updated) < maxRecentDirs {
+			updated = append(updated, d)
+		}
+	}
+	path, err := recentDirsPath()
+	if err != nil {
+		return err
+	}
+	if err := photonsr.FS.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding recent directories: %w", err)
+	}
+	return photonsr.WriteFileAtomic(path, append(data, '\n'), 0o600)
+}
+
+// autoDir is the -dir value that selects the detected project root.
+const autoDir = "auto"
+
+// projectRootMarkers are the files and directories that mark a project root, in
+// order of preference within one directory.
+var projectRootMarkers = []string{".git", "go.mod", "package.json"}
+
+// findProjectRoot returns the nearest directory at or above start that contains one
+// of projectRootMarkers, and the marker found there.
+func findProjectRoot(start string) (dir, marker string, err error) {
+	dir, marker, ok := findUpward(start, projectRootMarkers...)
+	if !ok {
+		abs, _ := filepath.Abs(start)
+		return "", "", fmt.Errorf("no project root (%s) found at or above '%s': %w", strings.Join(projectRootMarkers, ", "), abs, photonsr.ErrNotFound)
+	}
+	return dir, marker, nil
+}
+
+// findUpward returns the nearest directory at or above start that contains one of
+// markers, and the marker found there.
+func findUpward(start string, markers ...string) (dir, marker string, ok bool) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", "", false
+	}
+	for {
+		for _, m := range markers {
+			if _, err := os.Lstat(filepath.Join(dir, m)); err == nil {
+				return dir, m, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// quickPicks returns the directories offered in the wizard's directory step:
+// recently used directories that still exist, then the current working directory,
+// its project root (see findProjectRoot) and its git root. Each directory appears once.
+func quickPicks() []quickPick {
+	var picks []quickPick
+	seen := map[string]bool{}
+	add := func(path, kind, note string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			picks = append(picks, quickPick{path: path, kind: kind, note: note})
+		}
+	}
+	recent, _ := loadRecentDirs()
+	for _, d := range recent {
+		if info, err := os.Stat(d); err == nil && info.IsDir() {
+			add(d, quickPickRecent, "")
+		}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		add(cwd, quickPickCWD, "")
+		if root, marker, err := findProjectRoot(cwd); err == nil {
+			add(root, quickPickProject, marker)
+		}
+		if root, _, ok := findUpward(cwd, ".git"); ok {
+			add(root, quickPickGitRoot, "")
+		}
+	}
+	return picks
+}
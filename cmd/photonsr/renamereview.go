@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Rename Review ---
+
+// With -review, "photonsr rename-files" and "photonsr move-files" show their plan as a table of old and new
+// names before renaming anything. Rows that cannot be renamed (invalid names,
+// existing files, collisions) are shown in red, unchanged names dimmed. Space
+// excludes or includes the highlighted row; collisions are counted again among the
+// included rows, so excluding one side of a collision resolves it. Enter renames
+// the included rows once none of them has a problem; Esc renames nothing.
+
+// renameReview is the bubbletea model of the rename review.
+type renameReview struct {
+	opts      photonsr.RenameOptions
+	items     []photonsr.RenameItem
+	cursor    int
+	offset    int
+	width     int
+	height    int
+	confirmed bool
+	blocked   bool // Enter was pressed while an included row has a problem.
+}
+
+// performRenameReview plans the renames of opts, lets the user review them, and
+// performs those kept. It returns like PerformRename.
+func performRenameReview(opts photonsr.RenameOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	items, err := photonsr.PlanRenames(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(items) == 0 {
+		return photonsr.ApplyRenames(opts, items)
+	}
+	items, confirmed, err := reviewRenames(opts, items)
+	if err != nil {
+		return nil, 0, fmt.Errorf("running rename review: %w", err)
+	}
+	if !confirmed {
+		return []string{"Review cancelled; nothing was renamed."}, 0, nil
+	}
+	if opts.Layout != "" {
+		fmt.Fprintln(infoOut, tr("cli.progress.move"))
+	} else {
+		fmt.Fprintln(infoOut, tr("cli.progress.rename"))
+	}
+	return photonsr.ApplyRenames(opts, items)
+}
+
+// reviewRenames shows the plan in items and returns it with the user's exclusions,
+// and whether the user confirmed it.
+func reviewRenames(opts photonsr.RenameOptions, items []photonsr.RenameItem) ([]photonsr.RenameItem, bool, error) {
+	final, err := tea.NewProgram(renameReview{opts: opts, items: items, width: 80, height: 24}, tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, false, err
+	}
+	r := final.(renameReview)
+	return r.items, r.confirmed, nil
+}
+
+func (r renameReview) Init() tea.Cmd { return nil }
+
+// pageSize is the number of rows that fit between the header and the footer.
+func (r renameReview) pageSize() int {
+	return max(r.height-6, 1)
+}
+
+func (r renameReview) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		if msg.Width > 0 && msg.Height > 0 {
+			r.width, r.height = msg.Width, msg.Height
+		}
+	case tea.KeyMsg:
+		r.blocked = false
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			return r, tea.Quit
+		case "up", "k":
+			r.move(-1)
+		case "down", "j":
+			r.move(1)
+		case "pgup":
+			r.move(-r.pageSize())
+		case "pgdown":
+			r.move(r.pageSize())
+		case "home":
+			r.move(-len(r.items))
+		case "end":
+			r.move(len(r.items))
+		case " ", "x":
+			if it := &r.items[r.cursor]; !it.NoOp {
+				it.Excluded = !it.Excluded
+				photonsr.MarkCollisions(r.items, r.opts.System())
+			}
+		case "enter":
+			if r.problems() > 0 {
+				r.blocked = true
+				return r, nil
+			}
+			r.confirmed = true
+			return r, tea.Quit
+		}
+	}
+	return r, nil
+}
+
+// move moves the cursor by delta rows.
+func (r *renameReview) move(delta int) {
+	r.cursor = clampInt(r.cursor+delta, 0, len(r.items)-1)
+	r.offset = scrollOffset(r.offset, r.cursor, r.pageSize())
+}
+
+// problems returns the number of included rows that cannot be renamed.
+func (r renameReview) problems() int {
+	n := 0
+	for _, it := range r.items {
+		if it.Pending() && it.Reason() != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (r renameReview) View() string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("99"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	dimStyle := lipgloss.NewStyle().Faint(true)
+	excludedStyle := lipgloss.NewStyle().Faint(true).Strikethrough(true)
+
+	pending := 0
+	for _, it := range r.items {
+		if it.Pending() {
+			pending++
+		}
+	}
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(tr("review.title", r.opts.System())) + "\n\n")
+
+	// The old names take at most a third of the width, leaving room for the new name
+	// and its problem.
+	column := 0
+	end := min(r.offset+r.pageSize(), len(r.items))
+	for _, it := range r.items[r.offset:end] {
+		column = max(column, len([]rune(photonsr.RelPath(r.opts.Dir, it.From))))
+	}
+	column = min(column, max((r.width-10)/3, 10))
+	for i := r.offset; i < end; i++ {
+		it := r.items[i]
+		prefix := "  "
+		if i == r.cursor {
+			prefix = "> "
+		}
+		mark := "[x] "
+		if !it.Pending() {
+			mark = "[ ] "
+		}
+		from := photonsr.TruncateMiddle(photonsr.RelPath(r.opts.Dir, it.From), column)
+		row := fmt.Sprintf("%s%-*s -> %s", mark, column, from, photonsr.TruncateMiddle(r.opts.NewName(it), column))
+		switch {
+		case it.NoOp:
+			row = dimStyle.Render(row + "  " + tr("review.unchanged"))
+		case it.Excluded:
+			row = excludedStyle.Render(row)
+		case it.Reason() != "":
+			row = errorStyle.Render(row + "  " + it.Reason())
+		}
+		b.WriteString(prefix + row + "\n")
+	}
+
+	b.WriteString("\n")
+	if n := r.problems(); n > 0 {
+		line := tr("review.problems", n)
+		if r.blocked {
+			line = tr("review.blocked", n)
+		}
+		b.WriteString(errorStyle.Render(line) + "\n")
+	} else {
+		b.WriteString(tr("review.ready", pending, len(r.items)) + "\n")
+	}
+	b.WriteString(dimStyle.Render(tr("hint.review")))
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	"gopkg.in/yaml.v3"
+)
+
+// --- Rules Files ---
+
+// loadRules reads a YAML (or JSON) rules file and decodes hexadecimal text, so the
+// returned rules have Old, New and Severity set.
+func loadRules(path string) ([]photonsr.RuleSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file: %w", err)
+	}
+	var file photonsr.RulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parsing rules file '%s': %w", path, err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("rules file '%s' contains no rules: %w", path, photonsr.ErrInvalidOption)
+	}
+	// Whether a rule gives new text at all is only visible in the document itself.
+	var keys struct {
+		Rules []map[string]any `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing rules file '%s': %w", path, err)
+	}
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		_, hasNew := keys.Rules[i]["new"]
+		_, hasNewHex := keys.Rules[i]["new_hex"]
+		r.HasNew = hasNew || hasNewHex
+		if r.Skip {
+			if r.Pattern == "" {
+				return nil, fmt.Errorf("rule %d in '%s': a skip rule needs a pattern: %w", i+1, path, photonsr.ErrInvalidOption)
+			}
+			if r.Old != "" || r.OldHex != "" || r.Forbid != "" || r.ForbidHex != "" || r.HasNew || r.Regex || r.IgnoreCase {
+				return nil, fmt.Errorf("rule %d in '%s': a skip rule takes only a pattern: %w", i+1, path, photonsr.ErrInvalidOption)
+			}
+			if err := photonsr.ValidatePattern(r.Pattern); err != nil {
+				return nil, fmt.Errorf("rule %d in '%s': %w", i+1, path, err)
+			}
+			continue
+		}
+		if r.Forbid != "" || r.ForbidHex != "" {
+			if r.Old != "" || r.OldHex != "" {
+				return nil, fmt.Errorf("rule %d in '%s': old and forbid are mutually exclusive: %w", i+1, path, photonsr.ErrInvalidOption)
+			}
+			r.Old, r.OldHex = r.Forbid, r.ForbidHex
+		}
+		if r.OldHex != "" {
+			if r.Old != "" {
+				return nil, fmt.Errorf("rule %d in '%s': old and old_hex are mutually exclusive: %w", i+1, path, photonsr.ErrInvalidOption)
+			}
+			b, err := decodeHexArg(r.OldHex)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d in '%s': old_hex: %w", i+1, path, err)
+			}
+			r.Old = string(b)
+		}
+		if r.NewHex != "" {
+			if r.New != "" {
+				return nil, fmt.Errorf("rule %d in '%s': new and new_hex are mutually exclusive: %w", i+1, path, photonsr.ErrInvalidOption)
+			}
+			b, err := decodeHexArg(r.NewHex)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d in '%s': new_hex: %w", i+1, path, err)
+			}
+			r.New = string(b)
+		}
+		if r.Old == "" {
+			return nil, fmt.Errorf("rule %d in '%s': %w", i+1, path, photonsr.ErrEmptyOldText)
+		}
+		if r.Regex {
+			if _, err := regexp.Compile(r.Old); err != nil {
+				return nil, fmt.Errorf("rule %d in '%s': invalid regular expression: %v: %w", i+1, path, err, photonsr.ErrInvalidOption)
+			}
+		}
+		if r.Severity == "" {
+			r.Severity = photonsr.SeverityError
+		}
+		if !photonsr.ValidSeverity(r.Severity) {
+			return nil, fmt.Errorf("rule %d in '%s': unknown severity '%s' (expected error, warning or info): %w", i+1, path, r.Severity, photonsr.ErrInvalidOption)
+		}
+		if r.Pattern != "" {
+			if err := photonsr.ValidatePattern(r.Pattern); err != nil {
+				return nil, fmt.Errorf("rule %d in '%s': %w", i+1, path, err)
+			}
+		}
+	}
+	return file.Rules, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// loadTestRules writes content to a rules file and loads it.
+func loadTestRules(t *testing.T, content string) ([]photonsr.RuleSpec, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return loadRules(path)
+}
+
+func TestLoadRulesMatching(t *testing.T) {
+	rules, err := loadTestRules(t, `rules:
+  - old: 'v(\d+)'
+    new: "version $1"
+    pattern: "*.go"
+    regex: true
+  - old: "colour"
+    new: "color"
+    ignore_case: true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rules[0].Regex || rules[0].IgnoreCase || rules[1].Regex || !rules[1].IgnoreCase {
+		t.Errorf("loadRules = %+v, want the first rule regex and the second ignoring case", rules)
+	}
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name, content string
+	}{
+		{"bad regular expression", "rules:\n  - old: '('\n    new: x\n    regex: true\n"},
+		{"skip rule with regex", "rules:\n  - pattern: '*.min.js'\n    skip: true\n    regex: true\n"},
+		{"skip rule ignoring case", "rules:\n  - pattern: '*.min.js'\n    skip: true\n    ignore_case: true\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadTestRules(t, tt.content); !errors.Is(err, photonsr.ErrInvalidOption) {
+				t.Errorf("loadRules = %v, want an error wrapping ErrInvalidOption", err)
+			}
+		})
+	}
+}
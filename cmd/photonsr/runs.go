@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Run Records and Retry ---
+
+// retryOptionFlags are the flags recorded with a run and restored by "retry".
+// Flags that select files (-scope, -manifest) are not needed: a retry processes
+// exactly the files that failed.
+var retryOptionFlags = []string{
+	"dir", "pattern", "old", "new", "backup", "backup-conflict",
+	"jobs", "max-mem", "io-profile", "order", "sort-by", "max-size", "skip-binary", "same-length",
+	"confine", "rules", "regex", "ignore-case", "near",
+	"not-preceded-by", "not-followed-by", "line-mode", "anchor",
+}
+
+// failedFile is a file that could not be processed in a run.
+type failedFile struct {
+	Path  string `json:"path"`           // Absolute path of the file.
+	Code  string `json:"code,omitempty"` // Error code (see errorCode).
+	Error string `json:"error"`          // Error reported for the file.
+}
+
+// runRecord is saved when a run completes with per-file errors, so that the
+// failed files can be retried with "photonsr retry <id>".
+type runRecord struct {
+	ID      string            `json:"id"`      // Run identifier.
+	Time    string            `json:"time"`    // RFC 3339 time the run ended.
+	Options map[string]string `json:"options"` // Values of retryOptionFlags.
+	Failed  []failedFile      `json:"failed"`  // Files that failed.
+}
+
+// runRecordPath returns the path of the record of run id.
+func runRecordPath(id string) (string, error) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return "", err
+	}
+	if id == "" || filepath.Base(id) != id {
+		return "", fmt.Errorf("invalid run id '%s'", id)
+	}
+	return filepath.Join(dir, "runs", id+".json"), nil
+}
+
+// saveRunRecord writes rec to the state directory.
+func saveRunRecord(rec runRecord) error {
+	path, err := runRecordPath(rec.ID)
+	if err != nil {
+		return err
+	}
+	if err := photonsr.FS.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating run record directory: %w", err)
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding run record: %w", err)
+	}
+	return photonsr.WriteFileAtomic(path, append(data, '\n'), 0o600)
+}
+
+// loadRunRecord reads the record of run id.
+func loadRunRecord(id string) (runRecord, error) {
+	var rec runRecord
+	path, err := runRecordPath(id)
+	if err != nil {
+		return rec, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rec, fmt.Errorf("no retry list for run '%s' (runs without failures are not recorded)", id)
+	}
+	if err != nil {
+		return rec, fmt.Errorf("reading run record '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, fmt.Errorf("parsing run record '%s': %w", path, err)
+	}
+	return rec, nil
+}
+
+// removeRunRecord deletes the record of run id once its failures are resolved.
+func removeRunRecord(id string) error {
+	path, err := runRecordPath(id)
+	if err != nil {
+		return err
+	}
+	if err := photonsr.FS.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing run record '%s': %w", path, err)
+	}
+	return nil
+}
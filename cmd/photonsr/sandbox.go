@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Sandbox Mode ---
+
+// With -sandbox the operation runs on a temporary copy of -dir. Afterwards the
+// copy is compared with the real tree to show what the operation would have done,
+// and the real tree is checked to be exactly as it was before the run.
+
+// sandbox is a temporary copy of a directory tree.
+type sandbox struct {
+	realDir string               // The directory that was copied.
+	dir     string               // The copy; removed by remove.
+	files   int                  // Regular files copied.
+	bytes   int64                // Total size of the copied files.
+	before  map[string]fileStamp // State of realDir when it was copied, by relative path.
+	output  bool                 // Made by -out: the copy is the result and is kept.
+
+	link   string // How regular files are copied: an OutLink mode ("" copies).
+	cloned int    // Files cloned by reflink rather than copied.
+	linked int    // Files hard-linked rather than copied.
+}
+
+// fileStamp is what tells two states of a file apart without reading it.
+type fileStamp struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// treeStamps records the stamp of every entry below dir, by slash-separated
+// relative path. Internal entries of interrupted runs are ignored.
+func treeStamps(dir string) (map[string]fileStamp, error) {
+	stamps := map[string]fileStamp{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if photonsr.IsInternalEntry(info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		stamps[filepath.ToSlash(rel)] = fileStamp{size: info.Size(), mode: info.Mode(), modTime: info.ModTime()}
+		return nil
+	})
+	return stamps, err
+}
+
+// newSandbox copies dir, with permissions, modification times and symbolic links,
+// into a new temporary directory.
+func newSandbox(dir string) (*sandbox, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving '%s': %w", dir, err)
+	}
+	before, err := treeStamps(dir)
+	if err != nil {
+		return nil, fmt.Errorf("scanning '%s' for the sandbox: %w", dir, err)
+	}
+	tmp, err := os.MkdirTemp("", "photonsr-sandbox-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating sandbox: %w", err)
+	}
+	sb := &sandbox{realDir: dir, dir: tmp, before: before}
+	if err := sb.copyTree("sandbox"); err != nil {
+		sb.remove()
+		return nil, err
+	}
+	return sb, nil
+}
+
+// copyTree copies the entries of sb.before from sb.realDir into the existing
+// directory sb.dir, with permissions, modification times and symbolic links. into
+// names sb.dir in errors. Names that only differ in case are an error if sb.dir is
+// on a case-insensitive file system.
+func (sb *sandbox) copyTree(into string) error {
+	names := make([]string, 0, len(sb.before))
+	for name := range sb.before {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Parents before their contents.
+	var dirTimes []string
+	folded := map[string]string{} // Names copied, by lower case.
+	for _, name := range names {
+		stamp := sb.before[name]
+		src := filepath.Join(sb.realDir, filepath.FromSlash(name))
+		dst := filepath.Join(sb.dir, filepath.FromSlash(name))
+		if _, err := os.Lstat(dst); err == nil {
+			return fmt.Errorf("'%s' and '%s' differ only in case, and the %s cannot hold both (case-insensitive file system): %w", folded[strings.ToLower(name)], name, into, photonsr.ErrInvalidOption)
+		}
+		folded[strings.ToLower(name)] = name
+		var err error
+		switch {
+		case stamp.mode.IsDir():
+			err = os.Mkdir(dst, stamp.mode.Perm()|0o700)
+			dirTimes = append(dirTimes, name)
+		case stamp.mode&fs.ModeSymlink != 0:
+			var target string
+			if target, err = os.Readlink(src); err == nil {
+				err = os.Symlink(target, dst)
+			}
+		case stamp.mode.IsRegular():
+			err = sb.placeFile(src, dst, stamp)
+			sb.files++
+			sb.bytes += stamp.size
+		default:
+			continue // Devices, sockets and pipes are not copied.
+		}
+		if err != nil {
+			return fmt.Errorf("copying '%s' into the %s: %w", src, into, err)
+		}
+	}
+	// Directory times last, since creating their entries changed them.
+	for i := len(dirTimes) - 1; i >= 0; i-- {
+		stamp := sb.before[dirTimes[i]]
+		os.Chtimes(filepath.Join(sb.dir, filepath.FromSlash(dirTimes[i])), stamp.modTime, stamp.modTime)
+	}
+	return nil
+}
+
+// remove deletes the sandbox.
+func (sb *sandbox) remove() {
+	os.RemoveAll(sb.dir)
+}
+
+// pathFor returns the copy in the sandbox of path, or path itself if it is not
+// inside the copied tree.
+func (sb *sandbox) pathFor(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(sb.realDir, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return path
+	}
+	return filepath.Join(sb.dir, rel)
+}
+
+// untouched checks that the real tree is still in the state it was copied in. It
+// returns the relative paths that differ, which can only have been changed by
+// another process during the run.
+func (sb *sandbox) untouched() ([]string, error) {
+	after, err := treeStamps(sb.realDir)
+	if err != nil {
+		return nil, fmt.Errorf("rescanning '%s': %w", sb.realDir, err)
+	}
+	var changed []string
+	for name, stamp := range after {
+		if prev, ok := sb.before[name]; !ok || !sameStamp(prev, stamp) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range sb.before {
+		if _, ok := after[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// sameStamp compares two stamps. Directory times are ignored: reading a directory
+// does not change them, but tools scanning the tree in parallel may.
+func sameStamp(a, b fileStamp) bool {
+	if a.mode.IsDir() && b.mode.IsDir() {
+		return a.mode == b.mode
+	}
+	return a.size == b.size && a.mode == b.mode && a.modTime.Equal(b.modTime)
+}
+
+// changes describes how the sandbox differs from the real tree: files created,
+// deleted and modified, the latter with their changed lines.
+func (sb *sandbox) changes() ([]string, error) {
+	after, err := treeStamps(sb.dir)
+	if err != nil {
+		return nil, fmt.Errorf("scanning sandbox: %w", err)
+	}
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range sb.before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var out []string
+	for _, name := range names {
+		prev, existed := sb.before[name]
+		stamp, exists := after[name]
+		display := filepath.Join(sb.realDir, filepath.FromSlash(name))
+		switch {
+		case !exists && !prev.mode.IsDir():
+			out = append(out, fmt.Sprintf("  deleted:  %s", display))
+		case !existed && !stamp.mode.IsDir():
+			out = append(out, fmt.Sprintf("  created:  %s", display))
+		case exists && existed && stamp.mode.IsRegular() && prev.mode.IsRegular():
+			oldData, err := os.ReadFile(filepath.Join(sb.realDir, filepath.FromSlash(name)))
+			if err != nil {
+				return nil, fmt.Errorf("reading '%s': %w", display, err)
+			}
+			newData, err := os.ReadFile(filepath.Join(sb.dir, filepath.FromSlash(name)))
+			if err != nil {
+				return nil, fmt.Errorf("reading sandbox copy of '%s': %w", display, err)
+			}
+			if bytes.Equal(oldData, newData) {
+				if stamp.mode != prev.mode {
+					out = append(out, fmt.Sprintf("  mode:     %s (%s -> %s)", display, prev.mode, stamp.mode))
+				}
+				continue
+			}
+			out = append(out, fmt.Sprintf("  modified: %s", display))
+			out = append(out, photonsr.ChangedLines(oldData, newData, 2*previewMaxDiffLines)...)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Target Scan Statistics ---
+
+// Number of entries listed in a scan summary.
+const (
+	scanTopLargest    = 3
+	scanTopExtensions = 5
+)
+
+// extCount is the number of files with one extension.
+type extCount struct {
+	ext   string // Extension including the dot; empty for files without one.
+	count int
+}
+
+// scanStats summarizes the files a replacement would consider, so the scope can be
+// checked before running on a large tree.
+type scanStats struct {
+	files      int                  // Files matching the pattern.
+	totalSize  int64                // Combined size of those files.
+	largest    []photonsr.SizedFile // Largest files, biggest first (at most scanTopLargest).
+	extensions []extCount           // File counts per extension, most common first.
+}
+
+// scanTarget walks dir and collects statistics about the regular files matching
+// pattern. Only file metadata is read, so it is fast even on large trees.
+func scanTarget(dir, pattern string) (scanStats, error) {
+	var stats scanStats
+	if err := photonsr.ValidatePattern(pattern); err != nil {
+		return stats, err
+	}
+	byExt := map[string]int{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			return nil
+		}
+		if photonsr.IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || photonsr.IsInternalEntry(info) {
+			return nil
+		}
+		if matched, _ := photonsr.MatchesPattern(info.Name(), pattern); !matched {
+			return nil
+		}
+		stats.files++
+		stats.totalSize += info.Size()
+		byExt[fileExtension(info.Name())]++
+		stats.largest = append(stats.largest, photonsr.SizedFile{Path: path, Size: info.Size()})
+		sort.SliceStable(stats.largest, func(i, j int) bool { return stats.largest[i].Size > stats.largest[j].Size })
+		if len(stats.largest) > scanTopLargest {
+			stats.largest = stats.largest[:scanTopLargest]
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("scanning '%s': %w", dir, err)
+	}
+	for ext, n := range byExt {
+		stats.extensions = append(stats.extensions, extCount{ext: ext, count: n})
+	}
+	sort.Slice(stats.extensions, func(i, j int) bool {
+		a, b := stats.extensions[i], stats.extensions[j]
+		return a.count > b.count || (a.count == b.count && a.ext < b.ext)
+	})
+	return stats, nil
+}
+
+// fileExtension returns the lower-cased extension of name including the dot. Dot
+// files such as ".gitignore" have no extension.
+func fileExtension(name string) string {
+	ext := filepath.Ext(name)
+	if ext == name {
+		return ""
+	}
+	return strings.ToLower(ext)
+}
+
+// summaryLines renders the statistics for the wizard's confirmation screen.
+// Paths are shown relative to dir.
+func (s scanStats) summaryLines(dir string) []string {
+	lines := []string{tr("scan.files", photonsr.FormatCount(s.files), photonsr.FormatSize(s.totalSize))}
+	if s.files == 0 {
+		return lines
+	}
+	var largest []string
+	for _, f := range s.largest {
+		name := f.Path
+		if rel, err := filepath.Rel(dir, f.Path); err == nil {
+			name = rel
+		}
+		largest = append(largest, fmt.Sprintf("%s (%s)", name, photonsr.FormatSize(f.Size)))
+	}
+	lines = append(lines, tr("scan.largest", strings.Join(largest, ", ")))
+	var exts []string
+	others := 0
+	for i, e := range s.extensions {
+		if i >= scanTopExtensions {
+			others += e.count
+			continue
+		}
+		name := e.ext
+		if name == "" {
+			name = tr("scan.no_ext")
+		}
+		exts = append(exts, fmt.Sprintf("%s %s", name, photonsr.FormatCount(e.count)))
+	}
+	if others > 0 {
+		exts = append(exts, tr("scan.other_ext", photonsr.FormatCount(others)))
+	}
+	return append(lines, tr("scan.extensions", strings.Join(exts, ", ")))
+}
+
+// scanStatsMsg carries the statistics computed for the wizard's confirmation screen.
+type scanStatsMsg struct {
+	key   string // Scan key (see scanKey) the statistics belong to.
+	stats scanStats
+	err   error
+}
+
+// scanKey identifies the directory and pattern a summary was computed for.
+func scanKey(dir, pattern string) string {
+	return dir + "\x00" + pattern
+}
+
+// scheduleScan starts computing the scan summary when the replace confirmation
+// screen is shown for a directory and pattern not scanned yet.
+func (m *model) scheduleScan() tea.Cmd {
+	if m.step != stepConfirmOperation || m.selectedAction != actionReplace {
+		return nil
+	}
+	key := scanKey(m.targetDir, m.filePattern)
+	if key == m.scanKey {
+		return nil
+	}
+	m.scanKey = key
+	m.scanLoading = true
+	m.scanErr = nil
+	dir, pattern := m.targetDir, m.filePattern
+	return func() tea.Msg {
+		stats, err := scanTarget(dir, pattern)
+		return scanStatsMsg{key: key, stats: stats, err: err}
+	}
+}
+
+// handleScanStats stores a finished scan summary unless the inputs changed meanwhile.
+func (m *model) handleScanStats(msg scanStatsMsg) {
+	if msg.key != m.scanKey {
+		return
+	}
+	m.scanLoading = false
+	m.scan, m.scanErr = msg.stats, msg.err
+}
+
+// maxPatternSuggestions is the number of extensions suggested in the pattern step.
+const maxPatternSuggestions = 6
+
+// patternSuggestionsMsg carries the most common extensions of a target directory.
+type patternSuggestionsMsg struct {
+	dir        string
+	extensions []extCount
+}
+
+// patternSuggestionsCmd counts the extensions of the files in dir in the background.
+func patternSuggestionsCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		stats, _ := scanTarget(dir, "*")
+		var exts []extCount
+		for _, e := range stats.extensions {
+			if e.ext != "" && len(exts) < maxPatternSuggestions {
+				exts = append(exts, e)
+			}
+		}
+		return patternSuggestionsMsg{dir: dir, extensions: exts}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- SQL Dump Mode ---
+
+// With -sql, .sql files are treated as SQL dumps: they are always streamed, one
+// statement at a time, so dumps of any size are rewritten within a small, bounded
+// amount of memory, and -old is never matched across two statements. -sql-tables
+// restricts the replacement to the INSERT, REPLACE and COPY statements (COPY data
+// included) of the listed tables. String literals, quoted identifiers and comments
+// are recognized, so a ";" inside them does not end a statement.
+
+// sqlDump replaces text statement by statement in SQL dumps. It implements
+// StreamTransform.
+type sqlDump struct {
+	old, new []byte
+	tables   map[string]bool // Lower-case table names; nil for every statement.
+}
+
+// newSQLDump validates -sql-tables for a replacement of oldText by newText.
+func newSQLDump(oldText, newText, tables string) (*sqlDump, error) {
+	d := &sqlDump{old: []byte(oldText), new: []byte(newText)}
+	if strings.TrimSpace(tables) != "" {
+		d.tables = map[string]bool{}
+		for _, t := range strings.Split(tables, ",") {
+			t = strings.ToLower(strings.Trim(strings.TrimSpace(t), "`\"[]"))
+			if t == "" {
+				return nil, fmt.Errorf("-sql-tables '%s' has an empty table name: %w", tables, photonsr.ErrInvalidOption)
+			}
+			d.tables[t] = true
+		}
+	}
+	return d, nil
+}
+
+// Applies reports whether the file named name is an SQL file.
+func (d *sqlDump) Applies(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".sql")
+}
+
+// Apply rewrites an SQL dump held in memory.
+func (d *sqlDump) Apply(path string, content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if _, err := d.ApplyStream(&out, bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Lexical states of the SQL scanner.
+const (
+	sqlCode = iota
+	sqlSingleQuoted
+	sqlDoubleQuoted
+	sqlBacktickQuoted
+	sqlLineComment
+	sqlBlockComment
+)
+
+// ApplyStream copies the dump in src to dst, replacing the old text in the
+// statements it applies to.
+func (d *sqlDump) ApplyStream(dst io.Writer, src io.Reader) (int, error) {
+	r := bufio.NewReaderSize(src, 64<<10)
+	w := bufio.NewWriterSize(dst, 64<<10)
+	var stmt []byte
+	count, state, codeStart := 0, sqlCode, -1
+	var quote byte // Closing quote of the current quoted state.
+	next := func() byte {
+		if b, err := r.Peek(1); err == nil {
+			return b[0]
+		}
+		return 0
+	}
+
+	flush := func() error {
+		start := codeStart
+		if start < 0 {
+			start = len(stmt) // Only blanks and comments.
+		}
+		statement := stmt[start:]
+		table, copyData := sqlStatementTable(statement)
+		apply := d.tables == nil || d.tables[table] || d.tables[lastNamePart(table)]
+		if _, err := w.Write(stmt[:start]); err != nil {
+			return err
+		}
+		if apply {
+			count += bytes.Count(statement, d.old)
+			statement = bytes.ReplaceAll(statement, d.old, d.new)
+		}
+		if _, err := w.Write(statement); err != nil {
+			return err
+		}
+		stmt, codeStart = stmt[:0], -1
+		if copyData {
+			n, err := d.copyData(w, r, apply)
+			count += n
+			return err
+		}
+		return nil
+	}
+
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		stmt = append(stmt, c)
+		switch state {
+		case sqlCode:
+			switch {
+			case c == '-' && next() == '-', c == '#':
+				state = sqlLineComment
+				continue
+			case c == '/' && next() == '*':
+				state = sqlBlockComment
+				continue
+			case c == '\'':
+				state, quote = sqlSingleQuoted, c
+			case c == '"':
+				state, quote = sqlDoubleQuoted, c
+			case c == '`':
+				state, quote = sqlBacktickQuoted, c
+			}
+			if codeStart < 0 && c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				codeStart = len(stmt) - 1
+			}
+			if c == ';' {
+				if err := flush(); err != nil {
+					return count, err
+				}
+			}
+		case sqlSingleQuoted, sqlDoubleQuoted, sqlBacktickQuoted:
+			switch {
+			case c == '\\' && state != sqlBacktickQuoted:
+				if b, err := r.ReadByte(); err == nil {
+					stmt = append(stmt, b) // MySQL escape: the next byte is literal.
+				}
+			case c == quote && next() == quote:
+				b, _ := r.ReadByte()
+				stmt = append(stmt, b)
+			case c == quote:
+				state = sqlCode
+			}
+		case sqlLineComment:
+			if c == '\n' {
+				state = sqlCode
+			}
+		case sqlBlockComment:
+			if c == '/' && len(stmt) >= 3 && stmt[len(stmt)-2] == '*' {
+				state = sqlCode
+			}
+		}
+	}
+	if len(stmt) > 0 {
+		if err := flush(); err != nil {
+			return count, err
+		}
+	}
+	return count, w.Flush()
+}
+
+// copyData copies the data lines following a "COPY ... FROM stdin;" statement up to
+// and including its "\." terminator, replacing the old text in them if apply is set.
+func (d *sqlDump) copyData(w io.Writer, r *bufio.Reader, apply bool) (int, error) {
+	count := 0
+	for {
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			var rest []byte
+			rest, err = r.ReadBytes('\n')
+			line = append(append([]byte{}, line...), rest...)
+		}
+		if len(line) > 0 {
+			if apply {
+				count += bytes.Count(line, d.old)
+				line = bytes.ReplaceAll(line, d.old, d.new)
+			}
+			if _, werr := w.Write(line); werr != nil {
+				return count, werr
+			}
+		}
+		if err == io.EOF || bytes.Equal(bytes.TrimRight(line, "\r\n"), []byte(`\.`)) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// sqlStatementTable returns the lower-case table an INSERT, REPLACE or COPY
+// statement writes to, or "" for other statements, and whether the statement is a
+// COPY from stdin followed by data lines.
+func sqlStatementTable(stmt []byte) (string, bool) {
+	words := strings.Fields(strings.ReplaceAll(string(stmt[:min(len(stmt), 512)]), "(", " ("))
+	if len(words) < 2 {
+		return "", false
+	}
+	i := 0
+	switch strings.ToUpper(words[0]) {
+	case "INSERT", "REPLACE":
+		for i = 1; i < len(words) && strings.ToUpper(words[i]) != "INTO"; i++ {
+			switch strings.ToUpper(words[i]) {
+			case "IGNORE", "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY":
+			default:
+				return "", false
+			}
+		}
+		i++
+	case "COPY":
+		i = 1
+		upper := strings.ToUpper(string(stmt))
+		if i < len(words) && strings.Contains(upper, "FROM STDIN") {
+			return sqlTableName(words[i]), true
+		}
+		return "", false
+	default:
+		return "", false
+	}
+	if i >= len(words) {
+		return "", false
+	}
+	return sqlTableName(words[i]), false
+}
+
+// sqlTableName removes the quotes of a possibly qualified table name.
+func sqlTableName(word string) string {
+	return strings.ToLower(strings.NewReplacer("`", "", `"`, "", "[", "", "]", "").Replace(word))
+}
+
+// lastNamePart returns the table of a qualified "schema.table" name.
+func lastNamePart(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Local Usage Statistics ---
+
+// Usage statistics are aggregate counters kept in the state directory only; they
+// are never sent anywhere. "photonsr stats" shows them.
+const (
+	statsFile  = "stats.json"        // Counters in the state directory.
+	noStatsEnv = "PHOTONSR_NO_STATS" // Set to a true value to stop recording.
+
+	// manualEditCost is the assumed time to make one replacement by hand (open
+	// the file, find the text, edit, save), used to estimate the time saved.
+	manualEditCost = 30 * time.Second
+)
+
+// operationStats are the counters of one operation.
+type operationStats struct {
+	Runs          int   `json:"runs"`           // Completed runs, including failed ones.
+	FailedRuns    int   `json:"failed_runs"`    // Runs that ended with an error.
+	FilesScanned  int   `json:"files_scanned"`  // Files examined (replace, verify, lint).
+	ItemsAffected int   `json:"items_affected"` // Files modified, restored, cleaned or pruned.
+	DurationMS    int64 `json:"duration_ms"`    // Total run time, in milliseconds.
+}
+
+// usageStats are the counters of all operations since Since.
+type usageStats struct {
+	Since      time.Time                  `json:"since"`
+	Operations map[string]*operationStats `json:"operations"`
+}
+
+// statsPath returns the path of the statistics file.
+func statsPath() (string, error) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, statsFile), nil
+}
+
+// loadUsageStats returns the recorded statistics; a missing file means none yet.
+func loadUsageStats() (usageStats, error) {
+	stats := usageStats{Operations: map[string]*operationStats{}}
+	path, err := statsPath()
+	if err != nil {
+		return stats, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("reading usage statistics: %w", err)
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return stats, fmt.Errorf("parsing usage statistics '%s': %w", path, err)
+	}
+	if stats.Operations == nil {
+		stats.Operations = map[string]*operationStats{}
+	}
+	return stats, nil
+}
+
+// recordUsage adds one run of operation to the statistics unless $PHOTONSR_NO_STATS
+// is set. Failing to record is not worth interrupting the user for, so errors are
+// ignored.
+func recordUsage(operation string, filesScanned, itemsAffected int, failed bool, duration time.Duration) {
+	if photonsr.EnvBool(noStatsEnv) || operation == "" {
+		return
+	}
+	stats, err := loadUsageStats()
+	if err != nil {
+		return
+	}
+	if stats.Since.IsZero() {
+		stats.Since = time.Now().UTC()
+	}
+	op := stats.Operations[operation]
+	if op == nil {
+		op = &operationStats{}
+		stats.Operations[operation] = op
+	}
+	op.Runs++
+	if failed {
+		op.FailedRuns++
+	}
+	op.FilesScanned += filesScanned
+	op.ItemsAffected += itemsAffected
+	op.DurationMS += duration.Milliseconds()
+
+	path, err := statsPath()
+	if err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil || photonsr.FS.MkdirAll(filepath.Dir(path), 0o700) != nil {
+		return
+	}
+	photonsr.WriteFileAtomic(path, append(data, '\n'), 0o600)
+}
+
+// timeSaved estimates the time saved by the recorded replacements: manualEditCost
+// per modified file, less the time the runs took.
+func (s usageStats) timeSaved() time.Duration {
+	op := s.Operations["replace"]
+	if op == nil {
+		return 0
+	}
+	saved := time.Duration(op.ItemsAffected)*manualEditCost - time.Duration(op.DurationMS)*time.Millisecond
+	if saved < 0 {
+		return 0
+	}
+	return saved
+}
+
+// writeUsageStats prints the statistics as a table, one line per operation.
+func writeUsageStats(w io.Writer, s usageStats) {
+	if len(s.Operations) == 0 {
+		fmt.Fprintf(w, "No usage recorded yet.\n")
+		return
+	}
+	fmt.Fprintf(w, "Usage since %s (stored locally in %s; set %s=1 to stop recording):\n", s.Since.Local().Format("2006-01-02"), statsFile, noStatsEnv)
+	names := make([]string, 0, len(s.Operations))
+	for name := range s.Operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op := s.Operations[name]
+		parts := []string{fmt.Sprintf("%d run(s)", op.Runs)}
+		if op.FailedRuns > 0 {
+			parts = append(parts, fmt.Sprintf("%d failed", op.FailedRuns))
+		}
+		if op.FilesScanned > 0 {
+			parts = append(parts, fmt.Sprintf("%s file(s) scanned", photonsr.FormatCount(op.FilesScanned)))
+		}
+		if op.ItemsAffected > 0 {
+			parts = append(parts, fmt.Sprintf("%s file(s) %s", photonsr.FormatCount(op.ItemsAffected), operationVerb(name)))
+		}
+		parts = append(parts, fmt.Sprintf("%s total", photonsr.FormatDuration(time.Duration(op.DurationMS)*time.Millisecond)))
+		fmt.Fprintf(w, "  %-8s %s\n", name, strings.Join(parts, ", "))
+	}
+	if saved := s.timeSaved(); saved > 0 {
+		fmt.Fprintf(w, "Estimated time saved: %s (%s per modified file, less run time).\n", photonsr.FormatDuration(saved), manualEditCost)
+	}
+}
+
+// writeUsageStatsJSON writes the statistics to w as indented JSON.
+func writeUsageStatsJSON(w io.Writer, s usageStats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("encoding usage statistics: %w", err)
+	}
+	return nil
+}
+
+// writeUsageStatsNDJSON writes the statistics to w as one "operation" record per
+// operation followed by a "summary" record (see ndjsonWriter).
+func writeUsageStatsNDJSON(w io.Writer, s usageStats, crlf bool) error {
+	out := newNDJSONWriter(w, crlf)
+	names := make([]string, 0, len(s.Operations))
+	for name := range s.Operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := out.write("operation", struct {
+			Name string `json:"name"`
+			*operationStats
+		}{name, s.Operations[name]}); err != nil {
+			return err
+		}
+	}
+	return out.write("summary", struct {
+		Since       time.Time `json:"since"`
+		TimeSavedMS int64     `json:"time_saved_ms"`
+	}{s.Since, s.timeSaved().Milliseconds()})
+}
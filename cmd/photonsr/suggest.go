@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Did-You-Mean Suggestions ---
+
+// When a replacement finds its old text nowhere, the text is often mistyped. The
+// CLI and the wizard then look for the strings closest to it that the files do
+// contain, and suggest them: "Did you mean "docker-compose" instead of
+// "docker-comopse"?". Only a bounded sample of the files is read, so that a miss
+// in a large tree is not followed by a long search.
+
+// Bounds of the search for suggestions.
+const (
+	suggestMaxFiles    = 500     // Files read.
+	suggestMaxBytes    = 8 << 20 // Bytes read in total.
+	suggestMaxFileSize = 1 << 20 // Larger files are not read.
+	suggestLimit       = 3       // Suggestions returned.
+)
+
+// suggestion is a string close to an old text that was not found.
+type suggestion struct {
+	Text     string
+	Distance int // Edits turning Text into the old text.
+	Count    int // Occurrences in the files read.
+}
+
+// suggestMaxDistance returns the edits a suggestion for term may be away from it:
+// a third of its length, between 1 and 3. Terms of fewer than 3 characters get no
+// suggestions (0), as too much would be close to them.
+func suggestMaxDistance(term string) int {
+	n := utf8.RuneCountInString(term)
+	if n < 3 {
+		return 0
+	}
+	return min(max(n/3, 1), 3)
+}
+
+// suggestOldText returns the strings closest to opts.OldText found in a sample of
+// the files opts selects, the closest and most frequent first. Runs with rules, a
+// regular expression, context conditions (-near, -not-preceded-by, ...), -line-mode
+// or a transform get none.
+func suggestOldText(opts photonsr.ReplaceOptions) []suggestion {
+	maxDistance := suggestMaxDistance(opts.OldText)
+	if maxDistance == 0 || len(opts.Rules) > 0 || opts.UseRegex || opts.Contextual() || opts.Transform != nil {
+		return nil
+	}
+	found := map[string]*suggestion{}
+	files, read := 0, int64(0)
+	filepath.WalkDir(opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if files >= suggestMaxFiles || read >= suggestMaxBytes {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if photonsr.IsInternalEntry(info) || photonsr.TidySkipDirs[d.Name()] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > suggestMaxFileSize {
+			return nil
+		}
+		if _, _, isBackup := photonsr.ParseBackupName(d.Name()); isBackup {
+			return nil
+		}
+		if matched, _ := photonsr.MatchesPattern(d.Name(), opts.Pattern); !matched {
+			return nil
+		}
+		if opts.AllowedPaths != nil && !opts.AllowedPaths[photonsr.CanonicalPath(path)] {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		files++
+		read += int64(len(content))
+		if bytes.IndexByte(content[:min(len(content), photonsr.BinarySniffLen)], 0) >= 0 {
+			return nil
+		}
+		for _, m := range photonsr.FindApproximate(content, opts.OldText, maxDistance) {
+			if m.Distance == 0 {
+				continue // Present, but the file was left alone for another reason.
+			}
+			text := string(content[m.Start:m.End])
+			if s := found[text]; s != nil {
+				s.Count++
+			} else {
+				found[text] = &suggestion{Text: text, Distance: m.Distance, Count: 1}
+			}
+		}
+		return nil
+	})
+	var suggestions []suggestion
+	for _, s := range found {
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Text < b.Text
+	})
+	if len(suggestions) > suggestLimit {
+		suggestions = suggestions[:suggestLimit]
+	}
+	return suggestions
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- Search and Replacement Text Arguments ---
+
+// textArgFlags lists, for -old and -new, the flags that can supply the text. At
+// most one flag of each group may be given.
+var textArgFlags = map[string][]string{
+	"old": {"old", "old-stdin", "old-hex"},
+	"new": {"new", "new-stdin", "new-hex"},
+}
+
+// textArg is the text given for -old or -new through one of its input modes.
+type textArg struct {
+	name      string // "old" or "new".
+	text      string // Value of -old / -new.
+	fromStdin bool   // -old-stdin / -new-stdin: read the text verbatim from standard input.
+	hex       string // -old-hex / -new-hex: the text as hexadecimal bytes.
+}
+
+// resolveTextArgs returns the search and replacement text from their input modes.
+// Standard input can supply only one of them.
+func resolveTextArgs(old, new textArg, stdin io.Reader) (oldText, newText string, err error) {
+	if old.fromStdin && new.fromStdin {
+		return "", "", fmt.Errorf("-old-stdin and -new-stdin cannot both read standard input")
+	}
+	if oldText, err = old.resolve(stdin); err != nil {
+		return "", "", err
+	}
+	if newText, err = new.resolve(stdin); err != nil {
+		return "", "", err
+	}
+	return oldText, newText, nil
+}
+
+// resolve returns the text of a, checking that only one input mode was used.
+func (a textArg) resolve(stdin io.Reader) (string, error) {
+	var given []string
+	for _, name := range textArgFlags[a.name] {
+		if flagWasSet(name) {
+			given = append(given, "-"+name)
+		}
+	}
+	if len(given) > 1 {
+		return "", fmt.Errorf("%s are mutually exclusive", strings.Join(given, ", "))
+	}
+	switch {
+	case a.fromStdin:
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("-%s-stdin: reading standard input: %w", a.name, err)
+		}
+		return string(data), nil
+	case a.hex != "":
+		data, err := decodeHexArg(a.hex)
+		if err != nil {
+			return "", fmt.Errorf("-%s-hex: %w", a.name, err)
+		}
+		return string(data), nil
+	}
+	return a.text, nil
+}
+
+// decodeHexArg decodes hexadecimal bytes, ignoring whitespace and an optional "0x"
+// prefix: "DEADBEEF", "de ad be ef" and "0xdeadbeef" are the same four bytes.
+func decodeHexArg(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s = s[2:]
+	}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hexadecimal bytes: %w", err)
+	}
+	return data, nil
+}
+
+// recordTextOption stores text under name ("old" or "new") in a run record's
+// options. Text that is not valid UTF-8 would not survive JSON, so it is stored
+// hex-encoded under name+"-hex" instead.
+func recordTextOption(options map[string]string, name, text string) {
+	delete(options, name)
+	delete(options, name+"-hex")
+	if utf8.ValidString(text) {
+		options[name] = text
+	} else {
+		options[name+"-hex"] = hex.EncodeToString([]byte(text))
+	}
+}
+
+// textFlagGiven reports whether any flag supplying the text of name ("old" or
+// "new") was given on the command line.
+func textFlagGiven(name string) bool {
+	for _, f := range textArgFlags[name] {
+		if flagWasSet(f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1543 @@
+package main
+
+import (
+	"context" // Used to stop a running operation from the keyboard
+	"errors"  // Used for errors.Is to classify validation errors
+	"fmt"
+	"io"            // Required for io.Writer in list.ItemDelegate
+	"path/filepath" // Used for filepath.Dir in the directory picker
+	"strings"       // Used for strings.Builder and other string manipulations
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss" // For advanced terminal styling
+)
+
+// --- TUI Model and Logic ---
+
+// wizardStep defines the different stages or screens of the interactive TUI wizard.
+type wizardStep int
+
+const (
+	stepChooseAction          wizardStep = iota // Initial step: user selects the main action.
+	stepEnterDir                                // Step: user inputs the target directory.
+	stepRecoverRun                              // Step: user decides what to do with an interrupted run.
+	stepEnterPattern                            // Step: user inputs the file pattern (for 'replace').
+	stepEnterOldText                            // Step: user inputs the text to be searched (for 'replace').
+	stepConfirmIgnoreCase                       // Step: user chooses whether the old text matches regardless of case.
+	stepEnterNewText                            // Step: user inputs the replacement text.
+	stepConfirmBackup                           // Step: user confirms backup creation (for 'replace').
+	stepResolveBackupConflict                   // Step: user decides what to do with existing .bak files.
+	stepConfirmOperation                        // Step: user reviews and confirms the operation.
+	stepAdvancedOptions                         // Step: user adjusts advanced replacement options (opened from the summary).
+	stepShowResult                              // Step: displays the outcome of the operation.
+	stepError                                   // Step: displays an error message.
+	stepTutorialIntro                           // Step: introduces the tutorial and its sample files.
+	stepMatchHeatmap                            // Step: shows where the matches are (opened from the summary).
+)
+
+// Action constants identify the user-selectable operations. Their display titles
+// come from the message catalog (see actionKeys).
+const (
+	actionReplace  = "Replace Text in Files"
+	actionRestore  = "Restore Files from .bak"
+	actionClean    = "Clean .bak Backup Files"
+	actionTutorial = "Tutorial"
+	actionExit     = "Exit"
+)
+
+// actionKeys maps each action to its message catalog key.
+var actionKeys = map[string]string{
+	actionReplace:  "action.replace",
+	actionRestore:  "action.restore",
+	actionClean:    "action.clean",
+	actionTutorial: "action.tutorial",
+	actionExit:     "action.exit",
+}
+
+// Backup conflict choices offered when existing .bak files are detected.
+const (
+	conflictOverwrite = "conflict.overwrite"
+	conflictSkip      = "conflict.skip"
+	conflictVersion   = "conflict.version"
+)
+
+// conflictPolicies maps each backup conflict choice to its ReplaceOptions.BackupPolicy.
+var conflictPolicies = map[string]string{
+	conflictOverwrite: photonsr.BackupPolicyOverwrite,
+	conflictSkip:      photonsr.BackupPolicySkip,
+	conflictVersion:   photonsr.BackupPolicyVersion,
+}
+
+// Recovery choices offered when an interrupted run is found in the target directory.
+const (
+	recoverRollback = "recover.rollback"
+	recoverDiscard  = "recover.discard"
+	recoverIgnore   = "recover.ignore"
+)
+
+// recoverModes maps each recovery choice to its Recover* mode.
+var recoverModes = map[string]string{
+	recoverRollback: photonsr.RecoverRollback,
+	recoverDiscard:  photonsr.RecoverDiscard,
+	recoverIgnore:   photonsr.RecoverIgnore,
+}
+
+// model holds the entire state of the TUI application.
+type model struct {
+	step           wizardStep         // Current wizard step.
+	actionList     list.Model         // List for choosing the main action.
+	inputs         []textinput.Model  // Text input components.
+	focusedInput   int                // Index of the currently focused text input.
+	caseChoice     list.Model         // List for the Yes/No ignore-case choice.
+	backupChoice   list.Model         // List for Yes/No backup confirmation.
+	conflictChoice list.Model         // List for choosing the backup conflict policy.
+	recoverChoice  list.Model         // List for choosing how to recover an interrupted run.
+	spinner        spinner.Model      // Loading spinner.
+	isLoading      bool               // True if a background operation is in progress.
+	cancelRun      context.CancelFunc // Stops the running operation; nil when none runs.
+	stopping       bool               // True once the running operation was asked to stop.
+	resultMessages []string           // Messages to display after an operation.
+	errorMessage   string             // Error message to display.
+	noticeMessages []string           // Informational messages shown above the current step.
+	quitting       bool               // True if the application should quit.
+	accessible     bool               // Screen-reader friendly mode (see wizardConfig).
+
+	// Data collected from the wizard.
+	selectedAction string // e.g., "Replace Text".
+	targetDir      string // Target directory for the operation.
+	filePattern    string // File pattern (glob) for replacement.
+	oldText        string // Text to be replaced.
+	newText        string // Replacement text.
+	ignoreCase     bool   // Whether the old text matches regardless of case.
+	shouldBackup   bool   // Whether to create .bak files.
+	backupPolicy   string // Policy for files whose .bak already exists.
+	forceRestore   bool   // Restore even over files changed after their backup.
+	dryRun         bool   // The operation runs, or ran, as a preview: a dry run of a replacement, the backup diff of a restore or clean.
+	redundantOnly  bool   // The clean is limited to redundant backups, previewed by the backup check.
+	redundantFound int    // Redundant backups found by the backup check.
+
+	tutorial tutorialState // Running onboarding tutorial, if any.
+
+	advanced        advancedOptions // Settings of the advanced options screen.
+	advancedCursor  int             // Highlighted row of the advanced options screen.
+	editingAdvanced bool            // True while the size limit is being typed.
+
+	backupConflicts []string                  // Files that already have a .bak (detected before confirming).
+	interruptedRuns []photonsr.InterruptedRun // Interrupted runs in targetDir still awaiting a decision.
+
+	preview      previewState    // Live preview shown beside the steps on wide terminals.
+	heatmap      heatmapState    // Matches by directory, opened with t from the replace summary.
+	skippedPaths map[string]bool // Directories left out of the replacement in the heatmap.
+	picker       dirPicker       // Directory browser opened with Tab in stepEnterDir.
+	quickPicks   []quickPick     // Recent and suggested directories offered in stepEnterDir.
+	quickIndex   int             // Quick pick copied into the input; -1 for none.
+
+	suggestions     []extCount // Common extensions of targetDir, offered as patterns.
+	suggestionsDir  string     // Directory suggestions were requested for.
+	suggestionIndex int        // Suggestion copied into the pattern input; -1 for none.
+
+	scan         scanStats // Scope summary shown on the replace confirmation screen.
+	scanKey      string    // Directory and pattern scan belongs to (see scanKey).
+	scanLoading  bool      // True while scan is being computed.
+	scanErr      error     // Error that stopped the scan, if any.
+	pickerOpen   bool      // True while the directory browser is shown.
+	resultOffset int       // First result line shown in stepShowResult.
+
+	width  int // Terminal width.
+	height int // Terminal height.
+}
+
+// operationResultMsg is a tea.Msg for results from a background operation.
+type operationResultMsg struct {
+	detailMessages   []string     // Specific messages like "  - Modified: file.txt"
+	conflictMessages []string     // Resolutions applied to existing backups, one per file.
+	skippedMessages  []string     // Files deliberately left untouched, e.g. newer than their backup.
+	warnings         []string     // Non-fatal problems reported through OnWarning.
+	itemsAffected    int          // Number of files modified, restored, or cleaned
+	filesScanned     int          // For 'replace', total files scanned that matched pattern
+	suggestions      []suggestion // For 'replace' without matches, strings close to the old text.
+	redundant        int          // For the backup check, the redundant backups found.
+	interrupted      error        // Set if the operation was stopped before the end; the rest covers what it did until then.
+}
+
+// backupConflictsMsg is a tea.Msg carrying files whose .bak backup already exists.
+type backupConflictsMsg struct {
+	conflicts []string
+	err       error
+}
+
+// operationErrorMsg is a tea.Msg for an error from a background operation.
+type operationErrorMsg struct {
+	err      error
+	warnings []string // Non-fatal problems reported through OnWarning before the error.
+}
+
+// wizardConfig holds start-up options of the TUI wizard.
+type wizardConfig struct {
+	Accessible bool // Screen-reader friendly mode: numbered choices, no spinner animation.
+}
+
+// newWizardModel initializes the TUI model.
+func newWizardModel(cfg wizardConfig) model {
+	delegate := itemDelegate{numbered: cfg.Accessible}
+
+	actionItems := []list.Item{
+		item{id: actionReplace, title: tr("action.replace"), desc: tr("action.replace.desc")},
+		item{id: actionRestore, title: tr("action.restore"), desc: tr("action.restore.desc")},
+		item{id: actionClean, title: tr("action.clean"), desc: tr("action.clean.desc")},
+		item{id: actionTutorial, title: tr("action.tutorial"), desc: tr("action.tutorial.desc")},
+		item{id: actionExit, title: tr("action.exit"), desc: tr("action.exit.desc")},
+	}
+	actionL := list.New(actionItems, delegate, 0, 0)
+	actionL.Title = tr("menu.title")
+	actionL.SetShowStatusBar(false)
+	actionL.SetFilteringEnabled(false)
+	actionL.Styles.Title = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	inputs := make([]textinput.Model, 1) // Typically one active input.
+
+	caseItems := []list.Item{
+		item{id: "yes", title: tr("common.yes"), desc: tr("ignore_case.yes.desc")},
+		item{id: "no", title: tr("common.no"), desc: tr("ignore_case.no.desc")},
+	}
+	caseL := list.New(caseItems, delegate, 0, 0)
+	caseL.Title = tr("ignore_case.title")
+	caseL.SetShowStatusBar(false)
+	caseL.SetFilteringEnabled(false)
+	caseL.Styles.Title = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	caseL.Select(1) // Exact matching unless asked otherwise.
+
+	backupItems := []list.Item{
+		item{id: "yes", title: tr("common.yes"), desc: tr("backup.yes.desc")},
+		item{id: "no", title: tr("common.no"), desc: tr("backup.no.desc")},
+	}
+	backupL := list.New(backupItems, delegate, 0, 0)
+	backupL.Title = tr("backup.title")
+	backupL.SetShowStatusBar(false)
+	backupL.SetFilteringEnabled(false)
+	backupL.Styles.Title = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	conflictItems := []list.Item{
+		item{id: conflictOverwrite, title: tr(conflictOverwrite), desc: tr("conflict.overwrite.desc")},
+		item{id: conflictSkip, title: tr(conflictSkip), desc: tr("conflict.skip.desc")},
+		item{id: conflictVersion, title: tr(conflictVersion), desc: tr("conflict.version.desc")},
+	}
+	conflictL := list.New(conflictItems, delegate, 0, 0)
+	conflictL.Title = tr("conflict.title")
+	conflictL.SetShowStatusBar(false)
+	conflictL.SetFilteringEnabled(false)
+	conflictL.Styles.Title = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	recoverItems := []list.Item{
+		item{id: recoverRollback, title: tr(recoverRollback), desc: tr("recover.rollback.desc")},
+		item{id: recoverDiscard, title: tr(recoverDiscard), desc: tr("recover.discard.desc")},
+		item{id: recoverIgnore, title: tr(recoverIgnore), desc: tr("recover.ignore.desc")},
+	}
+	recoverL := list.New(recoverItems, delegate, 0, 0)
+	recoverL.Title = tr("recover.title")
+	recoverL.SetShowStatusBar(false)
+	recoverL.SetFilteringEnabled(false)
+	recoverL.Styles.Title = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205")) // Pink spinner.
+
+	return model{
+		step:           stepChooseAction,
+		actionList:     actionL,
+		inputs:         inputs,
+		caseChoice:     caseL,
+		backupChoice:   backupL,
+		conflictChoice: conflictL,
+		recoverChoice:  recoverL,
+		spinner:        s,
+		accessible:     cfg.Accessible,
+	}
+}
+
+// item implements list.Item for use in list.Model. id identifies the choice
+// independently of its (translated) title.
+type item struct {
+	id, title, desc string
+}
+
+func (i item) Title() string       { return i.title }
+func (i item) Description() string { return i.desc }
+func (i item) FilterValue() string { return i.title } // Used for filtering if enabled.
+
+// itemDelegate implements list.ItemDelegate for custom item rendering.
+// With numbered set, items are prefixed with their 1-based number.
+type itemDelegate struct {
+	numbered bool
+}
+
+func (d itemDelegate) Height() int                               { return 1 } // Or 2 if desc is always shown
+func (d itemDelegate) Spacing() int                              { return 0 }
+func (d itemDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil } // Not used here.
+
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(item)
+	if !ok {
+		return
+	}
+
+	var strBuilder strings.Builder
+	// Styles (can be pre-defined in model or globally for efficiency)
+	itemTitleStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedItemTitleStyle := lipgloss.NewStyle().PaddingLeft(0).Foreground(lipgloss.Color("62")).Bold(true) // A nice green.
+	itemDescStyle := lipgloss.NewStyle().PaddingLeft(4).Faint(true)                                          // Adjusted padding for alignment with "> "
+
+	title := i.Title()
+	if d.numbered {
+		title = fmt.Sprintf("%d. %s", index+1, title)
+	}
+	titleRender := itemTitleStyle.Render(title)
+	if index == m.Index() { // Is this item selected?
+		titleRender = selectedItemTitleStyle.Render("> " + title)
+	}
+	strBuilder.WriteString(titleRender)
+
+	// Only render description if it exists and maybe only for selected/hovered or if Height allows
+	// For simplicity here, render if exists. For a cleaner look with Height=1, desc could be omitted or shown elsewhere.
+	// If Height() is 1, this will likely be truncated or overlap.
+	// If you want multi-line items, delegate.Height() should be > 1.
+	if i.Description() != "" {
+		// Ensuring desc is on a new line if titles are single line
+		strBuilder.WriteString("\n")
+		descRender := itemDescStyle.Render(i.Description())
+		strBuilder.WriteString(descRender)
+	}
+	// Ensure consistent line breaks for item height
+	strBuilder.WriteString("\n")
+
+	fmt.Fprint(w, strBuilder.String())
+}
+
+// Init is the first command run when the Bubble Tea application starts.
+func (m model) Init() tea.Cmd {
+	if m.accessible {
+		return nil // No animation in accessible mode.
+	}
+	return m.spinner.Tick // Start spinner animation (only visible when isLoading).
+}
+
+// selectByNumber handles a digit key in accessible mode by selecting the matching
+// list item. It reports whether the key selected an item.
+func selectByNumber(l *list.Model, key string) bool {
+	if len(key) != 1 || key[0] < '1' || key[0] > '9' {
+		return false
+	}
+	index := int(key[0] - '1')
+	if index >= len(l.Items()) {
+		return false
+	}
+	l.Select(index)
+	return true
+}
+
+// Update handles incoming messages and updates the model's state. Afterwards the
+// live preview is refreshed if what it depends on has changed.
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	updated := next.(model)
+	previewCmd, scanCmd, suggestCmd := updated.schedulePreview(), updated.scheduleScan(), updated.requestPatternSuggestions()
+	if previewCmd != nil || scanCmd != nil || suggestCmd != nil {
+		return updated, tea.Batch(cmd, previewCmd, scanCmd, suggestCmd)
+	}
+	return updated, cmd
+}
+
+// update handles a single message; see Update.
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		listHeight := msg.Height - 8
+		if listHeight < 4 {
+			listHeight = 4
+		}
+		m.actionList.SetHeight(listHeight) // Use SetHeight for lists
+		m.actionList.SetWidth(m.contentWidth() - 4)
+		m.caseChoice.SetHeight(listHeight)
+		m.caseChoice.SetWidth(m.contentWidth() - 4)
+		m.backupChoice.SetHeight(listHeight)
+		m.backupChoice.SetWidth(m.contentWidth() - 4)
+		m.conflictChoice.SetHeight(listHeight)
+		m.conflictChoice.SetWidth(m.contentWidth() - 4)
+		m.recoverChoice.SetHeight(listHeight)
+		m.recoverChoice.SetWidth(m.contentWidth() - 4)
+
+		if len(m.inputs) > 0 && m.inputs[0].Focused() {
+			inputWidth := m.contentWidth() - 10
+			if inputWidth < 20 {
+				inputWidth = 20
+			}
+			m.inputs[0].Width = inputWidth
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.isLoading && m.cancelRun != nil && !m.stopping && (msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyEsc) {
+			m.stopping = true // Files being rewritten are completed; a second Ctrl+C quits.
+			m.cancelRun()
+			return m, nil
+		}
+		if msg.Type == tea.KeyCtrlC {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		if m.pickerOpen && m.step == stepEnterDir {
+			return m.updatePicker(msg)
+		}
+		if m.splitPane() {
+			switch msg.String() {
+			case "ctrl+n", "ctrl+p":
+				delta := 1
+				if msg.String() == "ctrl+p" {
+					delta = -1
+				}
+				cmd := m.selectPreviewFile(delta)
+				return m, cmd
+			}
+		}
+		if msg.String() == "esc" && m.step > stepChooseAction && !m.isLoading {
+			m.errorMessage = ""
+			if m.step == stepShowResult && m.dryRun {
+				m.dryRun, m.redundantOnly = false, false // Back to the summary the preview was started from.
+				m.resultMessages, m.resultOffset = nil, 0
+				m.step = stepConfirmOperation
+			} else if m.step == stepShowResult || m.step == stepError {
+				m.resetToMainMenu()
+			} else {
+				switch m.selectedAction {
+				case actionReplace:
+					switch m.step {
+					case stepEnterDir:
+						m.resetToMainMenu()
+					case stepEnterPattern:
+						if m.tutorial.active() {
+							m.resetToMainMenu() // The tutorial has no directory step to return to.
+						} else {
+							m.step = stepEnterDir
+							m.setupInputForCurrentStep()
+						}
+					case stepRecoverRun:
+						m.step = stepEnterDir
+						m.setupInputForCurrentStep()
+					case stepEnterOldText:
+						m.step = stepEnterPattern
+						m.setupInputForCurrentStep()
+					case stepConfirmIgnoreCase:
+						m.step = stepEnterOldText
+						m.setupInputForCurrentStep()
+					case stepEnterNewText:
+						if m.tutorial.active() {
+							m.step = stepEnterOldText
+							m.setupInputForCurrentStep()
+						} else {
+							m.step = stepConfirmIgnoreCase
+						}
+					case stepConfirmBackup:
+						m.step = stepEnterNewText
+						m.setupInputForCurrentStep()
+					case stepResolveBackupConflict:
+						m.step = stepConfirmBackup
+					case stepMatchHeatmap:
+						m.step = stepConfirmOperation
+					case stepAdvancedOptions:
+						if m.editingAdvanced {
+							m.editingAdvanced = false
+						} else {
+							m.step = stepConfirmOperation
+						}
+					case stepConfirmOperation:
+						if m.shouldBackup && len(m.backupConflicts) > 0 {
+							m.step = stepResolveBackupConflict
+						} else {
+							m.step = stepConfirmBackup
+						}
+					}
+				case actionRestore, actionClean:
+					switch m.step {
+					case stepEnterDir:
+						m.resetToMainMenu()
+					case stepRecoverRun, stepConfirmOperation:
+						m.step = stepEnterDir
+						m.setupInputForCurrentStep()
+					}
+				default:
+					m.resetToMainMenu()
+				}
+			}
+			return m, nil
+		}
+
+		switch m.step {
+		case stepChooseAction:
+			if m.accessible && selectByNumber(&m.actionList, msg.String()) {
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			if msg.String() == "enter" {
+				selectedItem, ok := m.actionList.SelectedItem().(item)
+				if ok {
+					m.selectedAction = selectedItem.id
+					switch m.selectedAction {
+					case actionReplace, actionRestore, actionClean:
+						m.step = stepEnterDir
+						m.setupInputForCurrentStep()
+					case actionTutorial:
+						m.startTutorial()
+						return m, nil
+					case actionExit:
+						m.quitting = true
+						return m, tea.Quit
+					}
+				}
+			}
+			m.actionList, cmd = m.actionList.Update(msg)
+			cmds = append(cmds, cmd)
+
+		case stepEnterDir:
+			if msg.String() == "tab" {
+				start := strings.TrimSpace(m.inputs[0].Value())
+				if start == "" {
+					start = "."
+				}
+				m.pickerOpen = true
+				cmd := m.picker.open(start)
+				return m, cmd
+			}
+			if (msg.String() == "up" || msg.String() == "down") && len(m.quickPicks) > 0 {
+				if msg.String() == "up" {
+					m.quickIndex = clampInt(m.quickIndex-1, -1, len(m.quickPicks)-1)
+				} else {
+					m.quickIndex = clampInt(m.quickIndex+1, -1, len(m.quickPicks)-1)
+				}
+				value := ""
+				if m.quickIndex >= 0 {
+					value = m.quickPicks[m.quickIndex].path
+				}
+				m.inputs[0].SetValue(value)
+				m.inputs[0].CursorEnd()
+				return m, nil
+			}
+			if msg.String() == "enter" {
+				m.targetDir = strings.TrimSpace(m.inputs[0].Value())
+				if m.targetDir == "" {
+					m.targetDir = "."
+				}
+				m.skippedPaths = nil
+				m.errorMessage = ""
+				if err := photonsr.ValidateDir(m.targetDir); err != nil {
+					switch {
+					case errors.Is(err, photonsr.ErrNotFound):
+						m.errorMessage = tr("err.dir_missing", m.targetDir)
+					case errors.Is(err, photonsr.ErrNotDirectory):
+						m.errorMessage = tr("err.not_dir", m.targetDir)
+					default:
+						m.errorMessage = tr("err.dir_access", m.targetDir, errors.Unwrap(err))
+					}
+					return m, nil
+				}
+				_ = rememberRecentDir(m.targetDir) // Best effort: the list is only a convenience.
+				m.noticeMessages = nil
+				runs, err := findInterruptedRuns(m.targetDir)
+				if err != nil {
+					m.errorMessage = tr("err.recover_check", err)
+					return m, nil
+				}
+				if len(runs) > 0 {
+					m.interruptedRuns = runs
+					m.recoverChoice.Select(0)
+					m.step = stepRecoverRun
+					return m, nil
+				}
+				m.advanceFromDir()
+			} else {
+				m.inputs[0], cmd = m.inputs[0].Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case stepEnterPattern:
+			if (msg.String() == "up" || msg.String() == "down") && len(m.suggestions) > 0 {
+				if msg.String() == "up" {
+					m.suggestionIndex = clampInt(m.suggestionIndex-1, -1, len(m.suggestions)-1)
+				} else {
+					m.suggestionIndex = clampInt(m.suggestionIndex+1, -1, len(m.suggestions)-1)
+				}
+				value := ""
+				if m.suggestionIndex >= 0 {
+					value = "*" + m.suggestions[m.suggestionIndex].ext
+				}
+				m.inputs[0].SetValue(value)
+				m.inputs[0].CursorEnd()
+				return m, nil
+			}
+			if msg.String() == "enter" {
+				m.filePattern = strings.TrimSpace(m.inputs[0].Value())
+				if m.filePattern == "" {
+					m.filePattern = "*"
+				}
+				m.errorMessage = ""
+				if err := photonsr.ValidatePattern(m.filePattern); err != nil {
+					m.errorMessage = tr("err.bad_pattern", errors.Unwrap(err))
+					return m, nil
+				}
+				m.step = stepEnterOldText
+				m.setupInputForCurrentStep()
+			} else {
+				m.inputs[0], cmd = m.inputs[0].Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case stepEnterOldText:
+			if msg.String() == "enter" {
+				m.oldText = m.inputs[0].Value()
+				m.errorMessage = ""
+				if m.oldText == "" && m.selectedAction == actionReplace {
+					m.errorMessage = tr("err.old_empty")
+					return m, nil
+				}
+				if m.tutorial.active() { // The tutorial keeps to its six steps.
+					m.step = stepEnterNewText
+					m.setupInputForCurrentStep()
+				} else {
+					m.step = stepConfirmIgnoreCase
+				}
+			} else {
+				m.inputs[0], cmd = m.inputs[0].Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case stepConfirmIgnoreCase:
+			if m.accessible && selectByNumber(&m.caseChoice, msg.String()) {
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			if msg.String() == "enter" {
+				if selectedItem, ok := m.caseChoice.SelectedItem().(item); ok {
+					m.ignoreCase = selectedItem.id == "yes"
+					m.step = stepEnterNewText
+					m.setupInputForCurrentStep()
+				}
+				return m, nil
+			}
+			m.caseChoice, cmd = m.caseChoice.Update(msg)
+			cmds = append(cmds, cmd)
+
+		case stepEnterNewText:
+			if msg.String() == "enter" {
+				m.newText = m.inputs[0].Value()
+				m.step = stepConfirmBackup
+			} else {
+				m.inputs[0], cmd = m.inputs[0].Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case stepConfirmBackup:
+			if m.accessible && selectByNumber(&m.backupChoice, msg.String()) {
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			if msg.String() == "enter" {
+				selectedItem, ok := m.backupChoice.SelectedItem().(item)
+				if ok {
+					m.shouldBackup = (selectedItem.id == "yes")
+					m.backupConflicts = nil
+					m.forceRestore = false
+					m.backupPolicy = ""
+					if !m.shouldBackup {
+						m.step = stepConfirmOperation
+						return m, nil
+					}
+					m.isLoading = true
+					return m, tea.Batch(m.detectBackupConflictsCmd(), m.spinner.Tick)
+				}
+			}
+			m.backupChoice, cmd = m.backupChoice.Update(msg)
+			cmds = append(cmds, cmd)
+
+		case stepRecoverRun:
+			if m.accessible && selectByNumber(&m.recoverChoice, msg.String()) {
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			if msg.String() == "enter" {
+				if selectedItem, ok := m.recoverChoice.SelectedItem().(item); ok {
+					run := m.interruptedRuns[0]
+					messages, err := photonsr.RecoverRun(run, recoverModes[selectedItem.id])
+					m.noticeMessages = append(m.noticeMessages, messages...)
+					if err != nil {
+						m.errorMessage = tr("err.recover_failed", err)
+						return m, nil
+					}
+					m.interruptedRuns = m.interruptedRuns[1:]
+					m.recoverChoice.Select(0)
+					if len(m.interruptedRuns) == 0 {
+						m.advanceFromDir()
+					}
+				}
+				return m, nil
+			}
+			m.recoverChoice, cmd = m.recoverChoice.Update(msg)
+			cmds = append(cmds, cmd)
+
+		case stepResolveBackupConflict:
+			if m.accessible && selectByNumber(&m.conflictChoice, msg.String()) {
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			}
+			if msg.String() == "enter" {
+				if selectedItem, ok := m.conflictChoice.SelectedItem().(item); ok {
+					m.backupPolicy = conflictPolicies[selectedItem.id]
+					m.step = stepConfirmOperation
+				}
+			}
+			m.conflictChoice, cmd = m.conflictChoice.Update(msg)
+			cmds = append(cmds, cmd)
+
+		case stepConfirmOperation:
+			if msg.String() == "f" && m.selectedAction == actionRestore {
+				m.forceRestore = !m.forceRestore
+			}
+			if msg.String() == "a" && m.selectedAction == actionReplace {
+				m.step = stepAdvancedOptions
+				m.advancedCursor = 0
+				return m, nil
+			}
+			if msg.String() == "t" && m.selectedAction == actionReplace {
+				cmd := m.openHeatmap()
+				return m, cmd
+			}
+			if msg.String() == "s" && len(m.skippedPaths) > 0 && !m.tutorial.active() {
+				m.saveSkippedPaths()
+				return m, nil
+			}
+			preview := (msg.String() == "p" && m.selectedAction == actionReplace) || (msg.String() == "d" && m.selectedAction != actionReplace)
+			check := msg.String() == "r" && m.selectedAction == actionClean
+			if msg.String() == "enter" || preview || check {
+				m.dryRun = preview || check
+				m.redundantOnly = check
+				m.isLoading = true
+				m.resultMessages = nil
+				m.errorMessage = ""
+				cmds = append(cmds, m.performOperationCmd())
+			}
+
+		case stepAdvancedOptions:
+			if m.editingAdvanced {
+				if msg.String() == "enter" {
+					if err := m.advanced.setText(m.advancedCursor, strings.TrimSpace(m.inputs[0].Value())); err != nil {
+						m.errorMessage = err.Error()
+						return m, nil
+					}
+					m.errorMessage = ""
+					m.editingAdvanced = false
+				} else {
+					m.inputs[0], cmd = m.inputs[0].Update(msg)
+					cmds = append(cmds, cmd)
+				}
+				break
+			}
+			switch msg.String() {
+			case "up", "k":
+				m.advancedCursor = clampInt(m.advancedCursor-1, 0, advancedRowCount-1)
+			case "down", "j":
+				m.advancedCursor = clampInt(m.advancedCursor+1, 0, advancedRowCount-1)
+			case "left", "h":
+				m.advanced.adjust(m.advancedCursor, -1)
+			case "right", "l", " ":
+				m.advanced.adjust(m.advancedCursor, 1)
+			case "enter":
+				if textRow(m.advancedCursor) {
+					m.setupInputForCurrentStep()
+					m.inputs[0].SetValue(m.advanced.text(m.advancedCursor))
+					m.inputs[0].Width = 20
+					m.inputs[0].CursorEnd()
+					m.editingAdvanced = true
+				} else {
+					m.step = stepConfirmOperation
+				}
+			}
+
+		case stepMatchHeatmap:
+			return m.updateHeatmap(msg)
+
+		case stepTutorialIntro:
+			if msg.String() == "enter" {
+				m.beginTutorialReplace()
+			}
+
+		case stepShowResult, stepError:
+			if msg.Type == tea.KeyEnter && m.step == stepShowResult && m.dryRun && m.redundantOnly && m.redundantFound == 0 {
+				m.dryRun, m.redundantOnly = false, false // Nothing to delete: back to the summary.
+				m.resultMessages, m.resultOffset = nil, 0
+				m.step = stepConfirmOperation
+				return m, nil
+			}
+			if msg.Type == tea.KeyEnter && m.step == stepShowResult && m.dryRun {
+				m.dryRun = false // Go on with the previewed operation.
+				m.isLoading = true
+				m.resultMessages, m.resultOffset = nil, 0
+				cmd := m.performOperationCmd() // Sets the cancel function m must be returned with.
+				return m, cmd
+			}
+			if msg.Type == tea.KeyEnter {
+				m.resetToMainMenu()
+			}
+			if msg.String() == "u" && m.step == stepShowResult && m.tutorial.active() && m.shouldBackup && !m.tutorial.undone && !m.dryRun {
+				cmd := m.undoTutorialReplace()
+				return m, cmd
+			}
+			if m.step == stepShowResult || m.step == stepError {
+				maxOffset := len(m.resultMessages) - m.pageSize()
+				switch msg.String() {
+				case "up", "k":
+					m.resultOffset--
+				case "down", "j":
+					m.resultOffset++
+				case "pgup":
+					m.resultOffset -= m.pageSize()
+				case "pgdown", " ":
+					m.resultOffset += m.pageSize()
+				case "home", "g":
+					m.resultOffset = 0
+				case "end", "G":
+					m.resultOffset = maxOffset
+				}
+				m.resultOffset = clampInt(m.resultOffset, 0, maxOffset)
+			}
+		}
+
+	case dirBatchMsg:
+		cmd := m.picker.handleBatch(msg)
+		return m, cmd
+
+	case previewTickMsg:
+		cmd := m.handlePreviewTick(msg)
+		return m, cmd
+	case previewMsg:
+		cmd := m.handlePreview(msg)
+		return m, cmd
+	case previewDiffMsg:
+		m.handlePreviewDiff(msg)
+		return m, nil
+	case heatmapMsg:
+		m.handleHeatmap(msg)
+		return m, nil
+	case scanStatsMsg:
+		m.handleScanStats(msg)
+		return m, nil
+	case patternSuggestionsMsg:
+		if msg.dir == m.suggestionsDir {
+			m.suggestions = msg.extensions
+		}
+		return m, nil
+
+	case operationResultMsg:
+		m.isLoading = false
+		m.cancelRun, m.stopping = nil, false
+		var finalMessages []string
+		summary := ""
+
+		switch m.selectedAction {
+		case actionReplace:
+			if msg.itemsAffected > 0 && m.dryRun {
+				summary = tr("result.dry_run", msg.itemsAffected)
+			} else if msg.itemsAffected > 0 {
+				summary = tr("result.modified", msg.itemsAffected)
+			} else if msg.filesScanned > 0 {
+				summary = tr("result.old_not_found")
+			} else { // filesScanned == 0
+				summary = tr("result.no_files")
+			}
+		case actionRestore:
+			if msg.itemsAffected > 0 {
+				summary = tr("result.restored", msg.itemsAffected)
+			} else {
+				// Check if core logic provided a "no files found" message
+				noFilesFoundMsgProvided := false
+				for _, detailMsg := range msg.detailMessages {
+					if strings.Contains(detailMsg, "No .bak files found to restore") {
+						summary = tr("result.no_restore")
+						noFilesFoundMsgProvided = true
+						break
+					}
+				}
+				if !noFilesFoundMsgProvided {
+					summary = tr("result.no_restore")
+				}
+			}
+		case actionClean:
+			if msg.itemsAffected > 0 {
+				summary = tr("result.cleaned", msg.itemsAffected)
+			} else {
+				noFilesFoundMsgProvided := false
+				for _, detailMsg := range msg.detailMessages {
+					if strings.Contains(detailMsg, "No .bak files found to clean") {
+						summary = tr("result.no_clean")
+						noFilesFoundMsgProvided = true
+						break
+					}
+				}
+				if !noFilesFoundMsgProvided {
+					summary = tr("result.no_clean")
+				}
+			}
+		}
+
+		m.redundantFound = msg.redundant
+		if m.dryRun && m.redundantOnly {
+			summary = tr("result.backup_check", msg.itemsAffected-msg.redundant, msg.redundant)
+		} else if m.dryRun && m.selectedAction != actionReplace {
+			summary = tr("result.backup_diff", msg.itemsAffected)
+			if msg.itemsAffected == 0 {
+				summary = tr("result.backup_diff_none")
+			}
+		}
+		if msg.interrupted != nil {
+			finalMessages = append(finalMessages, tr("result.interrupted", msg.interrupted))
+		}
+		if summary != "" {
+			finalMessages = append(finalMessages, summary)
+		}
+		for _, s := range msg.suggestions {
+			finalMessages = append(finalMessages, tr("result.did_you_mean", s.Text, m.oldText, s.Count))
+		}
+		if len(msg.detailMessages) > 0 && msg.itemsAffected > 0 { // Only add details if items were affected
+			if summary != "" {
+				finalMessages = append(finalMessages, "")
+			} // Add a blank line for separation
+			finalMessages = append(finalMessages, msg.detailMessages...)
+		}
+
+		if len(msg.skippedMessages) > 0 {
+			header := tr("result.skipped_header")
+			if m.selectedAction == actionReplace {
+				header = tr("result.skipped_limits_header")
+			}
+			finalMessages = append(finalMessages, "", header)
+			finalMessages = append(finalMessages, msg.skippedMessages...)
+		}
+		if len(msg.conflictMessages) > 0 {
+			finalMessages = append(finalMessages, "", tr("result.conflicts_header"))
+			finalMessages = append(finalMessages, msg.conflictMessages...)
+		}
+		if len(msg.warnings) > 0 {
+			finalMessages = append(finalMessages, "", tr("result.warnings_header"))
+			finalMessages = append(finalMessages, msg.warnings...)
+		}
+
+		if len(finalMessages) == 0 { // Fallback if no summary or details
+			finalMessages = append(finalMessages, tr("result.fallback"))
+		}
+
+		m.resultMessages = finalMessages
+		m.step = stepShowResult
+		return m, nil
+
+	case backupConflictsMsg:
+		m.isLoading = false
+		if msg.err != nil {
+			m.errorMessage = tr("err.conflict_check", msg.err)
+			return m, nil
+		}
+		m.backupConflicts = msg.conflicts
+		if len(m.backupConflicts) > 0 {
+			m.step = stepResolveBackupConflict
+			m.conflictChoice.Select(0)
+		} else {
+			m.step = stepConfirmOperation
+		}
+		return m, nil
+
+	case operationErrorMsg:
+		m.isLoading = false
+		m.cancelRun, m.stopping = nil, false
+		m.errorMessage = tr("err.operation_failed", msg.err)
+		m.resultMessages = nil
+		if len(msg.warnings) > 0 {
+			m.resultMessages = append([]string{tr("result.warnings_header")}, msg.warnings...)
+		}
+		m.step = stepError
+		return m, nil
+
+	case spinner.TickMsg:
+		var spCmd tea.Cmd
+		if m.isLoading {
+			m.spinner, spCmd = m.spinner.Update(msg)
+			cmds = append(cmds, spCmd)
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// advanceFromDir moves on from a validated target directory to the next step of the
+// selected action.
+func (m *model) advanceFromDir() {
+	switch m.selectedAction {
+	case actionReplace:
+		m.step = stepEnterPattern
+		m.setupInputForCurrentStep()
+	case actionRestore, actionClean:
+		m.step = stepConfirmOperation
+	}
+}
+
+// requestPatternSuggestions starts counting the extensions in targetDir unless they
+// were already counted for it.
+func (m *model) requestPatternSuggestions() tea.Cmd {
+	if m.step != stepEnterPattern || m.suggestionsDir == m.targetDir {
+		return nil
+	}
+	m.suggestionsDir = m.targetDir
+	m.suggestions = nil
+	return patternSuggestionsCmd(m.targetDir)
+}
+
+// updatePicker handles keys while the directory browser is open.
+func (m model) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.picker
+	switch msg.String() {
+	case "esc":
+		m.pickerOpen = false
+	case "enter":
+		m.inputs[0].SetValue(p.selected())
+		m.inputs[0].CursorEnd()
+		m.pickerOpen = false
+	case "up", "k":
+		p.move(-1, m.pageSize())
+	case "down", "j":
+		p.move(1, m.pageSize())
+	case "pgup":
+		p.move(-m.pageSize(), m.pageSize())
+	case "pgdown":
+		p.move(m.pageSize(), m.pageSize())
+	case "home", "g":
+		p.move(-len(p.entries), m.pageSize())
+	case "end", "G":
+		p.move(len(p.entries), m.pageSize())
+	case "right", "l":
+		if p.cursor < len(p.entries) {
+			return m, p.open(p.selected())
+		}
+	case "left", "h", "backspace":
+		if parent := filepath.Dir(p.dir); parent != p.dir {
+			return m, p.open(parent)
+		}
+	}
+	return m, nil
+}
+
+// pageSize returns the number of list rows that fit on screen in paged views.
+func (m model) pageSize() int {
+	if m.height == 0 {
+		return 20
+	}
+	size := m.height - 8
+	if size < 5 {
+		size = 5
+	}
+	return size
+}
+
+// setupInputForCurrentStep configures the text input field.
+func (m *model) setupInputForCurrentStep() {
+	if len(m.inputs) == 0 {
+		m.inputs = make([]textinput.Model, 1)
+	}
+	ti := textinput.New()
+	switch m.step {
+	case stepEnterDir:
+		ti.Placeholder = m.targetDir
+		if ti.Placeholder == "" {
+			ti.Placeholder = "."
+		}
+		m.quickPicks = quickPicks()
+		m.quickIndex = -1
+	case stepEnterPattern:
+		ti.Placeholder = m.filePattern
+		if ti.Placeholder == "" {
+			ti.Placeholder = "*"
+		}
+		m.suggestionIndex = -1
+	case stepEnterOldText:
+		ti.Placeholder = m.oldText
+	case stepEnterNewText:
+		ti.Placeholder = m.newText
+	case stepAdvancedOptions:
+		ti.Placeholder = "50M"
+		if m.advancedCursor == advancedExclude {
+			ti.Placeholder = "vendor,*.lock"
+		}
+	}
+	if example := m.tutorialExample(); example != "" {
+		ti.SetValue(example)
+		ti.CursorEnd()
+	}
+	ti.Focus()
+	ti.CharLimit = 256
+	currentInputWidth := m.contentWidth() - 10
+	if currentInputWidth < 20 {
+		currentInputWidth = 20
+	}
+	ti.Width = currentInputWidth
+	m.inputs[0] = ti
+	m.focusedInput = 0
+}
+
+// resetToMainMenu resets the model to the initial state.
+func (m *model) resetToMainMenu() {
+	m.endTutorial()
+	m.step = stepChooseAction
+	m.selectedAction = ""
+	m.targetDir = ""
+	m.filePattern = ""
+	m.oldText = ""
+	m.newText = ""
+	m.ignoreCase = false
+	m.caseChoice.Select(1)
+	m.shouldBackup = false
+	m.backupPolicy = ""
+	m.dryRun = false
+	m.redundantOnly = false
+	m.backupConflicts = nil
+	m.forceRestore = false
+	m.advanced = advancedOptions{}
+	m.editingAdvanced = false
+	m.interruptedRuns = nil
+	m.errorMessage = ""
+	m.noticeMessages = nil
+	m.resultMessages = nil
+	m.resultOffset = 0
+	m.pickerOpen = false
+	m.preview = previewState{}
+	m.heatmap = heatmapState{}
+	m.skippedPaths = nil
+	m.scanKey = ""
+	m.suggestions = nil
+	m.suggestionsDir = ""
+	m.actionList.ResetFilter()
+	m.actionList.Select(0)
+	m.isLoading = false
+}
+
+// wizardOperations maps each wizard action to the name of its operation.
+var wizardOperations = map[string]string{
+	actionReplace: "replace",
+	actionRestore: "restore",
+	actionClean:   "clean",
+}
+
+// performOperationCmd creates a tea.Cmd to run the core logic and record it in
+// the usage statistics. The operation can be stopped with m.cancelRun until its
+// result arrives.
+func (m *model) performOperationCmd() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelRun, m.stopping = cancel, false
+	op := *m
+	return func() tea.Msg {
+		defer cancel()
+		started := time.Now()
+		msg := op.runOperation(ctx)
+		if op.tutorial.active() || op.dryRun {
+			return msg // Tutorial runs and dry runs are not real usage.
+		}
+		switch msg := msg.(type) {
+		case operationResultMsg:
+			recordUsage(wizardOperations[op.selectedAction], msg.filesScanned, msg.itemsAffected, false, time.Since(started))
+		case operationErrorMsg:
+			recordUsage(wizardOperations[op.selectedAction], 0, 0, true, time.Since(started))
+		}
+		return msg
+	}
+}
+
+// runOperation runs the selected operation and returns its result message. Once ctx
+// is done, the operation stops and the result covers what it did until then.
+func (m model) runOperation(ctx context.Context) tea.Msg {
+	// The engine never prints; its warnings are listed on the result or error screen.
+	var warnings []string
+	onWarning := func(w photonsr.Warning) {
+		if w.Stage == "Newer" {
+			return
+		} // Restore lists these as skipped files already.
+		warnings = append(warnings, fmt.Sprintf("  - %v", w.Err))
+	}
+	if m.dryRun && m.redundantOnly { // List the orphaned and redundant backups before deleting the latter.
+		report, found, redundant, err := photonsr.PerformBackupCheckCtx(ctx, photonsr.BackupCheckOptions{Path: m.targetDir, OnWarning: onWarning})
+		interrupted := stopped(err)
+		if err != nil && interrupted == nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		return operationResultMsg{detailMessages: report, warnings: warnings, itemsAffected: found, filesScanned: found, redundant: redundant, interrupted: interrupted}
+	}
+	if m.dryRun && m.selectedAction != actionReplace { // Compare the backups before restoring or cleaning them.
+		changes, differing, err := photonsr.PerformBackupDiffCtx(ctx, photonsr.BackupDiffOptions{Path: m.targetDir, OnWarning: onWarning})
+		interrupted := stopped(err)
+		if err != nil && interrupted == nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		return operationResultMsg{detailMessages: changes, warnings: warnings, itemsAffected: differing, filesScanned: differing, interrupted: interrupted}
+	}
+	switch m.selectedAction {
+	case actionReplace:
+		opts := photonsr.ReplaceOptions{
+			Dir: m.targetDir, Pattern: m.filePattern, OldText: m.oldText,
+			NewText: m.newText, IgnoreCase: m.ignoreCase, ShouldBackup: m.shouldBackup,
+			BackupPolicy: m.backupPolicy, DryRun: m.dryRun, OnWarning: onWarning,
+		}
+		m.advanced.apply(&opts)
+		if len(m.skippedPaths) > 0 {
+			allowed, err := photonsr.AllowedPaths(m.targetDir, m.filePattern, m.skippedPaths)
+			if err != nil {
+				return operationErrorMsg{err: err, warnings: warnings}
+			}
+			opts.AllowedPaths = allowed
+		}
+		var conflictMsgs, skippedMsgs []string
+		saved, configPath, err := photonsr.ApplySavedExclusions(&opts)
+		if err != nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		if saved > 0 {
+			skippedMsgs = append(skippedMsgs, fmt.Sprintf("  - %d path(s) excluded for this replacement in %s", saved, configPath))
+		}
+		adminPolicy, err := loadPolicy()
+		if err != nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		notices, err := adminPolicy.enforce(&opts, m.targetDir, false)
+		if err != nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		for _, notice := range notices {
+			warnings = append(warnings, "  - "+notice)
+		}
+		opts.OnBackupConflict = func(path, resolution string) {
+			conflictMsgs = append(conflictMsgs, fmt.Sprintf("  - %s: %s", path, resolution))
+		}
+		opts.OnFileSkipped = func(path string, reason error) {
+			skippedMsgs = append(skippedMsgs, "  - "+reason.Error())
+		}
+		counts := map[string]int{}
+		opts.OnFileReplacements = func(path string, count int) { counts[path] = count }
+		patches := map[string]string{}
+		if m.dryRun {
+			opts.OnFilePatch = func(path, patch string) { patches[path] = patch }
+		}
+		modifiedPaths, scanned, err := photonsr.PerformReplacementCtx(ctx, opts)
+		interrupted := stopped(err)
+		if err != nil && interrupted == nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		if m.dryRun && interrupted == nil && adminPolicy != nil && adminPolicy.RequireDryRun {
+			if abs, err := filepath.Abs(m.targetDir); err == nil {
+				if err := recordDryRun(dryRunKey(abs, opts)); err != nil {
+					warnings = append(warnings, fmt.Sprintf("  - could not record the dry run: %v", err))
+				}
+			}
+		}
+		// PerformReplacement now returns detailed messages for "no files" or "no match" itself if needed,
+		// but TUI constructs its own summary. So, detailMessages here are only for *actual modifications*.
+		var dtlMsgs []string
+		if len(modifiedPaths) > 0 { // Only populate if there were actual modifications
+			for _, f := range modifiedPaths {
+				if m.dryRun {
+					dtlMsgs = append(dtlMsgs, tr("result.would_modify", f, counts[f]))
+					dtlMsgs = append(dtlMsgs, photonsr.PatchLines(patches[f], 0)...)
+				} else {
+					dtlMsgs = append(dtlMsgs, "  - Modified: "+f)
+				}
+			}
+		}
+		var suggestions []suggestion
+		if len(modifiedPaths) == 0 && scanned > 0 && interrupted == nil {
+			suggestions = suggestOldText(opts)
+		}
+		return operationResultMsg{detailMessages: dtlMsgs, conflictMessages: conflictMsgs, skippedMessages: skippedMsgs, warnings: warnings, itemsAffected: len(modifiedPaths), filesScanned: scanned, suggestions: suggestions, interrupted: interrupted}
+
+	case actionRestore:
+		allMsgs, restoredCount, err := photonsr.PerformRestoreCtx(ctx, photonsr.RestoreOptions{Dir: m.targetDir, Force: m.forceRestore, OnWarning: onWarning})
+		interrupted := stopped(err)
+		if err != nil && interrupted == nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		var dtlMsgs, skippedMsgs []string
+		for _, msg := range allMsgs {
+			if strings.HasPrefix(msg, "  - Skipped:") {
+				skippedMsgs = append(skippedMsgs, msg)
+			} else {
+				dtlMsgs = append(dtlMsgs, msg)
+			}
+		}
+		// Filter out the generic "No .bak files found..." from dtlMsgs if restoredCount is 0,
+		// as the TUI summary will handle this. Keep only specific file messages.
+		actualDetailMsgs := []string{}
+		if restoredCount > 0 {
+			for _, msg := range dtlMsgs {
+				if strings.HasPrefix(strings.TrimSpace(msg), "- ") {
+					actualDetailMsgs = append(actualDetailMsgs, msg)
+				}
+			}
+		} else if len(dtlMsgs) == 1 && strings.Contains(dtlMsgs[0], "No .bak files found") {
+			// If the only message is the "no files" summary from core, TUI will make its own.
+			// So, pass empty detailMessages.
+		} else {
+			actualDetailMsgs = dtlMsgs // pass through if it's something else
+		}
+		return operationResultMsg{detailMessages: actualDetailMsgs, skippedMessages: skippedMsgs, warnings: warnings, itemsAffected: restoredCount, filesScanned: restoredCount, interrupted: interrupted}
+
+	case actionClean:
+		dtlMsgs, cleanedCount, err := photonsr.PerformCleanCtx(ctx, photonsr.CleanOptions{Dir: m.targetDir, RedundantOnly: m.redundantOnly, OnWarning: onWarning})
+		interrupted := stopped(err)
+		if err != nil && interrupted == nil {
+			return operationErrorMsg{err: err, warnings: warnings}
+		}
+		actualDetailMsgs := []string{}
+		if cleanedCount > 0 {
+			for _, msg := range dtlMsgs {
+				if strings.HasPrefix(strings.TrimSpace(msg), "- ") {
+					actualDetailMsgs = append(actualDetailMsgs, msg)
+				}
+			}
+		} else if len(dtlMsgs) == 1 && strings.Contains(dtlMsgs[0], "No .bak files found") {
+			// as above
+		} else {
+			actualDetailMsgs = dtlMsgs
+		}
+		return operationResultMsg{detailMessages: actualDetailMsgs, warnings: warnings, itemsAffected: cleanedCount, filesScanned: cleanedCount, interrupted: interrupted}
+	}
+	return operationErrorMsg{err: fmt.Errorf("internal error: unknown action: %s", m.selectedAction)}
+}
+
+// stopped returns err if it tells that the operation was stopped from the wizard
+// (it wraps context.Canceled), to be reported with the partial result, or nil.
+func stopped(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// detectBackupConflictsCmd creates a tea.Cmd that looks for files whose .bak already exists.
+func (m model) detectBackupConflictsCmd() tea.Cmd {
+	opts := photonsr.ReplaceOptions{Dir: m.targetDir, Pattern: m.filePattern}
+	return func() tea.Msg {
+		conflicts, err := photonsr.FindBackupConflicts(opts)
+		return backupConflictsMsg{conflicts: conflicts, err: err}
+	}
+}
+
+// View renders the TUI. On wide terminals the replace steps are shown next to
+// the live preview.
+func (m model) View() string {
+	if m.splitPane() && !m.quitting && !m.isLoading {
+		return m.renderSplit(m.viewStep())
+	}
+	return m.viewStep()
+}
+
+// viewStep renders the current step.
+func (m model) viewStep() string {
+	if m.quitting {
+		return tr("view.goodbye")
+	}
+
+	var b strings.Builder
+	// Styles
+	titleStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1).Foreground(lipgloss.Color("99"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).MarginBottom(1)
+	resultHeaderStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("10")).MarginBottom(1)
+	infoStyle := lipgloss.NewStyle().Faint(true).MarginTop(1)
+	promptStyle := lipgloss.NewStyle().Bold(true)
+
+	if m.isLoading {
+		status := tr("view.processing")
+		if m.stopping {
+			status = tr("view.stopping")
+		}
+		if m.accessible {
+			b.WriteString(status + "\n")
+		} else {
+			b.WriteString(fmt.Sprintf("%s %s\n", m.spinner.View(), status))
+		}
+		if m.cancelRun != nil && !m.stopping {
+			b.WriteString(infoStyle.Render(tr("view.stop_hint")) + "\n")
+		}
+		return b.String()
+	}
+
+	if m.errorMessage != "" {
+		b.WriteString(errorStyle.Render(tr("err.prefix")+m.errorMessage) + "\n")
+	}
+	for _, notice := range m.noticeMessages {
+		b.WriteString(notice + "\n")
+	}
+	if hint := m.tutorialHint(); hint != "" {
+		tutorialStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("62")).Padding(0, 1).MarginBottom(1).Width(m.contentWidth() - 6)
+		b.WriteString(tutorialStyle.Render(hint) + "\n")
+	}
+
+	switch m.step {
+	case stepChooseAction:
+		b.WriteString(m.actionList.View())
+	case stepEnterDir:
+		if m.pickerOpen {
+			b.WriteString(promptStyle.Render(tr("picker.title")) + "\n")
+			b.WriteString(m.picker.view(m.pageSize(), m.width-4))
+			b.WriteString(infoStyle.Render(tr("hint.picker")))
+			break
+		}
+		b.WriteString(promptStyle.Render(tr("prompt.dir")) + "\n")
+		b.WriteString(m.inputs[0].View() + "\n")
+		if len(m.quickPicks) > 0 {
+			b.WriteString("\n" + tr("quickpick.title") + "\n")
+			for i, p := range m.quickPicks {
+				prefix := "  "
+				if i == m.quickIndex {
+					prefix = "> "
+				}
+				b.WriteString(prefix + photonsr.TruncateMiddle(p.path, m.contentWidth()-24) + " (" + p.label() + ")\n")
+			}
+		}
+		b.WriteString(infoStyle.Render(tr("hint.dir_input")))
+	case stepEnterPattern:
+		b.WriteString(promptStyle.Render(tr("prompt.pattern")) + "\n")
+		b.WriteString(m.inputs[0].View() + "\n")
+		if len(m.suggestions) > 0 {
+			chip := lipgloss.NewStyle().Padding(0, 1)
+			selectedChip := chip.Reverse(true)
+			var chips []string
+			for i, e := range m.suggestions {
+				label := fmt.Sprintf("*%s (%s)", e.ext, photonsr.FormatCount(e.count))
+				if i == m.suggestionIndex {
+					chips = append(chips, selectedChip.Render(label))
+				} else {
+					chips = append(chips, chip.Render(label))
+				}
+			}
+			b.WriteString("\n" + tr("suggest.title") + "\n" + strings.Join(chips, " ") + "\n")
+			b.WriteString(infoStyle.Render(tr("hint.pattern_input")))
+			break
+		}
+		b.WriteString(infoStyle.Render(tr("hint.confirm_input")))
+	case stepEnterOldText:
+		b.WriteString(promptStyle.Render(tr("prompt.old")) + "\n")
+		b.WriteString(m.inputs[0].View() + "\n")
+		b.WriteString(infoStyle.Render(tr("hint.confirm_input")))
+	case stepEnterNewText:
+		b.WriteString(promptStyle.Render(tr("prompt.new")) + "\n")
+		b.WriteString(m.inputs[0].View() + "\n")
+		b.WriteString(infoStyle.Render(tr("hint.confirm_input")))
+	case stepConfirmIgnoreCase:
+		b.WriteString(m.caseChoice.View())
+	case stepConfirmBackup:
+		b.WriteString(m.backupChoice.View())
+	case stepRecoverRun:
+		run := m.interruptedRuns[0]
+		b.WriteString(promptStyle.Render(tr("recover.found", run.Operation, run.Started, len(run.Writes))) + "\n\n")
+		b.WriteString(m.recoverChoice.View())
+	case stepResolveBackupConflict:
+		b.WriteString(promptStyle.Render(tr("conflict.count", len(m.backupConflicts))) + "\n\n")
+		b.WriteString(m.conflictChoice.View())
+	case stepConfirmOperation:
+		b.WriteString(titleStyle.Render(tr("confirm.title")) + "\n")
+		b.WriteString(tr("confirm.action", tr(actionKeys[m.selectedAction])))
+		b.WriteString(tr("confirm.dir", m.targetDir))
+		if m.selectedAction == actionReplace {
+			b.WriteString(tr("confirm.pattern", m.filePattern))
+			b.WriteString(tr("confirm.old", m.oldText))
+			b.WriteString(tr("confirm.new", m.newText))
+			b.WriteString(tr("confirm.ignore_case", yesNo(m.ignoreCase)))
+			b.WriteString(tr("confirm.backup", yesNo(m.shouldBackup)))
+			if m.shouldBackup && len(m.backupConflicts) > 0 {
+				b.WriteString(tr("confirm.existing", len(m.backupConflicts), m.backupPolicy))
+			}
+			b.WriteString(tr("confirm.advanced", m.advanced.summary()))
+			b.WriteString(tr("confirm.heatmap"))
+			b.WriteString(tr("confirm.dry_run"))
+			if len(m.skippedPaths) > 0 {
+				b.WriteString(tr("confirm.skipped", m.skippedPathList()))
+			}
+			b.WriteString("\n" + promptStyle.Render(tr("scan.title")) + "\n")
+			switch {
+			case m.scanLoading:
+				b.WriteString("  " + tr("scan.loading") + "\n")
+			case m.scanErr != nil:
+				b.WriteString("  " + tr("scan.error", m.scanErr) + "\n")
+			default:
+				for _, line := range m.scan.summaryLines(m.targetDir) {
+					b.WriteString("  " + photonsr.TruncateMiddle(line, m.contentWidth()-4) + "\n")
+				}
+			}
+		}
+		if m.selectedAction == actionRestore {
+			b.WriteString(tr("confirm.force_restore", yesNo(m.forceRestore)))
+		}
+		if m.selectedAction == actionRestore || m.selectedAction == actionClean {
+			b.WriteString(tr("confirm.backup_diff"))
+		}
+		if m.selectedAction == actionClean {
+			b.WriteString(tr("confirm.backup_check"))
+		}
+		b.WriteString("\n" + lipgloss.NewStyle().Bold(true).Render(tr("hint.proceed")))
+	case stepAdvancedOptions:
+		b.WriteString(titleStyle.Render(tr("advanced.title")) + "\n")
+		input := ""
+		if m.editingAdvanced {
+			input = m.inputs[0].View()
+		}
+		b.WriteString(m.advanced.view(m.advancedCursor, input))
+		if m.editingAdvanced {
+			b.WriteString(infoStyle.Render(tr("hint.confirm_input")))
+		} else {
+			b.WriteString(infoStyle.Render(tr("hint.advanced")))
+		}
+	case stepShowResult:
+		b.WriteString(resultHeaderStyle.Render(tr("result.header")) + "\n")
+		if len(m.resultMessages) > 0 {
+			b.WriteString(pageLines(m.resultMessages, m.resultOffset, m.pageSize(), m.width-4))
+		} else {
+			b.WriteString(tr("result.none") + "\n")
+		}
+		if m.dryRun && m.redundantOnly && m.redundantFound > 0 {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.backup_check", m.redundantFound)))
+		} else if m.dryRun && m.redundantOnly {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.backup_check_none")))
+		} else if m.dryRun && m.selectedAction != actionReplace {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.backup_diff")))
+		} else if m.dryRun {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.dry_run")))
+		} else if len(m.resultMessages) > m.pageSize() {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.scroll")))
+		} else {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.menu")))
+		}
+	case stepMatchHeatmap:
+		b.WriteString(titleStyle.Render(tr("heatmap.title", m.targetDir)) + "\n")
+		b.WriteString(m.heatmap.view(m.width-4, m.pageSize(), m.skippedPaths))
+		b.WriteString(infoStyle.Render(tr("hint.heatmap")))
+	case stepTutorialIntro:
+		b.WriteString(titleStyle.Render(tr("tutorial.title")) + "\n")
+		b.WriteString(tr("tutorial.intro", m.tutorial.dir))
+		b.WriteString("\n" + infoStyle.Render(tr("hint.tutorial_start")))
+	case stepError:
+		// Error message is displayed globally at the top, warnings that led to it below.
+		if len(m.resultMessages) > 0 {
+			b.WriteString(pageLines(m.resultMessages, m.resultOffset, m.pageSize(), m.width-4))
+		}
+		if len(m.resultMessages) > m.pageSize() {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.scroll")))
+		} else {
+			b.WriteString("\n" + infoStyle.Render(tr("hint.menu_or_back")))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Wizard Tutorial ---
+
+// tutorialFiles are the sample files of the tutorial sandbox, by relative path.
+var tutorialFiles = map[string]string{
+	"greeting.txt":  "Hello, colourful world!\nPick a colour you like.\n",
+	"notes/todo.md": "# To do\n\n- choose a colour scheme\n- check the colour contrast\n",
+	"config.ini":    "[theme]\naccent_colour = teal\n",
+}
+
+// tutorialState tracks a running tutorial. The zero value means no tutorial.
+type tutorialState struct {
+	dir    string // Sandbox holding the sample files; removed when the tutorial ends.
+	undone bool   // The replacement has been undone from its backups.
+}
+
+// active reports whether a tutorial is running.
+func (t tutorialState) active() bool {
+	return t.dir != ""
+}
+
+// createTutorialDir writes tutorialFiles to a new temporary directory.
+func createTutorialDir() (string, error) {
+	dir, err := os.MkdirTemp("", "photonsr-tutorial-*")
+	if err != nil {
+		return "", fmt.Errorf("creating tutorial directory: %w", err)
+	}
+	for name, content := range tutorialFiles {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("creating tutorial directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("writing tutorial file: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// startTutorial creates the sandbox and shows the tutorial introduction.
+func (m *model) startTutorial() {
+	dir, err := createTutorialDir()
+	if err != nil {
+		m.resetToMainMenu()
+		m.errorMessage = tr("err.tutorial_setup", err)
+		return
+	}
+	m.tutorial = tutorialState{dir: dir}
+	m.step = stepTutorialIntro
+}
+
+// beginTutorialReplace moves from the introduction to the guided replacement.
+func (m *model) beginTutorialReplace() {
+	m.selectedAction = actionReplace
+	m.targetDir = m.tutorial.dir
+	m.step = stepEnterPattern
+	m.setupInputForCurrentStep()
+}
+
+// undoTutorialReplace restores the sandbox from the backups of the replacement.
+func (m *model) undoTutorialReplace() tea.Cmd {
+	m.selectedAction = actionRestore
+	m.forceRestore = false
+	m.tutorial.undone = true
+	m.isLoading = true
+	m.resultMessages = nil
+	m.resultOffset = 0
+	return tea.Batch(m.performOperationCmd(), m.spinner.Tick)
+}
+
+// endTutorial deletes the sandbox. It is safe to call when no tutorial runs.
+func (m *model) endTutorial() {
+	if m.tutorial.active() {
+		os.RemoveAll(m.tutorial.dir)
+	}
+	m.tutorial = tutorialState{}
+}
+
+// tutorialExample returns the input prefilled at the current step of the tutorial,
+// or "" outside the tutorial and for steps already answered.
+func (m model) tutorialExample() string {
+	if !m.tutorial.active() {
+		return ""
+	}
+	switch {
+	case m.step == stepEnterPattern && m.filePattern == "":
+		return "*"
+	case m.step == stepEnterOldText && m.oldText == "":
+		return "colour"
+	case m.step == stepEnterNewText && m.newText == "":
+		return "color"
+	}
+	return ""
+}
+
+// tutorialHint returns the explanation shown above the current step during the
+// tutorial, or "".
+func (m model) tutorialHint() string {
+	if !m.tutorial.active() {
+		return ""
+	}
+	switch m.step {
+	case stepEnterPattern:
+		return tr("tutorial.hint.pattern")
+	case stepEnterOldText:
+		return tr("tutorial.hint.old")
+	case stepEnterNewText:
+		return tr("tutorial.hint.new")
+	case stepConfirmBackup, stepResolveBackupConflict:
+		return tr("tutorial.hint.backup")
+	case stepConfirmOperation:
+		return tr("tutorial.hint.confirm")
+	case stepShowResult:
+		switch {
+		case m.tutorial.undone:
+			return tr("tutorial.hint.done")
+		case m.shouldBackup:
+			return tr("tutorial.hint.undo")
+		default:
+			return tr("tutorial.hint.no_backup")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Unified Diffs ---
+
+// -diff prints what a replacement would change as a unified diff, the format of
+// "diff -u" and "git diff", which patch(1) and "git apply" accept: one "--- a/"
+// and "+++ b/" header per file, paths relative to -dir, then hunks of changed
+// lines with diffContextLines of context. The wizard's dry run shows the same
+// hunks under each file. Lines are matched with Myers' algorithm; past
+// diffMaxEdits differing lines, the changed block is shown as a whole instead.
+
+// colorizeDiff colors the removed and added lines and the hunk headers of a
+// unified diff for r.
+func colorizeDiff(r *lipgloss.Renderer, diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+		case strings.HasPrefix(line, "-"):
+			lines[i] = paint(r, "9", line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = paint(r, "10", line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = paint(r, "14", line)
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Update Notification ---
+
+// Environment variables controlling the update check.
+const (
+	noUpdateCheckEnv = "PHOTONSR_NO_UPDATE_CHECK" // Set to a true value to disable the startup check.
+	updateChannelEnv = "PHOTONSR_UPDATE_CHANNEL"  // "stable" (default) or "prerelease".
+)
+
+// Release channels: stable only considers full releases, prerelease also release
+// candidates and betas.
+const (
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+)
+
+const (
+	updateCheckFile     = "update-check.json"                                          // Cached result in the state directory.
+	updateCheckInterval = 24 * time.Hour                                               // Minimum time between startup checks.
+	updateCheckTimeout  = 3 * time.Second                                              // Bound on one request to the release API.
+	releasesAPI         = "https://api.github.com/repos/arwahdevops/PhotonSR/releases" // GitHub releases of the project.
+)
+
+// releaseInfo is the newest release of a channel.
+type releaseInfo struct {
+	Version string `json:"version"` // Tag of the release, e.g. "v1.4.0".
+	URL     string `json:"url"`     // Release page.
+}
+
+// updateCache is the result of the last check, kept so that startup checks hit the
+// network at most once per updateCheckInterval.
+type updateCache struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	Channel   string      `json:"channel"`
+	Latest    releaseInfo `json:"latest"`
+}
+
+// updateChannel returns the release channel selected by $PHOTONSR_UPDATE_CHANNEL.
+func updateChannel() (string, error) {
+	switch channel := strings.ToLower(strings.TrimSpace(os.Getenv(updateChannelEnv))); channel {
+	case "", ChannelStable:
+		return ChannelStable, nil
+	case ChannelPrerelease:
+		return ChannelPrerelease, nil
+	default:
+		return "", fmt.Errorf("unknown update channel '%s' in $%s (expected stable or prerelease)", channel, updateChannelEnv)
+	}
+}
+
+// fetchLatestRelease asks the release API for the newest release of channel.
+func fetchLatestRelease(ctx context.Context, channel string) (releaseInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesAPI+"?per_page=20", nil)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "photonsr/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return releaseInfo{}, fmt.Errorf("checking for updates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return releaseInfo{}, fmt.Errorf("checking for updates: %s", resp.Status)
+	}
+	var releases []struct {
+		TagName    string `json:"tag_name"`
+		HTMLURL    string `json:"html_url"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return releaseInfo{}, fmt.Errorf("checking for updates: decoding response: %w", err)
+	}
+	var latest releaseInfo
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && channel != ChannelPrerelease) {
+			continue
+		}
+		if latest.Version == "" || compareVersions(r.TagName, latest.Version) > 0 {
+			latest = releaseInfo{Version: r.TagName, URL: r.HTMLURL}
+		}
+	}
+	if latest.Version == "" {
+		return releaseInfo{}, fmt.Errorf("checking for updates: no %s release published", channel)
+	}
+	return latest, nil
+}
+
+// compareVersions compares two semantic versions such as "v1.2.3" and "1.3.0-rc.1",
+// returning -1, 0 or +1. A pre-release sorts before the release it precedes.
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// isReleaseBuild reports whether the binary was built from a release (the version
+// ldflag is set), so that it can be compared with published releases.
+func isReleaseBuild() bool {
+	return version != "dev" && version != ""
+}
+
+// updateNotice returns the one-line notice about latest, or "" if this build is
+// current.
+func updateNotice(latest releaseInfo) string {
+	if !isReleaseBuild() || compareVersions(latest.Version, version) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("A newer PhotonSR is available: %s (you have %s). %s", latest.Version, version, latest.URL)
+}
+
+// checkUpdateNow implements -check-update: it always queries the release API and
+// describes the result.
+func checkUpdateNow() (string, error) {
+	channel, err := updateChannel()
+	if err != nil {
+		return "", err
+	}
+	latest, err := fetchLatestRelease(context.Background(), channel)
+	if err != nil {
+		return "", err
+	}
+	saveUpdateCache(updateCache{CheckedAt: time.Now(), Channel: channel, Latest: latest})
+	if notice := updateNotice(latest); notice != "" {
+		return notice, nil
+	}
+	if !isReleaseBuild() {
+		return fmt.Sprintf("Development build (commit %s); the latest %s release is %s.", commit, channel, latest.Version), nil
+	}
+	return fmt.Sprintf("PhotonSR %s is up to date (latest %s release: %s).", version, channel, latest.Version), nil
+}
+
+// startUpdateCheck arranges the startup check: a notice from the last check is
+// printed to stderr when the process exits, and the check is refreshed in the
+// background if it is older than updateCheckInterval. It does nothing for
+// development builds, when stderr is not a terminal, or when $PHOTONSR_NO_UPDATE_CHECK
+// is set; failures are silent.
+func startUpdateCheck() {
+	if photonsr.EnvBool(noUpdateCheckEnv) || !isReleaseBuild() || !isTerminal(os.Stderr) {
+		return
+	}
+	channel, err := updateChannel()
+	if err != nil {
+		return
+	}
+	cache, _ := loadUpdateCache()
+	if cache.Channel == channel {
+		if notice := updateNotice(cache.Latest); notice != "" {
+			exitHooks = append(exitHooks, func() { fmt.Fprintln(os.Stderr, notice) })
+		}
+	}
+	if cache.Channel != channel || time.Since(cache.CheckedAt) >= updateCheckInterval {
+		go func() {
+			if latest, err := fetchLatestRelease(context.Background(), channel); err == nil {
+				saveUpdateCache(updateCache{CheckedAt: time.Now(), Channel: channel, Latest: latest})
+			}
+		}()
+	}
+}
+
+// loadUpdateCache returns the result of the last check; a missing cache is the zero value.
+func loadUpdateCache() (updateCache, error) {
+	var cache updateCache
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return cache, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, updateCheckFile))
+	if err != nil {
+		return cache, err
+	}
+	err = json.Unmarshal(data, &cache)
+	return cache, err
+}
+
+// saveUpdateCache records the result of a check. Errors are ignored: the check is
+// only repeated sooner.
+func saveUpdateCache(cache updateCache) {
+	dir, err := photonsr.StateDir()
+	if err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil || photonsr.FS.MkdirAll(dir, 0o700) != nil {
+		return
+	}
+	photonsr.WriteFileAtomic(filepath.Join(dir, updateCheckFile), append(data, '\n'), 0o600)
+}
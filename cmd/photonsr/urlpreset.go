@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- URL and Host Name Preset ---
+
+// With -preset url, -old and -new are the old and new URL or host name of a domain
+// migration. The new value must be a valid URL or host name, and -url-check can
+// confirm it resolves or answers before anything is written. Host names only match
+// as whole names: "example.com" does not match "myexample.com", "api.example.com"
+// or "example.com.au". The occurrences replaced are counted by the URL scheme in
+// front of them.
+
+// Presets accepted by -preset.
+const PresetURL = "url"
+
+// Reachability checks accepted by -url-check.
+const (
+	URLCheckNone = "none" // No check.
+	URLCheckDNS  = "dns"  // The new host name must resolve.
+	URLCheckHTTP = "http" // The new URL (https:// for a host name) must answer a HEAD request.
+)
+
+// urlCheckTimeout bounds the reachability check of -url-check.
+const urlCheckTimeout = 10 * time.Second
+
+// urlRewrite replaces a URL or host name by another. It implements Transform.
+type urlRewrite struct {
+	old, new string
+	host     bool // old and new are host names rather than URLs.
+	endsHost bool // old ends with its host, so the name must end where it does.
+
+	mu      sync.Mutex
+	schemes map[string]int // Occurrences replaced, by scheme ("" for none).
+}
+
+// newURLRewrite validates the values of -old and -new for -preset url.
+func newURLRewrite(oldValue, newValue string) (*urlRewrite, error) {
+	u := &urlRewrite{old: oldValue, new: newValue, schemes: map[string]int{}}
+	if !strings.Contains(oldValue, "://") {
+		if strings.Contains(newValue, "://") {
+			return nil, fmt.Errorf("-old is a host name but -new '%s' is a URL; give both as host names or both as URLs: %w", newValue, photonsr.ErrInvalidOption)
+		}
+		for _, h := range []string{oldValue, newValue} {
+			if !validHostPort(h) {
+				return nil, fmt.Errorf("'%s' is not a valid host name: %w", h, photonsr.ErrInvalidOption)
+			}
+		}
+		u.host, u.endsHost = true, true
+		return u, nil
+	}
+	parsed, err := url.Parse(newValue)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || !validHostPort(parsed.Host) {
+		return nil, fmt.Errorf("-new '%s' is not a valid URL with a scheme and a host: %w", newValue, photonsr.ErrInvalidOption)
+	}
+	rest := oldValue[strings.Index(oldValue, "://")+3:]
+	u.endsHost = !strings.ContainsAny(rest, "/?#")
+	return u, nil
+}
+
+// validHostPort reports whether h is a host name or IP address, optionally with a
+// port.
+func validHostPort(h string) bool {
+	if host, port, err := net.SplitHostPort(h); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+		h = host
+	}
+	if net.ParseIP(strings.Trim(h, "[]")) != nil {
+		return true
+	}
+	if len(h) == 0 || len(h) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(h, "."), ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			if !isHostChar(label[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isHostChar reports whether b can occur in a label of a host name.
+func isHostChar(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '_'
+}
+
+// Applies reports true: URLs are rewritten in files of any type.
+func (u *urlRewrite) Applies(name string) bool {
+	return true
+}
+
+// Apply replaces the whole-name occurrences of the old URL or host in content.
+func (u *urlRewrite) Apply(path string, content []byte) ([]byte, error) {
+	old := []byte(u.old)
+	var out []byte
+	counts := map[string]int{}
+	last := 0
+	for pos := 0; ; {
+		i := bytes.Index(content[pos:], old)
+		if i < 0 {
+			break
+		}
+		start, end := pos+i, pos+i+len(old)
+		pos = start + 1
+		if u.host && start > 0 && (isHostChar(content[start-1]) || content[start-1] == '.') {
+			continue
+		}
+		if u.endsHost && end < len(content) && (isHostChar(content[end]) ||
+			content[end] == '.' && end+1 < len(content) && isHostChar(content[end+1])) {
+			continue
+		}
+		out = append(out, content[last:start]...)
+		out = append(out, u.new...)
+		last, pos = end, end
+		counts[schemeBefore(content, start, u.host, u.old)]++
+	}
+	if len(counts) == 0 {
+		return content, nil
+	}
+	out = append(out, content[last:]...)
+	u.mu.Lock()
+	for scheme, n := range counts {
+		u.schemes[scheme] += n
+	}
+	u.mu.Unlock()
+	return out, nil
+}
+
+// schemeBefore returns the lower-case scheme of the URL whose host starts at i in
+// content, or of the old URL itself, or "" for a bare host name.
+func schemeBefore(content []byte, i int, host bool, old string) string {
+	if !host {
+		return strings.ToLower(old[:strings.Index(old, "://")])
+	}
+	if !bytes.HasSuffix(content[:i], []byte("://")) {
+		return ""
+	}
+	j := i - 3
+	for j > 0 && (isHostChar(content[j-1]) && content[j-1] != '_' || content[j-1] == '+' || content[j-1] == '.') {
+		j--
+	}
+	return strings.ToLower(string(content[j : i-3]))
+}
+
+// schemeCounts describes the replaced occurrences by scheme, most frequent first,
+// e.g. "https: 12, http: 3, no scheme: 5".
+func (u *urlRewrite) schemeCounts() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	schemes := make([]string, 0, len(u.schemes))
+	for s := range u.schemes {
+		schemes = append(schemes, s)
+	}
+	sort.Slice(schemes, func(i, j int) bool {
+		if u.schemes[schemes[i]] != u.schemes[schemes[j]] {
+			return u.schemes[schemes[i]] > u.schemes[schemes[j]]
+		}
+		return schemes[i] < schemes[j]
+	})
+	parts := make([]string, len(schemes))
+	for i, s := range schemes {
+		name := s
+		if name == "" {
+			name = "no scheme"
+		}
+		parts[i] = fmt.Sprintf("%s: %d", name, u.schemes[s])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkReachable runs the -url-check check of the new value.
+func (u *urlRewrite) checkReachable(ctx context.Context, mode string) error {
+	ctx, cancel := context.WithTimeout(ctx, urlCheckTimeout)
+	defer cancel()
+	target := u.new
+	if u.host {
+		target = "https://" + u.new + "/"
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("parsing '%s': %w", target, err)
+	}
+	switch mode {
+	case URLCheckNone:
+		return nil
+	case URLCheckDNS:
+		if net.ParseIP(strings.Trim(parsed.Hostname(), "[]")) != nil {
+			return nil
+		}
+		if _, err := net.DefaultResolver.LookupHost(ctx, parsed.Hostname()); err != nil {
+			return fmt.Errorf("new host '%s' does not resolve: %w", parsed.Hostname(), err)
+		}
+		return nil
+	case URLCheckHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+		if err != nil {
+			return fmt.Errorf("checking '%s': %w", target, err)
+		}
+		req.Header.Set("User-Agent", "photonsr/"+version)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("new URL '%s' is not reachable: %w", target, err)
+		}
+		resp.Body.Close() // Any HTTP response, even an error status, shows the server is there.
+		return nil
+	}
+	return fmt.Errorf("unknown -url-check '%s' (expected none, dns or http): %w", mode, photonsr.ErrInvalidOption)
+}
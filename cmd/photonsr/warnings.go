@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	photonsr "github.com/arwahdevops/PhotonSR"
+)
+
+// --- Operation Warnings ---
+
+// warnOut receives the warnings printed by the CLI; -quiet discards them.
+var warnOut io.Writer = os.Stderr
+
+// printWarning is the CLI's OnWarning callback. Warnings are yellow when stderr
+// is colored (see configureColor).
+func printWarning(w photonsr.Warning) {
+	fmt.Fprintln(warnOut, paint(stderrRenderer, "11", w.String()))
+}
@@ -0,0 +1,67 @@
+package photonsr
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// --- Path Confinement ---
+
+// ErrOutsideDir is wrapped by the skip reason of files that Confine keeps out of a
+// run because they resolve outside the target directory.
+var ErrOutsideDir = errors.New("path resolves outside the target directory")
+
+// confinement checks that paths stay inside a directory. The check goes through an
+// os.Root, which resolves every component with openat-style calls and refuses any
+// symbolic link that leads out of the directory, including absolute links; ".."
+// components are resolved the same way. A nil confinement allows every path.
+type confinement struct {
+	dir  string
+	root *os.Root
+}
+
+// openConfinement returns the confinement of dir, or nil if enabled is false.
+func openConfinement(dir string, enabled bool) (*confinement, error) {
+	if !enabled {
+		return nil, nil
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening '%s' for confinement: %w", dir, err)
+	}
+	return &confinement{dir: dir, root: root}, nil
+}
+
+// close releases the directory handle.
+func (c *confinement) close() {
+	if c != nil {
+		c.root.Close()
+	}
+}
+
+// check returns an error wrapping ErrOutsideDir if path, or the nearest of its
+// parents that exists, resolves outside the directory. Other failures (a missing
+// or unreadable file) are left to the operation itself to report.
+func (c *confinement) check(path string) error {
+	if c == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(c.dir, path)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return fmt.Errorf("'%s' is not below '%s': %w", path, c.dir, ErrOutsideDir)
+	}
+	for {
+		_, err := c.root.Stat(rel)
+		switch {
+		case err == nil, errors.Is(err, fs.ErrPermission):
+			return nil
+		case errors.Is(err, fs.ErrNotExist) && rel != ".":
+			rel = filepath.Dir(rel) // A file about to be created: its directory must be inside.
+		default:
+			return fmt.Errorf("'%s': %w", path, ErrOutsideDir)
+		}
+	}
+}
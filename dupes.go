@@ -0,0 +1,238 @@
+package photonsr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// --- Duplicate Finder ---
+
+// "photonsr dupes" reports the files of the target directory whose content is
+// identical. It selects files like a replacement (-pattern, -max-size, -scope),
+// compares sizes first and hashes (SHA-256) only files whose size is shared, so
+// most of a tree is never read. Names that are already hard links to one another
+// count once. With -dupes-link, every duplicate is replaced by a hard or symbolic
+// link to the first file of its group in walk order, which is kept. A duplicate
+// that changed since it was hashed is left alone.
+
+// Ways -dupes-link replaces duplicates.
+const (
+	DupesLinkNone    = ""        // Report only.
+	DupesLinkHard    = "hard"    // Hard link to the kept file; same file system only.
+	DupesLinkSymlink = "symlink" // Relative symbolic link to the kept file.
+)
+
+// DupesOptions holds all parameters for PerformDupes.
+type DupesOptions struct {
+	Dir         string // Target directory for the operation.
+	Pattern     string // File pattern (glob) of the files to compare.
+	MaxFileSize int64  // If > 0, larger files are not compared.
+	Link        string // DupesLinkNone, DupesLinkHard or DupesLinkSymlink.
+
+	// AllowedPaths, when non-nil, restricts the comparison to files whose canonical
+	// path (see CanonicalPath) is in the set, as for ReplaceOptions.
+	AllowedPaths map[string]bool
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformDupes from running with opts.
+func (opts DupesOptions) Validate() error {
+	switch opts.Link {
+	case DupesLinkNone, DupesLinkHard, DupesLinkSymlink:
+	default:
+		return fmt.Errorf("unknown -dupes-link '%s' (expected hard or symlink): %w", opts.Link, ErrInvalidOption)
+	}
+	if err := ValidateDir(opts.Dir); err != nil {
+		return err
+	}
+	return ValidatePattern(opts.Pattern)
+}
+
+// dupeFile is a file compared by PerformDupes.
+type dupeFile struct {
+	path string
+	info os.FileInfo
+}
+
+// PerformDupes finds the files of opts.Dir with identical content and, with
+// opts.Link, replaces the duplicates by links.
+// Returns:
+//   - []string: Messages listing the duplicate groups and the links made.
+//   - int: The number of duplicates replaced by links.
+//   - int: The number of files compared.
+//   - error: A fatal error or the first non-fatal error.
+func PerformDupes(opts DupesOptions) ([]string, int, int, error) {
+	return PerformDupesCtx(context.Background(), opts)
+}
+
+// PerformDupesCtx is PerformDupes with cancellation: once ctx is done, no further file
+// is read and the returned error wraps ctx.Err().
+func PerformDupesCtx(ctx context.Context, opts DupesOptions) ([]string, int, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, 0, err
+	}
+	var firstEncounteredError error
+	fail := func(stage string, err error) {
+		if firstEncounteredError == nil {
+			firstEncounteredError = err
+		}
+		warn(opts.OnWarning, "PerformDupes", stage, err, "Skipping")
+	}
+
+	bySize := map[int64][]dupeFile{}
+	var sizes []int64
+	compared := 0
+	walkErr := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			fail("Access", fmt.Errorf("accessing path '%s': %w", path, errInWalk))
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("duplicate search interrupted: %w", err)
+		}
+		if IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || IsInternalEntry(info) || info.Size() == 0 {
+			return nil
+		}
+		if _, _, isBackup := ParseBackupName(info.Name()); isBackup {
+			return nil
+		}
+		if matched, _ := MatchesPattern(info.Name(), opts.Pattern); !matched {
+			return nil
+		}
+		if (opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize) || (opts.AllowedPaths != nil && !opts.AllowedPaths[CanonicalPath(path)]) {
+			return nil
+		}
+		compared++
+		for _, f := range bySize[info.Size()] {
+			if os.SameFile(f.info, info) {
+				return nil // Already a hard link of a compared file.
+			}
+		}
+		if len(bySize[info.Size()]) == 0 {
+			sizes = append(sizes, info.Size())
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], dupeFile{path, info})
+		return nil
+	})
+	if walkErr != nil {
+		return nil, 0, compared, walkErr
+	}
+
+	var groups [][]dupeFile
+	for _, size := range sizes {
+		if len(bySize[size]) < 2 {
+			continue
+		}
+		byHash := map[string][]dupeFile{}
+		var hashes []string
+		for _, f := range bySize[size] {
+			if err := ctx.Err(); err != nil {
+				return nil, 0, compared, fmt.Errorf("duplicate search interrupted: %w", err)
+			}
+			sum, err := hashFile(f.path)
+			if err != nil {
+				fail("Read", fmt.Errorf("reading file '%s': %w", f.path, err))
+				continue
+			}
+			if len(byHash[sum]) == 0 {
+				hashes = append(hashes, sum)
+			}
+			byHash[sum] = append(byHash[sum], f)
+		}
+		for _, sum := range hashes {
+			if len(byHash[sum]) > 1 {
+				groups = append(groups, byHash[sum])
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return []string{fmt.Sprintf("No duplicates among %d file(s).", compared)}, 0, compared, firstEncounteredError
+	}
+
+	// Largest savings first; ties in walk order.
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i][0].info.Size()*int64(len(groups[i])-1) > groups[j][0].info.Size()*int64(len(groups[j])-1)
+	})
+	var messages []string
+	redundant, reclaimable, linked := 0, int64(0), 0
+	for _, group := range groups {
+		keep := group[0]
+		redundant += len(group) - 1
+		reclaimable += keep.info.Size() * int64(len(group)-1)
+		messages = append(messages, fmt.Sprintf("  - %d copies of %s:", len(group), FormatSize(keep.info.Size())))
+		messages = append(messages, fmt.Sprintf("      %s", RelPath(opts.Dir, keep.path)))
+		for _, dup := range group[1:] {
+			line := fmt.Sprintf("      %s", RelPath(opts.Dir, dup.path))
+			if opts.Link != DupesLinkNone {
+				if err := linkDuplicate(keep, dup, opts.Link); err != nil {
+					fail("Link", err)
+					line += " (not linked)"
+				} else {
+					linked++
+					line += " (linked)"
+				}
+			}
+			messages = append(messages, line)
+		}
+	}
+	header := fmt.Sprintf("%d duplicate group(s): %d redundant file(s), %s reclaimable:", len(groups), redundant, FormatSize(reclaimable))
+	return append([]string{header}, messages...), linked, compared, firstEncounteredError
+}
+
+// hashFile returns the hex SHA-256 of the content of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// linkDuplicate replaces dup by a link of the given kind to keep. The link is
+// created next to dup and renamed over it, so dup is never missing. Nothing is done
+// if either file changed since it was hashed.
+func linkDuplicate(keep, dup dupeFile, kind string) error {
+	for _, f := range []dupeFile{keep, dup} {
+		now, err := os.Lstat(f.path)
+		if err != nil {
+			return fmt.Errorf("checking '%s': %w", f.path, err)
+		}
+		if now.Size() != f.info.Size() || !now.ModTime().Equal(f.info.ModTime()) {
+			return fmt.Errorf("'%s' changed since it was compared", f.path)
+		}
+	}
+	tmp := filepath.Join(filepath.Dir(dup.path), fmt.Sprintf("%slink-%d", tempFilePrefix, os.Getpid()))
+	var err error
+	if kind == DupesLinkHard {
+		err = FS.Link(keep.path, tmp)
+	} else {
+		target, relErr := filepath.Rel(filepath.Dir(dup.path), keep.path)
+		if relErr != nil {
+			target, _ = filepath.Abs(keep.path)
+		}
+		err = FS.Symlink(target, tmp)
+	}
+	if err != nil {
+		return fmt.Errorf("linking '%s' to '%s': %w", dup.path, keep.path, err)
+	}
+	if err := FS.Rename(tmp, dup.path); err != nil {
+		FS.Remove(tmp)
+		return fmt.Errorf("replacing '%s' by a link: %w", dup.path, err)
+	}
+	return nil
+}
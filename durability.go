@@ -0,0 +1,60 @@
+package photonsr
+
+import (
+	"os"
+	"runtime"
+)
+
+// --- Write Durability ---
+
+// By default PhotonSR leaves flushing written files to the operating system: a
+// rewrite is atomic (a new file renamed over the old one), but after a power loss
+// or kernel crash the rename may have reached the disk without the new content.
+// -durability asks for the data, and optionally the directory entry, to be on disk
+// before a write is considered done, at the cost of speed.
+
+// Modes of -durability.
+const (
+	DurabilityNone  = "none"  // Leave flushing to the operating system (fastest).
+	DurabilityDSync = "dsync" // Flush the data of each file before it is renamed into place.
+	DurabilityFSync = "fsync" // Flush each file with its metadata, and its directory after the rename.
+)
+
+// Durability applies to every file written by the process: rewrites, backups,
+// restores and the run journal. It is set from -durability before anything is
+// written.
+var Durability = DurabilityNone
+
+// ValidDurability reports whether mode is a known -durability mode.
+func ValidDurability(mode string) bool {
+	return mode == DurabilityNone || mode == DurabilityDSync || mode == DurabilityFSync
+}
+
+// syncFile flushes f to disk as Durability requires.
+func syncFile(f *os.File) error {
+	switch Durability {
+	case DurabilityDSync:
+		return fdatasync(f)
+	case DurabilityFSync:
+		return f.Sync()
+	}
+	return nil
+}
+
+// syncDir flushes the entries of dir, so that a rename or new file in it survives
+// a crash, if Durability is DurabilityFSync. Windows cannot flush directories
+// and persists renames with the file system journal instead.
+func syncDir(dir string) error {
+	if Durability != DurabilityFSync || runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
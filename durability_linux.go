@@ -0,0 +1,13 @@
+//go:build linux
+
+package photonsr
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdatasync flushes the data of f, and only the metadata needed to read it back.
+func fdatasync(f *os.File) error {
+	return syscall.Fdatasync(int(f.Fd()))
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package photonsr
+
+import "os"
+
+// fdatasync flushes f. Outside Linux it is a full Sync.
+func fdatasync(f *os.File) error {
+	return f.Sync()
+}
@@ -0,0 +1,21 @@
+package photonsr
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// --- Error Taxonomy ---
+
+// Sentinel errors wrapped by engine errors, so callers can classify failures with
+// errors.Is. Permission and missing-file errors wrap fs.ErrPermission and
+// fs.ErrNotExist as returned by the os package.
+var (
+	ErrPermission       = fs.ErrPermission
+	ErrNotFound         = fs.ErrNotExist
+	ErrBinarySkipped    = errors.New("binary file skipped")
+	ErrTooLarge         = errors.New("file exceeds the size limit")
+	ErrChangedDuringRun = errors.New("file changed during the run")
+	ErrInterrupted      = context.Canceled
+)
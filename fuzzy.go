@@ -0,0 +1,105 @@
+package photonsr
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// ApproximateMatch is an occurrence of text within a few edits of a term.
+type ApproximateMatch struct {
+	Start    int // Byte offset of the first byte of the match.
+	End      int // Byte offset just past the last byte of the match.
+	Line     int // 1-based line of Start; lines are separated by '\n'.
+	Distance int // Edits (code points inserted, deleted or substituted) turning the match into the term.
+}
+
+// FindApproximate returns the non-overlapping substrings of content that are at most
+// maxDistance edits away from term, scanned left to right, to find near-misses of
+// it such as typos and spacing variants. Exact occurrences have Distance 0.
+// Matches never span lines. Of overlapping candidates the closest is kept, and of
+// equally close ones the shortest. It returns nil if maxDistance is negative or
+// not less than the length of term in code points, where anything would match.
+func FindApproximate(content []byte, term string, maxDistance int) []ApproximateMatch {
+	pattern := []rune(term)
+	if maxDistance < 0 || maxDistance >= len(pattern) {
+		return nil
+	}
+	var matches []ApproximateMatch
+	line := 1
+	for offset := 0; offset <= len(content); line++ {
+		end := bytes.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			end = len(content) - offset
+		}
+		for _, m := range approximateInLine(content[offset:offset+end], pattern, maxDistance) {
+			m.Start += offset
+			m.End += offset
+			m.Line = line
+			matches = append(matches, m)
+		}
+		offset += end + 1
+	}
+	return matches
+}
+
+// approximateInLine is FindApproximate within one line, with the approximate
+// string matching of Sellers: the edit distance of pattern to the best substring
+// of text ending at each position, where a substring may start anywhere for free.
+func approximateInLine(text []byte, pattern []rune, maxDistance int) []ApproximateMatch {
+	m := len(pattern)
+	// dist[i] and start[i]: the distance of pattern[:i] to the best substring ending
+	// at the current position, and the byte offset that substring starts at.
+	dist, start := make([]int, m+1), make([]int, m+1)
+	prevDist, prevStart := make([]int, m+1), make([]int, m+1)
+	for i := range prevDist {
+		prevDist[i] = i
+	}
+	var matches []ApproximateMatch
+	var best *ApproximateMatch // Best candidate of the current run of ends within maxDistance.
+	for pos := 0; pos < len(text); {
+		r, size := utf8.DecodeRune(text[pos:])
+		dist[0], start[0] = 0, pos+size
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if pattern[i-1] == r {
+				cost = 0
+			}
+			// Prefer the diagonal, then consuming text, so that matches stay short.
+			dist[i], start[i] = prevDist[i-1]+cost, prevStart[i-1]
+			if d := prevDist[i] + 1; d < dist[i] {
+				dist[i], start[i] = d, prevStart[i]
+			}
+			if d := dist[i-1] + 1; d < dist[i] {
+				dist[i], start[i] = d, start[i-1]
+			}
+		}
+		pos += size
+		if dist[m] <= maxDistance {
+			c := ApproximateMatch{Start: start[m], End: pos, Distance: dist[m]}
+			if best == nil || c.Distance < best.Distance || c.Distance == best.Distance && c.End-c.Start < best.End-best.Start {
+				best = &c
+			}
+		} else if best != nil {
+			matches = append(matches, *best)
+			best = nil
+		}
+		dist, prevDist = prevDist, dist
+		start, prevStart = prevStart, start
+	}
+	if best != nil {
+		matches = append(matches, *best)
+	}
+	return dropOverlaps(matches)
+}
+
+// dropOverlaps removes the matches that start before the end of the previous one.
+func dropOverlaps(matches []ApproximateMatch) []ApproximateMatch {
+	kept := matches[:0]
+	for _, m := range matches {
+		if len(kept) > 0 && m.Start < kept[len(kept)-1].End {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
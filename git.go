@@ -0,0 +1,122 @@
+package photonsr
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- Git Helpers ---
+
+// gitRefPrefix is the prefix of option values that name a git reference (e.g. "git:HEAD").
+const gitRefPrefix = "git:"
+
+// ParseGitRefSpec extracts the ref from a "git:<ref>" option value.
+// An empty ref ("git:") defaults to HEAD.
+func ParseGitRefSpec(spec string) (string, error) {
+	if !strings.HasPrefix(spec, gitRefPrefix) {
+		return "", fmt.Errorf("unsupported base '%s' (expected git:<ref>, e.g. git:HEAD)", spec)
+	}
+	ref := strings.TrimPrefix(spec, gitRefPrefix)
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return ref, nil
+}
+
+// runGit runs git with args in dir and returns its standard output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("running git %s: %w", strings.Join(args, " "), err)
+		}
+		return nil, fmt.Errorf("running git %s: %s", strings.Join(args, " "), msg)
+	}
+	return out, nil
+}
+
+// gitRepoRoot returns the absolute top-level directory of the git work tree containing dir.
+func gitRepoRoot(dir string) (string, error) {
+	out, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(strings.TrimSpace(string(out))), nil
+}
+
+// GitChangedFiles returns the absolute paths of files under dir whose working-tree
+// content differs from ref, including untracked (but not ignored) files.
+func GitChangedFiles(dir, ref string) (map[string]bool, error) {
+	root, err := gitRepoRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	changed := map[string]bool{}
+	// With -z, git separates the paths with NUL and leaves them unquoted, whatever
+	// core.quotePath says about non-ASCII names.
+	addPaths := func(out []byte) {
+		for _, name := range strings.Split(string(out), "\x00") {
+			if name != "" {
+				changed[filepath.Join(root, filepath.FromSlash(name))] = true
+			}
+		}
+	}
+
+	diffOut, err := runGit(root, "diff", "--name-only", "-z", "--no-renames", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	addPaths(diffOut)
+	untrackedOut, err := runGit(root, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	addPaths(untrackedOut)
+	return changed, nil
+}
+
+// scopeGitDiff is the -scope value prefix selecting files changed relative to a git ref.
+const scopeGitDiff = "git-diff"
+
+// ResolveScope turns a -scope value into the set of canonical paths to process.
+// Supported scopes: "git-diff" and "git-diff:<ref>" (ref defaults to HEAD).
+func ResolveScope(dir, scope string) (map[string]bool, error) {
+	kind, ref, _ := strings.Cut(scope, ":")
+	if kind != scopeGitDiff {
+		return nil, fmt.Errorf("unsupported scope '%s' (expected %s[:ref])", scope, scopeGitDiff)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	changed, err := GitChangedFiles(dir, ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving scope '%s': %w", scope, err)
+	}
+	return changed, nil
+}
+
+// CanonicalPath returns the absolute, symlink-resolved form of path so it can be
+// compared with paths reported by git. It falls back to the best form available.
+func CanonicalPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// Within reports whether path is below dir.
+func Within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
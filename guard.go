@@ -0,0 +1,76 @@
+package photonsr
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// guarded reports whether the match from start to end in content is left out by
+// rule.NotPrecededBy, rule.NotFollowedBy or rule.Anchor.
+func (rule Rule) guarded(content []byte, start, end int) bool {
+	return rule.NotPrecededBy != "" && rule.adjacent(content[:start], rule.NotPrecededBy, true) ||
+		rule.NotFollowedBy != "" && rule.adjacent(content[end:], rule.NotFollowedBy, false) ||
+		rule.Anchor != "" && !anchored(content, start, end, rule.Anchor)
+}
+
+// anchored reports whether the match from start to end in content is where anchor
+// requires it.
+func anchored(content []byte, start, end int, anchor string) bool {
+	switch anchor {
+	case AnchorBOL:
+		return start == 0 || content[start-1] == '\n'
+	case AnchorEOL:
+		rest := content[end:]
+		return len(rest) == 0 || rest[0] == '\n' || bytes.HasPrefix(rest, []byte("\r\n"))
+	case AnchorBOF:
+		return start == 0
+	case AnchorEOF:
+		rest := content[end:]
+		return len(rest) == 0 || string(rest) == "\n" || string(rest) == "\r\n"
+	}
+	return false
+}
+
+// adjacent reports whether text ends with guard (before set) or starts with it,
+// regardless of case if rule.IgnoreCase is set. Under simple case folding a guard
+// in another case has as many characters, so as many are compared.
+func (rule Rule) adjacent(text []byte, guard string, before bool) bool {
+	if !rule.IgnoreCase {
+		if before {
+			return bytes.HasSuffix(text, []byte(guard))
+		}
+		return bytes.HasPrefix(text, []byte(guard))
+	}
+	n, size := 0, 0
+	for count := utf8.RuneCountInString(guard); count > 0; count-- {
+		if n >= len(text) {
+			return false
+		}
+		if before {
+			_, size = utf8.DecodeLastRune(text[:len(text)-n])
+		} else {
+			_, size = utf8.DecodeRune(text[n:])
+		}
+		n += size
+	}
+	if before {
+		return bytes.EqualFold(text[len(text)-n:], []byte(guard))
+	}
+	return bytes.EqualFold(text[:n], []byte(guard))
+}
+
+// unguarded returns the spans, ascending pairs of start and end offsets in content
+// (possibly followed by group offsets), that the rule's guards and anchor do not
+// leave out.
+func (rule Rule) unguarded(content []byte, spans [][]int) [][]int {
+	if rule.NotPrecededBy == "" && rule.NotFollowedBy == "" && rule.Anchor == "" {
+		return spans
+	}
+	var kept [][]int
+	for _, span := range spans {
+		if !rule.guarded(content, span[0], span[1]) {
+			kept = append(kept, span)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,73 @@
+package photonsr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGuards(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    Rule
+		want    []int // Start offsets of the matches kept.
+	}{
+		{"not followed by", "concatenate cat", Rule{Old: "cat", NotFollowedBy: "e"}, []int{12}},
+		{"not preceded by", "xcat cat", Rule{Old: "cat", NotPrecededBy: "x"}, []int{5}},
+		{"both", "xcat caty cat", Rule{Old: "cat", NotPrecededBy: "x", NotFollowedBy: "y"}, []int{10}},
+		{"at the edges", "cat", Rule{Old: "cat", NotPrecededBy: "xx", NotFollowedBy: "yy"}, []int{0}},
+		{"overlapping occurrence", "baaa", Rule{Old: "aa", NotPrecededBy: "b"}, []int{2}},
+		{"ignore case", "XCAT cat caT!", Rule{Old: "cat", IgnoreCase: true, NotPrecededBy: "x", NotFollowedBy: "!"}, []int{5}},
+		{"guard folds to fewer bytes", "scat", Rule{Old: "cat", IgnoreCase: true, NotPrecededBy: "ſ"}, nil},
+		{"guard folds to more bytes", "ſcat", Rule{Old: "cat", IgnoreCase: true, NotPrecededBy: "S"}, nil},
+		{"case sensitive guard", "Xcat", Rule{Old: "cat", NotPrecededBy: "x"}, []int{1}},
+		{"regexp", "xa1 a2", Rule{Old: `a\d`, Regexp: true, NotPrecededBy: "x"}, []int{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := starts(FindMatches([]byte(tt.content), tt.rule)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMatches(%q) starts at %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyGuards(t *testing.T) {
+	got, _ := Apply([]byte("cat concatenate (cat)"), Rule{Old: "cat", New: "dog", NotFollowedBy: "e", NotPrecededBy: "n"})
+	if want := "dog concatenate (dog)"; string(got) != want {
+		t.Errorf("Apply = %q, want %q", got, want)
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    Rule
+		want    []int // Start offsets of the matches kept.
+	}{
+		{"start of line", "foo x\nfoo foo", Rule{Old: "foo", Anchor: AnchorBOL}, []int{0, 6}},
+		{"end of line", "x foo\r\nfoo y foo", Rule{Old: "foo", Anchor: AnchorEOL}, []int{2, 13}},
+		{"start of content", "foo foo\nfoo", Rule{Old: "foo", Anchor: AnchorBOF}, []int{0}},
+		{"end of content", "foo foo", Rule{Old: "foo", Anchor: AnchorEOF}, []int{4}},
+		{"before the final line break", "foo foo\r\n", Rule{Old: "foo", Anchor: AnchorEOF}, []int{4}},
+		{"not before other lines", "foo\n\n", Rule{Old: "foo", Anchor: AnchorEOF}, nil},
+		{"overlapping occurrence", "aaa", Rule{Old: "aa", Anchor: AnchorEOL}, []int{1}},
+		{"ignore case", "FOO\nfoo", Rule{Old: "foo", IgnoreCase: true, Anchor: AnchorBOL}, []int{0, 4}},
+		{"unknown anchor", "foo", Rule{Old: "foo", Anchor: "middle"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := starts(FindMatches([]byte(tt.content), tt.rule)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMatches(%q) starts at %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAnchorLines(t *testing.T) {
+	got, _ := Apply([]byte("  foo\nfoo bar"), Rule{Old: "foo", Anchor: AnchorBOL, Lines: true})
+	if want := "  foo\n"; string(got) != want {
+		t.Errorf("Apply = %q, want %q", got, want)
+	}
+}
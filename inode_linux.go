@@ -0,0 +1,16 @@
+//go:build linux
+
+package photonsr
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode number of info, or 0 if unavailable.
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return st.Ino
+	}
+	return 0
+}
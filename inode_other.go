@@ -0,0 +1,11 @@
+//go:build !linux
+
+package photonsr
+
+import "os"
+
+// fileInode returns the inode number of info, or 0 if unavailable. Without inode
+// numbers, OrderInode falls back to path order.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
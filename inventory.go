@@ -0,0 +1,220 @@
+package photonsr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- String Inventory ---
+
+// inventoryTextWidth is the width at which strings are shortened in the report;
+// the CSV export has them in full.
+const inventoryTextWidth = 60
+
+// InventoryOptions holds all parameters for PerformInventory.
+type InventoryOptions struct {
+	Dir         string // Target directory for the operation.
+	Pattern     string // File pattern (glob) of the files to read.
+	Expr        string // Regular expression (RE2 syntax) matching the strings to count.
+	Top         int    // Number of strings listed in the report; 0 lists them all.
+	MaxFileSize int64  // If > 0, larger files are counted but not read.
+
+	// AllowedPaths, when non-nil, restricts the inventory to files whose canonical
+	// path (see CanonicalPath) is in the set, as for ReplaceOptions.
+	AllowedPaths map[string]bool
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformInventory from running with opts.
+func (opts InventoryOptions) Validate() error {
+	if opts.Expr == "" {
+		return fmt.Errorf("inventory needs a regular expression matching the strings to count: %w", ErrInvalidOption)
+	}
+	if _, err := regexp.Compile(opts.Expr); err != nil {
+		return fmt.Errorf("invalid regular expression: %v: %w", err, ErrInvalidOption)
+	}
+	if opts.Top < 0 {
+		return fmt.Errorf("-top cannot be negative, got %d: %w", opts.Top, ErrInvalidOption)
+	}
+	if err := ValidatePattern(opts.Pattern); err != nil {
+		return err
+	}
+	return ValidateDir(opts.Dir)
+}
+
+// InventoryEntry is a string counted by PerformInventory.
+type InventoryEntry struct {
+	Text  string // The match, or its first capture group.
+	Count int    // Occurrences.
+	Files int    // Files containing it.
+	First string // First file containing it, relative to the directory.
+}
+
+// PerformInventory counts the strings matching opts.Expr in the files of opts.Dir
+// that match opts.Pattern. Nothing is modified.
+// Returns:
+//   - []string: The report.
+//   - []InventoryEntry: Every string found, most frequent first.
+//   - int: The number of files read.
+//   - error: A fatal error or the first file that could not be read.
+func PerformInventory(opts InventoryOptions) ([]string, []InventoryEntry, int, error) {
+	return PerformInventoryCtx(context.Background(), opts)
+}
+
+// PerformInventoryCtx is PerformInventory with cancellation: once ctx is done, no
+// further file is read and the returned error wraps ctx.Err().
+func PerformInventoryCtx(ctx context.Context, opts InventoryOptions) ([]string, []InventoryEntry, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, 0, err
+	}
+	re := regexp.MustCompile(opts.Expr)
+	group := 0
+	if re.NumSubexp() > 0 {
+		group = 1
+	}
+	var firstEncounteredError error
+	files, unread, binary, containing, total := 0, 0, 0, 0, 0
+	strs := map[string]*InventoryEntry{}
+
+	walkErr := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			accessErr := fmt.Errorf("accessing path '%s': %w", path, errInWalk)
+			if firstEncounteredError == nil {
+				firstEncounteredError = accessErr
+			}
+			warn(opts.OnWarning, "PerformInventory", "Access", accessErr, "Skipping")
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("inventory interrupted: %w", err)
+		}
+		if IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || IsInternalEntry(info) {
+			return nil
+		}
+		if _, _, isBackup := ParseBackupName(info.Name()); isBackup {
+			return nil
+		}
+		if matched, _ := MatchesPattern(info.Name(), opts.Pattern); !matched {
+			return nil
+		}
+		if opts.AllowedPaths != nil && !opts.AllowedPaths[CanonicalPath(path)] {
+			return nil
+		}
+		if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+			unread++
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			readErr := fmt.Errorf("reading file '%s': %w", path, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = readErr
+			}
+			warn(opts.OnWarning, "PerformInventory", "Read", readErr, "Skipping")
+			return nil
+		}
+		if bytes.IndexByte(content[:min(len(content), BinarySniffLen)], 0) >= 0 {
+			binary++
+			return nil
+		}
+		files++
+		seen := map[string]bool{}
+		for _, loc := range re.FindAllSubmatchIndex(content, -1) {
+			start, end := loc[2*group], loc[2*group+1]
+			if start < 0 {
+				continue // The group did not take part in the match.
+			}
+			text := string(content[start:end])
+			e := strs[text]
+			if e == nil {
+				e = &InventoryEntry{Text: text, First: RelPath(opts.Dir, path)}
+				strs[text] = e
+			}
+			e.Count++
+			total++
+			if !seen[text] {
+				seen[text] = true
+				e.Files++
+			}
+		}
+		if len(seen) > 0 {
+			containing++
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, nil, files, walkErr
+	}
+
+	entries := make([]InventoryEntry, 0, len(strs))
+	for _, e := range strs {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Text < b.Text
+	})
+
+	summary := fmt.Sprintf("%s occurrence(s) of %s distinct string(s) in %s of %s file(s) read.", FormatCount(total), FormatCount(len(entries)), FormatCount(containing), FormatCount(files))
+	var left []string
+	if binary > 0 {
+		left = append(left, FormatCount(binary)+" binary")
+	}
+	if unread > 0 {
+		left = append(left, FormatCount(unread)+" larger than -max-size")
+	}
+	if len(left) > 0 {
+		summary = strings.TrimSuffix(summary, ".") + " (not read: " + strings.Join(left, ", ") + ")."
+	}
+	messages := []string{summary}
+	if len(entries) == 0 {
+		return append(messages, fmt.Sprintf("No match of %q.", opts.Expr)), entries, files, firstEncounteredError
+	}
+	shown := entries
+	if opts.Top > 0 && len(shown) > opts.Top {
+		shown = shown[:opts.Top]
+		messages = append(messages, fmt.Sprintf("Top %d by occurrences:", opts.Top))
+	} else {
+		messages = append(messages, "By occurrences:")
+	}
+	messages = append(messages, fmt.Sprintf("  %8s  %6s  %s", "Count", "Files", "String"))
+	for _, e := range shown {
+		messages = append(messages, fmt.Sprintf("  %8s  %6s  %s", FormatCount(e.Count), FormatCount(e.Files), displayString(e.Text)))
+	}
+	if len(shown) < len(entries) {
+		messages = append(messages, fmt.Sprintf("  ... and %s more (see -top, or -csv for all of them)", FormatCount(len(entries)-len(shown))))
+	}
+	return messages, entries, files, firstEncounteredError
+}
+
+// displayString returns s on one line of the report: control characters are
+// escaped as in Go strings and long strings shortened in the middle.
+func displayString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			quoted := strconv.QuoteRune(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return TruncateMiddle(b.String(), inventoryTextWidth)
+}
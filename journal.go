@@ -0,0 +1,425 @@
+package photonsr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Run Journal and Crash Recovery ---
+
+// A replacement run keeps a workspace directory (".photonsr-run-*") in the target
+// directory for as long as it runs. Before a file is rewritten, its original content
+// is saved in the workspace and a "write" entry is appended to the journal; once the
+// run ends normally the workspace is removed. A workspace left behind therefore means
+// a run was interrupted, and its snapshots allow the whole run to be rolled back.
+// Runs of rename-files and move-files journal each rename as a "move" entry, and
+// tidy each removal as a "remove" or "rmdir" entry, which rolling back reverses.
+const (
+	RunWorkspacePrefix = ".photonsr-run-"
+	tempFilePrefix     = ".photonsr-tmp-"
+	JournalFileName    = "journal.jsonl"
+)
+
+// Recovery choices for interrupted runs (the -recover flag).
+const (
+	RecoverAsk      = "ask"      // Prompt on a terminal, otherwise warn and leave the run alone.
+	RecoverRollback = "rollback" // Restore every file the run touched to its original content.
+	RecoverDiscard  = "discard"  // Keep the files as they are and delete the leftovers.
+	RecoverIgnore   = "ignore"   // Leave the interrupted run untouched.
+)
+
+// ValidRecoverMode reports whether mode is a known -recover value.
+func ValidRecoverMode(mode string) bool {
+	switch mode {
+	case RecoverAsk, RecoverRollback, RecoverDiscard, RecoverIgnore:
+		return true
+	}
+	return false
+}
+
+// JournalEntry is one line of a run journal.
+type JournalEntry struct {
+	Op        string `json:"op"`                  // "begin", "write", "move", "remove", "rmdir" or "done".
+	Operation string `json:"operation,omitempty"` // Operation of the run ("begin" only).
+	PID       int    `json:"pid,omitempty"`       // Process that performed the run ("begin" only).
+	Host      string `json:"host,omitempty"`      // Machine that performed the run ("begin" only).
+	Time      string `json:"time,omitempty"`      // RFC 3339 start time ("begin" only).
+	Path      string `json:"path,omitempty"`      // Absolute path of the file being rewritten.
+	Snapshot  string `json:"snapshot,omitempty"`  // Name of the saved original inside the workspace.
+	Mode      uint32 `json:"mode,omitempty"`      // Permission bits of the original file or directory.
+	From      string `json:"from,omitempty"`      // Absolute path the file is moved from ("move" only).
+}
+
+// runJournal records the files rewritten by one run. It is safe for concurrent use.
+type runJournal struct {
+	mu        sync.Mutex
+	workspace string
+	f         *os.File
+	seq       int
+}
+
+// beginRunJournal creates the workspace and journal for a run of operation in dir.
+func beginRunJournal(dir, operation string) (*runJournal, error) {
+	workspace, err := FS.MkdirTemp(dir, RunWorkspacePrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("creating run workspace in '%s': %w", dir, err)
+	}
+	f, err := FS.OpenFile(filepath.Join(workspace, JournalFileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		FS.RemoveAll(workspace)
+		return nil, fmt.Errorf("creating run journal in '%s': %w", workspace, err)
+	}
+	if err := syncDir(dir); err != nil { // So that recovery finds the workspace.
+		f.Close()
+		FS.RemoveAll(workspace)
+		return nil, fmt.Errorf("flushing '%s': %w", dir, err)
+	}
+	j := &runJournal{workspace: workspace, f: f}
+	host, _ := os.Hostname()
+	begin := JournalEntry{Op: "begin", Operation: operation, PID: os.Getpid(), Host: host, Time: time.Now().UTC().Format(time.RFC3339)}
+	if err := j.append(begin); err != nil {
+		j.f.Close()
+		FS.RemoveAll(workspace)
+		return nil, err
+	}
+	return j, nil
+}
+
+// append writes one entry to the journal. The caller must hold j.mu or own j exclusively.
+func (j *runJournal) append(e JournalEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding journal entry: %w", err)
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing run journal: %w", err)
+	}
+	if err := syncFile(j.f); err != nil {
+		return fmt.Errorf("flushing run journal: %w", err)
+	}
+	return nil
+}
+
+// replaceFile saves before in the workspace, journals the write, and then atomically
+// replaces path with after.
+func (j *runJournal) replaceFile(path string, before, after []byte, perm os.FileMode) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving '%s': %w", path, err)
+	}
+	j.mu.Lock()
+	j.seq++
+	snapshot := "snap-" + strconv.Itoa(j.seq)
+	j.mu.Unlock()
+
+	if err := WriteFileAtomic(filepath.Join(j.workspace, snapshot), before, 0o600); err != nil {
+		return fmt.Errorf("saving original of '%s' for recovery: %w", path, err)
+	}
+	j.mu.Lock()
+	err = j.append(JournalEntry{Op: "write", Path: absPath, Snapshot: snapshot, Mode: uint32(perm.Perm())})
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(path, after, perm); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(JournalEntry{Op: "done", Path: absPath})
+}
+
+// moveFile journals the move, and then moves from to to, creating the directories
+// to needs.
+func (j *runJournal) moveFile(from, to string) error {
+	absFrom, err := filepath.Abs(from)
+	if err != nil {
+		return fmt.Errorf("resolving '%s': %w", from, err)
+	}
+	absTo, err := filepath.Abs(to)
+	if err != nil {
+		return fmt.Errorf("resolving '%s': %w", to, err)
+	}
+	j.mu.Lock()
+	err = j.append(JournalEntry{Op: "move", Path: absTo, From: absFrom})
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := FS.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	if err := FS.Rename(from, to); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(to)); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(JournalEntry{Op: "done", Path: absTo})
+}
+
+// removeEmpty journals the removal e ("remove" for an empty file, "rmdir" for an
+// empty directory), and then removes e.Path.
+func (j *runJournal) removeEmpty(e JournalEntry) error {
+	j.mu.Lock()
+	err := j.append(e)
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := FS.Remove(e.Path); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(JournalEntry{Op: "done", Path: e.Path})
+}
+
+// recreateRemoved recreates the empty file or directory removed by the "remove" or
+// "rmdir" entry e. An entry that exists again is left as it is.
+func recreateRemoved(e JournalEntry) error {
+	if e.Op == "rmdir" {
+		if err := FS.Mkdir(e.Path, os.FileMode(e.Mode)); err != nil && !os.IsExist(err) {
+			return err
+		}
+		return FS.Chmod(e.Path, os.FileMode(e.Mode))
+	}
+	f, err := FS.OpenFile(e.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(e.Mode))
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// finish closes the journal and removes the workspace of a run that ended normally.
+func (j *runJournal) finish() error {
+	j.f.Close()
+	if err := FS.RemoveAll(j.workspace); err != nil {
+		return fmt.Errorf("removing run workspace '%s': %w", j.workspace, err)
+	}
+	return nil
+}
+
+// replaceFileStream is replaceFile for files too large to load: the original is
+// cloned or copied to the workspace from disk, and fill writes the new content.
+func (j *runJournal) replaceFileStream(path string, perm os.FileMode, fill func(w io.Writer) error) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving '%s': %w", path, err)
+	}
+	j.mu.Lock()
+	j.seq++
+	snapshot := "snap-" + strconv.Itoa(j.seq)
+	j.mu.Unlock()
+
+	if err := cloneOrCopyFile(path, filepath.Join(j.workspace, snapshot)); err != nil {
+		return fmt.Errorf("saving original of '%s' for recovery: %w", path, err)
+	}
+	j.mu.Lock()
+	err = j.append(JournalEntry{Op: "write", Path: absPath, Snapshot: snapshot, Mode: uint32(perm.Perm())})
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomicFrom(path, perm, fill); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(JournalEntry{Op: "done", Path: absPath})
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it over
+// path, so that path never holds partially written content. Both are flushed as
+// Durability requires.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteFileAtomicFrom(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteFileAtomicFrom is WriteFileAtomic with the content produced by fill.
+// If path is a symlink, its target is replaced and the link is kept. The SELinux
+// context of an existing path is kept too, and with PreserveOwner its owner.
+func WriteFileAtomicFrom(path string, perm os.FileMode, fill func(w io.Writer) error) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	tmp, tmpPath, err := FS.CreateTemp(filepath.Dir(path), tempFilePrefix+"*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file for '%s': %w", path, err)
+	}
+	err = fill(tmp)
+	if err == nil {
+		err = syncFile(tmp)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = FS.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = copySecurityContext(path, tmpPath)
+	}
+	if info, statErr := os.Stat(path); err == nil && statErr == nil {
+		err = keepOwner(tmpPath, info)
+	}
+	if err == nil {
+		err = FS.Rename(tmpPath, path)
+	}
+	if err != nil {
+		FS.Remove(tmpPath)
+		return explainDenied(fmt.Errorf("writing '%s': %w", path, err))
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("flushing directory of '%s': %w", path, err)
+	}
+	return nil
+}
+
+// IsInternalEntry reports whether a walked entry belongs to PhotonSR's own
+// bookkeeping (a run workspace or a temporary file) and must not be processed.
+func IsInternalEntry(info os.FileInfo) bool {
+	if info.IsDir() {
+		return strings.HasPrefix(info.Name(), RunWorkspacePrefix)
+	}
+	return strings.HasPrefix(info.Name(), tempFilePrefix)
+}
+
+// InterruptedRun describes the workspace of a run that did not end normally.
+type InterruptedRun struct {
+	Workspace string         // Path of the run workspace.
+	Operation string         // Operation of the run.
+	Started   string         // Start time of the run (RFC 3339).
+	Writes    []JournalEntry // Files the run started or finished rewriting or moving, in order.
+}
+
+// rollback puts back the original content of every file the run touched, newest
+// first, then removes the run's leftovers.
+// Returns:
+//   - []string: Messages detailing individual actions taken.
+//   - error: The first error encountered; the workspace is kept if any file failed.
+func (r InterruptedRun) rollback() ([]string, error) {
+	var messages []string
+	var firstErr error
+	for i := len(r.Writes) - 1; i >= 0; i-- {
+		w := r.Writes[i]
+		if w.Op == "remove" || w.Op == "rmdir" {
+			if err := recreateRemoved(w); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("recreating '%s': %w", w.Path, err)
+				}
+				continue
+			}
+			messages = append(messages, fmt.Sprintf("  - Recreated: %s", w.Path))
+			continue
+		}
+		if w.Op == "move" {
+			if _, err := os.Lstat(w.Path); err != nil {
+				continue // Not moved yet.
+			}
+			if err := FS.Rename(w.Path, w.From); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("moving back '%s': %w", w.Path, err)
+				}
+				continue
+			}
+			messages = append(messages, fmt.Sprintf("  - Moved back: %s -> %s", w.Path, w.From))
+			continue
+		}
+		original, err := os.ReadFile(filepath.Join(r.Workspace, w.Snapshot))
+		if err == nil {
+			err = WriteFileAtomic(w.Path, original, os.FileMode(w.Mode))
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("rolling back '%s': %w", w.Path, err)
+			}
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("  - Rolled back: %s", w.Path))
+	}
+	if firstErr != nil {
+		return messages, firstErr
+	}
+	cleanup, err := r.discard()
+	return append(messages, cleanup...), err
+}
+
+// discard keeps the files as they are and removes the run's workspace and any
+// temporary files left next to the files it was rewriting.
+func (r InterruptedRun) discard() ([]string, error) {
+	var messages []string
+	dirs := map[string]bool{}
+	for _, w := range r.Writes {
+		dirs[filepath.Dir(w.Path)] = true
+	}
+	for dir := range dirs {
+		temps, _ := filepath.Glob(filepath.Join(dir, tempFilePrefix+"*"))
+		for _, tmp := range temps {
+			if FS.Remove(tmp) == nil {
+				messages = append(messages, fmt.Sprintf("  - Removed temporary file: %s", tmp))
+			}
+		}
+	}
+	if err := FS.RemoveAll(r.Workspace); err != nil {
+		return messages, fmt.Errorf("removing run workspace '%s': %w", r.Workspace, err)
+	}
+	messages = append(messages, fmt.Sprintf("  - Removed run workspace: %s", r.Workspace))
+	return messages, nil
+}
+
+// RecoverRun applies a recovery choice (RecoverRollback or RecoverDiscard) to r.
+func RecoverRun(r InterruptedRun, mode string) ([]string, error) {
+	switch mode {
+	case RecoverRollback:
+		return r.rollback()
+	case RecoverDiscard:
+		return r.discard()
+	}
+	return nil, nil
+}
+
+// Describe summarizes the run for prompts and warnings.
+func (r InterruptedRun) Describe() string {
+	return fmt.Sprintf("interrupted %s run started %s (%d file(s) being rewritten), workspace %s", r.Operation, r.Started, len(r.Writes), r.Workspace)
+}
+
+// StateDirEnv names the environment variable that overrides the state directory.
+const StateDirEnv = "PHOTONSR_STATE_DIR"
+
+// StateDir returns the directory where PhotonSR keeps data between runs:
+// $PHOTONSR_STATE_DIR, or "photonsr" in the user's configuration directory.
+func StateDir() (string, error) {
+	if dir := os.Getenv(StateDirEnv); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating state directory (set %s): %w", StateDirEnv, err)
+	}
+	return filepath.Join(base, "photonsr"), nil
+}
+
+// NewRunID returns a sortable, unique run identifier such as "20261016-130501-3fa2".
+func NewRunID() string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,56 @@
+package photonsr
+
+import "bytes"
+
+// lineSpans returns the spans, ascending pairs of start and end offsets in content
+// (possibly followed by group offsets), widened to the lines holding them for a
+// rule with Lines set. A line is taken from its start to its line break ("\n" or
+// "\r\n"), or past the line break if rule.New is empty, so that replacing it
+// deletes the line; spans on lines already taken are dropped. Each line keeps the
+// group offsets of its first span.
+func (rule Rule) lineSpans(content []byte, spans [][]int) [][]int {
+	if !rule.Lines {
+		return spans
+	}
+	var lines [][]int
+	lastBreak := -1 // Offset of the line break ending the last line taken.
+	for _, span := range spans {
+		start, end := span[0], span[1]
+		if start == len(content) && (start == 0 || content[start-1] == '\n') {
+			continue // An empty match past the last line is on no line.
+		}
+		if end > start && content[end-1] == '\n' {
+			end-- // A match ending with a line break ends on the line it breaks.
+		}
+		lineBreak := len(content)
+		if i := bytes.IndexByte(content[end:], '\n'); i >= 0 {
+			lineBreak = end + i
+		}
+		if start <= lastBreak {
+			if len(lines) > 0 && lineBreak > lastBreak { // Across more lines than the last one taken.
+				lines[len(lines)-1][1] = rule.lineEnd(content, lineBreak)
+				lastBreak = lineBreak
+			}
+			continue
+		}
+		lineStart := bytes.LastIndexByte(content[:start], '\n') + 1
+		lines = append(lines, append([]int{lineStart, rule.lineEnd(content, lineBreak)}, span[2:]...))
+		lastBreak = lineBreak
+	}
+	return lines
+}
+
+// lineEnd returns where the part of a line replaced by the rule ends, for a line
+// whose line break is at lineBreak (len(content) for the last line without one).
+func (rule Rule) lineEnd(content []byte, lineBreak int) int {
+	if lineBreak == len(content) {
+		return lineBreak
+	}
+	if rule.New == "" {
+		return lineBreak + 1
+	}
+	if lineBreak > 0 && content[lineBreak-1] == '\r' {
+		return lineBreak - 1
+	}
+	return lineBreak
+}
@@ -0,0 +1,45 @@
+package photonsr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    Rule
+		want    string
+		count   int
+	}{
+		{"replace", "a foo b\nbar\nfoo\n", Rule{Old: "foo", New: "X", Lines: true}, "X\nbar\nX\n", 2},
+		{"delete", "a foo b\nbar\nfoo\n", Rule{Old: "foo", Lines: true}, "bar\n", 2},
+		{"keep CRLF", "foo 1\r\nbar\r\n", Rule{Old: "foo", New: "X", Lines: true}, "X\r\nbar\r\n", 1},
+		{"delete CRLF", "foo 1\r\nbar\r\n", Rule{Old: "foo", Lines: true}, "bar\r\n", 1},
+		{"last line without break", "bar\nfoo", Rule{Old: "foo", Lines: true}, "bar\n", 1},
+		{"one line per line", "foo foo\nfoo", Rule{Old: "foo", New: "X", Lines: true}, "X\nX", 2},
+		{"across lines", "a\nb\nc", Rule{Old: "a\nb", New: "X", Lines: true}, "X\nc", 1},
+		{"ending with a line break", "a\nb\nc", Rule{Old: "a\n", New: "X", Lines: true}, "X\nb\nc", 1},
+		{"ignore case", "FOO\nbar", Rule{Old: "foo", New: "X", IgnoreCase: true, Lines: true}, "X\nbar", 1},
+		{"regexp anchors per line", "x\n  // c\ny\n", Rule{Old: `^\s*//.*$`, Regexp: true, Lines: true}, "x\ny\n", 1},
+		{"regexp groups of the first match", "v1 v2\nz", Rule{Old: `v(\d)`, New: "line $1", Regexp: true, Lines: true}, "line 1\nz", 1},
+		{"regexp whole line", "an id\n", Rule{Old: "id", New: "[$0]", Regexp: true, Lines: true}, "[an id]\n", 1},
+		{"empty match past the last line", "a\n", Rule{Old: "x*", New: "L", Regexp: true, Lines: true}, "L\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matches := Apply([]byte(tt.content), tt.rule)
+			if string(got) != tt.want || len(matches) != tt.count {
+				t.Errorf("Apply(%q) = %q with %d lines, want %q with %d", tt.content, got, len(matches), tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestFindMatchesLines(t *testing.T) {
+	got := FindMatches([]byte("x\na foo\r\nb"), Rule{Old: "foo", New: "X", Lines: true})
+	if want := []Match{{Start: 2, End: 7, Line: 2, Column: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindMatches = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,239 @@
+// Package photonsr is the library behind the PhotonSR command-line tool. It exposes
+// the text matching and splicing used by the tool, so that editor integrations and
+// other embedders find exactly the same matches as a PhotonSR run, and the file
+// operations themselves (PerformReplacement, PerformRestore, PerformClean and the
+// others), which the command-line tool and its wizard only configure and report.
+// The operations never print: problems go to the OnWarning callback of their
+// options.
+package photonsr
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Rule describes a replacement: every occurrence of Old is replaced by New.
+type Rule struct {
+	Old string // Literal text to search for. A rule with an empty Old matches nothing.
+	New string // Text that replaces each occurrence of Old.
+
+	// Regexp makes Old a regular expression (Go RE2 syntax) and lets New refer to
+	// its capture groups as $1 or ${name}, as in regexp.Regexp.Expand; "$$" is a
+	// literal "$". A rule whose Old does not compile matches nothing, so check it
+	// with regexp.Compile first.
+	Regexp bool
+
+	// IgnoreCase makes Old match regardless of case, following Unicode simple case
+	// folding as the (?i) flag of regexp does. New is inserted as it is.
+	IgnoreCase bool
+
+	// Near, if set, limits the rule to the occurrences of Old close to an
+	// occurrence of another text (see Near).
+	Near *Near
+
+	// NotPrecededBy and NotFollowedBy, if not empty, leave out the occurrences of
+	// Old that directly follow or precede that text, compared as Old is (following
+	// IgnoreCase): Old "cat" with NotFollowedBy "e" keeps "concatenate" intact. A
+	// literal occurrence left out does not hide one overlapping it; the matches of
+	// a Regexp rule are filtered as found.
+	NotPrecededBy string
+	NotFollowedBy string
+
+	// Lines replaces each line holding a match of Old as a whole by New, without
+	// its line break, or deletes it with its line break if New is empty. A match
+	// across lines selects all of them. With Regexp, ^ and $ match at the start and
+	// end of each line, and New is expanded with the groups of the first match on
+	// the line; $0 is the whole line.
+	Lines bool
+
+	// Anchor, if not empty, keeps only the occurrences of Old at the start or end
+	// of a line or of the content: AnchorBOL, AnchorEOL, AnchorBOF or AnchorEOF.
+	// Other values match nothing. As with the guards, a literal occurrence left
+	// out does not hide one overlapping it.
+	Anchor string
+}
+
+// Anchors for Rule.Anchor.
+const (
+	AnchorBOL = "bol" // At the start of a line.
+	AnchorEOL = "eol" // At the end of a line, before "\n" or "\r\n", or of the content.
+	AnchorBOF = "bof" // At the start of the content.
+	AnchorEOF = "eof" // At the end of the content, or before its final line break.
+)
+
+// compile returns the regular expression matching rule.Old, for a rule with Regexp
+// or IgnoreCase set.
+func (rule Rule) compile() (*regexp.Regexp, error) {
+	expr := rule.Old
+	if !rule.Regexp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if rule.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	if rule.Lines && rule.Regexp {
+		expr = "(?m)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// Match is one occurrence of a rule's Old text.
+type Match struct {
+	Start  int // Byte offset of the first byte of the match.
+	End    int // Byte offset just past the last byte of the match.
+	Line   int // 1-based line of Start; lines are separated by '\n'.
+	Column int // 1-based column of Start, counted in Unicode code points.
+}
+
+// FindMatches returns the non-overlapping occurrences of rule.Old in content,
+// scanned left to right. These are exactly the occurrences a PhotonSR run replaces.
+func FindMatches(content []byte, rule Rule) []Match {
+	if rule.Old == "" {
+		return nil
+	}
+	if rule.Regexp {
+		re, err := rule.compile()
+		if err != nil {
+			return nil
+		}
+		return locate(content, rule.lineSpans(content, rule.near(content, rule.unguarded(content, re.FindAllIndex(content, -1)))))
+	}
+	return locate(content, rule.lineSpans(content, rule.near(content, rule.literalSpans(content))))
+}
+
+// literalSpans returns the start and end offsets of the non-overlapping occurrences
+// of rule.Old in content, for a rule without Regexp, scanned left to right. An
+// occurrence left out by the rule's guards is skipped by one character only.
+func (rule Rule) literalSpans(content []byte) [][]int {
+	next := func(from int) (int, int) {
+		i := bytes.Index(content[from:], []byte(rule.Old))
+		if i < 0 {
+			return -1, -1
+		}
+		return from + i, from + i + len(rule.Old)
+	}
+	if rule.IgnoreCase {
+		re, err := rule.compile()
+		if err != nil {
+			return nil
+		}
+		next = func(from int) (int, int) {
+			loc := re.FindIndex(content[from:])
+			if loc == nil {
+				return -1, -1
+			}
+			return from + loc[0], from + loc[1]
+		}
+	}
+	var spans [][]int
+	for pos := 0; pos < len(content); {
+		start, end := next(pos)
+		if start < 0 {
+			break
+		}
+		if rule.guarded(content, start, end) {
+			_, size := utf8.DecodeRune(content[start:])
+			pos = start + size
+			continue
+		}
+		spans = append(spans, []int{start, end})
+		pos = end
+	}
+	return spans
+}
+
+// locate returns the matches at spans, ascending pairs of start and end offsets in
+// content, with their lines and columns.
+func locate(content []byte, spans [][]int) []Match {
+	var matches []Match
+	line, lineStart, scanned := 1, 0, 0
+	for _, span := range spans {
+		start := span[0]
+		// Advance the line count over the text since the previous match only,
+		// so the whole search stays linear in len(content).
+		for {
+			nl := bytes.IndexByte(content[scanned:start], '\n')
+			if nl < 0 {
+				break
+			}
+			line++
+			scanned += nl + 1
+			lineStart = scanned
+		}
+		scanned = start
+		matches = append(matches, Match{
+			Start:  start,
+			End:    span[1],
+			Line:   line,
+			Column: utf8.RuneCount(content[lineStart:start]) + 1,
+		})
+	}
+	return matches
+}
+
+// Apply returns content with every match of rule replaced by rule.New, along with
+// the matches that were replaced. When nothing matches, content itself is returned.
+func Apply(content []byte, rule Rule) ([]byte, []Match) {
+	if rule.Regexp && rule.Old != "" {
+		return applyRegexp(content, rule)
+	}
+	matches := FindMatches(content, rule)
+	if len(matches) == 0 {
+		return content, nil
+	}
+	return Splice(content, matches, []byte(rule.New)), matches
+}
+
+// applyRegexp is Apply for a rule with Regexp set: each match is replaced by
+// rule.New with its group references expanded.
+func applyRegexp(content []byte, rule Rule) ([]byte, []Match) {
+	re, err := rule.compile()
+	if err != nil {
+		return content, nil
+	}
+	groups := rule.lineSpans(content, rule.near(content, rule.unguarded(content, re.FindAllSubmatchIndex(content, -1))))
+	if len(groups) == 0 {
+		return content, nil
+	}
+	template := []byte(rule.New)
+	out := make([]byte, 0, len(content))
+	prev := 0
+	for _, g := range groups {
+		out = append(out, content[prev:g[0]]...)
+		out = re.Expand(out, template, content, g)
+		prev = g[1]
+	}
+	return append(out, content[prev:]...), locate(content, groups)
+}
+
+// ApplyAll applies rules to content one after the other, each to the result of the
+// previous one, as a PhotonSR run does with the rules that apply to a file. It
+// returns the result with the total number of replacements.
+func ApplyAll(content []byte, rules []Rule) ([]byte, int) {
+	count := 0
+	for _, rule := range rules {
+		var matches []Match
+		content, matches = Apply(content, rule)
+		count += len(matches)
+	}
+	return content, count
+}
+
+// Splice returns a copy of content in which each match is replaced by repl.
+// matches must be ascending and non-overlapping, as returned by FindMatches.
+// The output is built in a single buffer of the exact final size.
+func Splice(content []byte, matches []Match, repl []byte) []byte {
+	size := len(content)
+	for _, m := range matches {
+		size += len(repl) - (m.End - m.Start)
+	}
+	out := make([]byte, 0, size)
+	prev := 0
+	for _, m := range matches {
+		out = append(out, content[prev:m.Start]...)
+		out = append(out, repl...)
+		prev = m.End
+	}
+	return append(out, content[prev:]...)
+}
@@ -0,0 +1,150 @@
+package photonsr
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFindMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    Rule
+		want    []Match
+	}{
+		{"none", "abc", Rule{Old: "x"}, nil},
+		{"empty old", "abc", Rule{Old: ""}, nil},
+		{"start and end", "foo bar foo", Rule{Old: "foo"}, []Match{
+			{Start: 0, End: 3, Line: 1, Column: 1},
+			{Start: 8, End: 11, Line: 1, Column: 9},
+		}},
+		{"non-overlapping", "aaaa", Rule{Old: "aa"}, []Match{
+			{Start: 0, End: 2, Line: 1, Column: 1},
+			{Start: 2, End: 4, Line: 1, Column: 3},
+		}},
+		{"lines", "x\nab\n\n  ab", Rule{Old: "ab"}, []Match{
+			{Start: 2, End: 4, Line: 2, Column: 1},
+			{Start: 8, End: 10, Line: 4, Column: 3},
+		}},
+		{"columns count runes", "héé ab\r\n€ab", Rule{Old: "ab"}, []Match{
+			{Start: 6, End: 8, Line: 1, Column: 5},
+			{Start: 13, End: 15, Line: 2, Column: 2},
+		}},
+		{"across lines", "a\nb a\nb", Rule{Old: "a\nb"}, []Match{
+			{Start: 0, End: 3, Line: 1, Column: 1},
+			{Start: 4, End: 7, Line: 2, Column: 3},
+		}},
+		{"ignore case", "Straße STRASSE straße", Rule{Old: "STRAẞE", IgnoreCase: true}, []Match{
+			{Start: 0, End: 7, Line: 1, Column: 1},
+			{Start: 16, End: 23, Line: 1, Column: 16},
+		}},
+		{"regexp", "v1 v22\nv333", Rule{Old: `v\d+`, Regexp: true}, []Match{
+			{Start: 0, End: 2, Line: 1, Column: 1},
+			{Start: 3, End: 6, Line: 1, Column: 4},
+			{Start: 7, End: 11, Line: 2, Column: 1},
+		}},
+		{"invalid regexp", "abc", Rule{Old: "(", Regexp: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindMatches([]byte(tt.content), tt.rule); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMatches(%q, %+v) = %+v, want %+v", tt.content, tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    Rule
+		want    string
+		count   int
+	}{
+		{"literal", "foo bar foo", Rule{Old: "foo", New: "baz"}, "baz bar baz", 2},
+		{"delete", "a-b-c", Rule{Old: "-"}, "abc", 2},
+		{"no match", "abc", Rule{Old: "x", New: "y"}, "abc", 0},
+		{"ignore case", "Foo FOO", Rule{Old: "foo", New: "bar", IgnoreCase: true}, "bar bar", 2},
+		{"regexp groups", "v1.2 v3.4", Rule{Old: `v(\d+)\.(\d+)`, New: "v$2.${1}$$", Regexp: true}, "v2.1$ v4.3$", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matches := Apply([]byte(tt.content), tt.rule)
+			if string(got) != tt.want || len(matches) != tt.count {
+				t.Errorf("Apply(%q, %+v) = %q with %d matches, want %q with %d", tt.content, tt.rule, got, len(matches), tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestApplyAll(t *testing.T) {
+	got, count := ApplyAll([]byte("a b"), []Rule{{Old: "a", New: "b"}, {Old: "b", New: "c"}})
+	if string(got) != "c c" || count != 3 {
+		t.Errorf("ApplyAll = %q with %d replacements, want \"c c\" with 3", got, count)
+	}
+}
+
+func TestApplyReturnsContentUnchanged(t *testing.T) {
+	content := []byte("abc")
+	if got, _ := Apply(content, Rule{Old: "x", New: "y"}); &got[0] != &content[0] {
+		t.Error("Apply without a match copied the content")
+	}
+}
+
+func TestSplice(t *testing.T) {
+	content := []byte("0123456789")
+	matches := []Match{{Start: 0, End: 2}, {Start: 4, End: 4}, {Start: 8, End: 10}}
+	if got := Splice(content, matches, []byte("-")); string(got) != "-23-4567-" {
+		t.Errorf("Splice = %q, want \"-23-4567-\"", got)
+	}
+}
+
+// FuzzSplice checks that splicing the matches of a literal rule gives the result
+// of the naive bytes.ReplaceAll, with the matches at the offsets it replaces.
+func FuzzSplice(f *testing.F) {
+	f.Add([]byte("foo bar foo"), "foo", "baz")
+	f.Add([]byte("aaaa"), "aa", "a")
+	f.Add([]byte("é\nxé"), "é", "")
+	f.Fuzz(func(t *testing.T, content []byte, old, repl string) {
+		if old == "" {
+			return
+		}
+		matches := FindMatches(content, Rule{Old: old})
+		if want := bytes.Count(content, []byte(old)); len(matches) != want {
+			t.Fatalf("%d matches of %q, bytes.Count finds %d", len(matches), old, want)
+		}
+		for _, m := range matches {
+			if string(content[m.Start:m.End]) != old {
+				t.Fatalf("match %+v is %q, not %q", m, content[m.Start:m.End], old)
+			}
+			if want := bytes.Count(content[:m.Start], []byte("\n")) + 1; m.Line != want {
+				t.Fatalf("match %+v is on line %d", m, want)
+			}
+		}
+		got := Splice(content, matches, []byte(repl))
+		if want := bytes.ReplaceAll(content, []byte(old), []byte(repl)); !bytes.Equal(got, want) {
+			t.Fatalf("Splice = %q, bytes.ReplaceAll = %q", got, want)
+		}
+	})
+}
+
+// FuzzApply checks Apply with a literal rule against bytes.ReplaceAll.
+func FuzzApply(f *testing.F) {
+	f.Add([]byte("foo bar foo"), "foo", "baz")
+	f.Add([]byte("abab"), "ab", "ba")
+	f.Add([]byte(""), "x", "y")
+	f.Fuzz(func(t *testing.T, content []byte, old, repl string) {
+		if old == "" {
+			return
+		}
+		got, matches := Apply(content, Rule{Old: old, New: repl})
+		if want := bytes.ReplaceAll(content, []byte(old), []byte(repl)); !bytes.Equal(got, want) {
+			t.Fatalf("Apply = %q, bytes.ReplaceAll = %q", got, want)
+		}
+		if want := bytes.Count(content, []byte(old)); len(matches) != want {
+			t.Fatalf("Apply replaced %d matches, bytes.Count finds %d", len(matches), want)
+		}
+	})
+}
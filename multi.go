@@ -0,0 +1,223 @@
+package photonsr
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// --- Multi-Repository Batch ---
+
+// Ways a repository of a batch is obtained (RepoResult.Source).
+const (
+	RepoCloned  = "cloned"  // Cloned into the workspace.
+	RepoUpdated = "updated" // Already in the workspace; pulled.
+	RepoLocal   = "local"   // Local checkout, used as it is.
+)
+
+// MultiOptions holds all parameters for PerformMulti.
+type MultiOptions struct {
+	ReposFile string // File listing the repositories, one per line; '#' starts a comment.
+	Workspace string // Directory git URLs are cloned into.
+
+	// Replace is the replacement applied to each repository; its Dir is set to the
+	// repository's, and its callbacks are called for the files of all of them.
+	Replace ReplaceOptions
+}
+
+// Validate reports the first problem that would stop PerformMulti from running with opts.
+func (opts MultiOptions) Validate() error {
+	if opts.ReposFile == "" {
+		return fmt.Errorf("multi requires a list of repositories (-repos repos.txt): %w", ErrInvalidOption)
+	}
+	if opts.Replace.AllowedPaths != nil {
+		return fmt.Errorf("multi processes whole repositories; -scope, -manifest and retries cannot be used with it: %w", ErrInvalidOption)
+	}
+	if err := ValidateDir(opts.Workspace); err != nil {
+		return err
+	}
+	replace := opts.Replace
+	replace.Dir = opts.Workspace
+	return replace.Validate()
+}
+
+// RepoResult is the outcome of one repository of a batch.
+type RepoResult struct {
+	Repo     string `json:"repo"`            // Entry of the repos file.
+	Dir      string `json:"dir"`             // Checkout the replacement ran in.
+	Source   string `json:"source"`          // RepoCloned, RepoUpdated or RepoLocal.
+	Modified int    `json:"modified"`        // Files modified.
+	Scanned  int    `json:"scanned"`         // Files scanned.
+	Failed   int    `json:"failed"`          // Files that could not be processed.
+	Error    string `json:"error,omitempty"` // Why the repository failed, if it did.
+}
+
+// repoEntry is a line of a repos file.
+type repoEntry struct {
+	line   string // As written.
+	url    string // Set for a git URL.
+	dir    string // Checkout: local path, or clone destination for a URL.
+	source string
+}
+
+// scpURL matches the scp-like syntax git accepts for SSH URLs, e.g. "git@host:org/repo.git".
+var scpURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:`)
+
+// readReposFile parses the repos file at path. URLs are cloned into workspace,
+// under the last element of their path without ".git".
+func readReposFile(path, workspace string) ([]repoEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading repos file: %w", err)
+	}
+	defer f.Close()
+	var entries []repoEntry
+	seen := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		e := repoEntry{line: line}
+		if strings.Contains(line, "://") || scpURL.MatchString(line) {
+			name := strings.TrimSuffix(strings.TrimRight(line, "/"), ".git")
+			name = name[strings.LastIndexAny(name, "/:")+1:]
+			if name == "" || name == "." || name == ".." {
+				return nil, fmt.Errorf("%s:%d: no repository name in '%s': %w", path, n, line, ErrInvalidOption)
+			}
+			e.url, e.dir = line, filepath.Join(workspace, name)
+		} else {
+			e.dir, e.source = filepath.FromSlash(line), RepoLocal
+			if !filepath.IsAbs(e.dir) {
+				e.dir = filepath.Join(filepath.Dir(path), e.dir)
+			}
+		}
+		key := CanonicalPath(e.dir)
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("%s:%d: '%s' uses the same checkout as '%s': %w", path, n, line, prev, ErrInvalidOption)
+		}
+		seen[key] = line
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading repos file: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("repos file '%s' lists no repository: %w", path, ErrInvalidOption)
+	}
+	return entries, nil
+}
+
+// checkout clones or updates the repository of e if it is a URL, and sets e.source.
+func (e *repoEntry) checkout() error {
+	if e.url == "" {
+		return ValidateDir(e.dir)
+	}
+	if _, err := os.Stat(e.dir); os.IsNotExist(err) {
+		e.source = RepoCloned
+		_, err := runGit(filepath.Dir(e.dir), "clone", "--quiet", "--", e.url, filepath.Base(e.dir))
+		return firstLine(err)
+	}
+	e.source = RepoUpdated
+	if _, err := gitRepoRoot(e.dir); err != nil {
+		return fmt.Errorf("'%s' exists and is not a git checkout: %w", e.dir, err)
+	}
+	_, err := runGit(e.dir, "pull", "--quiet", "--ff-only")
+	return firstLine(err)
+}
+
+// firstLine returns err cut after its first line, as git explains some errors at
+// length, or nil.
+func firstLine(err error) error {
+	if err == nil {
+		return nil
+	}
+	if line, _, cut := strings.Cut(err.Error(), "\n"); cut {
+		return fmt.Errorf("%s", line)
+	}
+	return err
+}
+
+// PerformMulti applies opts.Replace to each repository of opts.ReposFile.
+// Returns:
+//   - []RepoResult: The outcome of each repository, in the order of the file.
+//   - []string: The files modified in all repositories.
+//   - error: A fatal error or the first error of a repository.
+func PerformMulti(opts MultiOptions) ([]RepoResult, []string, error) {
+	return PerformMultiCtx(context.Background(), opts)
+}
+
+// PerformMultiCtx is PerformMulti with cancellation: once ctx is done, no further
+// repository is started and the returned error wraps ctx.Err().
+func PerformMultiCtx(ctx context.Context, opts MultiOptions) ([]RepoResult, []string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+	entries, err := readReposFile(opts.ReposFile, opts.Workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	var results []RepoResult
+	var modified []string
+	var firstErr error
+	fail := func(r *RepoResult, err error) {
+		r.Error = err.Error()
+		if firstErr == nil {
+			firstErr = fmt.Errorf("repository '%s': %w", r.Repo, err)
+		}
+		warn(opts.Replace.OnWarning, "PerformMulti", "Repository", fmt.Errorf("repository '%s': %w", r.Repo, err), "Continuing with the next repository")
+	}
+	for i := range entries {
+		e := &entries[i]
+		if err := ctx.Err(); err != nil {
+			return results, modified, fmt.Errorf("batch interrupted after %d of %d repositories: %w", i, len(entries), err)
+		}
+		r := RepoResult{Repo: e.line, Dir: e.dir}
+		err := e.checkout()
+		r.Source = e.source
+		if err != nil {
+			fail(&r, err)
+			results = append(results, r)
+			continue
+		}
+		replace := opts.Replace
+		replace.Dir = e.dir
+		vcs := map[string]bool{}
+		for name := range TidySkipDirs {
+			vcs[filepath.Join(e.dir, name)] = true
+		}
+		if replace.AllowedPaths, err = AllowedPaths(e.dir, "*", vcs); err != nil {
+			fail(&r, err)
+			results = append(results, r)
+			continue
+		}
+		onFileError := replace.OnFileError
+		replace.OnFileError = func(path string, err error) {
+			r.Failed++
+			if onFileError != nil {
+				onFileError(path, err)
+			}
+		}
+		if _, _, err := ApplySavedExclusions(&replace); err != nil {
+			fail(&r, err)
+			results = append(results, r)
+			continue
+		}
+		files, scanned, err := PerformReplacementCtx(ctx, replace)
+		r.Modified, r.Scanned = len(files), scanned
+		modified = append(modified, files...)
+		if err != nil {
+			fail(&r, err)
+		}
+		results = append(results, r)
+	}
+	return results, modified, firstErr
+}
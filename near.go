@@ -0,0 +1,59 @@
+package photonsr
+
+import (
+	"bytes"
+	"sort"
+	"unicode/utf8"
+)
+
+// Near is a condition on the surroundings of a match: Term must occur within
+// Within lines of it (Lines set) or Within characters of it. A distance of 0
+// means on the same line, or touching or overlapping the match. Term is literal,
+// and matched regardless of case if the rule's IgnoreCase is set.
+type Near struct {
+	Term   string // Text that must occur close to the match.
+	Within int    // Largest distance between the match and Term.
+	Lines  bool   // Within counts lines rather than characters.
+}
+
+// near returns the spans, ascending pairs of start and end offsets in content
+// (possibly followed by group offsets), that satisfy rule.Near.
+func (rule Rule) near(content []byte, spans [][]int) [][]int {
+	if rule.Near == nil || len(spans) == 0 {
+		return spans
+	}
+	terms := FindMatches(content, Rule{Old: rule.Near.Term, IgnoreCase: rule.IgnoreCase})
+	if len(terms) == 0 {
+		return nil
+	}
+	lines := locate(content, spans)
+	var kept [][]int
+	for i, span := range spans {
+		// Terms do not overlap, so the last one starting before the match is also
+		// the one ending closest to it, and the next one the closest after it.
+		next := sort.Search(len(terms), func(k int) bool { return terms[k].Start >= span[0] })
+		if next < len(terms) && rule.Near.reaches(content, span[0], span[1], lines[i].Line, terms[next]) ||
+			next > 0 && rule.Near.reaches(content, span[0], span[1], lines[i].Line, terms[next-1]) {
+			kept = append(kept, span)
+		}
+	}
+	return kept
+}
+
+// reaches reports whether the term occurrence t is within n.Within of the match
+// from start to end, which begins on line.
+func (n *Near) reaches(content []byte, start, end, line int, t Match) bool {
+	if t.Start < end && start < t.End {
+		return true // Overlapping.
+	}
+	if n.Lines {
+		if t.Start >= end {
+			return t.Line-(line+bytes.Count(content[start:end], []byte{'\n'})) <= n.Within
+		}
+		return line-(t.Line+bytes.Count(content[t.Start:t.End], []byte{'\n'})) <= n.Within
+	}
+	if t.Start >= end {
+		return utf8.RuneCount(content[end:t.Start]) <= n.Within
+	}
+	return utf8.RuneCount(content[t.End:start]) <= n.Within
+}
@@ -0,0 +1,53 @@
+package photonsr
+
+import (
+	"reflect"
+	"testing"
+)
+
+// starts returns the start offsets of matches.
+func starts(matches []Match) []int {
+	var out []int
+	for _, m := range matches {
+		out = append(out, m.Start)
+	}
+	return out
+}
+
+func TestNear(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    Rule
+		want    []int // Start offsets of the matches kept.
+	}{
+		{"no term", "foo foo", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 100}}, nil},
+		{"characters after", "foo..bar----------foo...bar", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 2}}, []int{0}},
+		{"characters before", "bar..foo----------bar...foo", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 2}}, []int{5}},
+		{"characters are runes", "fooéébar", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 2}}, []int{0}},
+		{"touching", "foobar foo", Rule{Old: "foo", Near: &Near{Term: "bar"}}, []int{0}},
+		{"overlapping", "food", Rule{Old: "foo", Near: &Near{Term: "od"}}, []int{0}},
+		{"same line", "foo x bar\nfoo\nbar", Rule{Old: "foo", Near: &Near{Term: "bar", Lines: true}}, []int{0}},
+		{"lines after", "foo\n\nbar\nfoo\n\n\nbar", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 2, Lines: true}}, []int{0, 9}},
+		{"lines before", "bar\n\n\nfoo\nbar\nfoo", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 1, Lines: true}}, []int{6, 14}},
+		{"lines from the end of a match", "a\nb\n\nbar", Rule{Old: "a\nb", Near: &Near{Term: "bar", Within: 2, Lines: true}}, []int{0}},
+		{"ignore case", "FOO Bar", Rule{Old: "foo", IgnoreCase: true, Near: &Near{Term: "bAR", Within: 1}}, []int{0}},
+		{"case sensitive term", "foo Bar", Rule{Old: "foo", Near: &Near{Term: "bar", Within: 1}}, nil},
+		{"regexp", "v1 x\n...\nv2", Rule{Old: `v\d`, Regexp: true, Near: &Near{Term: "x", Within: 1}}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := starts(FindMatches([]byte(tt.content), tt.rule)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMatches(%q) starts at %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyNear(t *testing.T) {
+	rule := Rule{Old: `id(\d)`, New: "ID$1", Regexp: true, Near: &Near{Term: "user", Within: 0, Lines: true}}
+	got, _ := Apply([]byte("user id1\nid2\nid3 user"), rule)
+	if want := "user ID1\nid2\nID3 user"; string(got) != want {
+		t.Errorf("Apply = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package photonsr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// --- Files Open for Writing ---
+
+// Rewriting a file that another process is still writing, such as an active log,
+// races with it: whatever it writes after PhotonSR read the file goes to the old,
+// replaced file and is lost. With -skip-open, the files open for writing by other
+// processes are found once, before the files are processed, and skipped with the
+// process named in the report. On Linux /proc is scanned, elsewhere lsof is asked;
+// either way only the processes the user may inspect are seen.
+
+// ErrOpenForWrite is wrapped by the skip reason of files open for writing by
+// another process.
+var ErrOpenForWrite = errors.New("file is open for writing by another process")
+
+// openWriter is a process that has a file open for writing.
+type openWriter struct {
+	file    os.FileInfo
+	pid     int
+	command string
+}
+
+// openWriters lists the files below a directory that are open for writing.
+type openWriters []openWriter
+
+// check returns an error wrapping ErrOpenForWrite if the file at path, described
+// by info, is open for writing.
+func (ws openWriters) check(path string, info os.FileInfo) error {
+	for _, w := range ws {
+		if os.SameFile(w.file, info) {
+			return fmt.Errorf("'%s' is open for writing by process %d (%s): %w", path, w.pid, w.command, ErrOpenForWrite)
+		}
+	}
+	return nil
+}
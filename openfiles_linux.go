@@ -0,0 +1,65 @@
+//go:build linux
+
+package photonsr
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// findOpenWriters lists the files below dir that other processes have open for
+// writing, from /proc.
+func findOpenWriters(dir string) (openWriters, error) {
+	root := CanonicalPath(dir) + string(filepath.Separator)
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	var writers openWriters
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		base := filepath.Join("/proc", p.Name())
+		fds, err := os.ReadDir(filepath.Join(base, "fd"))
+		if err != nil {
+			continue // Gone, or another user's process.
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(base, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(target, root) || !openedForWrite(filepath.Join(base, "fdinfo", fd.Name())) {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(base, "fd", fd.Name()))
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			comm, _ := os.ReadFile(filepath.Join(base, "comm"))
+			writers = append(writers, openWriter{file: info, pid: pid, command: strings.TrimSpace(string(comm))})
+		}
+	}
+	return writers, nil
+}
+
+// openedForWrite reports whether the fdinfo file at path describes a descriptor
+// opened for writing (O_WRONLY or O_RDWR).
+func openedForWrite(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "flags:"); ok {
+			flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+			return err == nil && flags&3 != 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+//go:build !linux
+
+package photonsr
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// findOpenWriters lists the files below dir that other processes have open for
+// writing, as reported by lsof.
+func findOpenWriters(dir string) (openWriters, error) {
+	lsof, err := exec.LookPath("lsof")
+	if err != nil {
+		return nil, fmt.Errorf("lsof is needed to find files open for writing: %w", err)
+	}
+	// Exit status 1 only means that nothing is open below dir.
+	out, err := exec.Command(lsof, "-w", "-n", "-P", "-F", "pcan", "+D", CanonicalPath(dir)).Output()
+	if err != nil && len(out) == 0 {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("running lsof: %w", err)
+	}
+	self := os.Getpid()
+	var writers openWriters
+	pid, command, access := 0, "", ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		switch field, value := line[0], line[1:]; field {
+		case 'p':
+			pid, _ = strconv.Atoi(value)
+		case 'c':
+			command = value
+		case 'f':
+			access = ""
+		case 'a':
+			access = value
+		case 'n':
+			if pid == self || !strings.ContainsAny(access, "wu") {
+				continue
+			}
+			if info, err := os.Stat(value); err == nil && info.Mode().IsRegular() {
+				writers = append(writers, openWriter{file: info, pid: pid, command: command})
+			}
+		}
+	}
+	return writers, nil
+}
@@ -0,0 +1,33 @@
+package photonsr
+
+import (
+	"fmt"
+	"os"
+)
+
+// --- File Ownership ---
+
+// A rewrite replaces a file with a new one, which belongs to the user running
+// PhotonSR. Run with sudo on a shared tree, that would leave every rewritten file
+// and backup owned by root. With -preserve-owner (which needs root) they are given
+// back the owner and group of the file they replace or copy.
+
+// PreserveOwner is set from -preserve-owner, before anything is written, when the
+// process runs as root.
+var PreserveOwner bool
+
+// keepOwner gives the file at path the owner and group recorded in info, if
+// PreserveOwner is set.
+func keepOwner(path string, info os.FileInfo) error {
+	if !PreserveOwner {
+		return nil
+	}
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return nil
+	}
+	if err := FS.Lchown(path, uid, gid); err != nil {
+		return fmt.Errorf("restoring owner %d:%d of '%s': %w", uid, gid, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package photonsr
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the owner and group of the file described by info.
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package photonsr
+
+import "os"
+
+// fileOwner returns the owner and group of the file described by info. Windows
+// files have no numeric owner, so there is nothing to preserve.
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,71 @@
+package photonsr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// --- Administrator Policy ---
+
+// ErrPolicy is wrapped by the errors of runs that the policy refuses, and by the
+// skip reason of files below its forbidden paths.
+var ErrPolicy = errors.New("refused by the administrator's policy")
+
+// forbiddenPath reports whether path is, or is below, one of the absolute paths of
+// forbidden, or has a name matching one of its patterns.
+func forbiddenPath(path string, forbidden []string) bool {
+	if len(forbidden) == 0 {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for _, f := range forbidden {
+		if filepath.IsAbs(f) {
+			if abs == f || Within(f, abs) {
+				return true
+			}
+		} else if matched, _ := MatchesPattern(filepath.Base(path), f); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// countModifications returns how many of the files at paths, with infos, opts would
+// modify, without writing anything. Files that cannot be read are not counted; the
+// run itself reports them.
+func countModifications(ctx context.Context, paths []string, infos []os.FileInfo, opts ReplaceOptions) (int, error) {
+	n := 0
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return n, fmt.Errorf("counting modifications interrupted: %w", err)
+		}
+		info := infos[i]
+		if forbiddenPath(path, opts.Forbidden) || (opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if opts.SkipBinary && bytes.IndexByte(content[:min(len(content), BinarySniffLen)], 0) >= 0 {
+			continue
+		}
+		changed, count := applyRules(content, opts.rulesFor(info.Name()))
+		if opts.transforms(info.Name()) {
+			if transformed, err := opts.Transform.Apply(path, changed); err == nil && !bytes.Equal(transformed, changed) {
+				count++
+			}
+		}
+		if count > 0 {
+			n++
+		}
+	}
+	return n, nil
+}
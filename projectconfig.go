@@ -0,0 +1,140 @@
+package photonsr
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --- Project Configuration ---
+
+// projectConfigName is the name of the project configuration file.
+const projectConfigName = ".photonsr.yaml"
+
+// ProjectConfig is the layout of the project configuration file:
+//
+//	exclusions:
+//	  - old: "Copyright 2023"
+//	    new: "Copyright 2024"
+//	    paths:
+//	      - third_party
+//	      - docs/HISTORY.md
+type ProjectConfig struct {
+	Exclusions []SavedExclusion `yaml:"exclusions"`
+}
+
+// SavedExclusion lists the paths a replacement of Old by New skips.
+type SavedExclusion struct {
+	Old   string   `yaml:"old"`
+	New   string   `yaml:"new"`
+	Paths []string `yaml:"paths"` // Relative to the directory of the file, with '/' separators.
+}
+
+// FindProjectConfig returns the path of the project configuration that applies to
+// dir, and whether it exists. Without one, the path is that of a new file in dir.
+func FindProjectConfig(dir string) (string, bool, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false, fmt.Errorf("resolving directory '%s': %w", dir, err)
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		path := filepath.Join(d, projectConfigName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, true, nil
+		}
+		if filepath.Dir(d) == d {
+			return filepath.Join(abs, projectConfigName), false, nil
+		}
+	}
+}
+
+// LoadProjectConfig reads the project configuration at path; a missing file is an
+// empty configuration.
+func LoadProjectConfig(path string) (ProjectConfig, error) {
+	var config ProjectConfig
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("reading project configuration: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&config); err != nil && len(bytes.TrimSpace(data)) > 0 {
+		return config, fmt.Errorf("parsing project configuration '%s': %w", path, err)
+	}
+	return config, nil
+}
+
+// Exclusion returns the saved exclusion of the replacement of oldText by newText, or nil.
+func (c *ProjectConfig) Exclusion(oldText, newText string) *SavedExclusion {
+	for i := range c.Exclusions {
+		if c.Exclusions[i].Old == oldText && c.Exclusions[i].New == newText {
+			return &c.Exclusions[i]
+		}
+	}
+	return nil
+}
+
+// ApplySavedExclusions restricts opts to the files outside the paths saved for its
+// replacement in the project configuration, keeping any restriction already in
+// opts.AllowedPaths. The configuration itself, which holds the old text, is left
+// alone too. Runs with a rules file have no saved exclusions.
+// Returns:
+//   - int: The number of saved paths that apply to opts.Dir.
+//   - string: The path of the project configuration.
+//   - error: An error if the configuration cannot be read.
+func ApplySavedExclusions(opts *ReplaceOptions) (int, string, error) {
+	if opts.OldText == "" || len(opts.Rules) > 0 {
+		return 0, "", nil
+	}
+	configPath, found, err := FindProjectConfig(opts.Dir)
+	if err != nil || !found {
+		return 0, "", err
+	}
+	config, err := LoadProjectConfig(configPath)
+	if err != nil {
+		return 0, configPath, err
+	}
+	ex := config.Exclusion(opts.OldText, opts.NewText)
+	if ex == nil {
+		return 0, configPath, nil
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return 0, configPath, fmt.Errorf("resolving directory '%s': %w", opts.Dir, err)
+	}
+	skipped := map[string]bool{}
+	applied := 0
+	for _, p := range ex.Paths {
+		path := filepath.Join(filepath.Dir(configPath), filepath.FromSlash(p))
+		if path == dir || Within(path, dir) {
+			opts.AllowedPaths = map[string]bool{} // The whole directory is excluded.
+			return 1, configPath, nil
+		}
+		if Within(dir, path) {
+			skipped[filepath.Join(opts.Dir, RelPath(dir, path))] = true
+			applied++
+		}
+	}
+	if Within(dir, configPath) {
+		skipped[filepath.Join(opts.Dir, RelPath(dir, configPath))] = true
+	}
+	allowed, err := AllowedPaths(opts.Dir, opts.Pattern, skipped)
+	if err != nil {
+		return 0, configPath, err
+	}
+	if opts.AllowedPaths != nil {
+		for path := range allowed {
+			if !opts.AllowedPaths[path] {
+				delete(allowed, path)
+			}
+		}
+	}
+	opts.AllowedPaths = allowed
+	return applied, configPath, nil
+}
@@ -0,0 +1,440 @@
+package photonsr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// --- File Renaming ---
+
+// "photonsr rename-files" replaces -old by -new in the names of the files matching
+// -pattern. All renames are planned first and checked against the naming rules of
+// the target system (-target-os): characters it forbids, reserved names, and length
+// limits of names and paths. A rename onto an existing file, or two files renamed
+// to the same name, is a collision. If any planned rename has a problem, they are
+// all reported and nothing is renamed. Backups keep their names.
+//
+// "photonsr move-files" is the same operation with new paths computed from the
+// -layout template instead, relative to -dir: "{ext}/{name}" groups files by
+// extension, "{yyyy}/{mm}/{name}" by modification date, "{name}" flattens the tree.
+// Directories are created as needed; directories left empty stay. Both operations
+// journal every rename, so an interrupted run can be rolled back like a replacement.
+
+// ErrInvalidRename is wrapped by the error of a rename run stopped by problems in
+// its plan.
+var ErrInvalidRename = errors.New("planned renames are invalid")
+
+// Target systems of -target-os.
+const (
+	TargetLinux    = "linux"
+	TargetDarwin   = "darwin"
+	TargetWindows  = "windows"
+	TargetPortable = "portable" // Valid on all of the above.
+)
+
+// RenameOptions holds all parameters for renaming files.
+type RenameOptions struct {
+	Dir     string // Target directory for the operation.
+	Pattern string // File pattern (glob) to match files to rename.
+	OldText string // The text to be replaced in file names.
+	NewText string // The text to replace the OldText with.
+
+	// Layout, if set, makes the operation move-files: each file is moved to the
+	// path the template gives, relative to Dir (see expandLayout), and OldText and
+	// NewText are not used.
+	Layout string
+
+	// TargetOS is the system whose naming rules new names must follow: TargetLinux,
+	// TargetDarwin, TargetWindows or TargetPortable; "" for the running system.
+	TargetOS string
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformRename from running with opts.
+func (opts RenameOptions) Validate() error {
+	if opts.Layout != "" {
+		if err := validateLayout(opts.Layout); err != nil {
+			return err
+		}
+	} else if opts.OldText == "" {
+		return ErrEmptyOldText
+	}
+	switch opts.TargetOS {
+	case "", TargetLinux, TargetDarwin, TargetWindows, TargetPortable:
+	default:
+		return fmt.Errorf("unknown target system '%s' (expected linux, darwin, windows or portable): %w", opts.TargetOS, ErrInvalidOption)
+	}
+	if err := ValidateDir(opts.Dir); err != nil {
+		return err
+	}
+	return ValidatePattern(opts.Pattern)
+}
+
+// System returns the system whose naming rules apply.
+func (opts RenameOptions) System() string {
+	if opts.TargetOS != "" {
+		return opts.TargetOS
+	}
+	switch runtime.GOOS {
+	case TargetDarwin, TargetWindows:
+		return runtime.GOOS
+	}
+	return TargetLinux
+}
+
+// operation returns the name of the operation opts describe.
+func (opts RenameOptions) operation() string {
+	if opts.Layout != "" {
+		return "move-files"
+	}
+	return "rename-files"
+}
+
+// NewName returns how the new path of it is shown: the new name of a renamed file,
+// or the path relative to Dir of a moved one.
+func (opts RenameOptions) NewName(it RenameItem) string {
+	if opts.Layout != "" {
+		return RelPath(opts.Dir, it.To)
+	}
+	return filepath.Base(it.To)
+}
+
+// layoutPlaceholder matches the placeholders of a -layout template.
+var layoutPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// validateLayout reports why layout is not a usable -layout template, or nil.
+func validateLayout(layout string) error {
+	if filepath.IsAbs(layout) || strings.HasPrefix(layout, "/") {
+		return fmt.Errorf("-layout '%s' must be relative to -dir: %w", layout, ErrInvalidOption)
+	}
+	for _, p := range layoutPlaceholder.FindAllString(layout, -1) {
+		switch p {
+		case "{name}", "{stem}", "{ext}", "{dir}", "{yyyy}", "{mm}", "{dd}":
+		default:
+			return fmt.Errorf("unknown -layout placeholder %s (expected {name}, {stem}, {ext}, {dir}, {yyyy}, {mm} or {dd}): %w", p, ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// expandLayout returns the path, relative to the target directory, that layout gives
+// the file at rel: {name} is its name, {stem} the name without extension, {ext} the
+// lower-case extension without the dot ("none" if it has none), {dir} its directory,
+// and {yyyy}, {mm} and {dd} the date it was last modified. A layout ending in "/"
+// keeps the name.
+func expandLayout(layout, rel string, info os.FileInfo) string {
+	if strings.HasSuffix(layout, "/") {
+		layout += "{name}"
+	}
+	name := info.Name()
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if ext == "" {
+		ext = "none"
+	}
+	mod := info.ModTime()
+	return filepath.Clean(filepath.FromSlash(strings.NewReplacer(
+		"{name}", name,
+		"{stem}", strings.TrimSuffix(name, filepath.Ext(name)),
+		"{ext}", ext,
+		"{dir}", filepath.ToSlash(filepath.Dir(rel)),
+		"{yyyy}", mod.Format("2006"),
+		"{mm}", mod.Format("01"),
+		"{dd}", mod.Format("02"),
+	).Replace(layout)))
+}
+
+// RenameItem is one planned rename.
+type RenameItem struct {
+	From, To string // Paths before and after.
+	Problem  string // Why the new name is invalid or taken; "" if it is not.
+	Collides int    // Included renames to the same new name, if more than one.
+	NoOp     bool   // The new name is the old one; nothing to do.
+	Excluded bool   // Left out by the user, e.g. in the wizard's review.
+}
+
+// Reason returns why the rename cannot be done, or "".
+func (it RenameItem) Reason() string {
+	switch {
+	case it.Problem != "":
+		return it.Problem
+	case it.Collides > 1:
+		return fmt.Sprintf("%d files would get this name", it.Collides)
+	}
+	return ""
+}
+
+// Pending reports whether the rename is to be done.
+func (it RenameItem) Pending() bool {
+	return !it.NoOp && !it.Excluded
+}
+
+// PlanRenames lists the renames of opts in path order, with their problems.
+func PlanRenames(opts RenameOptions) ([]RenameItem, error) {
+	var items []RenameItem
+	err := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			warn(opts.OnWarning, "PerformRename", "Access", fmt.Errorf("accessing path '%s': %w", path, errInWalk), "Skipping")
+			return nil
+		}
+		if IsInternalEntry(info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || opts.Layout == "" && !strings.Contains(info.Name(), opts.OldText) {
+			return nil
+		}
+		if _, _, isBackup := ParseBackupName(info.Name()); isBackup {
+			return nil
+		}
+		if matched, _ := MatchesPattern(info.Name(), opts.Pattern); !matched {
+			return nil
+		}
+		if opts.Layout != "" {
+			rel := RelPath(opts.Dir, path)
+			to := expandLayout(opts.Layout, rel, info)
+			items = append(items, RenameItem{From: path, To: filepath.Join(opts.Dir, to), NoOp: to == rel})
+			return nil
+		}
+		name := strings.ReplaceAll(info.Name(), opts.OldText, opts.NewText)
+		items = append(items, RenameItem{From: path, To: filepath.Join(filepath.Dir(path), name), NoOp: name == info.Name()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	target := opts.System()
+	for i := range items {
+		it := &items[i]
+		if it.NoOp {
+			continue
+		}
+		if opts.Layout != "" {
+			if problem := checkLayoutPath(opts.Dir, it.To, target); problem != "" {
+				it.Problem = problem
+				continue
+			}
+		} else if problem := checkFileName(filepath.Base(it.To), target); problem != "" {
+			it.Problem = problem
+			continue
+		}
+		if problem := checkPathLength(it.To, target); problem != "" {
+			it.Problem = problem
+			continue
+		}
+		if existing, err := os.Lstat(it.To); err == nil {
+			if current, err := os.Lstat(it.From); err != nil || !os.SameFile(existing, current) {
+				it.Problem = "a file with this name already exists"
+			}
+		}
+	}
+	MarkCollisions(items, target)
+	return items, nil
+}
+
+// MarkCollisions counts, for every pending rename, the pending renames to the same
+// new name, as target compares names.
+func MarkCollisions(items []RenameItem, target string) {
+	key := func(it RenameItem) string {
+		if target != TargetLinux || caseInsensitiveDir(filepath.Dir(it.From)) {
+			return strings.ToLower(it.To)
+		}
+		return it.To
+	}
+	counts := map[string]int{}
+	for _, it := range items {
+		if it.Pending() {
+			counts[key(it)]++
+		}
+	}
+	for i := range items {
+		items[i].Collides = 0
+		if items[i].Pending() {
+			items[i].Collides = counts[key(items[i])]
+		}
+	}
+}
+
+// windowsReserved are the device names Windows reserves, with or without extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// checkFileName returns why name cannot be a file name on target, or "".
+func checkFileName(name, target string) string {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Sprintf("'%s' is not a file name", name)
+	case strings.ContainsAny(name, "/\x00"):
+		return "the name contains '/' or a NUL character"
+	}
+	windows := target == TargetWindows || target == TargetPortable
+	darwin := target == TargetDarwin || target == TargetPortable
+	if windows {
+		if i := strings.IndexFunc(name, func(r rune) bool { return r < 0x20 || strings.ContainsRune(`<>:"\|?*`, r) }); i >= 0 {
+			return fmt.Sprintf("%q is not allowed in Windows file names", name[i:i+1])
+		}
+		base := strings.ToUpper(strings.TrimRight(strings.SplitN(name, ".", 2)[0], " "))
+		if windowsReserved[base] {
+			return fmt.Sprintf("%s is a reserved device name on Windows", base)
+		}
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+			return "Windows drops a trailing dot or space from file names"
+		}
+		if n := len(utf16.Encode([]rune(name))); n > 255 {
+			return fmt.Sprintf("the name is %d UTF-16 units long; Windows allows 255", n)
+		}
+	}
+	if darwin {
+		if strings.ContainsRune(name, ':') {
+			return "':' is shown as '/' on macOS"
+		}
+		if !utf8.ValidString(name) {
+			return "macOS file names must be valid UTF-8"
+		}
+	}
+	if len(name) > 255 {
+		return fmt.Sprintf("the name is %d bytes long; the limit is 255", len(name))
+	}
+	return ""
+}
+
+// checkLayoutPath returns why a file cannot be moved to path, a path inside dir
+// computed from a -layout template, or "".
+func checkLayoutPath(dir, path, target string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "the layout puts the file outside the directory"
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if problem := checkFileName(part, target); problem != "" {
+			return problem
+		}
+	}
+	for parent := filepath.Dir(path); parent != dir && parent != "."; parent = filepath.Dir(parent) {
+		if info, err := os.Lstat(parent); err == nil && !info.IsDir() {
+			return fmt.Sprintf("'%s' is a file, not a directory", RelPath(dir, parent))
+		}
+	}
+	return ""
+}
+
+// checkPathLength returns why path is too long on target, or "".
+func checkPathLength(path, target string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	limit := 4095 // PATH_MAX on Linux, less the terminating NUL.
+	switch target {
+	case TargetDarwin:
+		limit = 1023
+	case TargetWindows, TargetPortable:
+		limit = 259 // MAX_PATH, unless long paths are enabled.
+	}
+	if len(abs) > limit {
+		return fmt.Sprintf("the path is %d characters long; the limit on %s is %d", len(abs), target, limit)
+	}
+	return ""
+}
+
+// PerformRename renames or moves the files of opts.Dir as planned by PlanRenames. If any
+// planned rename has a problem, nothing is renamed and the error wraps
+// ErrInvalidRename.
+// Returns:
+//   - []string: Messages listing the renames done, or the problems found.
+//   - int: The number of files renamed or moved.
+//   - error: An error if a fatal issue occurred or the first non-fatal error.
+func PerformRename(opts RenameOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	items, err := PlanRenames(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ApplyRenames(opts, items)
+}
+
+// ApplyRenames performs the pending renames of items in a journaled run, unless one
+// of them has a problem.
+func ApplyRenames(opts RenameOptions, items []RenameItem) ([]string, int, error) {
+	var pending, problems []string
+	for _, it := range items {
+		if !it.Pending() {
+			continue
+		}
+		pending = append(pending, it.From)
+		if problem := it.Reason(); problem != "" {
+			problems = append(problems, fmt.Sprintf("  - %s -> %s: %s", RelPath(opts.Dir, it.From), opts.NewName(it), problem))
+		}
+	}
+	if len(pending) == 0 {
+		if opts.Layout != "" {
+			return []string{"Every file is already where the layout puts it."}, 0, nil
+		}
+		return []string{"No file names to change."}, 0, nil
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		messages := append([]string{fmt.Sprintf("%d of %d planned rename(s) cannot be done on %s; nothing was renamed:", len(problems), len(pending), opts.System())}, problems...)
+		return messages, 0, fmt.Errorf("%d planned rename(s) have problems: %w", len(problems), ErrInvalidRename)
+	}
+
+	journal, err := beginRunJournal(opts.Dir, opts.operation())
+	if err != nil {
+		return nil, 0, err
+	}
+	var messages []string
+	var firstEncounteredError error
+	renamed := 0
+	for _, it := range items {
+		if !it.Pending() {
+			continue
+		}
+		if err := journal.moveFile(it.From, it.To); err != nil {
+			renameErr := fmt.Errorf("renaming '%s': %w", it.From, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = renameErr
+			}
+			warn(opts.OnWarning, "PerformRename", "Rename", renameErr, "Skipping")
+			continue
+		}
+		renamed++
+		messages = append(messages, fmt.Sprintf("  - %s -> %s", RelPath(opts.Dir, it.From), opts.NewName(it)))
+	}
+	if err := journal.finish(); err != nil && firstEncounteredError == nil {
+		firstEncounteredError = err
+	}
+	if renamed > 0 {
+		header := "Renamed files:"
+		if opts.Layout != "" {
+			header = "Moved files:"
+		}
+		messages = append([]string{header}, messages...)
+	}
+	return messages, renamed, firstEncounteredError
+}
+
+// RelPath returns path relative to dir, or path itself if it has no such form.
+func RelPath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+	return path
+}
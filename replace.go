@@ -0,0 +1,607 @@
+package photonsr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// --- Replacement ---
+
+// SHA256Hex returns the hex-encoded SHA-256 digest of data.
+func SHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AllowedPaths returns the canonical paths of the files of dir matching pattern
+// that are neither skipped nor in a skipped directory, as a
+// ReplaceOptions.AllowedPaths set. Entries that cannot be read are left to the
+// replacement to report.
+func AllowedPaths(dir, pattern string, skipped map[string]bool) (map[string]bool, error) {
+	allowed := map[string]bool{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if skipped[path] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matched, _ := MatchesPattern(info.Name(), pattern); matched && !skipped[path] {
+			allowed[CanonicalPath(path)] = true
+		}
+		return nil
+	})
+	return allowed, err
+}
+
+// ReplaceOptions holds all parameters for the text replacement operation.
+type ReplaceOptions struct {
+	Dir          string // Target directory for the operation.
+	Pattern      string // File pattern (glob) to match files for replacement.
+	OldText      string // The text to be searched for and replaced.
+	NewText      string // The text to replace the OldText with.
+	ShouldBackup bool   // Flag indicating whether to create .bak backup files.
+
+	// UseRegex makes OldText a Go regular expression (RE2 syntax) and lets NewText
+	// refer to its capture groups as $1 or ${name}. Rules stay literal. Files too
+	// large for MaxMemory are skipped, since a match may span the whole file.
+	UseRegex bool
+
+	// IgnoreCase makes OldText and the text rules match regardless of case (see
+	// Rule.IgnoreCase); the new text is inserted exactly as given.
+	IgnoreCase bool
+
+	// Near, if set, limits OldText to the matches close to another text (see
+	// Near and -near). It does not apply to the text rules.
+	Near *Near
+
+	// NotPrecededBy and NotFollowedBy, if not empty, leave out the matches of
+	// OldText directly after or before that text (see Rule.NotPrecededBy).
+	// They do not apply to the text rules.
+	NotPrecededBy string
+	NotFollowedBy string
+
+	// Anchor, if not empty, keeps only the matches of OldText at the start or end of
+	// a line or of the file (see Rule.Anchor). It does not apply to the
+	// text rules or with UseRegex.
+	Anchor string
+
+	// LineMode replaces every line holding a match of OldText as a whole by NewText,
+	// or deletes it if NewText is empty (see Rule.Lines). It does not
+	// apply to the text rules.
+	LineMode bool
+
+	// DryRun only reports what the run would do: the files that would change are
+	// returned as modified, but nothing is written, backed up or journaled, and
+	// MaxModified is not checked. OnFileReplacements tells how much would change.
+	DryRun bool
+
+	// Rules is a per-file-type policy, usually from a rules file: each text rule
+	// replaces its Old by its New in the files its pattern matches (Pattern if it has
+	// none), and files matching a skip rule are left alone. All rules applying to a
+	// file are applied in order, in the same walk, followed by OldText -> NewText
+	// for files matching Pattern. OldText may be empty when Rules has text rules.
+	Rules     []RuleSpec
+	RulesFile string // Not processed if inside Dir, as it contains every old text.
+
+	// Transform, if set, also rewrites every file matching Pattern that it applies
+	// to, after the rules. OldText may then be empty. See Transform.
+	Transform Transform
+
+	// Confine skips files (reason wrapping ErrOutsideDir) that resolve outside Dir,
+	// through symbolic links or otherwise, and checks every path again before it is
+	// read or written, so the run cannot touch anything outside Dir.
+	Confine bool
+
+	// AllowedPaths, when non-nil, restricts processing to files whose canonical
+	// path (see CanonicalPath) is in the set. An empty, non-nil set matches nothing.
+	AllowedPaths map[string]bool
+
+	// BackupPolicy decides what happens when a file's .bak already exists:
+	// BackupPolicyOverwrite (default when empty), BackupPolicySkip, BackupPolicyVersion or BackupPolicyAsk.
+	BackupPolicy string
+	// ResolveBackupConflict is consulted per conflicting file when BackupPolicy is
+	// BackupPolicyAsk and returns the policy to apply to that file.
+	ResolveBackupConflict func(path string) string
+	// OnBackupConflict, if set, is called with the resolution applied to each file
+	// whose backup already existed.
+	OnBackupConflict func(path, resolution string)
+
+	// OnFileStarted, if set, is called when a worker starts on a file. Unlike the
+	// other callbacks it is called from the workers: one call at a time, but maybe
+	// while another callback runs.
+	OnFileStarted func(path string)
+	// OnFileModified, if set, is called after a file has been rewritten successfully
+	// with the hex SHA-256 of its content before and after the replacement (used e.g.
+	// by the audit log).
+	OnFileModified func(path, hashBefore, hashAfter string)
+	// OnFileReplacements, if set, is called for each file rewritten, or that would
+	// be in a dry run, with the number of replacements made in it; a Transform
+	// counts as one.
+	OnFileReplacements func(path string, count int)
+	// OnFileDiff, if set, is called after a file has been rewritten in memory with a
+	// sample of its changed lines (see sampleDiff). Streamed files have none.
+	OnFileDiff func(path string, lines []string)
+	// OnFilePatch, if set, is called after a file has been rewritten in memory with
+	// the unified diff of the change, its paths relative to Dir (see unifiedDiff).
+	// Streamed files have none; a warning tells which.
+	OnFilePatch func(path, patch string)
+	// OnFileError, if set, is called with the first error of each file that could
+	// not be processed (e.g. to record it for a later retry). The Err* sentinels classify it.
+	OnFileError func(path string, err error)
+	// OnFileSkipped, if set, is called for files deliberately left alone because of
+	// MaxFileSize, SkipBinary, Confine, Transform, Immutable, SkipOpen or Forbidden;
+	// reason wraps ErrTooLarge, ErrBinarySkipped, ErrOutsideDir, ErrNotApplicable,
+	// ErrImmutable, ErrOpenForWrite or ErrPolicy.
+	OnFileSkipped func(path string, reason error)
+	// OnWarning, if set, receives the non-fatal problems of the run (see Warning).
+	OnWarning func(Warning)
+
+	MaxFileSize int64  // If > 0, files larger than this many bytes are skipped.
+	SameLength  bool   // Require OldText and NewText to have the same length in bytes, so file offsets are kept (binary patches).
+	SkipBinary  bool   // Skip files that look binary (a NUL byte in the first 8000 bytes).
+	Immutable   string // Files marked immutable or append-only: ImmutableSkip (default when empty) or ImmutableError.
+	SkipOpen    bool   // Skip files open for writing by another process (reason wrapping ErrOpenForWrite).
+
+	// Forbidden and MaxModified carry the administrator's policy (see policy.enforce).
+	Forbidden   []string // Absolute paths, with everything below them, and name patterns of files left alone (reason wrapping ErrPolicy).
+	MaxModified int      // If > 0, the run is refused before anything is written (ErrPolicy) when it would modify more files.
+
+	// MaxMemory, if > 0, bounds the bytes of file content held in memory by all
+	// workers together. Workers wait for budget before loading a file, and files too
+	// large to ever fit are rewritten by streaming instead of being loaded.
+	MaxMemory int64
+
+	// Callbacks are never invoked concurrently, even when Jobs > 1.
+	Jobs   int    // Number of files processed concurrently (values below 1 mean 1).
+	SortBy string // Order of the returned modified files: SortByPath (default) or SortByCompletion.
+	Order  string // Order in which files are processed: OrderPath (default), OrderSizeDesc, OrderMtime or OrderInode.
+
+	ReadAhead int // Read buffer size, in bytes, for streamed files (0 = default).
+}
+
+// PerformReplacement is the core function for searching and replacing text in files.
+// Matching files are collected first and then processed by up to opts.Jobs workers;
+// the returned list of modified files is ordered according to opts.SortBy.
+// Returns:
+//   - []string: A slice of paths to files that were actually modified.
+//   - int: The total number of files that matched the pattern and were processed (read attempt).
+//   - error: An error if a fatal issue occurred or the first non-fatal error.
+func PerformReplacement(opts ReplaceOptions) ([]string, int, error) {
+	return PerformReplacementCtx(context.Background(), opts)
+}
+
+// PerformReplacementCtx is PerformReplacement with cancellation: once ctx is done, no
+// further file is started, files being rewritten are completed, and the run's journal
+// is closed normally. The returned error then wraps ctx.Err().
+func PerformReplacementCtx(ctx context.Context, opts ReplaceOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	confine, err := openConfinement(opts.Dir, opts.Confine)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer confine.close()
+
+	rulesFile := ""
+	if opts.RulesFile != "" {
+		rulesFile = CanonicalPath(opts.RulesFile)
+	}
+	var firstEncounteredError error
+	var candidates []string
+	var candidateInfos []os.FileInfo
+
+	walkErr := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			accessErr := fmt.Errorf("accessing path '%s': %w", path, errInWalk)
+			if firstEncounteredError == nil {
+				firstEncounteredError = accessErr
+			}
+			warn(opts.OnWarning, "PerformReplacement", "Access", accessErr, "Skipping")
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("replacement interrupted while scanning: %w", err)
+		}
+		if IsInternalEntry(info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		if (len(opts.rulesFor(info.Name())) == 0 && !opts.transforms(info.Name())) || (rulesFile != "" && CanonicalPath(path) == rulesFile) {
+			return nil
+		}
+		if opts.AllowedPaths != nil && !opts.AllowedPaths[CanonicalPath(path)] {
+			return nil
+		}
+		candidates = append(candidates, path)
+		candidateInfos = append(candidateInfos, info)
+		return nil
+	})
+	if walkErr != nil {
+		return []string{}, 0, walkErr
+	}
+	if len(candidates) == 0 {
+		return []string{}, 0, firstEncounteredError
+	}
+
+	if opts.MaxModified > 0 && !opts.DryRun {
+		n, err := countModifications(ctx, candidates, candidateInfos, opts)
+		if err != nil {
+			return []string{}, 0, err
+		}
+		if n > opts.MaxModified {
+			return []string{}, len(candidates), fmt.Errorf("the run would modify %d files, more than the %d allowed: %w", n, opts.MaxModified, ErrPolicy)
+		}
+	}
+
+	var writers openWriters
+	if opts.SkipOpen {
+		if writers, err = findOpenWriters(opts.Dir); err != nil {
+			warn(opts.OnWarning, "PerformReplacement", "OpenFiles", fmt.Errorf("finding files open for writing: %w", err), "Continuing without the check")
+		}
+	}
+
+	// Every rewrite is journaled so that an interrupted run can be rolled back.
+	var journal *runJournal // nil for a dry run.
+	if !opts.DryRun {
+		if journal, err = beginRunJournal(opts.Dir, "replace"); err != nil {
+			return []string{}, 0, err
+		}
+	}
+
+	// ResolveBackupConflict may prompt the user, so never call it from two workers at once.
+	var askMu sync.Mutex
+	if opts.ResolveBackupConflict != nil {
+		ask := opts.ResolveBackupConflict
+		opts.ResolveBackupConflict = func(path string) string {
+			askMu.Lock()
+			defer askMu.Unlock()
+			return ask(path)
+		}
+	}
+
+	// OnFileStarted is called from the workers, so never from two at once.
+	if opts.OnFileStarted != nil {
+		var startMu sync.Mutex
+		started := opts.OnFileStarted
+		opts.OnFileStarted = func(path string) {
+			startMu.Lock()
+			defer startMu.Unlock()
+			started(path)
+		}
+	}
+
+	outcomes := make([]fileOutcome, len(candidates))
+	modifiedFiles := []string{}
+	processed := 0
+	budget := newMemoryBudget(opts.MaxMemory)
+	order := dispatchOrder(candidateInfos, opts.Order)
+	forEachParallel(ctx, len(candidates), opts.Jobs, func(k int) {
+		i := order[k]
+		if opts.OnFileStarted != nil {
+			opts.OnFileStarted(candidates[i])
+		}
+		outcomes[i] = replaceInFile(candidates[i], candidateInfos[i], opts, journal, budget, confine, writers)
+	}, func(k int) {
+		i := order[k]
+		processed++
+		o := &outcomes[i]
+		for _, w := range o.warnings {
+			warn(opts.OnWarning, w.Op, w.Stage, w.Err, w.Action)
+		}
+		if o.resolution != "" && opts.OnBackupConflict != nil {
+			opts.OnBackupConflict(o.path, o.resolution)
+		}
+		if o.err != nil && opts.OnFileError != nil {
+			opts.OnFileError(o.path, o.err)
+		}
+		if o.skipped != nil && opts.OnFileSkipped != nil {
+			opts.OnFileSkipped(o.path, o.skipped)
+		}
+		if o.modified {
+			if opts.SortBy == SortByCompletion {
+				modifiedFiles = append(modifiedFiles, o.path)
+			}
+			if opts.OnFileModified != nil && !opts.DryRun {
+				opts.OnFileModified(o.path, o.hashBefore, o.hashAfter)
+			}
+			if opts.OnFileReplacements != nil {
+				opts.OnFileReplacements(o.path, o.count)
+			}
+			if o.diff != nil && opts.OnFileDiff != nil {
+				opts.OnFileDiff(o.path, o.diff)
+			}
+			if opts.OnFilePatch != nil {
+				if o.streamed {
+					warn(opts.OnWarning, "PerformReplacement", "Diff", fmt.Errorf("'%s' is too large to diff within the memory limit", o.path), "Listing it without a diff")
+				} else {
+					opts.OnFilePatch(o.path, o.patch)
+				}
+			}
+		}
+	})
+	if journal != nil {
+		if err := journal.finish(); err != nil && firstEncounteredError == nil {
+			firstEncounteredError = err
+		}
+	}
+	if processed < len(candidates) && ctx.Err() != nil {
+		firstEncounteredError = fmt.Errorf("replacement interrupted after %d of %d file(s): %w", processed, len(candidates), ctx.Err())
+	}
+
+	for _, o := range outcomes {
+		if o.modified && opts.SortBy != SortByCompletion {
+			modifiedFiles = append(modifiedFiles, o.path)
+		}
+		if o.err != nil && firstEncounteredError == nil {
+			firstEncounteredError = o.err
+		}
+	}
+	return modifiedFiles, len(candidates), firstEncounteredError
+}
+
+// fileOutcome is the result of processing a single file in PerformReplacement.
+type fileOutcome struct {
+	path       string
+	modified   bool      // The file was rewritten, or would be in a dry run.
+	count      int       // Replacements made in the file.
+	resolution string    // How an existing backup was handled ("" if there was none).
+	hashBefore string    // SHA-256 of the content before the rewrite.
+	hashAfter  string    // SHA-256 of the content after the rewrite.
+	diff       []string  // Sample of the changed lines, if ReplaceOptions.OnFileDiff is set.
+	patch      string    // Unified diff of the change, if ReplaceOptions.OnFilePatch is set.
+	streamed   bool      // The file was streamed rather than rewritten in memory.
+	skipped    error     // Why the file was deliberately left alone, if it was.
+	err        error     // First error encountered for this file.
+	warnings   []Warning // Problems met, passed to OnWarning in dispatch order.
+}
+
+// warn records a warning about the file for ReplaceOptions.OnWarning.
+func (o *fileOutcome) warn(stage string, err error, action string) {
+	o.warnings = append(o.warnings, Warning{Op: "PerformReplacement", Stage: stage, Err: err, Action: action})
+}
+
+// replaceInFile rewrites a single file through journal, backing it up first if
+// requested, loading it into memory within budget or streaming it if it can never
+// fit. Files and backups outside confine, and files open in writers, are left alone.
+// In a dry run, journal is nil and the file is only read.
+// It is safe to call concurrently for different paths.
+func replaceInFile(path string, info os.FileInfo, opts ReplaceOptions, journal *runJournal, budget *memoryBudget, confine *confinement, writers openWriters) fileOutcome {
+	outcome := fileOutcome{path: path}
+
+	if err := confine.check(path); err != nil {
+		outcome.skipped = err
+		return outcome
+	}
+	if forbiddenPath(path, opts.Forbidden) {
+		outcome.skipped = fmt.Errorf("'%s' is a forbidden path: %w", path, ErrPolicy)
+		return outcome
+	}
+	if opts.ShouldBackup {
+		if err := confine.check(BackupPathFor(path)); err != nil {
+			outcome.skipped = fmt.Errorf("backup of '%s': %w", path, err)
+			return outcome
+		}
+	}
+
+	if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+		outcome.skipped = fmt.Errorf("'%s' is %s, above the %s limit: %w", path, FormatSize(info.Size()), FormatSize(opts.MaxFileSize), ErrTooLarge)
+		return outcome
+	}
+	if opts.SkipBinary {
+		binary, err := looksBinary(path)
+		if err != nil {
+			outcome.err = fmt.Errorf("reading file '%s': %w", path, err)
+			outcome.warn("Read", outcome.err, "Skipping")
+			return outcome
+		}
+		if binary {
+			outcome.skipped = fmt.Errorf("'%s' looks binary: %w", path, ErrBinarySkipped)
+			return outcome
+		}
+	}
+
+	if err := writers.check(path, info); err != nil {
+		outcome.skipped = err
+		return outcome
+	}
+	if err := checkMutable(path); err != nil {
+		if opts.Immutable == ImmutableError {
+			outcome.err = err
+			outcome.warn("Immutable", err, "Skipping")
+		} else {
+			outcome.skipped = err
+		}
+		return outcome
+	}
+
+	transform := opts.transforms(info.Name())
+	stream, streamed := opts.Transform.(StreamTransform)
+	streamed = streamed && transform
+	if transform && !streamed && !budget.fits(inMemoryCost(info)) {
+		outcome.skipped = fmt.Errorf("'%s' is %s, too large to transform within the memory limit: %w", path, FormatSize(info.Size()), ErrTooLarge)
+		return outcome
+	}
+
+	rules := opts.rulesFor(info.Name())
+	cost := inMemoryCost(info)
+	if streamed {
+		return streamReplaceInFile(path, info, rules, stream, opts, journal, confine, outcome)
+	}
+	if !budget.fits(cost) {
+		for _, rule := range rules {
+			if !rule.ReadsWhole() {
+				continue
+			}
+			task := "check the surroundings of matches"
+			if rule.Regexp {
+				task = "match a regular expression"
+			}
+			outcome.skipped = fmt.Errorf("'%s' is %s, too large to %s within the memory limit: %w", path, FormatSize(info.Size()), task, ErrTooLarge)
+			return outcome
+		}
+		return streamReplaceInFile(path, info, rules, nil, opts, journal, confine, outcome)
+	}
+	budget.acquire(cost)
+	defer budget.release(cost)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		readErr := fmt.Errorf("reading file '%s': %w", path, err)
+		if outcome.err == nil {
+			outcome.err = readErr
+		}
+		outcome.warn("Read", readErr, "Skipping")
+		return outcome
+	}
+
+	newContent, count := applyRules(content, rules)
+	if transform {
+		transformed, err := opts.Transform.Apply(path, newContent)
+		switch {
+		case errors.Is(err, ErrNotApplicable):
+			outcome.skipped = err
+			return outcome
+		case err != nil:
+			transformErr := fmt.Errorf("transforming '%s': %w", path, err)
+			if outcome.err == nil {
+				outcome.err = transformErr
+			}
+			outcome.warn("Transform", transformErr, "Skipping modification for this file")
+			return outcome
+		case !bytes.Equal(transformed, newContent):
+			newContent = transformed
+			count++
+		}
+	}
+	outcome.count = count
+	if count > 0 && opts.DryRun {
+		outcome.modified = true
+		if opts.OnFileDiff != nil {
+			outcome.diff = sampleDiff(content, newContent, sampleDiffLines)
+		}
+		if opts.OnFilePatch != nil {
+			outcome.patch = filePatch(opts.Dir, path, content, newContent)
+		}
+		return outcome
+	}
+	if count > 0 {
+		if err := checkBeforeWrite(path, info, confine); err != nil {
+			if outcome.err == nil {
+				outcome.err = err
+			}
+			outcome.warn("Changed", err, "Skipping modification for this file")
+			return outcome
+		}
+		backedUp := backUpBeforeWrite(path, opts, &outcome)
+		if err := journal.replaceFile(path, content, newContent, info.Mode()); err != nil {
+			writeErr := fmt.Errorf("writing modified content to '%s': %w", path, err)
+			if outcome.err == nil {
+				outcome.err = writeErr
+			}
+			outcome.warn("Write", writeErr, "Skipping modification for this file")
+			return outcome
+		}
+		if backedUp {
+			markBackupCurrent(path, BackupPathFor(path))
+		}
+		outcome.modified = true
+		outcome.hashBefore, outcome.hashAfter = SHA256Hex(content), SHA256Hex(newContent)
+		if opts.OnFileDiff != nil {
+			outcome.diff = sampleDiff(content, newContent, sampleDiffLines)
+		}
+		if opts.OnFilePatch != nil {
+			outcome.patch = filePatch(opts.Dir, path, content, newContent)
+		}
+	}
+	return outcome
+}
+
+// backUpBeforeWrite makes the backup of path that opts ask for, right before the
+// file is rewritten, so that files left unchanged get none. The conflict resolution
+// and any error are recorded in outcome; an error does not stop the rewrite.
+// It reports whether the file has a backup, made now or kept from an earlier run.
+func backUpBeforeWrite(path string, opts ReplaceOptions, outcome *fileOutcome) bool {
+	if !opts.ShouldBackup {
+		return false
+	}
+	resolution, err := createBackupWithPolicy(path, opts.BackupPolicy, opts.ResolveBackupConflict)
+	outcome.resolution = resolution
+	if err != nil {
+		backupErr := fmt.Errorf("creating backup for '%s': %w", path, err)
+		if outcome.err == nil {
+			outcome.err = backupErr
+		}
+		outcome.warn("Backup", backupErr, "Continuing without backup for this file")
+		return false
+	}
+	return true
+}
+
+// BinarySniffLen is the number of leading bytes examined by looksBinary.
+const BinarySniffLen = 8000
+
+// looksBinary reports whether the file at path has a NUL byte in its first
+// BinarySniffLen bytes, the heuristic git uses to tell binary files from text.
+func looksBinary(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, BinarySniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(head[:n], 0) >= 0, nil
+}
+
+// checkBeforeWrite is checkUnchanged, preceded by a second confinement check of
+// path in case the tree was changed since the file was found.
+func checkBeforeWrite(path string, info os.FileInfo, confine *confinement) error {
+	if err := confine.check(path); err != nil {
+		return err
+	}
+	return checkUnchanged(path, info)
+}
+
+// checkUnchanged returns an error wrapping ErrChangedDuringRun if the file at path
+// no longer has the size and modification time it had when the run found it.
+func checkUnchanged(path string, info os.FileInfo) error {
+	current, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("checking '%s' before writing: %w", path, err)
+	}
+	if current.Size() != info.Size() || !current.ModTime().Equal(info.ModTime()) {
+		return fmt.Errorf("'%s' was modified by another process during the run: %w", path, ErrChangedDuringRun)
+	}
+	return nil
+}
+
+// MatchesPattern checks if a filename matches the given glob pattern.
+func MatchesPattern(filename, pattern string) (bool, error) {
+	if pattern == "" || pattern == "*" {
+		return true, nil
+	}
+	return filepath.Match(pattern, filename)
+}
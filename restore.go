@@ -0,0 +1,232 @@
+package photonsr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// --- Restore ---
+
+// RestoreOptions holds all parameters for the restore operation.
+type RestoreOptions struct {
+	Dir   string // Target directory for the operation.
+	Force bool   // Restore even when the current file was changed after its backup was made.
+
+	// Confine skips backups whose backup or original path resolves outside Dir.
+	Confine bool
+
+	// To, if set, is a directory the backed-up originals are copied into, at their
+	// paths relative to Dir, instead of being restored in place: the backups and the
+	// files of Dir are left alone. Force then overwrites files already in To.
+	To string
+
+	Jobs int // Number of backups restored concurrently (values below 1 mean 1).
+
+	// OnProgress, if set, is called after each backup has been handled with the
+	// number handled so far and the number found. It is never called concurrently.
+	OnProgress func(done, total int)
+	// OnFileRestored, if set, is called for each file restored with the hex SHA-256
+	// of its content before (empty if it did not exist) and after the restore (used
+	// e.g. by the audit log). It is never called concurrently.
+	OnFileRestored func(path, hashBefore, hashAfter string)
+	OnWarning      func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// PerformRestore restores files from .bak backups, each from its newest backup
+// (see newestBackups). Unless opts.Force is set, a backup
+// is not restored over a file that was modified after the backup was taken.
+// With opts.To, the originals are copied there instead (see RestoreOptions.To).
+// The backups are found first and then restored by up to opts.Jobs workers; the
+// messages keep the traversal order.
+// Returns:
+//   - []string: Slice of messages detailing individual actions taken.
+//   - int: Number of files successfully restored.
+//   - error: The first non-fatal error encountered or walk error.
+func PerformRestore(opts RestoreOptions) ([]string, int, error) {
+	return PerformRestoreCtx(context.Background(), opts)
+}
+
+// PerformRestoreCtx is PerformRestore with cancellation: once ctx is done, no further
+// backup is restored and the returned error wraps ctx.Err().
+func PerformRestoreCtx(ctx context.Context, opts RestoreOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	confine, err := openConfinement(opts.Dir, opts.Confine)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer confine.close()
+
+	backups, infos, firstEncounteredError, walkErr := findBackups(ctx, opts.Dir, "restore", opts.OnWarning, "PerformRestore")
+	if walkErr != nil {
+		return nil, 0, walkErr
+	}
+	if opts.To != "" {
+		if err := FS.MkdirAll(opts.To, 0o755); err != nil {
+			return nil, 0, fmt.Errorf("creating restore directory '%s': %w", opts.To, err)
+		}
+		backups, infos = outsideDir(opts.To, backups, infos) // Earlier copies are not backups of Dir.
+	}
+	backups, infos = newestBackups(backups, infos) // Older versions stay for a later restore.
+	outcomes := make([]backupOutcome, len(backups))
+	processed := forEachBackup(ctx, backups, opts.Jobs, func(i int) {
+		if opts.To != "" {
+			outcomes[i] = restoreBackupTo(backups[i], opts, confine)
+		} else {
+			outcomes[i] = restoreBackup(backups[i], infos[i], opts, confine)
+		}
+	}, func(i, done int) {
+		for _, w := range outcomes[i].warnings {
+			warn(opts.OnWarning, w.Op, w.Stage, w.Err, w.Action)
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, len(backups))
+		}
+	})
+
+	var messages []string
+	filesRestored := 0
+	filesSkipped := 0
+	for _, o := range outcomes {
+		if o.message != "" {
+			messages = append(messages, o.message)
+		}
+		if o.done {
+			filesRestored++
+			if opts.OnFileRestored != nil {
+				opts.OnFileRestored(o.path, o.hashBefore, o.hashAfter)
+			}
+		}
+		if o.kept {
+			filesSkipped++
+		}
+		if o.err != nil && firstEncounteredError == nil {
+			firstEncounteredError = o.err
+		}
+	}
+	if processed < len(backups) && ctx.Err() != nil {
+		return messages, filesRestored, fmt.Errorf("restore interrupted after %d of %d backup(s): %w", processed, len(backups), ctx.Err())
+	}
+	// Summary message for "no files found" is now primarily handled by the caller (CLI/TUI)
+	// based on filesRestored count and error state. This function returns the raw data.
+	// However, if this function were to be used standalone, a "no files found" message here might be useful.
+	// For now, we keep it lean. The TUI/CLI will explicitly check filesRestored.
+	if filesRestored == 0 && filesSkipped == 0 && firstEncounteredError == nil {
+		// This explicit message can be useful if this function is called directly
+		// and the caller doesn't build its own summary.
+		messages = append(messages, "No .bak files found to restore in the specified directory.")
+	}
+	return messages, filesRestored, firstEncounteredError
+}
+
+// restoreBackup restores the backup at path, with info, over its original file,
+// unless either resolves outside confine or, without opts.Force, the original
+// changed after the backup was made. It is safe to call concurrently for different
+// paths.
+func restoreBackup(path string, info os.FileInfo, opts RestoreOptions, confine *confinement) backupOutcome {
+	var o backupOutcome
+	originalPath := backupOriginal(path)
+	for _, p := range []string{path, originalPath} {
+		if err := confine.check(p); err != nil {
+			o.warn("PerformRestore", "Confine", err, "Skipping")
+			o.message = fmt.Sprintf("  - Skipped: %s resolves outside the target directory", p)
+			o.kept = true
+			return o
+		}
+	}
+	if !opts.Force {
+		newer, err := originalChangedSinceBackup(originalPath, path, info)
+		if err != nil {
+			o.err = fmt.Errorf("comparing '%s' with its backup: %w", originalPath, err)
+			o.warn("PerformRestore", "Compare", o.err, "Skipping")
+			return o
+		}
+		if newer {
+			o.warn("PerformRestore", "Newer", fmt.Errorf("'%s' was modified after its backup was made", originalPath), "Skipping (use -force to overwrite)")
+			o.message = fmt.Sprintf("  - Skipped: %s is newer than its backup %s", originalPath, path)
+			o.kept = true
+			return o
+		}
+	}
+	if opts.OnFileRestored != nil {
+		o.hashRestore(originalPath, path)
+	}
+	if err := FS.Rename(path, originalPath); err != nil {
+		o.err = fmt.Errorf("restoring backup '%s' to '%s': %w", path, originalPath, err)
+		o.warn("PerformRestore", "Rename", o.err, "")
+		return o
+	}
+	// The file is back to the content it had while the newest remaining version was
+	// the current backup, so that version can be restored in turn.
+	if n, err := nextBackupVersion(originalPath); err == nil && n > 1 {
+		markBackupCurrent(originalPath, versionedBackupPath(originalPath, n-1))
+	}
+	o.message = fmt.Sprintf("  - Restored: %s from %s", originalPath, path)
+	o.done = true
+	return o
+}
+
+// restoreBackupTo copies the backup at path to opts.To, at the path of its original
+// relative to opts.Dir, unless the backup resolves outside confine or, without
+// opts.Force, the copy exists already. It is safe to call concurrently for
+// different paths.
+func restoreBackupTo(path string, opts RestoreOptions, confine *confinement) backupOutcome {
+	var o backupOutcome
+	if err := confine.check(path); err != nil {
+		o.warn("PerformRestore", "Confine", err, "Skipping")
+		o.message = fmt.Sprintf("  - Skipped: %s resolves outside the target directory", path)
+		o.kept = true
+		return o
+	}
+	target := filepath.Join(opts.To, RelPath(opts.Dir, backupOriginal(path)))
+	if _, err := os.Lstat(target); err == nil && !opts.Force {
+		o.warn("PerformRestore", "Exists", fmt.Errorf("'%s' already exists", target), "Skipping (use -force to overwrite)")
+		o.message = fmt.Sprintf("  - Skipped: %s already exists", target)
+		o.kept = true
+		return o
+	}
+	if opts.OnFileRestored != nil {
+		o.hashRestore(target, path)
+	}
+	err := FS.MkdirAll(filepath.Dir(target), 0o755)
+	if err == nil {
+		err = CopyFile(path, target)
+	}
+	if err != nil {
+		o.err = fmt.Errorf("copying backup '%s' to '%s': %w", path, target, err)
+		o.warn("PerformRestore", "Copy", o.err, "")
+		return o
+	}
+	o.message = fmt.Sprintf("  - Restored: %s from %s", target, path)
+	o.done = true
+	return o
+}
+
+// hashRestore records in o the restore of the backup at backupPath to path, with
+// the hashes of both. A hash that cannot be computed is left empty.
+func (o *backupOutcome) hashRestore(path, backupPath string) {
+	o.path = path
+	o.hashBefore, _ = hashFile(path)
+	o.hashAfter, _ = hashFile(backupPath)
+}
+
+// outsideDir returns the backups at paths, with their infos, that are not below dir.
+func outsideDir(dir string, paths []string, infos []os.FileInfo) ([]string, []os.FileInfo) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return paths, infos
+	}
+	var keptPaths []string
+	var keptInfos []os.FileInfo
+	for i, path := range paths {
+		if p, err := filepath.Abs(path); err == nil && Within(abs, p) {
+			continue
+		}
+		keptPaths = append(keptPaths, path)
+		keptInfos = append(keptInfos, infos[i])
+	}
+	return keptPaths, keptInfos
+}
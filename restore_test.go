@@ -0,0 +1,150 @@
+package photonsr
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// replaceInTestDir runs PerformReplacement of old by new over dir with opts' other
+// fields, failing t on error.
+func replaceInTestDir(t *testing.T, dir, old, new string, opts ReplaceOptions) {
+	t.Helper()
+	opts.Dir, opts.OldText, opts.NewText = dir, old, new
+	if _, _, err := PerformReplacement(opts); err != nil {
+		t.Fatalf("replacing %q by %q: %v", old, new, err)
+	}
+}
+
+// readTestFile returns the content of the file at path, failing t on error.
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPerformRestoreVersions(t *testing.T) {
+	t.Setenv(StateDirEnv, t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("v0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := ReplaceOptions{Pattern: "*.txt", ShouldBackup: true, BackupPolicy: BackupPolicyVersion}
+	replaceInTestDir(t, dir, "v0", "v1", opts)
+	replaceInTestDir(t, dir, "v1", "v2", opts)
+
+	for _, want := range []string{"v1", "v0"} {
+		if _, n, err := PerformRestore(RestoreOptions{Dir: dir}); err != nil || n != 1 {
+			t.Fatalf("PerformRestore restored %d file(s), error %v; want 1 file restored", n, err)
+		}
+		if got := readTestFile(t, file); got != want {
+			t.Fatalf("after the restore a.txt is %q, want %q", got, want)
+		}
+	}
+	if _, n, _ := PerformRestore(RestoreOptions{Dir: dir}); n != 0 {
+		t.Errorf("a third PerformRestore restored %d file(s), want none", n)
+	}
+}
+
+func TestPerformReplacementBacksUpChangedFilesOnly(t *testing.T) {
+	t.Setenv(StateDirEnv, t.TempDir())
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "foo", "b.txt": "bar"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := ReplaceOptions{Pattern: "*.txt", ShouldBackup: true, BackupPolicy: BackupPolicyVersion}
+	replaceInTestDir(t, dir, "foo", "baz", opts)
+	replaceInTestDir(t, dir, "nothing", "x", opts)
+	replaceInTestDir(t, dir, "nothing", "x", opts)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.txt", "a.txt.bak", "b.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("the directory holds %v, want %v", names, want)
+	}
+}
+
+func TestPerformRestore(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name   string
+		edited bool // Whether the file was modified after its backup.
+		force  bool
+		want   string
+	}{
+		{"unchanged since the backup", false, false, "original"},
+		{"edited since the backup", true, false, "edited"},
+		{"edited and forced", true, true, "original"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "a.txt")
+			writeTestFile(t, file, "rewritten")
+			writeTestFile(t, file+".bak", "original")
+			if tt.edited {
+				setTestModTime(t, file+".bak", past)
+				writeTestFile(t, file, "edited")
+			} else {
+				markBackupCurrent(file, file+".bak")
+			}
+			_, n, err := PerformRestore(RestoreOptions{Dir: dir, Force: tt.force})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, file); got != tt.want {
+				t.Errorf("a.txt is %q, want %q", got, tt.want)
+			}
+			_, statErr := os.Stat(file + ".bak")
+			if restored := tt.want == "original"; (n == 1) != restored || os.IsNotExist(statErr) != restored {
+				t.Errorf("PerformRestore restored %d file(s), backup error %v", n, statErr)
+			}
+		})
+	}
+}
+
+func TestPerformRestoreTo(t *testing.T) {
+	dir, to := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "sub", "a.txt")
+	writeTestFile(t, file, "rewritten")
+	writeTestFile(t, file+".bak", "original")
+
+	if _, n, err := PerformRestore(RestoreOptions{Dir: dir, To: to}); err != nil || n != 1 {
+		t.Fatalf("PerformRestore restored %d file(s), error %v; want 1", n, err)
+	}
+	copied := filepath.Join(to, "sub", "a.txt")
+	if got := readTestFile(t, copied); got != "original" {
+		t.Errorf("the copy is %q, want \"original\"", got)
+	}
+	if got := readTestFile(t, file); got != "rewritten" {
+		t.Errorf("a.txt is %q, want it left alone", got)
+	}
+	if got := readTestFile(t, file+".bak"); got != "original" {
+		t.Errorf("the backup is %q, want it left alone", got)
+	}
+
+	writeTestFile(t, copied, "kept")
+	if _, n, _ := PerformRestore(RestoreOptions{Dir: dir, To: to}); n != 0 || readTestFile(t, copied) != "kept" {
+		t.Errorf("PerformRestore without Force restored %d file(s) over an existing copy", n)
+	}
+	if _, n, err := PerformRestore(RestoreOptions{Dir: dir, To: to, Force: true}); err != nil || n != 1 || readTestFile(t, copied) != "original" {
+		t.Errorf("PerformRestore with Force restored %d file(s), error %v; want the copy overwritten", n, err)
+	}
+}
@@ -0,0 +1,149 @@
+package photonsr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// --- Backup Retention ---
+
+// RetentionPolicy bounds how many backups are kept. Zero values mean "no limit".
+type RetentionPolicy struct {
+	KeepLast     int           // Maximum number of backups (.bak plus .bak.N) kept per file.
+	MaxAge       time.Duration // Backups last modified longer ago than this are removed.
+	MaxTotalSize int64         // Upper bound, in bytes, on the combined size of all backups.
+}
+
+// IsZero reports whether the policy imposes no limit at all.
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepLast <= 0 && p.MaxAge <= 0 && p.MaxTotalSize <= 0
+}
+
+// backupEntry is one backup file found while pruning.
+type backupEntry struct {
+	path    string
+	version int // 0 for the primary .bak, N for .bak.N (higher is more recent).
+	modTime time.Time
+	size    int64
+}
+
+// PruneOptions holds all parameters for the PerformPrune function.
+type PruneOptions struct {
+	Dir    string          // Target directory for the operation.
+	Policy RetentionPolicy // Limits the backups must be brought within.
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformPrune from running with opts.
+func (opts PruneOptions) Validate() error {
+	return ValidateDir(opts.Dir)
+}
+
+// PerformPrune deletes backups under opts.Dir that fall outside opts.Policy. Per file, the
+// primary .bak is considered the newest backup, followed by archived .bak.N files
+// in descending N. The size limit is enforced last, removing the oldest backups first.
+// Returns:
+//   - []string: Slice of messages detailing individual actions taken.
+//   - int: Number of backup files removed.
+//   - error: The first non-fatal error encountered or walk error.
+func PerformPrune(opts PruneOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	policy := opts.Policy
+	var messages []string
+	var firstEncounteredError error
+	filesPruned := 0
+
+	byOriginal := map[string][]backupEntry{}
+	walkErr := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			accessErr := fmt.Errorf("accessing '%s' during prune: %w", path, errInWalk)
+			if firstEncounteredError == nil {
+				firstEncounteredError = accessErr
+			}
+			warn(opts.OnWarning, "PerformPrune", "Access", accessErr, "Skipping")
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		original, version, ok := ParseBackupName(info.Name())
+		if !ok {
+			return nil
+		}
+		key := filepath.Join(filepath.Dir(path), original)
+		byOriginal[key] = append(byOriginal[key], backupEntry{path: path, version: version, modTime: info.ModTime(), size: info.Size()})
+		return nil
+	})
+	if walkErr != nil {
+		return messages, filesPruned, walkErr
+	}
+
+	remove := func(e backupEntry, reason string) bool {
+		if err := FS.Remove(e.path); err != nil {
+			removeErr := fmt.Errorf("pruning backup '%s': %w", e.path, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = removeErr
+			}
+			warn(opts.OnWarning, "PerformPrune", "Remove", removeErr, "")
+			return false
+		}
+		messages = append(messages, fmt.Sprintf("  - Pruned: %s (%s)", e.path, reason))
+		filesPruned++
+		return true
+	}
+
+	originals := make([]string, 0, len(byOriginal))
+	for original := range byOriginal {
+		originals = append(originals, original)
+	}
+	sort.Strings(originals)
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	var kept []backupEntry
+	for _, original := range originals {
+		entries := byOriginal[original]
+		sort.Slice(entries, func(i, j int) bool {
+			if (entries[i].version == 0) != (entries[j].version == 0) {
+				return entries[i].version == 0
+			}
+			return entries[i].version > entries[j].version
+		})
+		for i, e := range entries {
+			switch {
+			case policy.KeepLast > 0 && i >= policy.KeepLast:
+				remove(e, fmt.Sprintf("more than %d backup(s) of this file", policy.KeepLast))
+			case policy.MaxAge > 0 && e.modTime.Before(cutoff):
+				remove(e, fmt.Sprintf("older than %s", policy.MaxAge))
+			default:
+				kept = append(kept, e)
+			}
+		}
+	}
+
+	if policy.MaxTotalSize > 0 {
+		var total int64
+		for _, e := range kept {
+			total += e.size
+		}
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].modTime.Before(kept[j].modTime) })
+		for _, e := range kept {
+			if total <= policy.MaxTotalSize {
+				break
+			}
+			if remove(e, fmt.Sprintf("total backup size above %s", FormatSize(policy.MaxTotalSize))) {
+				total -= e.size
+			}
+		}
+	}
+
+	if filesPruned == 0 && firstEncounteredError == nil {
+		messages = append(messages, "No backups needed pruning under the retention policy.")
+	}
+	return messages, filesPruned, firstEncounteredError
+}
@@ -0,0 +1,96 @@
+package photonsr
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBackup is a backup file created by writeTestBackups.
+type testBackup struct {
+	name string
+	size int
+	age  time.Duration // How long ago the backup was last modified.
+}
+
+// writeTestBackups creates backups in a new directory and returns it.
+func writeTestBackups(t *testing.T, backups []testBackup) string {
+	t.Helper()
+	dir := t.TempDir()
+	now := time.Now()
+	for _, b := range backups {
+		path := filepath.Join(dir, b.name)
+		writeTestFile(t, path, strings.Repeat("x", b.size))
+		setTestModTime(t, path, now.Add(-b.age))
+	}
+	return dir
+}
+
+// remainingFiles returns the sorted names of the files in dir.
+func remainingFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPerformPrune(t *testing.T) {
+	hour := time.Hour
+	backups := []testBackup{
+		// The archived versions are older the lower their number, the current
+		// backup newest; the modification times of b's backups disagree with that.
+		{"a.txt.bak", 10, 1 * hour},
+		{"a.txt.bak.1", 10, 30 * hour},
+		{"a.txt.bak.2", 10, 20 * hour},
+		{"a.txt.bak.10", 10, 10 * hour},
+		{"b.txt.bak", 10, 40 * hour},
+		{"b.txt.bak.1", 10, 2 * hour},
+	}
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string // The backups left.
+	}{
+		{"no limit", RetentionPolicy{}, []string{"a.txt.bak", "a.txt.bak.1", "a.txt.bak.10", "a.txt.bak.2", "b.txt.bak", "b.txt.bak.1"}},
+		{"keep last", RetentionPolicy{KeepLast: 2}, []string{"a.txt.bak", "a.txt.bak.10", "b.txt.bak", "b.txt.bak.1"}},
+		{"keep last one", RetentionPolicy{KeepLast: 1}, []string{"a.txt.bak", "b.txt.bak"}},
+		{"max age", RetentionPolicy{MaxAge: 25 * hour}, []string{"a.txt.bak", "a.txt.bak.10", "a.txt.bak.2", "b.txt.bak.1"}},
+		{"max total size", RetentionPolicy{MaxTotalSize: 25}, []string{"a.txt.bak", "b.txt.bak.1"}},
+		{"keep last and max age", RetentionPolicy{KeepLast: 2, MaxAge: 5 * hour}, []string{"a.txt.bak", "b.txt.bak.1"}},
+		{"keep last and max total size", RetentionPolicy{KeepLast: 3, MaxTotalSize: 30}, []string{"a.txt.bak", "a.txt.bak.10", "b.txt.bak.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTestBackups(t, backups)
+			_, n, err := PerformPrune(PruneOptions{Dir: dir, Policy: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := remainingFiles(t, dir)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PerformPrune left %v, want %v", got, tt.want)
+			}
+			if want := len(backups) - len(tt.want); n != want {
+				t.Errorf("PerformPrune pruned %d file(s), want %d", n, want)
+			}
+		})
+	}
+}
+
+func TestPerformPruneIgnoresOtherFiles(t *testing.T) {
+	dir := writeTestBackups(t, []testBackup{{"a.txt", 10, 50 * time.Hour}, {"a.txt.bak.x", 10, 50 * time.Hour}})
+	if _, n, err := PerformPrune(PruneOptions{Dir: dir, Policy: RetentionPolicy{KeepLast: 1, MaxAge: time.Hour, MaxTotalSize: 1}}); err != nil || n != 0 {
+		t.Errorf("PerformPrune pruned %d file(s), error %v; want none", n, err)
+	}
+}
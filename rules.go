@@ -0,0 +1,118 @@
+package photonsr
+
+// --- Rules Files ---
+
+// RuleSpec is one rule of a rules file. Old and New may instead be given as
+// hexadecimal bytes (OldHex, NewHex). Lint configurations usually name the old
+// text Forbid, which is the same field under another key. A Skip rule has only a
+// pattern and keeps the files it matches out of the run altogether. Regex and
+// IgnoreCase set the matching of one rule, so that a single rules file can treat
+// file types differently.
+type RuleSpec struct {
+	Old       string `yaml:"old"`
+	OldHex    string `yaml:"old_hex"`
+	Forbid    string `yaml:"forbid"`
+	ForbidHex string `yaml:"forbid_hex"`
+	New       string `yaml:"new"`
+	NewHex    string `yaml:"new_hex"`
+	Pattern   string `yaml:"pattern"`  // File pattern; empty means the -pattern flag.
+	Message   string `yaml:"message"`  // Explanation reported with each occurrence.
+	Severity  string `yaml:"severity"` // SeverityError (default), SeverityWarning or SeverityInfo.
+	Skip      bool   `yaml:"skip"`     // Leave files matching Pattern alone (e.g. "*.min.js").
+
+	Regex      bool `yaml:"regex"`       // Old is a regular expression, as with -regex; New may use $1.
+	IgnoreCase bool `yaml:"ignore_case"` // Match Old regardless of case, as -ignore-case does for every rule.
+
+	HasNew bool `yaml:"-"` // New or NewHex was given, even if empty; required to replace.
+}
+
+// Severities of a rule. Only occurrences of SeverityError rules fail "photonsr lint";
+// "photonsr verify" fails on any occurrence.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ValidSeverity reports whether s is a known rule severity.
+func ValidSeverity(s string) bool {
+	return s == SeverityError || s == SeverityWarning || s == SeverityInfo
+}
+
+// RulesFile is the layout of a rules file:
+//
+//	rules:
+//	  - old: "Copyright 2023"
+//	    new: "Copyright 2024"
+//	    pattern: "*.go"
+//	  - old: 'v(\d+)\.(\d+)'
+//	    new: "v$1.$2.0"
+//	    pattern: "*.md"
+//	    regex: true
+//	  - forbid: "ioutil.ReadFile"
+//	    message: "use os.ReadFile"
+//	    severity: warning
+//	  - pattern: "*.min.js"
+//	    skip: true
+type RulesFile struct {
+	Rules []RuleSpec `yaml:"rules"`
+}
+
+// rulesFor returns the replacements to make in the file named name, in order (see
+// ReplaceOptions.Rules). None means the file is not processed.
+func (opts ReplaceOptions) rulesFor(name string) []Rule {
+	indexes, skipped := matchingRules(opts.Rules, name, opts.Pattern)
+	if skipped {
+		return nil
+	}
+	var rules []Rule
+	for _, i := range indexes {
+		rules = append(rules, opts.Rules[i].Rule(opts.IgnoreCase))
+	}
+	if opts.OldText != "" {
+		if matched, _ := MatchesPattern(name, opts.Pattern); matched {
+			rules = append(rules, Rule{Old: opts.OldText, New: opts.NewText, Regexp: opts.UseRegex, IgnoreCase: opts.IgnoreCase, Near: opts.Near,
+				NotPrecededBy: opts.NotPrecededBy, NotFollowedBy: opts.NotFollowedBy, Lines: opts.LineMode, Anchor: opts.Anchor})
+		}
+	}
+	return rules
+}
+
+// Rule returns the rule replacing r.Old by r.New, matching it regardless of case if
+// r.IgnoreCase or ignoreCase is set.
+func (r RuleSpec) Rule(ignoreCase bool) Rule {
+	return Rule{Old: r.Old, New: r.New, Regexp: r.Regex, IgnoreCase: r.IgnoreCase || ignoreCase}
+}
+
+// Contextual reports whether replacing OldText depends on the text around its
+// matches (Near, a guard, Anchor or LineMode), so that files are read whole to
+// replace it.
+func (opts ReplaceOptions) Contextual() bool {
+	return opts.Near != nil || opts.NotPrecededBy != "" || opts.NotFollowedBy != "" || opts.Anchor != "" || opts.LineMode
+}
+
+// applyRules applies rules to content one after the other and returns the result
+// with the total number of replacements.
+func applyRules(content []byte, rules []Rule) ([]byte, int) {
+	return ApplyAll(content, rules)
+}
+
+// matchingRules returns the indexes of the text rules that apply to the file named
+// name, in order: those whose pattern, or defaultPattern for rules without one,
+// matches it. It returns skipped if a skip rule matches the file, and then no rules.
+func matchingRules(rules []RuleSpec, name, defaultPattern string) (indexes []int, skipped bool) {
+	for i, r := range rules {
+		pattern := r.Pattern
+		if pattern == "" {
+			pattern = defaultPattern
+		}
+		if matched, _ := MatchesPattern(name, pattern); !matched {
+			continue
+		}
+		if r.Skip {
+			return nil, true
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, false
+}
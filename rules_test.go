@@ -0,0 +1,36 @@
+package photonsr
+
+import "testing"
+
+func TestRulesFor(t *testing.T) {
+	opts := ReplaceOptions{
+		Pattern: "*",
+		Rules: []RuleSpec{
+			{Old: `v(\d+)`, New: "version $1", Pattern: "*.go", Regex: true},
+			{Old: "colour", New: "color", Pattern: "*.md", IgnoreCase: true},
+			{Old: "v1", New: "one"},
+			{Pattern: "*.min.js", Skip: true},
+		},
+	}
+	tests := []struct {
+		name, content, want string
+	}{
+		{"main.go", "v1 v22 Colour", "version 1 version 22 Colour"},
+		{"README.md", `v1 v\d+ Colour COLOUR`, `one v\d+ color color`},
+		{"app.min.js", "v1 colour", "v1 colour"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := applyRules([]byte(tt.content), opts.rulesFor(tt.name)); string(got) != tt.want {
+				t.Errorf("the rules for %s turn %q into %q, want %q", tt.name, tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesForIgnoreCase(t *testing.T) {
+	opts := ReplaceOptions{Pattern: "*", IgnoreCase: true, Rules: []RuleSpec{{Old: "foo", New: "bar"}}}
+	if got, _ := applyRules([]byte("FOO"), opts.rulesFor("a.txt")); string(got) != "bar" {
+		t.Errorf("with IgnoreCase the rules turn \"FOO\" into %q, want \"bar\"", got)
+	}
+}
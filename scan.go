@@ -0,0 +1,445 @@
+package photonsr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- Content Scan Report ---
+
+// "photonsr scan" reports what the files a replacement would select contain, so
+// that options such as -skip-binary or -max-size can be chosen before running it:
+// how many are text and how many binary, the encodings and line-ending styles
+// found, the largest files, and, with -old, the files that contain the text and how
+// often. With -fuzzy, the near-misses of the text within that many edits (typos,
+// spacing variants) are listed too, to help decide on exact replacement rules.
+// Unlike the wizard's scan summary, which only reads metadata, every file is
+// read (streamed, so large files are fine); -max-size leaves larger ones unread.
+
+// scanListLimit is the number of files listed under each heading of the report.
+const scanListLimit = 10
+
+// Encodings reported by "photonsr scan".
+const (
+	encodingASCII    = "ASCII"
+	encodingUTF8     = "UTF-8"
+	encodingUTF8BOM  = "UTF-8 with BOM"
+	encodingUTF16LE  = "UTF-16LE"
+	encodingUTF16BE  = "UTF-16BE"
+	encodingEightBit = "other 8-bit (not UTF-8)"
+)
+
+// Line-ending styles reported by "photonsr scan".
+const (
+	endingsLF    = "LF"
+	endingsCRLF  = "CRLF"
+	endingsCR    = "CR"
+	endingsMixed = "mixed"
+	endingsNone  = "none"
+)
+
+// ScanOptions holds all parameters for PerformScan.
+type ScanOptions struct {
+	Dir         string // Target directory for the operation.
+	Pattern     string // File pattern (glob) of the files to scan.
+	Term        string // If set, files containing it are listed with their occurrence counts.
+	MaxFileSize int64  // If > 0, larger files are counted but not read.
+	Fuzzy       int    // If > 0, near-misses of Term within this many edits are listed too.
+
+	// AllowedPaths, when non-nil, restricts the scan to files whose canonical path
+	// (see CanonicalPath) is in the set, as for ReplaceOptions.
+	AllowedPaths map[string]bool
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// fileContent is what PerformScan found in one file.
+type fileContent struct {
+	binary   bool
+	encoding string // For text files.
+	endings  string // For text files.
+	matches  int    // Occurrences of the term.
+}
+
+// nearMiss is a variant of the term found by PerformScan with Fuzzy set.
+type nearMiss struct {
+	text     string
+	distance int
+	count    int    // Occurrences.
+	files    int    // Files containing it.
+	first    string // First file containing it, relative to the directory.
+}
+
+// PerformScan reads the files of opts.Dir that match opts.Pattern and reports what
+// they contain. Nothing is modified.
+// Returns:
+//   - []string: The report.
+//   - int: The number of files containing opts.Term.
+//   - int: The number of files scanned.
+//   - error: A fatal error or the first file that could not be read.
+func PerformScan(opts ScanOptions) ([]string, int, int, error) {
+	return PerformScanCtx(context.Background(), opts)
+}
+
+// PerformScanCtx is PerformScan with cancellation: once ctx is done, no further file
+// is read and the returned error wraps ctx.Err().
+func PerformScanCtx(ctx context.Context, opts ScanOptions) ([]string, int, int, error) {
+	if err := ValidateDir(opts.Dir); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := ValidatePattern(opts.Pattern); err != nil {
+		return nil, 0, 0, err
+	}
+	if opts.Fuzzy > 0 && opts.Fuzzy >= utf8.RuneCountInString(opts.Term) {
+		return nil, 0, 0, fmt.Errorf("-fuzzy %d needs an -old text longer than %d character(s), or anything would match: %w", opts.Fuzzy, opts.Fuzzy, ErrInvalidOption)
+	}
+	var firstEncounteredError error
+	var largest []SizedFile
+	var total int64
+	files, unread, text, binary := 0, 0, 0, 0
+	encodings, endings := map[string]int{}, map[string]int{}
+	var mixed, eightBit, containing []string
+	matches := map[string]int{}
+	totalMatches := 0
+	near := map[string]*nearMiss{}
+
+	walkErr := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			accessErr := fmt.Errorf("accessing path '%s': %w", path, errInWalk)
+			if firstEncounteredError == nil {
+				firstEncounteredError = accessErr
+			}
+			warn(opts.OnWarning, "PerformScan", "Access", accessErr, "Skipping")
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("scan interrupted: %w", err)
+		}
+		if IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || IsInternalEntry(info) {
+			return nil
+		}
+		if _, _, isBackup := ParseBackupName(info.Name()); isBackup {
+			return nil
+		}
+		if matched, _ := MatchesPattern(info.Name(), opts.Pattern); !matched {
+			return nil
+		}
+		if opts.AllowedPaths != nil && !opts.AllowedPaths[CanonicalPath(path)] {
+			return nil
+		}
+		files++
+		total += info.Size()
+		largest = append(largest, SizedFile{Path: path, Size: info.Size()})
+		sort.SliceStable(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+		if len(largest) > scanListLimit {
+			largest = largest[:scanListLimit]
+		}
+		if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+			unread++
+			return nil
+		}
+		content, err := readFileContent(path, []byte(opts.Term))
+		if err != nil {
+			readErr := fmt.Errorf("reading file '%s': %w", path, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = readErr
+			}
+			warn(opts.OnWarning, "PerformScan", "Read", readErr, "Skipping")
+			return nil
+		}
+		rel := RelPath(opts.Dir, path)
+		if content.binary {
+			binary++
+		} else {
+			text++
+			encodings[content.encoding]++
+			endings[content.endings]++
+			if content.endings == endingsMixed {
+				mixed = append(mixed, rel)
+			}
+			if content.encoding == encodingEightBit {
+				eightBit = append(eightBit, rel)
+			}
+		}
+		if opts.Fuzzy > 0 && !content.binary {
+			variants, err := findNearMisses(path, opts.Term, opts.Fuzzy)
+			if err != nil {
+				readErr := fmt.Errorf("reading file '%s': %w", path, err)
+				if firstEncounteredError == nil {
+					firstEncounteredError = readErr
+				}
+				warn(opts.OnWarning, "PerformScan", "Read", readErr, "Skipping")
+			}
+			for text, v := range variants {
+				n := near[text]
+				if n == nil {
+					n = &nearMiss{text: text, distance: v.distance, first: rel}
+					near[text] = n
+				}
+				n.count += v.count
+				n.files++
+			}
+		}
+		if content.matches > 0 {
+			containing = append(containing, rel)
+			matches[rel] = content.matches
+			totalMatches += content.matches
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, 0, files, walkErr
+	}
+
+	summary := fmt.Sprintf("Scanned %s file(s), %s.", FormatCount(files), FormatSize(total))
+	if unread > 0 {
+		summary = fmt.Sprintf("Scanned %s file(s), %s (%s larger than -max-size not read).", FormatCount(files), FormatSize(total), FormatCount(unread))
+	}
+	messages := []string{summary}
+	if files == 0 {
+		return messages, 0, files, firstEncounteredError
+	}
+	messages = append(messages, fmt.Sprintf("Content: %s text, %s binary.", FormatCount(text), FormatCount(binary)))
+	if text > 0 {
+		messages = append(messages, "Encodings: "+countList(encodings, []string{encodingASCII, encodingUTF8, encodingUTF8BOM, encodingUTF16LE, encodingUTF16BE, encodingEightBit})+".")
+		messages = append(messages, ListFiles("Not UTF-8 (replacement text is written as given):", eightBit, nil)...)
+		messages = append(messages, "Line endings: "+countList(endings, []string{endingsLF, endingsCRLF, endingsCR, endingsMixed, endingsNone})+".")
+		messages = append(messages, ListFiles("Mixed line endings:", mixed, nil)...)
+	}
+	var sizes []string
+	for _, f := range largest {
+		sizes = append(sizes, fmt.Sprintf("  - %s (%s)", RelPath(opts.Dir, f.Path), FormatSize(f.Size)))
+	}
+	messages = append(messages, "Largest files:")
+	messages = append(messages, sizes...)
+	if opts.Term != "" {
+		if len(containing) == 0 {
+			messages = append(messages, fmt.Sprintf("No file contains %q.", opts.Term))
+		} else {
+			sort.SliceStable(containing, func(i, j int) bool { return matches[containing[i]] > matches[containing[j]] })
+			header := fmt.Sprintf("%s file(s) contain %q (%s occurrence(s)):", FormatCount(len(containing)), opts.Term, FormatCount(totalMatches))
+			messages = append(messages, ListFiles(header, containing, matches)...)
+		}
+	}
+	if opts.Fuzzy > 0 {
+		messages = append(messages, nearMissMessages(opts.Term, opts.Fuzzy, near)...)
+	}
+	return messages, len(containing), files, firstEncounteredError
+}
+
+// countList formats counts as "key n, key n", in the order of keys, leaving out
+// keys without files.
+func countList(counts map[string]int, keys []string) string {
+	var parts []string
+	for _, k := range keys {
+		if counts[k] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", k, FormatCount(counts[k])))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ListFiles returns header followed by up to scanListLimit of paths, with their
+// count in counts if given, or nothing if paths is empty.
+func ListFiles(header string, paths []string, counts map[string]int) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	lines := []string{header}
+	for i, p := range paths {
+		if i == scanListLimit {
+			lines = append(lines, fmt.Sprintf("  ... and %s more", FormatCount(len(paths)-i)))
+			break
+		}
+		if counts != nil {
+			p = fmt.Sprintf("%s: %s", p, FormatCount(counts[p]))
+		}
+		lines = append(lines, "  - "+p)
+	}
+	return lines
+}
+
+// readFileContent streams the file at path and classifies its content, counting the
+// occurrences of term if it is not empty.
+func readFileContent(path string, term []byte) (fileContent, error) {
+	var c fileContent
+	f, err := os.Open(path)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64<<10)
+	var partial []byte // Incomplete UTF-8 sequence at the end of the previous chunk.
+	var tail []byte    // Last len(term)-1 bytes read, where a term may start.
+	lf, crlf, cr := 0, 0, 0
+	first, ascii, validUTF8, prevCR := true, true, true, false
+	for {
+		n, err := f.Read(buf)
+		chunk := buf[:n]
+		if first && n > 0 {
+			first = false
+			switch {
+			case bytes.HasPrefix(chunk, []byte{0xEF, 0xBB, 0xBF}):
+				c.encoding = encodingUTF8BOM
+			case bytes.HasPrefix(chunk, []byte{0xFF, 0xFE}):
+				c.encoding = encodingUTF16LE
+			case bytes.HasPrefix(chunk, []byte{0xFE, 0xFF}):
+				c.encoding = encodingUTF16BE
+			default:
+				if bytes.IndexByte(chunk[:min(n, BinarySniffLen)], 0) >= 0 {
+					c.binary = true
+					return c, nil
+				}
+			}
+		}
+		for _, b := range chunk {
+			if b >= 0x80 {
+				ascii = false
+			}
+			switch {
+			case b == '\n' && prevCR:
+				crlf++
+			case b == '\n':
+				lf++
+			case prevCR:
+				cr++
+			}
+			prevCR = b == '\r'
+		}
+		if !ascii && validUTF8 {
+			data := append(partial, chunk...)
+			end := len(data)
+			for k := 1; k <= utf8.UTFMax && k <= len(data); k++ {
+				if utf8.RuneStart(data[len(data)-k]) {
+					if !utf8.FullRune(data[len(data)-k:]) {
+						end = len(data) - k
+					}
+					break
+				}
+			}
+			validUTF8 = utf8.Valid(data[:end])
+			partial = append([]byte(nil), data[end:]...)
+		}
+		if len(term) > 0 && n > 0 {
+			data := append(tail, chunk...)
+			c.matches += bytes.Count(data, term)
+			tail = append([]byte(nil), data[max(len(data)-len(term)+1, 0):]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	if prevCR {
+		cr++
+	}
+	if c.encoding == "" {
+		switch {
+		case ascii:
+			c.encoding = encodingASCII
+		case validUTF8 && len(partial) == 0:
+			c.encoding = encodingUTF8
+		default:
+			c.encoding = encodingEightBit
+		}
+	}
+	styles := 0
+	for _, n := range []int{lf, crlf, cr} {
+		if n > 0 {
+			styles++
+		}
+	}
+	switch {
+	case styles == 0:
+		c.endings = endingsNone
+	case styles > 1:
+		c.endings = endingsMixed
+	case lf > 0:
+		c.endings = endingsLF
+	case crlf > 0:
+		c.endings = endingsCRLF
+	default:
+		c.endings = endingsCR
+	}
+	return c, nil
+}
+
+// findNearMisses returns the variants of term within maxDistance edits found in
+// the lines of the file at path, by text, with their distance and occurrences.
+// Exact occurrences are left out.
+func findNearMisses(path, term string, maxDistance int) (map[string]nearMiss, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	variants := map[string]nearMiss{}
+	r := bufio.NewReaderSize(f, 64<<10)
+	for {
+		line, err := r.ReadBytes('\n')
+		for _, m := range FindApproximate(bytes.TrimSuffix(line, []byte{'\n'}), term, maxDistance) {
+			if m.Distance > 0 {
+				text := string(line[m.Start:m.End])
+				v := variants[text]
+				v.distance = m.Distance
+				v.count++
+				variants[text] = v
+			}
+		}
+		if err == io.EOF {
+			return variants, nil
+		}
+		if err != nil {
+			return variants, err
+		}
+	}
+}
+
+// nearMissMessages lists the near-misses of term, the most frequent first.
+func nearMissMessages(term string, maxDistance int, near map[string]*nearMiss) []string {
+	if len(near) == 0 {
+		return []string{fmt.Sprintf("No near-misses of %q within %d edit(s).", term, maxDistance)}
+	}
+	list := make([]*nearMiss, 0, len(near))
+	for _, n := range near {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].count != list[j].count {
+			return list[i].count > list[j].count
+		}
+		if list[i].distance != list[j].distance {
+			return list[i].distance < list[j].distance
+		}
+		return list[i].text < list[j].text
+	})
+	messages := []string{fmt.Sprintf("%s near-miss(es) of %q within %d edit(s):", FormatCount(len(list)), term, maxDistance)}
+	for i, n := range list {
+		if i == scanListLimit {
+			messages = append(messages, fmt.Sprintf("  ... and %s more", FormatCount(len(list)-i)))
+			break
+		}
+		messages = append(messages, fmt.Sprintf("  - %q (%d edit(s)): %s occurrence(s) in %s file(s), e.g. %s", n.text, n.distance, FormatCount(n.count), FormatCount(n.files), n.first))
+	}
+	return messages
+}
+
+// SizedFile is a file with its size, as listed among the largest files.
+type SizedFile struct {
+	Path string
+	Size int64
+}
@@ -0,0 +1,52 @@
+package photonsr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// --- Hardened Systems ---
+
+// On hardened Linux systems a write can fail for reasons a plain permission error
+// does not explain: files marked immutable or append-only (chattr +i, +a) cannot
+// be replaced even by root, and SELinux may deny an access the file mode allows.
+// Immutable files are found before anything is written and skipped (or failed,
+// with -immutable error); denied writes name SELinux when it is enforcing. A
+// rewrite creates a new file, which would get the default security context of its
+// directory, so the context of the original is copied onto it first.
+
+var (
+	// ErrImmutable is wrapped by the skip reason of files marked immutable or
+	// append-only.
+	ErrImmutable = errors.New("file is immutable")
+	// ErrSecurityContext is wrapped by failures to keep the SELinux context of a
+	// rewritten file.
+	ErrSecurityContext = errors.New("security context not preserved")
+)
+
+// What a replacement does with immutable files (ReplaceOptions.Immutable).
+const (
+	ImmutableSkip  = "skip"  // Leave them alone and report them as skipped.
+	ImmutableError = "error" // Report them as failed files.
+)
+
+// checkMutable returns an error wrapping ErrImmutable if the file at path is
+// marked immutable or append-only.
+func checkMutable(path string) error {
+	switch immutable, appendOnly := fileAttributes(path); {
+	case immutable:
+		return fmt.Errorf("'%s' is marked immutable (chattr -i to allow changes): %w", path, ErrImmutable)
+	case appendOnly:
+		return fmt.Errorf("'%s' is marked append-only (chattr -a to allow changes): %w", path, ErrImmutable)
+	}
+	return nil
+}
+
+// explainDenied adds the likely cause to a permission error met while writing
+// path, when SELinux is enforcing.
+func explainDenied(err error) error {
+	if err == nil || !errors.Is(err, ErrPermission) || !selinuxEnforcing() {
+		return err
+	}
+	return fmt.Errorf("%w (SELinux is enforcing; check the denials with 'ausearch -m avc -ts recent')", err)
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package photonsr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Inode flags read with FS_IOC_GETFLAGS (see ioctl_iflags(2)).
+const (
+	fsIocGetFlags = 0x80086601
+	fsImmutableFl = 0x00000010
+	fsAppendFl    = 0x00000020
+)
+
+// selinuxXattr holds the SELinux context of a file.
+const selinuxXattr = "security.selinux"
+
+// fileAttributes reports whether the file at path is marked immutable or
+// append-only. File systems without inode flags report neither.
+func fileAttributes(path string) (immutable, appendOnly bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, false
+	}
+	defer f.Close()
+	var flags int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocGetFlags, uintptr(unsafe.Pointer(&flags))); errno != 0 {
+		return false, false
+	}
+	return flags&fsImmutableFl != 0, flags&fsAppendFl != 0
+}
+
+// selinuxEnforcing reports whether SELinux is enabled and enforcing.
+func selinuxEnforcing() bool {
+	data, err := os.ReadFile("/sys/fs/selinux/enforce")
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+// copySecurityContext gives dst the SELinux context of src, if src has one. The
+// error wraps ErrSecurityContext.
+func copySecurityContext(src, dst string) error {
+	buf := make([]byte, 256)
+	n, err := syscall.Getxattr(src, selinuxXattr, buf)
+	if errors.Is(err, syscall.ERANGE) {
+		if n, err = syscall.Getxattr(src, selinuxXattr, nil); err == nil {
+			buf = make([]byte, n)
+			n, err = syscall.Getxattr(src, selinuxXattr, buf)
+		}
+	}
+	if err != nil || n == 0 {
+		return nil // No context (no SELinux, or a file system without labels).
+	}
+	current := make([]byte, n+1)
+	if m, err := syscall.Getxattr(dst, selinuxXattr, current); err == nil && string(current[:m]) == string(buf[:n]) {
+		return nil
+	}
+	if err := FS.Setxattr(dst, selinuxXattr, buf[:n]); err != nil {
+		return fmt.Errorf("setting SELinux context %s on '%s': %v: %w", strings.TrimRight(string(buf[:n]), "\x00"), dst, err, ErrSecurityContext)
+	}
+	return nil
+}
+
+// setxattr sets the extended attribute attr of the file at path to data.
+func setxattr(path, attr string, data []byte) error {
+	return syscall.Setxattr(path, attr, data, 0)
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package photonsr
+
+import "errors"
+
+// fileAttributes reports whether the file at path is marked immutable or
+// append-only. Inode flags are only read on Linux.
+func fileAttributes(path string) (immutable, appendOnly bool) {
+	return false, false
+}
+
+// selinuxEnforcing reports whether SELinux is enforcing, which it never is
+// outside Linux.
+func selinuxEnforcing() bool {
+	return false
+}
+
+// copySecurityContext keeps the SELinux context of src on dst; there is none to
+// keep outside Linux.
+func copySecurityContext(src, dst string) error {
+	return nil
+}
+
+// setxattr sets an extended attribute, which PhotonSR only does on Linux.
+func setxattr(path, attr string, data []byte) error {
+	return errors.ErrUnsupported
+}
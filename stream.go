@@ -0,0 +1,137 @@
+package photonsr
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// streamChunkSize is the amount of input ApplyStream reads at a time.
+const streamChunkSize = 64 * 1024
+
+// ReadsWhole reports whether ApplyStream reads the input of rule whole rather than
+// a chunk at a time: for a Regexp rule, or one with Near, a guard (NotPrecededBy,
+// NotFollowedBy), Lines or an Anchor, whose matches depend on text anywhere around
+// them. Such input must fit in memory.
+func (rule Rule) ReadsWhole() bool {
+	return rule.Regexp || rule.Near != nil || rule.NotPrecededBy != "" || rule.NotFollowedBy != "" || rule.Lines || rule.Anchor != ""
+}
+
+// ApplyStream copies src to dst, replacing every occurrence of rule.Old by rule.New,
+// without holding more than a chunk of the input in memory. It replaces exactly the
+// occurrences Apply would, and returns how many were replaced. A Regexp rule can
+// match text of any length, so its input is read whole and passed to Apply.
+// IgnoreCase rules are streamed like literal ones: a match of Old in another case
+// has as many characters, each of at most utf8.UTFMax bytes. The rules depending on
+// the text around their matches are read whole too (see ReadsWhole).
+func ApplyStream(dst io.Writer, src io.Reader, rule Rule) (int, error) {
+	if rule.Old == "" {
+		_, err := io.Copy(dst, src)
+		return 0, err
+	}
+	if rule.ReadsWhole() {
+		content, err := io.ReadAll(src)
+		if err != nil {
+			return 0, err
+		}
+		content, matches := Apply(content, rule)
+		_, err = dst.Write(content)
+		return len(matches), err
+	}
+	if rule.IgnoreCase {
+		return applyStreamFolded(dst, src, rule)
+	}
+	old, repl := []byte(rule.Old), []byte(rule.New)
+	buf := make([]byte, 0, streamChunkSize+len(old))
+	chunk := make([]byte, streamChunkSize)
+	count := 0
+	for {
+		n, readErr := src.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		atEOF := readErr == io.EOF
+		if readErr != nil && !atEOF {
+			return count, readErr
+		}
+
+		pos := 0
+		for {
+			i := bytes.Index(buf[pos:], old)
+			if i < 0 {
+				break
+			}
+			if _, err := dst.Write(buf[pos : pos+i]); err != nil {
+				return count, err
+			}
+			if _, err := dst.Write(repl); err != nil {
+				return count, err
+			}
+			count++
+			pos += i + len(old)
+		}
+		// A match starting before keep would have been found above, so everything
+		// before it is final; the tail may be the start of a match split by the chunk.
+		keep := len(buf)
+		if !atEOF {
+			keep = max(pos, len(buf)-len(old)+1)
+		}
+		if _, err := dst.Write(buf[pos:keep]); err != nil {
+			return count, err
+		}
+		if atEOF {
+			return count, nil
+		}
+		buf = append(buf[:0], buf[keep:]...)
+	}
+}
+
+// applyStreamFolded is ApplyStream for a literal rule with IgnoreCase set.
+func applyStreamFolded(dst io.Writer, src io.Reader, rule Rule) (int, error) {
+	re, err := rule.compile()
+	if err != nil {
+		_, err := io.Copy(dst, src)
+		return 0, err
+	}
+	maxLen := utf8.UTFMax * utf8.RuneCountInString(rule.Old) // Longest possible match.
+	repl := []byte(rule.New)
+	buf := make([]byte, 0, streamChunkSize+maxLen)
+	chunk := make([]byte, streamChunkSize)
+	count := 0
+	for {
+		n, readErr := src.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		atEOF := readErr == io.EOF
+		if readErr != nil && !atEOF {
+			return count, readErr
+		}
+
+		// Matches starting before keep lie wholly in buf, so they are final; later
+		// ones may continue in the next chunk.
+		keep := len(buf)
+		if !atEOF {
+			keep = max(len(buf)-maxLen+1, 0)
+		}
+		pos := 0
+		for {
+			loc := re.FindIndex(buf[pos:])
+			if loc == nil || pos+loc[0] >= keep {
+				break
+			}
+			if _, err := dst.Write(buf[pos : pos+loc[0]]); err != nil {
+				return count, err
+			}
+			if _, err := dst.Write(repl); err != nil {
+				return count, err
+			}
+			count++
+			pos += loc[1]
+		}
+		keep = max(keep, pos)
+		if _, err := dst.Write(buf[pos:keep]); err != nil {
+			return count, err
+		}
+		if atEOF {
+			return count, nil
+		}
+		buf = append(buf[:0], buf[keep:]...)
+	}
+}
@@ -0,0 +1,49 @@
+package photonsr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadsWhole(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want bool
+	}{
+		{Rule{Old: "a"}, false},
+		{Rule{Old: "a", IgnoreCase: true}, false},
+		{Rule{Old: "a", Regexp: true}, true},
+		{Rule{Old: "a", Near: &Near{Term: "b"}}, true},
+		{Rule{Old: "a", NotPrecededBy: "b"}, true},
+		{Rule{Old: "a", NotFollowedBy: "b"}, true},
+		{Rule{Old: "a", Lines: true}, true},
+		{Rule{Old: "a", Anchor: AnchorEOL}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.ReadsWhole(); got != tt.want {
+			t.Errorf("%+v.ReadsWhole() = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+// TestApplyStream checks that ApplyStream replaces what Apply does, for an input
+// of several chunks with occurrences across their boundaries.
+func TestApplyStream(t *testing.T) {
+	content := []byte(strings.Repeat("x", streamChunkSize-2) + "foo\nFOO " + strings.Repeat("foo bar\n", streamChunkSize/4))
+	rules := []Rule{
+		{Old: "foo", New: "baz"},
+		{Old: "foo", New: "", IgnoreCase: true},
+		{Old: "foo", New: "-", Anchor: AnchorBOL},
+		{Old: "foo", New: "-", NotFollowedBy: "\n"},
+		{Old: "bar", New: "L", Lines: true},
+	}
+	for _, rule := range rules {
+		var out bytes.Buffer
+		n, err := ApplyStream(&out, bytes.NewReader(content), rule)
+		want, matches := Apply(content, rule)
+		if err != nil || n != len(matches) || !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("ApplyStream(%+v) replaced %d (err %v), Apply %d; same output: %v", rule, n, err, len(matches), bytes.Equal(out.Bytes(), want))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package photonsr
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// --- Streaming Replacement ---
+
+// inMemoryCost estimates the memory needed to rewrite a file in memory: its content
+// plus the rewritten copy.
+func inMemoryCost(info os.FileInfo) int64 {
+	return 2 * info.Size()
+}
+
+// streamReplaceInFile is the part of replaceInFile after the checks for files that
+// do not fit in the memory budget, or are handled by a StreamTransform. The file is
+// scanned once to see whether it needs a rewrite and then, after the backup step,
+// rewritten by streaming it through ApplyStream and the transform, if not nil. A nil
+// journal is a dry run, which stops after the scan.
+func streamReplaceInFile(path string, info os.FileInfo, rules []Rule, transform StreamTransform, opts ReplaceOptions, journal *runJournal, confine *confinement, outcome fileOutcome) fileOutcome {
+	outcome.streamed = true
+	count, err := streamRules(path, io.Discard, nil, rules, transform, opts.ReadAhead)
+	if err != nil {
+		readErr := fmt.Errorf("reading file '%s': %w", path, err)
+		if outcome.err == nil {
+			outcome.err = readErr
+		}
+		outcome.warn("Read", readErr, "Skipping")
+		return outcome
+	}
+	outcome.count = count
+	if count == 0 {
+		return outcome
+	}
+	if journal == nil {
+		outcome.modified = true
+		return outcome
+	}
+
+	if err := checkBeforeWrite(path, info, confine); err != nil {
+		if outcome.err == nil {
+			outcome.err = err
+		}
+		outcome.warn("Changed", err, "Skipping modification for this file")
+		return outcome
+	}
+	backedUp := backUpBeforeWrite(path, opts, &outcome)
+	before, after := sha256.New(), sha256.New()
+	err = journal.replaceFileStream(path, info.Mode(), func(w io.Writer) error {
+		_, err := streamRules(path, io.MultiWriter(w, after), before, rules, transform, opts.ReadAhead)
+		return err
+	})
+	if err != nil {
+		writeErr := fmt.Errorf("writing modified content to '%s': %w", path, err)
+		if outcome.err == nil {
+			outcome.err = writeErr
+		}
+		outcome.warn("Write", writeErr, "Skipping modification for this file")
+		return outcome
+	}
+	if backedUp {
+		markBackupCurrent(path, BackupPathFor(path))
+	}
+	outcome.modified = true
+	outcome.hashBefore = hex.EncodeToString(before.Sum(nil))
+	outcome.hashAfter = hex.EncodeToString(after.Sum(nil))
+	return outcome
+}
+
+// streamRules streams the file at path through rules and transform, if not nil,
+// into dst, also copying the original content to tee if it is non-nil. readAhead,
+// if > 0, sets the size of the reads issued to the file. It returns the number of
+// replacements.
+func streamRules(path string, dst, tee io.Writer, rules []Rule, transform StreamTransform, readAhead int) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var src io.Reader = f
+	if readAhead > 0 {
+		src = bufio.NewReaderSize(f, readAhead)
+	}
+	if tee != nil {
+		src = io.TeeReader(src, tee)
+	}
+	if transform == nil {
+		return applyStreamRules(dst, src, rules)
+	}
+	pr, pw := io.Pipe()
+	rulesCount := make(chan int, 1)
+	go func() {
+		n, err := applyStreamRules(pw, src, rules)
+		pw.CloseWithError(err)
+		rulesCount <- n
+	}()
+	n, err := transform.ApplyStream(dst, pr)
+	pr.CloseWithError(err) // Unblocks the rules if the transform failed.
+	return n + <-rulesCount, err
+}
+
+// applyStreamRules is applyRules for streams: each rule after the first reads the
+// output of the previous one through a pipe, so the rules run concurrently and the
+// file is still read only once.
+func applyStreamRules(dst io.Writer, src io.Reader, rules []Rule) (int, error) {
+	switch len(rules) {
+	case 0:
+		_, err := io.Copy(dst, src)
+		return 0, err
+	case 1:
+		return ApplyStream(dst, src, rules[0])
+	}
+	pr, pw := io.Pipe()
+	firstCount := make(chan int, 1)
+	go func() {
+		n, err := ApplyStream(pw, src, rules[0])
+		pw.CloseWithError(err)
+		firstCount <- n
+	}()
+	n, err := applyStreamRules(dst, pr, rules[1:])
+	pr.CloseWithError(err) // Unblocks the first rule if the rest failed.
+	return n + <-firstCount, err
+}
@@ -0,0 +1,299 @@
+package photonsr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Empty File and Directory Cleanup ---
+
+// "photonsr tidy" removes the zero-byte files and the empty directories below the
+// target directory, a frequent follow-up to mass deletions and restores. A
+// directory left empty once its empty files and directories are removed is removed
+// too; the target directory itself stays. Version control directories are never
+// entered, and empty files that mean something by their name alone (.gitkeep,
+// __init__.py, ...) are kept, as are the entries matching -exclude. Removals are
+// journaled like rewrites, and the list of a run is kept in the state directory, so
+// "photonsr tidy -undo <id>" recreates everything the run removed.
+
+// tidyKeepNames are empty files kept because their existence is what matters.
+var tidyKeepNames = map[string]bool{
+	".gitkeep": true, ".keep": true, ".nojekyll": true, "__init__.py": true, "py.typed": true,
+}
+
+// TidySkipDirs are directories tidy never enters.
+var TidySkipDirs = map[string]bool{".git": true, ".hg": true, ".svn": true}
+
+// Kinds of entries "photonsr tidy" removes (the -empty flag).
+const (
+	TidyFiles = "files"
+	TidyDirs  = "dirs"
+)
+
+// TidyOptions holds all parameters for PerformTidy.
+type TidyOptions struct {
+	Dir     string   // Target directory for the operation.
+	Files   bool     // Remove zero-byte files.
+	Dirs    bool     // Remove empty directories.
+	Exclude []string // Name patterns (globs) of entries to keep; matching directories are not entered.
+	DryRun  bool     // Only report what would be removed.
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Validate reports the first problem that would stop PerformTidy from running with opts.
+func (opts TidyOptions) Validate() error {
+	if !opts.Files && !opts.Dirs {
+		return fmt.Errorf("nothing to tidy: -empty must name files, dirs or both: %w", ErrInvalidOption)
+	}
+	for _, pattern := range opts.Exclude {
+		if err := ValidatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return ValidateDir(opts.Dir)
+}
+
+// ParseKinds sets Files and Dirs from the value of -empty, a comma-separated list of
+// TidyFiles and TidyDirs.
+func (opts *TidyOptions) ParseKinds(kinds string) error {
+	for _, kind := range strings.Split(kinds, ",") {
+		switch strings.TrimSpace(kind) {
+		case TidyFiles:
+			opts.Files = true
+		case TidyDirs:
+			opts.Dirs = true
+		case "":
+		default:
+			return fmt.Errorf("unknown -empty kind '%s' (expected files or dirs): %w", kind, ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// excluded reports whether the entry named name matches an -exclude pattern.
+func (opts TidyOptions) excluded(name string) bool {
+	for _, pattern := range opts.Exclude {
+		if matched, _ := MatchesPattern(name, pattern); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// tidyRecord lists what a tidy run removed, for "photonsr tidy -undo".
+type tidyRecord struct {
+	ID      string         `json:"id"`      // Run identifier.
+	Time    string         `json:"time"`    // RFC 3339 time the run ended.
+	Dir     string         `json:"dir"`     // Absolute target directory.
+	Removed []JournalEntry `json:"removed"` // "remove" and "rmdir" entries, in order.
+}
+
+// tidyRecordPath returns the path of the record of tidy run id.
+func tidyRecordPath(id string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	if id == "" || filepath.Base(id) != id {
+		return "", fmt.Errorf("invalid run id '%s'", id)
+	}
+	return filepath.Join(dir, "tidy", id+".json"), nil
+}
+
+// PerformTidy removes the empty files and directories of opts.Dir.
+// Returns:
+//   - []string: Messages listing what was (or would be) removed.
+//   - int: The number of entries removed.
+//   - error: A fatal error or the first non-fatal error.
+func PerformTidy(opts TidyOptions) ([]string, int, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, 0, err
+	}
+	t := &tidyRun{opts: opts}
+	if !opts.DryRun {
+		journal, err := beginRunJournal(opts.Dir, "tidy")
+		if err != nil {
+			return nil, 0, err
+		}
+		t.journal = journal
+	}
+	t.dir(opts.Dir)
+	if t.journal != nil {
+		if err := t.journal.finish(); err != nil && t.firstErr == nil {
+			t.firstErr = err
+		}
+	}
+
+	if len(t.removed) == 0 {
+		return []string{"No empty files or directories to remove."}, 0, t.firstErr
+	}
+	dirs := 0
+	for _, e := range t.removed {
+		if e.Op == "rmdir" {
+			dirs++
+		}
+	}
+	counts := fmt.Sprintf("%d empty file(s) and %d empty director(ies)", len(t.removed)-dirs, dirs)
+	if opts.DryRun {
+		return append([]string{"Would remove " + counts + " (dry run):"}, t.messages...), 0, t.firstErr
+	}
+	messages := append([]string{"Removed " + counts + ":"}, t.messages...)
+	abs, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		abs = opts.Dir
+	}
+	rec := tidyRecord{ID: NewRunID(), Time: time.Now().UTC().Format(time.RFC3339), Dir: abs, Removed: t.removed}
+	if _, readOnly := FS.(*ReadOnlyFS); readOnly {
+		// Nothing was removed, so there is nothing to undo.
+	} else if err := saveTidyRecord(rec); err != nil {
+		warn(opts.OnWarning, "PerformTidy", "Record", err, "Continuing without undo")
+	} else {
+		messages = append(messages, fmt.Sprintf("Undo with: photonsr tidy -undo %s", rec.ID))
+	}
+	return messages, len(t.removed), t.firstErr
+}
+
+// tidyRun is the state of one PerformTidy walk.
+type tidyRun struct {
+	opts     TidyOptions
+	journal  *runJournal // nil for a dry run.
+	removed  []JournalEntry
+	messages []string
+	firstErr error
+}
+
+// dir tidies the directory at path and reports whether everything in it was removed.
+func (t *tidyRun) dir(path string) bool {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		t.fail("Access", fmt.Errorf("reading directory '%s': %w", path, err))
+		return false
+	}
+	empty := true
+	for _, e := range entries {
+		child := filepath.Join(path, e.Name())
+		info, err := e.Info()
+		if err != nil {
+			t.fail("Access", fmt.Errorf("accessing path '%s': %w", child, err))
+			empty = false
+			continue
+		}
+		if IsInternalEntry(info) || t.opts.excluded(e.Name()) {
+			empty = false
+			continue
+		}
+		switch {
+		case info.IsDir():
+			if TidySkipDirs[e.Name()] || !t.dir(child) || !t.opts.Dirs {
+				empty = false
+				continue
+			}
+			if !t.remove(child, info, "rmdir") {
+				empty = false
+			}
+		case info.Mode().IsRegular() && info.Size() == 0 && t.opts.Files && !tidyKeepNames[e.Name()]:
+			if _, _, isBackup := ParseBackupName(e.Name()); isBackup || !t.remove(child, info, "remove") {
+				empty = false
+			}
+		default:
+			empty = false
+		}
+	}
+	return empty
+}
+
+// remove removes the empty file or directory at path (op "remove" or "rmdir") and
+// reports whether it is gone, or would be in a dry run.
+func (t *tidyRun) remove(path string, info os.FileInfo, op string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	entry := JournalEntry{Op: op, Path: abs, Mode: uint32(info.Mode().Perm())}
+	if t.journal != nil {
+		if err := t.journal.removeEmpty(entry); err != nil {
+			t.fail("Remove", fmt.Errorf("removing '%s': %w", path, err))
+			return false
+		}
+	}
+	t.removed = append(t.removed, entry)
+	line := "  - " + RelPath(t.opts.Dir, path)
+	if op == "rmdir" {
+		line += string(filepath.Separator)
+	}
+	t.messages = append(t.messages, line)
+	return true
+}
+
+// fail records a non-fatal error of the run.
+func (t *tidyRun) fail(stage string, err error) {
+	if t.firstErr == nil {
+		t.firstErr = err
+	}
+	warn(t.opts.OnWarning, "PerformTidy", stage, err, "Skipping")
+}
+
+// saveTidyRecord writes rec to the state directory.
+func saveTidyRecord(rec tidyRecord) error {
+	path, err := tidyRecordPath(rec.ID)
+	if err != nil {
+		return err
+	}
+	if err := FS.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating tidy record directory: %w", err)
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding tidy record: %w", err)
+	}
+	return WriteFileAtomic(path, append(data, '\n'), 0o600)
+}
+
+// PerformTidyUndo recreates the files and directories removed by tidy run id, and
+// forgets the run once all of them are back.
+// Returns:
+//   - []string: Messages listing what was recreated.
+//   - int: The number of entries recreated.
+//   - error: An error if the record cannot be read, or the first entry not recreated.
+func PerformTidyUndo(id string) ([]string, int, error) {
+	path, err := tidyRecordPath(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, 0, fmt.Errorf("no tidy run '%s' to undo", id)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading tidy record '%s': %w", path, err)
+	}
+	var rec tidyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, 0, fmt.Errorf("parsing tidy record '%s': %w", path, err)
+	}
+	var messages []string
+	var firstErr error
+	recreated := 0
+	for i := len(rec.Removed) - 1; i >= 0; i-- {
+		e := rec.Removed[i]
+		if err := recreateRemoved(e); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("recreating '%s': %w", e.Path, err)
+			}
+			continue
+		}
+		recreated++
+		messages = append(messages, fmt.Sprintf("  - Recreated: %s", e.Path))
+	}
+	if firstErr == nil {
+		if err := FS.Remove(path); err != nil {
+			firstErr = fmt.Errorf("removing tidy record '%s': %w", path, err)
+		}
+	}
+	return messages, recreated, firstErr
+}
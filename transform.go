@@ -0,0 +1,47 @@
+package photonsr
+
+import (
+	"errors"
+	"io"
+)
+
+// --- Content Transforms ---
+
+// ErrNotApplicable is wrapped by the skip reason of files a Transform leaves alone
+// on purpose, e.g. because it does not know their format.
+var ErrNotApplicable = errors.New("operation does not apply to the file")
+
+// Transform rewrites whole files, for operations that cannot be expressed as
+// literal rules (license headers, structured formats). It runs in the replacement
+// walk, after the text rules of each file, so backups, the journal and Confine
+// apply to it as to any replacement. Files larger than MaxMemory are skipped, since
+// a transform needs the whole content, unless it is a StreamTransform.
+type Transform interface {
+	// Applies reports whether the transform handles files with the given name.
+	Applies(name string) bool
+	// Apply returns the new content of the file at path, content itself if nothing
+	// changes, or an error; an error wrapping ErrNotApplicable skips the file.
+	// Apply may be called concurrently for different files.
+	Apply(path string, content []byte) ([]byte, error)
+}
+
+// StreamTransform is a Transform that rewrites files as streams, for formats whose
+// files can be larger than memory. Files it applies to are always streamed, through
+// the text rules first and then ApplyStream.
+type StreamTransform interface {
+	Transform
+	// ApplyStream copies src to dst rewritten and returns the number of changes.
+	ApplyStream(dst io.Writer, src io.Reader) (int, error)
+}
+
+// transforms reports whether opts.Transform handles the file named name.
+func (opts ReplaceOptions) transforms(name string) bool {
+	if opts.Transform == nil || !opts.Transform.Applies(name) {
+		return false
+	}
+	if _, skipped := matchingRules(opts.Rules, name, opts.Pattern); skipped {
+		return false
+	}
+	matched, _ := MatchesPattern(name, opts.Pattern)
+	return matched
+}
@@ -0,0 +1,316 @@
+package photonsr
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// --- Unified Diffs ---
+
+// sampleDiffLines is the length of the sample diffs passed to
+// ReplaceOptions.OnFileDiff, removed and added lines together.
+const sampleDiffLines = 12
+
+// sampleDiff returns up to limit lines describing how after differs from before:
+// "@@ line N @@" before each group of changed lines, then the lines as they were
+// ("-") and as they are ("+"). Lines are compared one by one when the number of
+// lines is the same; otherwise the lines between the common start and end are
+// shown as one change.
+func sampleDiff(before, after []byte, limit int) []string {
+	old, cur := bytes.Split(before, []byte("\n")), bytes.Split(after, []byte("\n"))
+	var out []string
+	add := func(prefix string, line []byte) bool {
+		if len(out) >= limit {
+			out = append(out, "@@ ... @@")
+			return false
+		}
+		out = append(out, prefix+strings.TrimSuffix(string(line), "\r"))
+		return true
+	}
+	if len(old) == len(cur) {
+		last := -2 // Index of the previous changed line; -2 gives the first change a header.
+		for i := range old {
+			if bytes.Equal(old[i], cur[i]) {
+				continue
+			}
+			if i != last+1 {
+				out = append(out, fmt.Sprintf("@@ line %d @@", i+1))
+			}
+			last = i
+			if !add("-", old[i]) || !add("+", cur[i]) {
+				break
+			}
+		}
+		return out
+	}
+	start := 0
+	for start < len(old) && start < len(cur) && bytes.Equal(old[start], cur[start]) {
+		start++
+	}
+	end := 0
+	for end < len(old)-start && end < len(cur)-start && bytes.Equal(old[len(old)-1-end], cur[len(cur)-1-end]) {
+		end++
+	}
+	out = append(out, fmt.Sprintf("@@ line %d @@", start+1))
+	for _, line := range old[start : len(old)-end] {
+		if !add("-", line) {
+			return out
+		}
+	}
+	for _, line := range cur[start : len(cur)-end] {
+		if !add("+", line) {
+			return out
+		}
+	}
+	return out
+}
+
+// diffContextLines is the number of unchanged lines shown around each change.
+const diffContextLines = 3
+
+// diffMaxEdits bounds the work of matching lines: files differing in more lines
+// than this get a single block of removed and added lines.
+const diffMaxEdits = 2000
+
+// diffOp is one line of an edit script: kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	a, b int // Line index in the old content (' ', '-') and the new content (' ', '+').
+}
+
+// unifiedDiff returns the unified diff turning before into after, with the headers
+// "--- oldName" and "+++ NewName", or "" if they are equal. Binary content is
+// reported in one line, as diff does.
+func unifiedDiff(oldName, newName string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+	if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName)
+	}
+	a, b := splitLines(before), splitLines(after)
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range diffHunks(diffLines(a, b)) {
+		first := hunk[0]
+		oldStart, newStart := first.a+1, first.b+1
+		oldLen, newLen := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen))
+		for _, op := range hunk {
+			line := ""
+			switch op.kind {
+			case '-':
+				line = a[op.a]
+			default:
+				line = b[op.b]
+			}
+			out.WriteByte(op.kind)
+			out.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+// filePatch returns the unified diff of the file at path, below dir, turning before
+// into after, with git's "a/" and "b/" prefixes so that "patch -p1" applies it in dir.
+func filePatch(dir, path string, before, after []byte) string {
+	name := filepath.ToSlash(RelPath(dir, path))
+	return unifiedDiff("a/"+name, "b/"+name, before, after)
+}
+
+// hunkRange formats the start and length of one side of a hunk header: the length
+// is left out when it is 1, and an empty side starts at the line before it.
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
+
+// splitLines splits data after each "\n"; the last line lacks one if data does.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			lines = append(lines, string(data))
+			break
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+// diffLines returns an edit script turning the lines a into the lines b, with
+// every line of both in order.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', a: i, b: i})
+	}
+	middle := myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if middle == nil { // Too many differences: one block.
+		for i := prefix; i < len(a)-suffix; i++ {
+			middle = append(middle, diffOp{kind: '-', a: i - prefix})
+		}
+		for j := prefix; j < len(b)-suffix; j++ {
+			middle = append(middle, diffOp{kind: '+', a: len(a) - suffix - prefix, b: j - prefix})
+		}
+	}
+	for _, op := range middle {
+		ops = append(ops, diffOp{kind: op.kind, a: op.a + prefix, b: op.b + prefix})
+	}
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, diffOp{kind: ' ', a: len(a) - suffix + i, b: len(b) - suffix + i})
+	}
+	return ops
+}
+
+// myersDiff returns a shortest edit script turning a into b, or nil if it needs more
+// than diffMaxEdits removals and additions. Removed and added lines also carry the
+// position they have on the other side, for hunk headers.
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return []diffOp{}
+	}
+	limit := min(n+m, diffMaxEdits)
+	// v[k+limit+1] is the furthest x reached on diagonal k (x - y = k). trace[d]
+	// keeps the diagonals -d-1..d+1 as they were before step d, to walk back.
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[limit-d:limit+d+3]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+limit+1] < v[k+1+limit+1]) {
+				x = v[k+1+limit+1] // Down: an addition.
+			} else {
+				x = v[k-1+limit+1] + 1 // Right: a removal.
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[k+limit+1] = x
+			if x >= n && y >= m {
+				return myersBacktrack(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+// myersBacktrack rebuilds the edit script from the trace of myersDiff.
+func myersBacktrack(trace [][]int, n, m int) []diffOp {
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		at := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{kind: ' ', a: x, b: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: '+', a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: '-', a: x, b: y})
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// diffHunks groups the changes of ops into hunks with up to diffContextLines kept
+// lines around them; changes closer than twice that share a hunk.
+func diffHunks(ops []diffOp) [][]diffOp {
+	var hunks [][]diffOp
+	start, end := -1, -1 // The current hunk is ops[start:end].
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		if start >= 0 && i-end > 2*diffContextLines {
+			hunks = append(hunks, ops[start:min(end+diffContextLines, len(ops))])
+			start = -1
+		}
+		if start < 0 {
+			start = max(i-diffContextLines, 0)
+		}
+		end = i + 1
+	}
+	if start >= 0 {
+		hunks = append(hunks, ops[start:min(end+diffContextLines, len(ops))])
+	}
+	return hunks
+}
+
+// PatchLines returns the hunks of a unified diff, such as a patch passed to
+// ReplaceOptions.OnFilePatch, without its "---" and "+++" header, as lines
+// indented by four spaces with tabs expanded, for listings that name the file
+// already. At most limit lines are returned, all if limit is 0.
+func PatchLines(patch string, limit int) []string {
+	if patch == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(patch, "\n"), "\n")
+	if strings.HasPrefix(lines[0], "--- ") {
+		lines = lines[2:]
+	}
+	if limit > 0 && len(lines) > limit {
+		lines = append(lines[:limit], "...")
+	}
+	for i, line := range lines {
+		lines[i] = "    " + strings.ReplaceAll(line, "\t", "    ")
+	}
+	return lines
+}
+
+// ChangedLines lists how after differs from before as PatchLines does, so that a
+// comparison reads like the output of -diff. Binary content is summarized in one
+// line rather than diffed.
+func ChangedLines(before, after []byte, limit int) []string {
+	if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+		return []string{fmt.Sprintf("    binary content changed (%s -> %s)", FormatSize(int64(len(before))), FormatSize(int64(len(after))))}
+	}
+	return PatchLines(unifiedDiff("a", "b", before, after), limit)
+}
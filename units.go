@@ -0,0 +1,127 @@
+package photonsr
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Unit Parsing Helpers ---
+
+// EnvBool reports whether the environment variable name is set to a true value
+// ("1", "true", "yes", "on"; case-insensitive).
+func EnvBool(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// ParseAge parses an age such as "7d", "2w", "36h" or "90m". In addition to the
+// units understood by time.ParseDuration it accepts d (days) and w (weeks).
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if num, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age '%s'", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s' (use e.g. 7d, 2w, 36h)", s)
+	}
+	return d, nil
+}
+
+// ParseSize parses a byte size such as "512", "64K", "10MB" or "1.5G" (binary
+// multiples: 1K = 1024 bytes).
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "IB"), "B")
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s' (use e.g. 512K, 10M, 1G)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize renders a byte count in human-readable binary units (e.g. "1.5 MiB").
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatCount formats n with thousands separators, e.g. 1204 as "1,204".
+func FormatCount(n int) string {
+	if n < 0 {
+		return "-" + FormatCount(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// FormatDuration formats d rounded for display: "850ms", "12.4s", "3m20s", "5h12m".
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	case d < time.Hour:
+		return d.Round(time.Second).String()
+	default:
+		return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	}
+}
+
+// TruncateMiddle shortens s to at most width runes by replacing its middle with an
+// ellipsis, keeping both the start and the (usually more telling) end of long paths.
+func TruncateMiddle(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	if width < 5 {
+		return string(r[:width])
+	}
+	head := (width - 1) / 3
+	tail := width - 1 - head
+	return fmt.Sprintf("%s…%s", string(r[:head]), string(r[len(r)-tail:]))
+}
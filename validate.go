@@ -0,0 +1,169 @@
+package photonsr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// --- Option Validation ---
+
+// Validation errors, for use with errors.Is. Errors about the target directory wrap
+// ErrNotFound, ErrPermission or ErrNotDirectory instead.
+var (
+	ErrEmptyOldText  = errors.New("text to replace (OldText) cannot be empty")
+	ErrNotDirectory  = errors.New("not a directory")
+	ErrInvalidOption = errors.New("invalid option")
+)
+
+// Validate reports the first problem that would stop PerformReplacement from running
+// with opts. Frontends call it to reject bad input before any work starts;
+// PerformReplacement calls it as well.
+func (opts ReplaceOptions) Validate() error {
+	textRules := 0
+	for i, r := range opts.Rules {
+		switch {
+		case r.Skip:
+			if r.Pattern == "" {
+				return fmt.Errorf("rule %d: a skip rule needs a pattern: %w", i+1, ErrInvalidOption)
+			}
+		case r.Old == "":
+			return fmt.Errorf("rule %d: %w", i+1, ErrEmptyOldText)
+		case !r.HasNew:
+			return fmt.Errorf("rule %d: no new text (give new: \"\" to delete the old text): %w", i+1, ErrInvalidOption)
+		case r.Regex && opts.SameLength:
+			return fmt.Errorf("rule %d: SameLength cannot be used with a regex rule, as the length of each replacement depends on its match: %w", i+1, ErrInvalidOption)
+		case opts.SameLength && len(r.Old) != len(r.New):
+			return fmt.Errorf("rule %d: old and new text must have the same length: %d and %d bytes: %w", i+1, len(r.Old), len(r.New), ErrInvalidOption)
+		default:
+			textRules++
+		}
+		if r.Regex {
+			if _, err := regexp.Compile(r.Old); err != nil {
+				return fmt.Errorf("rule %d: invalid regular expression: %v: %w", i+1, err, ErrInvalidOption)
+			}
+		}
+		if r.Pattern != "" {
+			if err := ValidatePattern(r.Pattern); err != nil {
+				return fmt.Errorf("rule %d: %w", i+1, err)
+			}
+		}
+	}
+	if opts.OldText == "" && textRules == 0 && opts.Transform == nil {
+		return ErrEmptyOldText
+	}
+	if opts.UseRegex {
+		if opts.OldText == "" {
+			return fmt.Errorf("UseRegex needs a regular expression in OldText: %w", ErrInvalidOption)
+		}
+		if opts.SameLength {
+			return fmt.Errorf("SameLength cannot be used with UseRegex, as the length of each replacement depends on its match: %w", ErrInvalidOption)
+		}
+		if _, err := regexp.Compile(opts.OldText); err != nil {
+			return fmt.Errorf("invalid regular expression: %v: %w", err, ErrInvalidOption)
+		}
+	}
+	if opts.Near != nil {
+		if opts.OldText == "" {
+			return fmt.Errorf("Near needs the text to replace in OldText: %w", ErrInvalidOption)
+		}
+		if opts.Near.Term == "" || opts.Near.Within < 0 {
+			return fmt.Errorf("Near needs a term and a distance of 0 or more: %w", ErrInvalidOption)
+		}
+	}
+	if (opts.NotPrecededBy != "" || opts.NotFollowedBy != "") && opts.OldText == "" {
+		return fmt.Errorf("NotPrecededBy and NotFollowedBy need the text to replace in OldText: %w", ErrInvalidOption)
+	}
+	if opts.Anchor != "" {
+		switch {
+		case opts.OldText == "":
+			return fmt.Errorf("Anchor needs the text to replace in OldText: %w", ErrInvalidOption)
+		case opts.UseRegex:
+			return fmt.Errorf("Anchor is for literal text; anchor a regular expression with ^, $, \\A or \\z instead: %w", ErrInvalidOption)
+		case opts.Anchor != AnchorBOL && opts.Anchor != AnchorEOL && opts.Anchor != AnchorBOF && opts.Anchor != AnchorEOF:
+			return fmt.Errorf("unknown anchor %q (expected bol, eol, bof or eof): %w", opts.Anchor, ErrInvalidOption)
+		}
+	}
+	if opts.LineMode {
+		if opts.OldText == "" {
+			return fmt.Errorf("LineMode needs the text selecting the lines in OldText: %w", ErrInvalidOption)
+		}
+		if opts.SameLength {
+			return fmt.Errorf("SameLength cannot be used with LineMode, as whole lines are replaced: %w", ErrInvalidOption)
+		}
+	}
+	if !opts.UseRegex && opts.SameLength && len(opts.OldText) != len(opts.NewText) {
+		return fmt.Errorf("old and new text must have the same length: %d and %d bytes: %w", len(opts.OldText), len(opts.NewText), ErrInvalidOption)
+	}
+	if err := ValidateDir(opts.Dir); err != nil {
+		return err
+	}
+	if err := ValidatePattern(opts.Pattern); err != nil {
+		return err
+	}
+	if !validBackupPolicy(opts.BackupPolicy) {
+		return fmt.Errorf("unknown backup conflict policy '%s': %w", opts.BackupPolicy, ErrInvalidOption)
+	}
+	if opts.Immutable != "" && opts.Immutable != ImmutableSkip && opts.Immutable != ImmutableError {
+		return fmt.Errorf("unknown immutable file policy '%s' (expected skip or error): %w", opts.Immutable, ErrInvalidOption)
+	}
+	if !validSortBy(opts.SortBy) {
+		return fmt.Errorf("unknown result ordering '%s': %w", opts.SortBy, ErrInvalidOption)
+	}
+	if !validOrder(opts.Order) {
+		return fmt.Errorf("unknown processing order '%s': %w", opts.Order, ErrInvalidOption)
+	}
+	if opts.Jobs < 0 || opts.MaxMemory < 0 || opts.MaxFileSize < 0 || opts.ReadAhead < 0 {
+		return fmt.Errorf("Jobs, MaxMemory, MaxFileSize and ReadAhead cannot be negative: %w", ErrInvalidOption)
+	}
+	return nil
+}
+
+// Validate reports the first problem that would stop PerformRestore from running with opts.
+func (opts RestoreOptions) Validate() error {
+	if opts.Jobs < 0 {
+		return fmt.Errorf("Jobs cannot be negative: %w", ErrInvalidOption)
+	}
+	if opts.To != "" {
+		dir, errDir := filepath.Abs(opts.Dir)
+		to, errTo := filepath.Abs(opts.To)
+		if errDir == nil && errTo == nil && (dir == to || Within(to, dir)) {
+			return fmt.Errorf("the restore directory '%s' cannot be -dir or contain it: %w", opts.To, ErrInvalidOption)
+		}
+		if info, err := os.Stat(opts.To); err == nil && !info.IsDir() {
+			return fmt.Errorf("the restore directory '%s' is not a directory: %w", opts.To, ErrInvalidOption)
+		}
+	}
+	return ValidateDir(opts.Dir)
+}
+
+// Validate reports the first problem that would stop PerformClean from running with opts.
+func (opts CleanOptions) Validate() error {
+	if opts.OlderThan < 0 || opts.Jobs < 0 {
+		return fmt.Errorf("OlderThan and Jobs cannot be negative: %w", ErrInvalidOption)
+	}
+	return ValidateDir(opts.Dir)
+}
+
+// ValidateDir checks that dir exists and is a directory.
+func ValidateDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("checking target directory '%s': %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("target '%s' is %w", dir, ErrNotDirectory)
+	}
+	return nil
+}
+
+// ValidatePattern checks that pattern is a well-formed glob; the error wraps
+// filepath.ErrBadPattern.
+func ValidatePattern(pattern string) error {
+	if _, err := MatchesPattern("", pattern); err != nil {
+		return fmt.Errorf("invalid file pattern '%s': %w", pattern, err)
+	}
+	return nil
+}
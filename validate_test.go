@@ -0,0 +1,149 @@
+package photonsr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nopTransform is a Transform that changes nothing; Validate only checks that one
+// is set.
+type nopTransform struct{}
+
+func (nopTransform) Applies(string) bool                            { return true }
+func (nopTransform) Apply(_ string, content []byte) ([]byte, error) { return content, nil }
+
+// testDirs returns an existing directory and the path of a regular file in it.
+func testDirs(t *testing.T) (dir, file string) {
+	t.Helper()
+	dir = t.TempDir()
+	file = filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, file
+}
+
+func TestReplaceOptionsValidate(t *testing.T) {
+	dir, file := testDirs(t)
+	rule := RuleSpec{Old: "a", New: "b", HasNew: true}
+	tests := []struct {
+		name string
+		edit func(*ReplaceOptions)
+		want error // nil for valid options
+	}{
+		{"valid", func(*ReplaceOptions) {}, nil},
+		{"rules only", func(o *ReplaceOptions) { o.OldText, o.Rules = "", []RuleSpec{rule} }, nil},
+		{"transform only", func(o *ReplaceOptions) { o.OldText, o.Transform = "", nopTransform{} }, nil},
+		{"skip rule with pattern", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{Skip: true, Pattern: "*.min.js"}} }, nil},
+		{"regex", func(o *ReplaceOptions) { o.UseRegex, o.OldText = true, `a(\d+)` }, nil},
+		{"every guard", func(o *ReplaceOptions) {
+			o.Near = &Near{Term: "x", Within: 0}
+			o.NotPrecededBy, o.NotFollowedBy, o.Anchor, o.LineMode = "p", "f", AnchorBOL, true
+		}, nil},
+		{"same length", func(o *ReplaceOptions) { o.SameLength = true }, nil},
+		{"regex rule", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{Old: `a(\d)`, New: "b$1", HasNew: true, Regex: true}} }, nil},
+
+		{"skip rule without pattern", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{Skip: true}} }, ErrInvalidOption},
+		{"rule without old text", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{New: "b", HasNew: true}} }, ErrEmptyOldText},
+		{"rule without new text", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{Old: "a"}} }, ErrInvalidOption},
+		{"rule of another length", func(o *ReplaceOptions) {
+			o.SameLength, o.Rules = true, []RuleSpec{{Old: "a", New: "bb", HasNew: true}}
+		}, ErrInvalidOption},
+		{"regex rule with bad expression", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{Old: "(", New: "b", HasNew: true, Regex: true}} }, ErrInvalidOption},
+		{"regex rule with same length", func(o *ReplaceOptions) {
+			o.SameLength, o.Rules = true, []RuleSpec{{Old: "a.", New: "bb", HasNew: true, Regex: true}}
+		}, ErrInvalidOption},
+		{"rule with bad pattern", func(o *ReplaceOptions) { o.Rules = []RuleSpec{{Old: "a", New: "b", HasNew: true, Pattern: "["}} }, filepath.ErrBadPattern},
+		{"no old text", func(o *ReplaceOptions) { o.OldText = "" }, ErrEmptyOldText},
+		{"skip rules only", func(o *ReplaceOptions) { o.OldText, o.Rules = "", []RuleSpec{{Skip: true, Pattern: "*"}} }, ErrEmptyOldText},
+		{"regex without expression", func(o *ReplaceOptions) { o.UseRegex, o.OldText, o.Rules = true, "", []RuleSpec{rule} }, ErrInvalidOption},
+		{"regex with same length", func(o *ReplaceOptions) { o.UseRegex, o.SameLength, o.NewText = true, true, "a" }, ErrInvalidOption},
+		{"bad regex", func(o *ReplaceOptions) { o.UseRegex, o.OldText = true, "(" }, ErrInvalidOption},
+		{"near without old text", func(o *ReplaceOptions) { o.OldText, o.Rules, o.Near = "", []RuleSpec{rule}, &Near{Term: "x"} }, ErrInvalidOption},
+		{"near without term", func(o *ReplaceOptions) { o.Near = &Near{Within: 3} }, ErrInvalidOption},
+		{"near with negative distance", func(o *ReplaceOptions) { o.Near = &Near{Term: "x", Within: -1} }, ErrInvalidOption},
+		{"not preceded by without old text", func(o *ReplaceOptions) { o.OldText, o.Rules, o.NotPrecededBy = "", []RuleSpec{rule}, "x" }, ErrInvalidOption},
+		{"not followed by without old text", func(o *ReplaceOptions) { o.OldText, o.Rules, o.NotFollowedBy = "", []RuleSpec{rule}, "x" }, ErrInvalidOption},
+		{"anchor without old text", func(o *ReplaceOptions) { o.OldText, o.Rules, o.Anchor = "", []RuleSpec{rule}, AnchorEOL }, ErrInvalidOption},
+		{"anchor with regex", func(o *ReplaceOptions) { o.UseRegex, o.Anchor = true, AnchorBOF }, ErrInvalidOption},
+		{"unknown anchor", func(o *ReplaceOptions) { o.Anchor = "middle" }, ErrInvalidOption},
+		{"line mode without old text", func(o *ReplaceOptions) { o.OldText, o.Rules, o.LineMode = "", []RuleSpec{rule}, true }, ErrInvalidOption},
+		{"line mode with same length", func(o *ReplaceOptions) { o.LineMode, o.SameLength = true, true }, ErrInvalidOption},
+		{"new text of another length", func(o *ReplaceOptions) { o.SameLength, o.NewText = true, "bb" }, ErrInvalidOption},
+		{"missing directory", func(o *ReplaceOptions) { o.Dir = filepath.Join(dir, "missing") }, ErrNotFound},
+		{"directory is a file", func(o *ReplaceOptions) { o.Dir = file }, ErrNotDirectory},
+		{"bad pattern", func(o *ReplaceOptions) { o.Pattern = "[" }, filepath.ErrBadPattern},
+		{"unknown backup policy", func(o *ReplaceOptions) { o.BackupPolicy = "merge" }, ErrInvalidOption},
+		{"unknown immutable policy", func(o *ReplaceOptions) { o.Immutable = "force" }, ErrInvalidOption},
+		{"unknown result ordering", func(o *ReplaceOptions) { o.SortBy = "name" }, ErrInvalidOption},
+		{"unknown processing order", func(o *ReplaceOptions) { o.Order = "random" }, ErrInvalidOption},
+		{"negative jobs", func(o *ReplaceOptions) { o.Jobs = -1 }, ErrInvalidOption},
+		{"negative memory", func(o *ReplaceOptions) { o.MaxMemory = -1 }, ErrInvalidOption},
+		{"negative file size", func(o *ReplaceOptions) { o.MaxFileSize = -1 }, ErrInvalidOption},
+		{"negative read-ahead", func(o *ReplaceOptions) { o.ReadAhead = -1 }, ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ReplaceOptions{Dir: dir, OldText: "a", NewText: "b"}
+			tt.edit(&opts)
+			checkValidate(t, opts.Validate(), tt.want)
+		})
+	}
+}
+
+func TestRestoreOptionsValidate(t *testing.T) {
+	dir, file := testDirs(t)
+	tests := []struct {
+		name string
+		opts RestoreOptions
+		want error
+	}{
+		{"valid", RestoreOptions{Dir: dir}, nil},
+		{"to elsewhere", RestoreOptions{Dir: dir, To: filepath.Join(t.TempDir(), "out")}, nil},
+		{"negative jobs", RestoreOptions{Dir: dir, Jobs: -1}, ErrInvalidOption},
+		{"to is the directory", RestoreOptions{Dir: dir, To: dir}, ErrInvalidOption},
+		{"to contains the directory", RestoreOptions{Dir: dir, To: filepath.Dir(dir)}, ErrInvalidOption},
+		{"to is a file", RestoreOptions{Dir: t.TempDir(), To: file}, ErrInvalidOption},
+		{"missing directory", RestoreOptions{Dir: filepath.Join(dir, "missing")}, ErrNotFound},
+		{"directory is a file", RestoreOptions{Dir: file}, ErrNotDirectory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.opts.Validate(), tt.want)
+		})
+	}
+}
+
+func TestCleanOptionsValidate(t *testing.T) {
+	dir, file := testDirs(t)
+	tests := []struct {
+		name string
+		opts CleanOptions
+		want error
+	}{
+		{"valid", CleanOptions{Dir: dir, OlderThan: time.Hour, Jobs: 4}, nil},
+		{"negative age", CleanOptions{Dir: dir, OlderThan: -time.Hour}, ErrInvalidOption},
+		{"negative jobs", CleanOptions{Dir: dir, Jobs: -1}, ErrInvalidOption},
+		{"missing directory", CleanOptions{Dir: filepath.Join(dir, "missing")}, ErrNotFound},
+		{"directory is a file", CleanOptions{Dir: file}, ErrNotDirectory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.opts.Validate(), tt.want)
+		})
+	}
+}
+
+// checkValidate fails t unless err is nil when want is, or wraps want.
+func checkValidate(t *testing.T, err, want error) {
+	t.Helper()
+	switch {
+	case want == nil && err != nil:
+		t.Fatalf("Validate() = %v, want nil", err)
+	case want != nil && !errors.Is(err, want):
+		t.Fatalf("Validate() = %v, want an error wrapping %v", err, want)
+	}
+}
@@ -0,0 +1,128 @@
+package photonsr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// --- Rule Verification ---
+
+// ErrRulesViolated is wrapped by the error reported when a verified tree still
+// contains the old text of a rule.
+var ErrRulesViolated = errors.New("rules violated")
+
+// VerifyOptions holds all parameters for the PerformVerify function.
+type VerifyOptions struct {
+	Dir         string     // Target directory for the operation.
+	Pattern     string     // File pattern for rules that do not set their own.
+	Rules       []RuleSpec // Rules whose old text must no longer occur; skip rules exclude files.
+	MaxFileSize int64      // If > 0, larger files are not checked.
+	SkipBinary  bool       // Do not check files that look binary.
+	RulesFile   string     // Not checked if inside Dir, as it contains every old text.
+
+	OnWarning func(Warning) // If set, receives the non-fatal problems of the run (see Warning).
+}
+
+// Violation is an occurrence of a rule's old text in a verified tree.
+type Violation struct {
+	Path     string `json:"path"`              // File containing the old text.
+	Line     int    `json:"line"`              // 1-based line of the occurrence.
+	Column   int    `json:"column"`            // 1-based column, in characters.
+	Rule     int    `json:"rule"`              // 1-based index of the rule in the rules file.
+	Severity string `json:"severity"`          // Severity of the rule.
+	Message  string `json:"message,omitempty"` // Message of the rule, if any.
+}
+
+// Format formats v like a compiler diagnostic: "path:line:column: severity: message".
+// The old text is shown if the rule has no message.
+func (v Violation) Format(old string) string {
+	message := v.Message
+	if message == "" {
+		message = fmt.Sprintf("found %q", old)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s (rule %d)", v.Path, v.Line, v.Column, v.Severity, message, v.Rule)
+}
+
+// PerformVerify checks, without modifying anything, that no rule's old text occurs
+// in the files of opts.Dir that the rule applies to. Backup files are not checked.
+// Returns:
+//   - []Violation: Every occurrence found, in walk order.
+//   - int: Number of files checked.
+//   - error: A fatal error, or the first file that could not be read.
+func PerformVerify(opts VerifyOptions) ([]Violation, int, error) {
+	return PerformVerifyCtx(context.Background(), opts)
+}
+
+// PerformVerifyCtx is PerformVerify with cancellation: once ctx is done, no further
+// file is checked and the returned error wraps ctx.Err().
+func PerformVerifyCtx(ctx context.Context, opts VerifyOptions) ([]Violation, int, error) {
+	if err := ValidateDir(opts.Dir); err != nil {
+		return nil, 0, err
+	}
+	if err := ValidatePattern(opts.Pattern); err != nil {
+		return nil, 0, err
+	}
+	rulesFile := ""
+	if opts.RulesFile != "" {
+		rulesFile = CanonicalPath(opts.RulesFile)
+	}
+	var violations []Violation
+	var firstEncounteredError error
+	filesChecked := 0
+
+	walkErr := filepath.Walk(opts.Dir, func(path string, info os.FileInfo, errInWalk error) error {
+		if errInWalk != nil {
+			accessErr := fmt.Errorf("accessing path '%s': %w", path, errInWalk)
+			if firstEncounteredError == nil {
+				firstEncounteredError = accessErr
+			}
+			warn(opts.OnWarning, "PerformVerify", "Access", accessErr, "Skipping")
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("verification interrupted: %w", err)
+		}
+		if IsInternalEntry(info) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || IsInternalEntry(info) {
+			return nil
+		}
+		if _, _, isBackup := ParseBackupName(info.Name()); isBackup || (rulesFile != "" && CanonicalPath(path) == rulesFile) {
+			return nil
+		}
+		applicable, _ := matchingRules(opts.Rules, info.Name(), opts.Pattern)
+		if len(applicable) == 0 || (opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize) {
+			return nil
+		}
+		if opts.SkipBinary {
+			if binary, err := looksBinary(path); err == nil && binary {
+				return nil
+			}
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			readErr := fmt.Errorf("reading file '%s': %w", path, err)
+			if firstEncounteredError == nil {
+				firstEncounteredError = readErr
+			}
+			warn(opts.OnWarning, "PerformVerify", "Read", readErr, "Skipping")
+			return nil
+		}
+		filesChecked++
+		for _, i := range applicable {
+			for _, m := range FindMatches(content, opts.Rules[i].Rule(false)) {
+				r := opts.Rules[i]
+				violations = append(violations, Violation{Path: path, Line: m.Line, Column: m.Column, Rule: i + 1, Severity: r.Severity, Message: r.Message})
+			}
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return violations, filesChecked, walkErr
+	}
+	return violations, filesChecked, firstEncounteredError
+}
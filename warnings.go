@@ -0,0 +1,32 @@
+package photonsr
+
+import "fmt"
+
+// --- Operation Warnings ---
+
+// Warning is a non-fatal problem met by an operation, such as a file that could not
+// be read and was skipped. The engine never prints warnings itself: each options
+// struct has an OnWarning callback through which the frontend receives them (the CLI
+// prints them to stderr, the wizard lists them on its result screen).
+type Warning struct {
+	Op     string // Operation that met the problem, e.g. "PerformReplacement".
+	Stage  string // Step that failed, e.g. "Read" or "Backup".
+	Err    error  // The problem.
+	Action string // What was done about it, e.g. "Skipping"; may be empty.
+}
+
+// String formats w as the CLI prints it.
+func (w Warning) String() string {
+	s := fmt.Sprintf("Warning (CoreLogic - %s - %s): %v.", w.Op, w.Stage, w.Err)
+	if w.Action != "" {
+		s += " " + w.Action + "."
+	}
+	return s
+}
+
+// warn passes a warning to onWarning, if set.
+func warn(onWarning func(Warning), op, stage string, err error, action string) {
+	if onWarning != nil {
+		onWarning(Warning{Op: op, Stage: stage, Err: err, Action: action})
+	}
+}
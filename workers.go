@@ -0,0 +1,193 @@
+package photonsr
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+)
+
+// --- Worker Pool ---
+
+// Orderings for results produced by the worker pool.
+const (
+	SortByPath       = "path"       // Traversal order, independent of scheduling (default).
+	SortByCompletion = "completion" // The order in which files finished processing.
+)
+
+// Orders in which the worker pool is handed files (ReplaceOptions.Order).
+const (
+	OrderPath     = "path"      // Traversal order (default).
+	OrderSizeDesc = "size-desc" // Largest files first.
+	OrderMtime    = "mtime"     // Most recently modified files first.
+	OrderInode    = "inode"     // Ascending inode number, approximating on-disk layout.
+)
+
+// validOrder reports whether order is a known dispatch order ("" means OrderPath).
+func validOrder(order string) bool {
+	switch order {
+	case "", OrderPath, OrderSizeDesc, OrderMtime, OrderInode:
+		return true
+	}
+	return false
+}
+
+// dispatchOrder returns the indices of infos in the order they should be processed.
+// Ties keep traversal order.
+func dispatchOrder(infos []os.FileInfo, order string) []int {
+	indices := make([]int, len(infos))
+	for i := range indices {
+		indices[i] = i
+	}
+	switch order {
+	case OrderSizeDesc:
+		sort.SliceStable(indices, func(a, b int) bool {
+			return infos[indices[a]].Size() > infos[indices[b]].Size()
+		})
+	case OrderMtime:
+		sort.SliceStable(indices, func(a, b int) bool {
+			return infos[indices[a]].ModTime().After(infos[indices[b]].ModTime())
+		})
+	case OrderInode:
+		sort.SliceStable(indices, func(a, b int) bool {
+			return fileInode(infos[indices[a]]) < fileInode(infos[indices[b]])
+		})
+	}
+	return indices
+}
+
+// validSortBy reports whether sortBy is a known result ordering ("" means SortByPath).
+func validSortBy(sortBy string) bool {
+	return sortBy == "" || sortBy == SortByPath || sortBy == SortByCompletion
+}
+
+// forEachParallel calls work(i) for every i in [0, n) using up to jobs goroutines.
+// done(i) is called on the calling goroutine, one at a time and in completion order,
+// after work(i) has returned, so it may safely touch unsynchronized state.
+// Once ctx is cancelled no further work is started; work already started completes.
+func forEachParallel(ctx context.Context, n, jobs int, work func(i int), done func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > n {
+		jobs = n
+	}
+	if jobs <= 1 {
+		for i := 0; i < n && ctx.Err() == nil; i++ {
+			work(i)
+			done(i)
+		}
+		return
+	}
+
+	indices := make(chan int)
+	completed := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				work(i)
+				completed <- i
+			}
+		}()
+	}
+	go func() {
+	dispatch:
+		for i := 0; i < n; i++ {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		close(indices)
+		wg.Wait()
+		close(completed)
+	}()
+	for i := range completed {
+		done(i)
+	}
+}
+
+// forEachBackup runs work over the backups at paths, in traversal order, with
+// forEachParallel. A backup of a backup (x.bak.bak) is only handled once the backup
+// it would replace (x.bak) has been, as in a serial run. done(i, n) is called like
+// forEachParallel's done, with n the number of backups handled so far.
+// Returns the number of backups handled, fewer than len(paths) if ctx was cancelled.
+func forEachBackup(ctx context.Context, paths []string, jobs int, work func(i int), done func(i, n int)) int {
+	index := make(map[string]int, len(paths))
+	var waves [][]int
+	wave := make([]int, len(paths))
+	for i, path := range paths {
+		if j, ok := index[backupOriginal(path)]; ok {
+			wave[i] = wave[j] + 1
+		}
+		index[path] = i
+		if wave[i] == len(waves) {
+			waves = append(waves, nil)
+		}
+		waves[wave[i]] = append(waves[wave[i]], i)
+	}
+	handled := 0
+	for _, w := range waves {
+		if ctx.Err() != nil {
+			break
+		}
+		forEachParallel(ctx, len(w), jobs, func(k int) { work(w[k]) }, func(k int) {
+			handled++
+			done(w[k], handled)
+		})
+	}
+	return handled
+}
+
+// memoryBudget limits the bytes of file content held in memory by concurrent
+// workers. A nil *memoryBudget imposes no limit.
+type memoryBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int64
+	used  int64
+}
+
+// newMemoryBudget returns a budget of limit bytes, or nil if limit is not positive.
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	b := &memoryBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// fits reports whether n bytes can ever be acquired from the budget.
+func (b *memoryBudget) fits(n int64) bool {
+	return b == nil || n <= b.limit
+}
+
+// acquire blocks until n bytes of the budget are free and reserves them.
+// n must satisfy fits.
+func (b *memoryBudget) acquire(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	for b.used+n > b.limit {
+		b.cond.Wait()
+	}
+	b.used += n
+	b.mu.Unlock()
+}
+
+// release returns n bytes acquired earlier to the budget.
+func (b *memoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
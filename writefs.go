@@ -0,0 +1,252 @@
+package photonsr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- File System Writes ---
+
+// Every change PhotonSR makes to the file system goes through fsys. -read-only
+// swaps it for a ReadOnlyFS, which changes nothing and records what would have
+// changed instead, so that an exploratory run cannot modify a file whatever code
+// path it takes, bugs included. Reading goes through the os package directly.
+
+// WriteFS performs the changes to the file system, with the semantics of the os
+// functions of the same names. CreateTemp also returns the name of the file, which
+// callers must use instead of the file's Name.
+type WriteFS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) // To write; reading uses os.
+	CreateTemp(dir, pattern string) (*os.File, string, error)
+	MkdirTemp(dir, pattern string) (string, error)
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(name string, perm os.FileMode) error
+	Rename(from, to string) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Chmod(name string, mode os.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	Lchown(name string, uid, gid int) error
+	Link(oldname, newname string) error
+	Symlink(oldname, newname string) error
+	Setxattr(name, attr string, data []byte) error
+}
+
+// FS is the WriteFS of the process.
+var FS WriteFS = osFS{}
+
+// osFS is the WriteFS that changes the file system.
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) CreateTemp(dir, pattern string) (*os.File, string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, f.Name(), nil
+}
+
+func (osFS) MkdirTemp(dir, pattern string) (string, error)     { return os.MkdirTemp(dir, pattern) }
+func (osFS) Mkdir(name string, perm os.FileMode) error         { return os.Mkdir(name, perm) }
+func (osFS) MkdirAll(name string, perm os.FileMode) error      { return os.MkdirAll(name, perm) }
+func (osFS) Rename(from, to string) error                      { return os.Rename(from, to) }
+func (osFS) Remove(name string) error                          { return os.Remove(name) }
+func (osFS) RemoveAll(name string) error                       { return os.RemoveAll(name) }
+func (osFS) Chmod(name string, mode os.FileMode) error         { return os.Chmod(name, mode) }
+func (osFS) Chtimes(name string, atime, mtime time.Time) error { return os.Chtimes(name, atime, mtime) }
+func (osFS) Lchown(name string, uid, gid int) error            { return os.Lchown(name, uid, gid) }
+func (osFS) Link(oldname, newname string) error                { return os.Link(oldname, newname) }
+func (osFS) Symlink(oldname, newname string) error             { return os.Symlink(oldname, newname) }
+func (osFS) Setxattr(name, attr string, data []byte) error     { return setxattr(name, attr, data) }
+
+// IntendedWrite is a change that a read-only run did not make.
+type IntendedWrite struct {
+	Op   string `json:"op"`             // "write", "rename", "remove", "mkdir", "chmod", "chtimes", "chown", "link", "symlink" or "xattr".
+	Path string `json:"path"`           // Path changed.
+	From string `json:"from,omitempty"` // For "rename", "link" and "symlink": the source or link target.
+}
+
+// String describes w for the CLI, e.g. "rename a.txt.bak -> a.txt".
+func (w IntendedWrite) String() string {
+	if w.From != "" {
+		return fmt.Sprintf("%s %s -> %s", w.Op, w.From, w.Path)
+	}
+	return w.Op + " " + w.Path
+}
+
+// ReadOnlyFS is the WriteFS of -read-only. Files opened to be written are the null
+// device; temporary files and directories are only names, which later changes of
+// them are not recorded under. It is safe for concurrent use.
+type ReadOnlyFS struct {
+	mu     sync.Mutex
+	seq    int
+	temps  map[string]bool // Temporary names handed out, which do not exist.
+	writes []IntendedWrite
+	seen   map[IntendedWrite]bool
+}
+
+// NewReadOnlyFS returns an empty ReadOnlyFS.
+func NewReadOnlyFS() *ReadOnlyFS {
+	return &ReadOnlyFS{temps: map[string]bool{}, seen: map[IntendedWrite]bool{}}
+}
+
+// Intended returns the changes recorded so far, in order, each once.
+func (r *ReadOnlyFS) Intended() []IntendedWrite {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]IntendedWrite(nil), r.writes...)
+}
+
+// temp reports whether name is, or is inside, a temporary name. The caller must
+// hold r.mu.
+func (r *ReadOnlyFS) temp(name string) bool {
+	for p := filepath.Clean(name); ; p = filepath.Dir(p) {
+		if r.temps[p] {
+			return true
+		}
+		if filepath.Dir(p) == p {
+			return false
+		}
+	}
+}
+
+// record notes the change w, unless it is to a temporary name.
+func (r *ReadOnlyFS) record(w IntendedWrite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.temp(w.Path) {
+		return
+	}
+	if abs, err := filepath.Abs(w.Path); err == nil {
+		w.Path = abs
+	}
+	if !r.seen[w] {
+		r.seen[w] = true
+		r.writes = append(r.writes, w)
+	}
+}
+
+// tempName returns a new temporary name in dir for pattern, as os.CreateTemp would.
+func (r *ReadOnlyFS) tempName(dir, pattern string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	prefix, suffix, found := strings.Cut(pattern, "*")
+	if !found {
+		prefix, suffix = pattern, ""
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%sread-only-%d%s", prefix, r.seq, suffix))
+	r.temps[name] = true
+	return name
+}
+
+func (r *ReadOnlyFS) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) == 0 {
+		return os.OpenFile(name, flag, perm)
+	}
+	r.record(IntendedWrite{Op: "write", Path: name})
+	return os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+}
+
+func (r *ReadOnlyFS) CreateTemp(dir, pattern string) (*os.File, string, error) {
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, r.tempName(dir, pattern), nil
+}
+
+func (r *ReadOnlyFS) MkdirTemp(dir, pattern string) (string, error) {
+	return r.tempName(dir, pattern), nil
+}
+
+func (r *ReadOnlyFS) Mkdir(name string, perm os.FileMode) error {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return os.ErrExist
+	}
+	r.record(IntendedWrite{Op: "mkdir", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) MkdirAll(name string, perm os.FileMode) error {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return nil
+	}
+	r.record(IntendedWrite{Op: "mkdir", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) Rename(from, to string) error {
+	r.mu.Lock()
+	fromTemp := r.temp(from)
+	r.mu.Unlock()
+	if fromTemp {
+		r.record(IntendedWrite{Op: "write", Path: to})
+	} else {
+		r.record(IntendedWrite{Op: "rename", Path: to, From: from})
+	}
+	return nil
+}
+
+func (r *ReadOnlyFS) Remove(name string) error {
+	r.record(IntendedWrite{Op: "remove", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) RemoveAll(name string) error {
+	r.record(IntendedWrite{Op: "remove", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) Chmod(name string, mode os.FileMode) error {
+	r.record(IntendedWrite{Op: "chmod", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) Chtimes(name string, atime, mtime time.Time) error {
+	r.record(IntendedWrite{Op: "chtimes", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) Lchown(name string, uid, gid int) error {
+	r.record(IntendedWrite{Op: "chown", Path: name})
+	return nil
+}
+
+func (r *ReadOnlyFS) Link(oldname, newname string) error {
+	r.record(IntendedWrite{Op: "link", Path: newname, From: oldname})
+	return nil
+}
+
+func (r *ReadOnlyFS) Symlink(oldname, newname string) error {
+	r.record(IntendedWrite{Op: "symlink", Path: newname, From: oldname})
+	return nil
+}
+
+func (r *ReadOnlyFS) Setxattr(name, attr string, data []byte) error {
+	r.record(IntendedWrite{Op: "xattr", Path: name})
+	return nil
+}
+
+// ReadOnlyMessages describes the changes a read-only run discarded.
+func ReadOnlyMessages(writes []IntendedWrite) []string {
+	if len(writes) == 0 {
+		return []string{"Read-only run: nothing was written, and nothing would have been."}
+	}
+	messages := []string{fmt.Sprintf("Read-only run: nothing was written (not even the run's history or undo records); %d change(s) discarded:", len(writes))}
+	for _, w := range writes {
+		messages = append(messages, "  - "+w.String())
+	}
+	return messages
+}